@@ -5,6 +5,8 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"english-at-lima-cms/internal/handlers"
+
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
@@ -13,6 +15,7 @@ func TestPublicPageContent(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	router := gin.Default()
 
+	router.SetFuncMap(handlers.TemplateFuncs())
 	router.LoadHTMLGlob("templates/*")
 
 	router.GET("/public", func(c *gin.Context) {
@@ -0,0 +1,338 @@
+// Package anki arma mazos de Anki (.apkg) con las frases: un ZIP con la
+// colección en SQLite (collection.anki2, esquema 11), los audios numerados y
+// el índice "media" que los relaciona con su nombre.
+package anki
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Los ids salen de la frase y no de la hora: volver a exportar la misma frase
+// da la misma nota, y Anki la actualiza en vez de duplicarla
+const (
+	ModelID    = 1729000000001 // tipo de nota «English at Lima»
+	noteIDBase = 1500000000000
+	cardIDBase = 1550000000000
+	deckIDBase = 1600000000000
+
+	// modelMod fija la fecha del tipo de nota: sólo cambia si cambian sus
+	// campos o plantillas, para que Anki no lo vea modificado en cada import
+	modelMod = 1729000000
+)
+
+// Note es una frase lista para el mazo
+type Note struct {
+	SentenceID int
+	English    string
+	Spanish    string
+	IPA        string
+	Audio      string // nombre del audio dentro del paquete ("" si no tiene)
+	Deck       string // nombre completo del mazo; "::" separa los submazos
+	Tags       []string
+	// Anki sólo sobrescribe una nota existente si la importada es más reciente
+	Modified time.Time
+}
+
+// GUID es el identificador con el que Anki reconoce la nota al reimportarla
+func (n Note) GUID() string { return "eal-sentence-" + strconv.Itoa(n.SentenceID) }
+
+// Media es un archivo adjunto; se abre al escribirlo en el paquete
+type Media struct {
+	Name string
+	Open func() (io.ReadCloser, error)
+}
+
+// Plantillas: inglés → español y español → inglés
+var templates = []struct{ name, qfmt, afmt string }{
+	{
+		"Inglés → Español",
+		`<div class="en">{{English}}</div>{{#IPA}}<div class="ipa">{{IPA}}</div>{{/IPA}}{{Audio}}`,
+		`{{FrontSide}}<hr id="answer"><div class="es">{{Spanish}}</div>`,
+	},
+	{
+		"Español → Inglés",
+		`<div class="es">{{Spanish}}</div>`,
+		`{{FrontSide}}<hr id="answer"><div class="en">{{English}}</div>{{#IPA}}<div class="ipa">{{IPA}}</div>{{/IPA}}{{Audio}}`,
+	},
+}
+
+var fields = []string{"English", "Spanish", "IPA", "Audio"}
+
+const css = `.card { font-family: Arial, sans-serif; font-size: 24px; text-align: center; color: #111; background: #fff; }
+.ipa { color: #666; font-size: 18px; margin-top: 0.5em; }
+.es { color: #1e40af; }`
+
+// Write escribe el paquete en w. La colección se arma entera antes de
+// escribir nada; los audios se copian después uno a uno. Un audio que no se
+// puede abrir se salta (la tarjeta queda sin sonido) y se devuelve su nombre.
+func Write(w io.Writer, notes []Note, media []Media, now time.Time) ([]string, error) {
+	collection, err := buildCollection(notes, now)
+	if err != nil {
+		return nil, err
+	}
+	zw := zip.NewWriter(w)
+	f, err := zw.Create("collection.anki2")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Write(collection); err != nil {
+		return nil, err
+	}
+
+	index := map[string]string{}
+	var skipped []string
+	for _, m := range media {
+		rc, err := m.Open()
+		if err != nil {
+			skipped = append(skipped, m.Name)
+			continue
+		}
+		// Dentro del paquete los archivos se llaman 0, 1, 2…
+		num := strconv.Itoa(len(index))
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: num, Method: zip.Store})
+		if err == nil {
+			_, err = io.Copy(f, rc)
+		}
+		rc.Close()
+		if err != nil {
+			return skipped, fmt.Errorf("audio %s: %w", m.Name, err)
+		}
+		index[num] = m.Name
+	}
+
+	f, err = zw.Create("media")
+	if err != nil {
+		return skipped, err
+	}
+	if err := json.NewEncoder(f).Encode(index); err != nil {
+		return skipped, err
+	}
+	return skipped, zw.Close()
+}
+
+// buildCollection crea la base SQLite con el esquema 11 de Anki
+func buildCollection(notes []Note, now time.Time) ([]byte, error) {
+	db := &database{}
+	col := db.createTable("col", schemaCol)
+	notesT := db.createTable("notes", schemaNotes)
+	notesT.createIndex("ix_notes_usn", "CREATE INDEX ix_notes_usn on notes (usn)", 4)
+	notesT.createIndex("ix_notes_csum", "CREATE INDEX ix_notes_csum on notes (csum)", 8)
+	cards := db.createTable("cards", schemaCards)
+	cards.createIndex("ix_cards_usn", "CREATE INDEX ix_cards_usn on cards (usn)", 5)
+	cards.createIndex("ix_cards_nid", "CREATE INDEX ix_cards_nid on cards (nid)", 1)
+	cards.createIndex("ix_cards_sched", "CREATE INDEX ix_cards_sched on cards (did, queue, due)", 2, 7, 8)
+	revlog := db.createTable("revlog", schemaRevlog)
+	revlog.createIndex("ix_revlog_usn", "CREATE INDEX ix_revlog_usn on revlog (usn)", 2)
+	revlog.createIndex("ix_revlog_cid", "CREATE INDEX ix_revlog_cid on revlog (cid)", 1)
+	db.createTable("graves", schemaGraves)
+
+	decks := map[string]int64{}
+	for i, n := range notes {
+		did := addDeck(decks, n.Deck)
+		nid := int64(noteIDBase + n.SentenceID)
+		audio := ""
+		if n.Audio != "" {
+			audio = "[sound:" + n.Audio + "]"
+		}
+		flds := strings.Join([]string{html.EscapeString(n.English), html.EscapeString(n.Spanish), html.EscapeString(n.IPA), audio}, "\x1f")
+		mod := n.Modified.Unix()
+		notesT.insert(nid, nil, n.GUID(), int64(ModelID), mod, -1, tagString(n.Tags), flds, n.English, checksum(n.English), 0, "")
+		for ord := range templates {
+			cid := int64(cardIDBase + 2*n.SentenceID + ord)
+			// Tarjeta nueva: due es la posición en la cola de nuevas
+			cards.insert(cid, nil, nid, did, ord, mod, -1, 0, 0, i+1, 0, 0, 0, 0, 0, 0, 0, 0, "")
+		}
+	}
+
+	conf, models, deckJSON, dconf, err := colJSON(decks, now)
+	if err != nil {
+		return nil, err
+	}
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	col.insert(1, nil, day.Unix(), now.UnixMilli(), now.UnixMilli(), 11, 0, 0, 0, conf, models, deckJSON, dconf, "{}")
+	return db.bytes()
+}
+
+// addDeck registra el mazo y sus padres («A::B» necesita «A») y devuelve su id
+func addDeck(decks map[string]int64, name string) int64 {
+	parts := strings.Split(name, "::")
+	for i := range parts {
+		prefix := strings.Join(parts[:i+1], "::")
+		if _, ok := decks[prefix]; !ok {
+			decks[prefix] = deckID(prefix)
+		}
+	}
+	return decks[name]
+}
+
+// deckID es fijo para cada nombre de mazo
+func deckID(name string) int64 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name))
+	return deckIDBase + int64(h.Sum32())
+}
+
+// tagString es el formato de etiquetas de Anki: separadas y rodeadas por
+// espacios, sin espacios dentro de cada una
+func tagString(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	clean := make([]string, len(tags))
+	for i, t := range tags {
+		clean[i] = strings.Join(strings.Fields(t), "_")
+	}
+	return " " + strings.Join(clean, " ") + " "
+}
+
+// checksum son los primeros 32 bits del SHA-1 del primer campo, como Anki
+// los usa para detectar duplicados
+func checksum(field string) int64 {
+	sum := sha1.Sum([]byte(field))
+	return int64(binary.BigEndian.Uint32(sum[:4]))
+}
+
+func colJSON(decks map[string]int64, now time.Time) (conf, models, deckJSON, dconf string, err error) {
+	// Toda colección tiene el mazo 1 «Default»; el primero de los nuestros es
+	// el mazo activo
+	first := int64(0)
+	deckMap := map[string]interface{}{}
+	all := map[string]int64{"Default": 1}
+	for name, id := range decks {
+		if first == 0 || id < first {
+			first = id
+		}
+		all[name] = id
+	}
+	if first == 0 {
+		first = 1
+	}
+	for name, id := range all {
+		deckMap[strconv.FormatInt(id, 10)] = map[string]interface{}{
+			"id": id, "name": name, "desc": "", "conf": 1, "dyn": 0, "collapsed": false, "browserCollapsed": false,
+			"extendNew": 0, "extendRev": 50, "mod": now.Unix(), "usn": -1,
+			"newToday": []int{0, 0}, "revToday": []int{0, 0}, "lrnToday": []int{0, 0}, "timeToday": []int{0, 0},
+		}
+	}
+
+	flds := make([]map[string]interface{}, len(fields))
+	for i, name := range fields {
+		flds[i] = map[string]interface{}{"name": name, "ord": i, "font": "Arial", "size": 20, "rtl": false, "sticky": false, "media": []string{}}
+	}
+	tmpls := make([]map[string]interface{}, len(templates))
+	req := make([][]interface{}, len(templates))
+	for i, t := range templates {
+		tmpls[i] = map[string]interface{}{"name": t.name, "ord": i, "qfmt": t.qfmt, "afmt": t.afmt, "bqfmt": "", "bafmt": "", "did": nil}
+		// Cada tarjeta necesita el campo de su pregunta: English o Spanish
+		req[i] = []interface{}{i, "all", []int{i}}
+	}
+	model := map[string]interface{}{
+		"id": ModelID, "name": "English at Lima — Frase", "type": 0, "mod": modelMod, "usn": -1,
+		"sortf": 0, "did": first, "flds": flds, "tmpls": tmpls, "req": req, "css": css, "tags": []string{}, "vers": []int{},
+		"latexPre":  "\\documentclass[12pt]{article}\n\\special{papersize=3in,5in}\n\\usepackage[utf8]{inputenc}\n\\usepackage{amssymb,amsmath}\n\\pagestyle{empty}\n\\setlength{\\parindent}{0in}\n\\begin{document}\n",
+		"latexPost": "\\end{document}",
+	}
+
+	parts := []interface{}{
+		map[string]interface{}{
+			"activeDecks": []int64{first}, "curDeck": first, "curModel": strconv.Itoa(ModelID), "nextPos": 1,
+			"addToCur": true, "collapseTime": 1200, "dueCounts": true, "estTimes": true, "newBury": true,
+			"newSpread": 0, "sortBackwards": false, "sortType": "noteFld", "timeLim": 0,
+		},
+		map[string]interface{}{strconv.Itoa(ModelID): model},
+		deckMap,
+		map[string]interface{}{"1": map[string]interface{}{
+			"id": 1, "name": "Default", "mod": 0, "usn": 0, "maxTaken": 60, "autoplay": true, "timer": 0, "replayq": true,
+			"new":   map[string]interface{}{"delays": []int{1, 10}, "ints": []int{1, 4, 7}, "initialFactor": 2500, "order": 1, "perDay": 20, "bury": true, "separate": true},
+			"rev":   map[string]interface{}{"perDay": 200, "ease4": 1.3, "fuzz": 0.05, "ivlFct": 1, "maxIvl": 36500, "minSpace": 1, "bury": true},
+			"lapse": map[string]interface{}{"delays": []int{10}, "leechAction": 0, "leechFails": 8, "minInt": 1, "mult": 0},
+		}},
+	}
+	out := make([]string, len(parts))
+	for i, p := range parts {
+		b, err := json.Marshal(p)
+		if err != nil {
+			return "", "", "", "", err
+		}
+		out[i] = string(b)
+	}
+	return out[0], out[1], out[2], out[3], nil
+}
+
+// Esquema 11 de Anki, tal como lo escriben Anki 2.1 y los generadores de mazos
+const (
+	schemaCol = `CREATE TABLE col (
+    id              integer primary key,
+    crt             integer not null,
+    mod             integer not null,
+    scm             integer not null,
+    ver             integer not null,
+    dty             integer not null,
+    usn             integer not null,
+    ls              integer not null,
+    conf            text not null,
+    models          text not null,
+    decks           text not null,
+    dconf           text not null,
+    tags            text not null
+)`
+	schemaNotes = `CREATE TABLE notes (
+    id              integer primary key,
+    guid            text not null,
+    mid             integer not null,
+    mod             integer not null,
+    usn             integer not null,
+    tags            text not null,
+    flds            text not null,
+    sfld            integer not null,
+    csum            integer not null,
+    flags           integer not null,
+    data            text not null
+)`
+	schemaCards = `CREATE TABLE cards (
+    id              integer primary key,
+    nid             integer not null,
+    did             integer not null,
+    ord             integer not null,
+    mod             integer not null,
+    usn             integer not null,
+    type            integer not null,
+    queue           integer not null,
+    due             integer not null,
+    ivl             integer not null,
+    factor          integer not null,
+    reps            integer not null,
+    lapses          integer not null,
+    left            integer not null,
+    odue            integer not null,
+    odid            integer not null,
+    flags           integer not null,
+    data            text not null
+)`
+	schemaRevlog = `CREATE TABLE revlog (
+    id              integer primary key,
+    cid             integer not null,
+    usn             integer not null,
+    ease            integer not null,
+    ivl             integer not null,
+    lastIvl         integer not null,
+    factor          integer not null,
+    time            integer not null,
+    type            integer not null
+)`
+	schemaGraves = `CREATE TABLE graves (
+    usn             integer not null,
+    oid             integer not null,
+    type            integer not null
+)`
+)
@@ -0,0 +1,188 @@
+package anki
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVarint(t *testing.T) {
+	tests := []struct {
+		v    uint64
+		want string
+	}{
+		{0, "00"},
+		{127, "7f"},
+		{128, "8100"},
+		{16383, "ff7f"},
+		{16384, "818000"},
+		{1<<56 - 1, "ffffffffffffff7f"},
+		{1 << 63, "c08080808080808000"}, // el noveno byte lleva 8 bits
+	}
+	for _, tt := range tests {
+		if got := hex.EncodeToString(appendVarint(nil, tt.v)); got != tt.want {
+			t.Errorf("varint(%d) = %s, esperaba %s", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestRecord(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []interface{}
+		want   string
+	}{
+		{"Constantes 0 y 1", []interface{}{0, 1}, "030809"},
+		{"Negativo de un byte", []interface{}{-1}, "0201ff"},
+		{"Entero de 6 bytes", []interface{}{int64(1) << 40}, "0205010000000000"},
+		{"NULL y texto de 2 bytes", []interface{}{nil, "ñ"}, "030011c3b1"},
+		{"Blob", []interface{}{[]byte{0xAB}}, "020eab"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := hex.EncodeToString(record(tt.values))
+			if got != tt.want {
+				t.Errorf("record = %s, esperaba %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func sampleNotes() []Note {
+	mod := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	return []Note{
+		{SentenceID: 7, English: "Can I have the bill?", Spanish: "¿Me trae la cuenta?", IPA: "/kæn aɪ/", Audio: "english-at-lima-7.mp3",
+			Deck: "English at Lima::A2", Tags: []string{"restaurant", "small talk"}, Modified: mod},
+		{SentenceID: 9, English: "Fish & <chips>", Spanish: "Pescado", Deck: "English at Lima::A1", Modified: mod},
+	}
+}
+
+func readZip(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(b)
+	}
+	return files
+}
+
+func TestWrite(t *testing.T) {
+	media := []Media{
+		{Name: "english-at-lima-7.mp3", Open: func() (io.ReadCloser, error) { return io.NopCloser(strings.NewReader("ID3 audio")), nil }},
+		{Name: "english-at-lima-8.mp3", Open: func() (io.ReadCloser, error) { return nil, errors.New("no existe") }},
+	}
+	var buf bytes.Buffer
+	skipped, err := Write(&buf, sampleNotes(), media, time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(skipped) != 1 || skipped[0] != "english-at-lima-8.mp3" {
+		t.Errorf("saltados: %v", skipped)
+	}
+	files := readZip(t, buf.Bytes())
+	if files["0"] != "ID3 audio" || strings.TrimSpace(files["media"]) != `{"0":"english-at-lima-7.mp3"}` {
+		t.Errorf("media = %q, 0 = %q", files["media"], files["0"])
+	}
+	if !strings.HasPrefix(files["collection.anki2"], "SQLite format 3\x00") {
+		t.Error("collection.anki2 no es una base SQLite")
+	}
+}
+
+// Exportar otro día da la misma colección salvo la fecha: notas, tarjetas y
+// mazos conservan sus ids y Anki actualiza en vez de duplicar
+func TestStableIDs(t *testing.T) {
+	a, err := buildCollection(sampleNotes(), time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := buildCollection(sampleNotes(), time.Date(2027, 3, 2, 8, 0, 0, 0, time.UTC))
+	if len(a) != len(b) {
+		t.Fatalf("tamaños distintos: %d y %d", len(a), len(b))
+	}
+	for _, want := range []string{"eal-sentence-7", "eal-sentence-9"} {
+		if !bytes.Contains(a, []byte(want)) || !bytes.Contains(b, []byte(want)) {
+			t.Errorf("falta el GUID %s", want)
+		}
+	}
+	if deckID("English at Lima::A1") != deckID("English at Lima::A1") || deckID("English at Lima::A1") == deckID("English at Lima::A2") {
+		t.Error("ids de mazo inestables o repetidos")
+	}
+}
+
+func TestTagString(t *testing.T) {
+	if got := tagString([]string{"small talk", "food", "nivel::A1"}); got != " small_talk food nivel::A1 " {
+		t.Errorf("tagString = %q", got)
+	}
+	if tagString(nil) != "" {
+		t.Error("sin etiquetas debe quedar vacío")
+	}
+}
+
+// Abre la colección con el sqlite3 de verdad, si está instalado
+func TestCollectionWithSQLite(t *testing.T) {
+	bin, err := exec.LookPath("sqlite3")
+	if err != nil {
+		t.Skip("sqlite3 no está instalado")
+	}
+	notes := sampleNotes()
+	// Suficientes notas para b-trees de varios niveles y un campo desbordado
+	for i := 100; i < 3100; i++ {
+		notes = append(notes, Note{SentenceID: i, English: "Sentence " + strings.Repeat("x", i%40), Spanish: "Frase", Deck: "English at Lima::B1"})
+	}
+	notes[2].Spanish = strings.Repeat("larga ", 2000)
+	data, err := buildCollection(notes, time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "collection.anki2")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	query := func(sql string) string {
+		out, err := exec.Command(bin, path, sql).CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: %v\n%s", sql, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	tests := []struct{ sql, want string }{
+		{"pragma integrity_check", "ok"},
+		{"select ver from col", "11"},
+		{"select count(*) from notes", "3002"},
+		{"select count(*) from cards", "6004"},
+		// Los campos van separados por el carácter 0x1f
+		{"select guid || ' ' || replace(flds, char(31), '|') || ' [' || tags || ']' from notes where id = 1500000000007",
+			"eal-sentence-7 Can I have the bill?|¿Me trae la cuenta?|/kæn aɪ/|[sound:english-at-lima-7.mp3] [ restaurant small_talk ]"},
+		{"select replace(flds, char(31), '|') from notes where guid = 'eal-sentence-9'", "Fish &amp; &lt;chips&gt;|Pescado||"},
+		{"select length(flds) from notes where id = 1500000000100", "12032"},
+		{"select count(*) from cards indexed by ix_cards_nid where nid = 1500000000009", "2"},
+		{"select json_extract(decks, '$.1.name') from col", "Default"},
+		{"select json_extract(models, '$.1729000000001.flds[3].name') from col", "Audio"},
+	}
+	for _, tt := range tests {
+		if got := query(tt.sql); got != tt.want {
+			t.Errorf("%s\n  = %q\n  esperaba %q", tt.sql, got, tt.want)
+		}
+	}
+	for _, deck := range []string{"English at Lima", "English at Lima::A1", "English at Lima::A2", "English at Lima::B1"} {
+		if got := query("select count(*) from json_each((select decks from col)) where json_extract(value, '$.name') = '" + deck + "'"); got != "1" {
+			t.Errorf("mazo %s: %s", deck, got)
+		}
+	}
+}
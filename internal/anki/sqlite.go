@@ -0,0 +1,485 @@
+package anki
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// Escritor mínimo del formato de archivo de SQLite 3
+// (https://www.sqlite.org/fileformat.html): crea una base nueva con tablas,
+// índices de columnas enteras y filas, sin páginas libres ni journal. Es lo
+// justo para el collection.anki2 del paquete y evita depender de cgo o de
+// un SQLite entero para escribir un archivo que sólo se genera.
+
+const (
+	pageSize = 4096
+
+	// Tipos de página de un b-tree
+	pageIndexInterior = 0x02
+	pageTableInterior = 0x05
+	pageIndexLeaf     = 0x0A
+	pageTableLeaf     = 0x0D
+
+	// Umbrales de carga local antes de desbordar (sección 1.6 del formato)
+	maxLocalTable = pageSize - 35
+	maxLocalIndex = (pageSize-12)*64/255 - 23
+	minLocal      = (pageSize-12)*32/255 - 23
+)
+
+type sqlRow struct {
+	rowid  int64
+	values []interface{}
+}
+
+type sqlIndex struct {
+	name, sql string
+	cols      []int
+}
+
+type sqlTable struct {
+	name, sql string
+	rows      []sqlRow
+	indexes   []sqlIndex
+}
+
+// database acumula el esquema y las filas; bytes() arma el archivo
+type database struct {
+	tables []*sqlTable
+}
+
+func (d *database) createTable(name, sql string) *sqlTable {
+	t := &sqlTable{name: name, sql: sql}
+	d.tables = append(d.tables, t)
+	return t
+}
+
+// createIndex declara un índice sobre columnas enteras de la tabla
+func (t *sqlTable) createIndex(name, sql string, cols ...int) {
+	t.indexes = append(t.indexes, sqlIndex{name: name, sql: sql, cols: cols})
+}
+
+// insert añade una fila. Los valores pueden ser nil, int, int64, string o
+// []byte; la columna INTEGER PRIMARY KEY va como nil porque es el rowid.
+func (t *sqlTable) insert(rowid int64, values ...interface{}) {
+	t.rows = append(t.rows, sqlRow{rowid: rowid, values: values})
+}
+
+// bytes escribe la base completa: la página 1 lleva la cabecera y
+// sqlite_master; cada tabla e índice tiene su propio b-tree detrás
+func (d *database) bytes() ([]byte, error) {
+	p := &pager{}
+	p.alloc() // página 1, se rellena al final
+	var master []sqlRow
+	add := func(kind, name, table string, root uint32, sql string) {
+		master = append(master, sqlRow{rowid: int64(len(master) + 1), values: []interface{}{kind, name, table, int64(root), sql}})
+	}
+	for _, t := range d.tables {
+		sort.Slice(t.rows, func(i, j int) bool { return t.rows[i].rowid < t.rows[j].rowid })
+		for i := 1; i < len(t.rows); i++ {
+			if t.rows[i].rowid == t.rows[i-1].rowid {
+				return nil, fmt.Errorf("tabla %s: rowid %d repetido", t.name, t.rows[i].rowid)
+			}
+		}
+		root := p.tableTree(t.rows)
+		add("table", t.name, t.name, root, t.sql)
+		for _, ix := range t.indexes {
+			entries, err := indexEntries(t, ix)
+			if err != nil {
+				return nil, err
+			}
+			add("index", ix.name, t.name, p.indexTree(entries), ix.sql)
+		}
+	}
+
+	var cells [][]byte
+	for _, r := range master {
+		cells = append(cells, p.tableLeafCell(r.rowid, record(r.values)))
+	}
+	if !fits(100+8, len(cells), totalLen(cells)) {
+		return nil, errors.New("el esquema no cabe en la primera página")
+	}
+	writePage(p.page(1), 100, pageTableLeaf, cells, 0)
+	writeHeader(p.page(1), len(p.pages))
+	return slices.Concat(p.pages...), nil
+}
+
+// writeHeader rellena los 100 bytes de cabecera del archivo
+func writeHeader(b []byte, pages int) {
+	be := binary.BigEndian
+	copy(b, "SQLite format 3\x00")
+	be.PutUint16(b[16:], pageSize)
+	b[18], b[19] = 1, 1              // versiones de escritura y lectura: journal clásico
+	b[21], b[22], b[23] = 64, 32, 32 // fracciones de carga fijas del formato
+	be.PutUint32(b[24:], 1)          // contador de cambios
+	be.PutUint32(b[28:], uint32(pages))
+	be.PutUint32(b[40:], 1)       // cookie del esquema
+	be.PutUint32(b[44:], 4)       // formato de esquema 4
+	be.PutUint32(b[56:], 1)       // texto en UTF-8
+	be.PutUint32(b[92:], 1)       // el tamaño en páginas vale para este contador
+	be.PutUint32(b[96:], 3045000) // SQLITE_VERSION_NUMBER de referencia
+}
+
+// --- Páginas ---
+
+type pager struct {
+	pages [][]byte
+}
+
+func (p *pager) alloc() uint32 {
+	p.pages = append(p.pages, make([]byte, pageSize))
+	return uint32(len(p.pages))
+}
+
+func (p *pager) page(n uint32) []byte { return p.pages[n-1] }
+
+// fits indica si n celdas que suman size bytes caben tras una cabecera de hdr bytes
+func fits(hdr, n, size int) bool { return hdr+2*n+size <= pageSize }
+
+func totalLen(cells [][]byte) int {
+	n := 0
+	for _, c := range cells {
+		n += len(c)
+	}
+	return n
+}
+
+// writePage coloca las celdas desde el final de la página y sus punteros
+// tras la cabecera. off es 100 en la página 1 y 0 en el resto.
+func writePage(b []byte, off int, kind byte, cells [][]byte, right uint32) {
+	hdr := 8
+	if kind == pageTableInterior || kind == pageIndexInterior {
+		hdr = 12
+		binary.BigEndian.PutUint32(b[off+8:], right)
+	}
+	b[off] = kind
+	binary.BigEndian.PutUint16(b[off+3:], uint16(len(cells)))
+	end := pageSize
+	for i, c := range cells {
+		end -= len(c)
+		copy(b[end:], c)
+		binary.BigEndian.PutUint16(b[off+hdr+2*i:], uint16(end))
+	}
+	binary.BigEndian.PutUint16(b[off+5:], uint16(end))
+}
+
+// localSize es la parte de la carga que queda en la página; el resto va a
+// páginas de desbordamiento
+func localSize(n, maxLocal int) int {
+	if n <= maxLocal {
+		return n
+	}
+	k := minLocal + (n-minLocal)%(pageSize-4)
+	if k <= maxLocal {
+		return k
+	}
+	return minLocal
+}
+
+// payloadSize es lo que ocupa en la celda una carga de n bytes
+func payloadSize(n, maxLocal int) int {
+	size := varintLen(uint64(n)) + localSize(n, maxLocal)
+	if n > maxLocal {
+		size += 4
+	}
+	return size
+}
+
+// appendPayload escribe la longitud de la carga y la carga
+func (p *pager) appendPayload(b, payload []byte, maxLocal int) []byte {
+	return p.appendLocal(appendVarint(b, uint64(len(payload))), payload, maxLocal)
+}
+
+// appendLocal escribe la parte local de la carga y, si no cabe entera, el
+// puntero a la cadena de páginas de desbordamiento con el resto
+func (p *pager) appendLocal(b, payload []byte, maxLocal int) []byte {
+	local := localSize(len(payload), maxLocal)
+	b = append(b, payload[:local]...)
+	if local == len(payload) {
+		return b
+	}
+	rest := payload[local:]
+	first := p.alloc()
+	b = binary.BigEndian.AppendUint32(b, first)
+	for pg := first; ; {
+		n := min(len(rest), pageSize-4)
+		copy(p.page(pg)[4:], rest[:n])
+		rest = rest[n:]
+		if len(rest) == 0 {
+			return b
+		}
+		next := p.alloc()
+		binary.BigEndian.PutUint32(p.page(pg), next)
+		pg = next
+	}
+}
+
+// Celda de hoja de tabla: longitud de la carga, rowid y carga
+func tableLeafSize(rowid int64, rec []byte) int {
+	return varintLen(uint64(rowid)) + payloadSize(len(rec), maxLocalTable)
+}
+
+func (p *pager) tableLeafCell(rowid int64, rec []byte) []byte {
+	b := appendVarint(nil, uint64(len(rec)))
+	b = appendVarint(b, uint64(rowid))
+	return p.appendLocal(b, rec, maxLocalTable)
+}
+
+// --- B-trees ---
+
+// treeKey separa dos hijos de una página interior: en tablas el mayor rowid
+// del hijo izquierdo; en índices, una entrada completa que sube desde las hojas
+type treeKey struct {
+	rowid int64
+	rec   []byte
+}
+
+// tableTree guarda las filas (ya ordenadas) y devuelve la página raíz
+func (p *pager) tableTree(rows []sqlRow) uint32 {
+	var children []uint32
+	var keys []treeKey
+	var cells [][]byte
+	used := 0
+	flush := func(last int64) {
+		pg := p.alloc()
+		writePage(p.page(pg), 0, pageTableLeaf, cells, 0)
+		children = append(children, pg)
+		keys = append(keys, treeKey{rowid: last})
+		cells, used = nil, 0
+	}
+	for i, r := range rows {
+		rec := record(r.values)
+		// Se mide antes de escribir: las páginas de desbordamiento se piden al codificar
+		if len(cells) > 0 && !fits(8, len(cells)+1, used+tableLeafSize(r.rowid, rec)) {
+			flush(rows[i-1].rowid)
+		}
+		cell := p.tableLeafCell(r.rowid, rec)
+		cells = append(cells, cell)
+		used += len(cell)
+	}
+	if len(cells) > 0 || len(children) == 0 {
+		var last int64
+		if len(rows) > 0 {
+			last = rows[len(rows)-1].rowid
+		}
+		flush(last)
+	}
+	return p.interiorLevels(pageTableInterior, children, keys[:len(keys)-1])
+}
+
+// indexTree guarda las entradas ordenadas de un índice. A diferencia de las
+// tablas, cada entrada vive en un solo sitio: las que separan hojas suben a
+// la página interior.
+func (p *pager) indexTree(entries [][]byte) uint32 {
+	var groups [][][]byte
+	var seps []treeKey
+	var cur [][]byte
+	used := 0
+	for _, e := range entries {
+		size := payloadSize(len(e), maxLocalIndex)
+		if len(cur) > 0 && !fits(8, len(cur)+1, used+size) {
+			groups = append(groups, cur)
+			seps = append(seps, treeKey{rec: e})
+			cur, used = nil, 0
+			continue
+		}
+		cur = append(cur, e)
+		used += size
+	}
+	if len(cur) == 0 && len(seps) > 0 {
+		// El último separador no puede dejar una hoja vacía detrás: baja a
+		// la hoja nueva y sube la última entrada de la anterior
+		prev := groups[len(groups)-1]
+		cur = [][]byte{seps[len(seps)-1].rec}
+		seps[len(seps)-1] = treeKey{rec: prev[len(prev)-1]}
+		groups[len(groups)-1] = prev[:len(prev)-1]
+	}
+	groups = append(groups, cur)
+
+	children := make([]uint32, len(groups))
+	for i, g := range groups {
+		cells := make([][]byte, len(g))
+		for j, e := range g {
+			cells[j] = p.appendPayload(nil, e, maxLocalIndex)
+		}
+		children[i] = p.alloc()
+		writePage(p.page(children[i]), 0, pageIndexLeaf, cells, 0)
+	}
+	return p.interiorLevels(pageIndexInterior, children, seps)
+}
+
+// interiorLevels apila páginas interiores hasta que queda una sola raíz.
+// seps[i] va entre children[i] y children[i+1]; el separador que queda entre
+// dos páginas interiores sube al nivel de arriba.
+func (p *pager) interiorLevels(kind byte, children []uint32, seps []treeKey) uint32 {
+	cellSize := func(k treeKey) int {
+		if kind == pageTableInterior {
+			return 4 + varintLen(uint64(k.rowid))
+		}
+		return 4 + payloadSize(len(k.rec), maxLocalIndex)
+	}
+	for len(children) > 1 {
+		var up []uint32
+		var upSeps []treeKey
+		for i := 0; i < len(children); i++ {
+			start, used := i, 0
+			for i+1 < len(children) && fits(12, i-start+1, used+cellSize(seps[i])) {
+				used += cellSize(seps[i])
+				i++
+			}
+			// Una página interior necesita al menos una celda: no se deja un
+			// hijo suelto para la siguiente
+			if i+2 == len(children) && i-start >= 2 {
+				i--
+			}
+			cells := make([][]byte, 0, i-start)
+			for j := start; j < i; j++ {
+				cell := binary.BigEndian.AppendUint32(nil, children[j])
+				if kind == pageTableInterior {
+					cell = appendVarint(cell, uint64(seps[j].rowid))
+				} else {
+					cell = p.appendPayload(cell, seps[j].rec, maxLocalIndex)
+				}
+				cells = append(cells, cell)
+			}
+			pg := p.alloc()
+			writePage(p.page(pg), 0, kind, cells, children[i])
+			up = append(up, pg)
+			if i+1 < len(children) {
+				upSeps = append(upSeps, seps[i])
+			}
+		}
+		children, seps = up, upSeps
+	}
+	return children[0]
+}
+
+// indexEntries arma y ordena las entradas del índice: las columnas indexadas
+// seguidas del rowid
+func indexEntries(t *sqlTable, ix sqlIndex) ([][]byte, error) {
+	keys := make([][]int64, len(t.rows))
+	for i, r := range t.rows {
+		for _, col := range ix.cols {
+			v, ok := intValue(r.values[col])
+			if !ok {
+				return nil, fmt.Errorf("índice %s: la columna %d no es entera", ix.name, col)
+			}
+			keys[i] = append(keys[i], v)
+		}
+		keys[i] = append(keys[i], r.rowid)
+	}
+	sort.Slice(keys, func(i, j int) bool { return slices.Compare(keys[i], keys[j]) < 0 })
+	entries := make([][]byte, len(keys))
+	for i, k := range keys {
+		values := make([]interface{}, len(k))
+		for j, v := range k {
+			values[j] = v
+		}
+		entries[i] = record(values)
+	}
+	return entries, nil
+}
+
+func intValue(v interface{}) (int64, bool) {
+	switch v := v.(type) {
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	}
+	return 0, false
+}
+
+// --- Registros ---
+
+// record codifica una fila: cabecera con el tipo de cada columna y después
+// los valores
+func record(values []interface{}) []byte {
+	var types, body []byte
+	for _, v := range values {
+		if n, ok := intValue(v); ok {
+			t, size := intSerial(n)
+			types = appendVarint(types, t)
+			var buf [8]byte
+			binary.BigEndian.PutUint64(buf[:], uint64(n))
+			body = append(body, buf[8-size:]...)
+			continue
+		}
+		switch v := v.(type) {
+		case nil:
+			types = appendVarint(types, 0)
+		case string:
+			types = appendVarint(types, uint64(2*len(v)+13))
+			body = append(body, v...)
+		case []byte:
+			types = appendVarint(types, uint64(2*len(v)+12))
+			body = append(body, v...)
+		default:
+			panic(fmt.Sprintf("anki: tipo de columna no soportado %T", v))
+		}
+	}
+	// La longitud de la cabecera se cuenta a sí misma
+	hl := len(types) + 1
+	for varintLen(uint64(hl))+len(types) != hl {
+		hl = varintLen(uint64(hl)) + len(types)
+	}
+	return slices.Concat(appendVarint(nil, uint64(hl)), types, body)
+}
+
+// intSerial elige el tipo entero más corto: 8 y 9 son las constantes 0 y 1
+func intSerial(n int64) (uint64, int) {
+	switch {
+	case n == 0:
+		return 8, 0
+	case n == 1:
+		return 9, 0
+	case n >= -1<<7 && n < 1<<7:
+		return 1, 1
+	case n >= -1<<15 && n < 1<<15:
+		return 2, 2
+	case n >= -1<<23 && n < 1<<23:
+		return 3, 3
+	case n >= -1<<31 && n < 1<<31:
+		return 4, 4
+	case n >= -1<<47 && n < 1<<47:
+		return 5, 6
+	}
+	return 6, 8
+}
+
+// appendVarint codifica en big-endian de 7 bits por byte; el noveno byte,
+// si hace falta, lleva 8 bits
+func appendVarint(b []byte, v uint64) []byte {
+	if v > 1<<56-1 {
+		var buf [9]byte
+		buf[8] = byte(v)
+		v >>= 8
+		for i := 7; i >= 0; i-- {
+			buf[i] = byte(v&0x7f) | 0x80
+			v >>= 7
+		}
+		return append(b, buf[:]...)
+	}
+	var buf [8]byte
+	n := 0
+	for {
+		buf[n] = byte(v & 0x7f)
+		v >>= 7
+		n++
+		if v == 0 {
+			break
+		}
+	}
+	for i := n - 1; i >= 0; i-- {
+		c := buf[i]
+		if i > 0 {
+			c |= 0x80
+		}
+		b = append(b, c)
+	}
+	return b
+}
+
+func varintLen(v uint64) int { return len(appendVarint(nil, v)) }
@@ -0,0 +1,301 @@
+// Package backup guarda y restaura el sitio completo: cada tabla va como
+// NDJSON (una fila JSON por línea) dentro de un ZIP, con un manifiesto que
+// lleva la versión del esquema, el número de filas y el SHA-256 de cada tabla.
+package backup
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+)
+
+const (
+	Format  = "english-at-lima-backup"
+	Version = 1 // versión del formato del archivo
+
+	// SchemaVersion sube cada vez que una migración del README cambia columnas:
+	// una copia sólo se restaura en el mismo esquema en el que se hizo
+	SchemaVersion = 3 // 2: exam_attempts.attempt_no; 3: lti_grades
+
+	manifestName = "manifest.json"
+	batchSize    = 500
+	maxLine      = 16 << 20 // fila más larga que se acepta al leer (16 MB)
+)
+
+// Table es una tabla de la copia y su clave primaria
+type Table struct {
+	Name string
+	Key  string
+}
+
+func (t Table) file() string { return "tables/" + t.Name + ".ndjson" }
+
+// Tables son todas las tablas del sitio, en orden de restauración: primero
+// las que otras referencian
+var Tables = []Table{
+	{"sentences", "id"},
+	{"quizzes", "id"},
+	{"resources", "id"},
+	{"media", "id"},
+	{"courses", "id"},
+	{"units", "id"},
+	{"lessons", "id"},
+	{"lesson_items", "id"},
+	{"lesson_completions", "id"},
+	{"exams", "id"},
+	{"exam_attempts", "id"},
+	{"embed_keys", "id"},
+	{"lti_platforms", "id"},
+	{"lti_grades", "attempt"},
+	{"staff_roles", "email"},
+	{"review_events", "id"},
+	{"notifications", "id"},
+	{"audit_logs", "id"},
+	{"blacklisted_ips", "ip"},
+}
+
+// Manifest describe la copia; va al final del ZIP porque las sumas se
+// calculan mientras se escriben las tablas. Una copia cortada no lo tiene.
+type Manifest struct {
+	Format    string      `json:"format"`
+	Version   int         `json:"version"`
+	Schema    int         `json:"schema"`
+	CreatedAt string      `json:"created_at"`
+	Tables    []TableInfo `json:"tables"`
+}
+
+// TableInfo es una tabla dentro de la copia
+type TableInfo struct {
+	Name   string `json:"name"`
+	Key    string `json:"key"`
+	File   string `json:"file"`
+	Rows   int    `json:"rows"`
+	SHA256 string `json:"sha256"`
+}
+
+// Source lee todas las filas de una tabla ordenadas por su clave
+type Source interface {
+	Rows(table, key string, each func(json.RawMessage) error) error
+}
+
+// Store es el destino de la restauración
+type Store interface {
+	Empty(table string) (bool, error)
+	// Put inserta las filas conservando sus claves; las que ya existen se sobrescriben
+	Put(table, key string, rows []json.RawMessage) error
+}
+
+// Write escribe la copia en w tabla a tabla, sin tener más de una fila en memoria
+func Write(w io.Writer, src Source, now time.Time) (Manifest, error) {
+	m := Manifest{Format: Format, Version: Version, Schema: SchemaVersion, CreatedAt: now.UTC().Format(time.RFC3339)}
+	zw := zip.NewWriter(w)
+
+	for _, t := range Tables {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: t.file(), Method: zip.Deflate, Modified: now})
+		if err != nil {
+			return m, err
+		}
+		h := sha256.New()
+		out := io.MultiWriter(f, h)
+		info := TableInfo{Name: t.Name, Key: t.Key, File: t.file()}
+
+		var line bytes.Buffer
+		err = src.Rows(t.Name, t.Key, func(row json.RawMessage) error {
+			line.Reset()
+			// Compactar garantiza una fila por línea
+			if err := json.Compact(&line, row); err != nil {
+				return err
+			}
+			line.WriteByte('\n')
+			info.Rows++
+			_, err := out.Write(line.Bytes())
+			return err
+		})
+		if err != nil {
+			return m, fmt.Errorf("tabla %s: %w", t.Name, err)
+		}
+		info.SHA256 = hex.EncodeToString(h.Sum(nil))
+		m.Tables = append(m.Tables, info)
+	}
+
+	f, err := zw.Create(manifestName)
+	if err != nil {
+		return m, err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(m); err != nil {
+		return m, err
+	}
+	return m, zw.Close()
+}
+
+// Verify lee el manifiesto, comprueba formato y versión del esquema y recorre
+// cada tabla comparando filas y SHA-256. No escribe nada.
+func Verify(zr *zip.Reader) (Manifest, error) {
+	var m Manifest
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	mf, ok := files[manifestName]
+	if !ok {
+		return m, errors.New("la copia no tiene manifiesto: está incompleta o no es una copia del sitio")
+	}
+	rc, err := mf.Open()
+	if err != nil {
+		return m, err
+	}
+	err = json.NewDecoder(io.LimitReader(rc, 1<<20)).Decode(&m)
+	rc.Close()
+	if err != nil {
+		return m, fmt.Errorf("manifiesto ilegible: %w", err)
+	}
+
+	switch {
+	case m.Format != Format:
+		return m, errors.New("el archivo no es una copia del sitio")
+	case m.Version != Version:
+		return m, fmt.Errorf("formato de copia v%d no soportado (esta versión lee v%d)", m.Version, Version)
+	case m.Schema != SchemaVersion:
+		return m, fmt.Errorf("la copia es del esquema %d y la base usa el %d: aplique las migraciones antes de restaurar", m.Schema, SchemaVersion)
+	}
+
+	for _, info := range m.Tables {
+		t, ok := lookup(info.Name)
+		if !ok || info.Key != t.Key || info.File != t.file() {
+			return m, fmt.Errorf("tabla desconocida en la copia: %s", info.Name)
+		}
+		f, ok := files[info.File]
+		if !ok {
+			return m, fmt.Errorf("falta el archivo de la tabla %s", info.Name)
+		}
+		h := sha256.New()
+		rows := 0
+		err := eachLine(f, h, func([]byte) error { rows++; return nil })
+		if err != nil {
+			return m, fmt.Errorf("tabla %s: %w", info.Name, err)
+		}
+		if rows != info.Rows || hex.EncodeToString(h.Sum(nil)) != info.SHA256 {
+			return m, fmt.Errorf("la tabla %s está dañada: no coincide con el manifiesto", info.Name)
+		}
+	}
+	return m, nil
+}
+
+func lookup(name string) (Table, bool) {
+	i := slices.IndexFunc(Tables, func(t Table) bool { return t.Name == name })
+	if i < 0 {
+		return Table{}, false
+	}
+	return Tables[i], true
+}
+
+// eachLine recorre el NDJSON de la tabla pasando cada línea por h y
+// exigiendo que cada una sea un objeto JSON
+func eachLine(f *zip.File, h io.Writer, each func([]byte) error) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	sc := bufio.NewScanner(io.TeeReader(rc, h))
+	sc.Buffer(make([]byte, 64<<10), maxLine)
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Bytes()
+		if len(line) == 0 || line[0] != '{' || !json.Valid(line) {
+			return fmt.Errorf("línea %d: no es un objeto JSON", n)
+		}
+		if err := each(line); err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
+
+// Modos de restauración
+const (
+	ModeEmpty = "empty" // sólo en una base vacía: todas las tablas deben estar vacías
+	ModeMerge = "merge" // sobre una base con datos: la copia gana si la clave coincide
+)
+
+// Restore verifica la copia entera y, si todo cuadra, la vuelca en store por
+// lotes en el orden de Tables. Devuelve cuántas filas se escribieron por tabla.
+func Restore(zr *zip.Reader, store Store, mode string) (map[string]int, error) {
+	if mode != ModeEmpty && mode != ModeMerge {
+		return nil, fmt.Errorf("modo de restauración desconocido: %s", mode)
+	}
+	m, err := Verify(zr)
+	if err != nil {
+		return nil, err
+	}
+	infos := map[string]TableInfo{}
+	for _, info := range m.Tables {
+		infos[info.Name] = info
+	}
+
+	if mode == ModeEmpty {
+		var busy []string
+		for _, t := range Tables {
+			if _, ok := infos[t.Name]; !ok {
+				continue
+			}
+			empty, err := store.Empty(t.Name)
+			if err != nil {
+				return nil, fmt.Errorf("tabla %s: %w", t.Name, err)
+			}
+			if !empty {
+				busy = append(busy, t.Name)
+			}
+		}
+		if len(busy) > 0 {
+			return nil, fmt.Errorf("la base no está vacía (%v); use el modo merge", busy)
+		}
+	}
+
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	written := map[string]int{}
+	for _, t := range Tables {
+		info, ok := infos[t.Name]
+		if !ok {
+			continue
+		}
+		var batch []json.RawMessage
+		flush := func() error {
+			if len(batch) == 0 {
+				return nil
+			}
+			if err := store.Put(t.Name, t.Key, batch); err != nil {
+				return err
+			}
+			written[t.Name] += len(batch)
+			batch = nil
+			return nil
+		}
+		err := eachLine(files[info.File], io.Discard, func(line []byte) error {
+			batch = append(batch, json.RawMessage(bytes.Clone(line)))
+			if len(batch) == batchSize {
+				return flush()
+			}
+			return nil
+		})
+		if err == nil {
+			err = flush()
+		}
+		if err != nil {
+			return written, fmt.Errorf("tabla %s (restauradas %d filas): %w", t.Name, written[t.Name], err)
+		}
+	}
+	return written, nil
+}
@@ -0,0 +1,232 @@
+package backup
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memDB hace de Supabase: filas por tabla en el orden de inserción
+type memDB struct {
+	rows map[string][]string
+	puts map[string][]int // tamaño de cada lote escrito
+}
+
+func newMemDB() *memDB { return &memDB{rows: map[string][]string{}, puts: map[string][]int{}} }
+
+func (m *memDB) Rows(table, key string, each func(json.RawMessage) error) error {
+	for _, r := range m.rows[table] {
+		if err := each(json.RawMessage(r)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *memDB) Empty(table string) (bool, error) { return len(m.rows[table]) == 0, nil }
+
+// Put sustituye la fila con la misma clave o la añade
+func (m *memDB) Put(table, key string, rows []json.RawMessage) error {
+	m.puts[table] = append(m.puts[table], len(rows))
+	for _, raw := range rows {
+		var row map[string]interface{}
+		if err := json.Unmarshal(raw, &row); err != nil {
+			return err
+		}
+		replaced := false
+		for i, old := range m.rows[table] {
+			var o map[string]interface{}
+			_ = json.Unmarshal([]byte(old), &o)
+			if o[key] == row[key] {
+				m.rows[table][i], replaced = string(raw), true
+			}
+		}
+		if !replaced {
+			m.rows[table] = append(m.rows[table], string(raw))
+		}
+	}
+	return nil
+}
+
+func sampleDB() *memDB {
+	db := newMemDB()
+	db.rows["sentences"] = []string{
+		`{"id": 1, "english": "Good morning",
+		  "spanish": "Buenos días"}`, // JSON con saltos de línea: debe quedar en una sola
+		`{"id":2,"english":"See you","spanish":"Nos vemos"}`,
+	}
+	db.rows["staff_roles"] = []string{`{"email":"ana@example.com","role":"reviewer"}`}
+	db.rows["audit_logs"] = []string{`{"id":9,"ip_address":"1.2.3.4","event_type":"XSS","input_data":"<script>\n</script>"}`}
+	return db
+}
+
+func writeBackup(t *testing.T, src Source) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := Write(&buf, src, time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func openZip(t *testing.T, data []byte) *zip.Reader {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zr
+}
+
+// rewrite copia el ZIP cambiando (o quitando, con nil) los archivos indicados
+func rewrite(t *testing.T, data []byte, change map[string]func([]byte) []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range openZip(t, data).File {
+		rc, _ := f.Open()
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		fn, ok := change[f.Name]
+		if ok && fn == nil {
+			continue
+		}
+		if ok {
+			content = fn(content)
+		}
+		w, _ := zw.Create(f.Name)
+		_, _ = w.Write(content)
+	}
+	_ = zw.Close()
+	return buf.Bytes()
+}
+
+func TestWriteManifest(t *testing.T) {
+	data := writeBackup(t, sampleDB())
+	m, err := Verify(openZip(t, data))
+	if err != nil {
+		t.Fatalf("la copia recién hecha no verifica: %v", err)
+	}
+	if m.Schema != SchemaVersion || m.CreatedAt != "2026-10-19T12:00:00Z" || len(m.Tables) != len(Tables) {
+		t.Errorf("manifiesto inesperado: %+v", m)
+	}
+	rows := map[string]int{}
+	for _, info := range m.Tables {
+		rows[info.Name] = info.Rows
+	}
+	if rows["sentences"] != 2 || rows["staff_roles"] != 1 || rows["audit_logs"] != 1 || rows["quizzes"] != 0 {
+		t.Errorf("filas por tabla: %v", rows)
+	}
+
+	// El manifiesto es lo último del ZIP: una copia cortada no lo tiene
+	files := openZip(t, data).File
+	if files[len(files)-1].Name != manifestName {
+		t.Errorf("el último archivo es %s", files[len(files)-1].Name)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	good := writeBackup(t, sampleDB())
+	bumpSchema := func(b []byte) []byte {
+		return bytes.Replace(b, []byte(fmt.Sprintf(`"schema": %d`, SchemaVersion)), []byte(`"schema": 999`), 1)
+	}
+	tests := []struct {
+		name   string
+		change map[string]func([]byte) []byte
+		want   string
+	}{
+		{"Sin manifiesto", map[string]func([]byte) []byte{manifestName: nil}, "no tiene manifiesto"},
+		{"Otro esquema", map[string]func([]byte) []byte{manifestName: bumpSchema}, "esquema 999"},
+		{"Tabla alterada", map[string]func([]byte) []byte{
+			"tables/sentences.ndjson": func(b []byte) []byte { return bytes.Replace(b, []byte("Good"), []byte("Bad!"), 1) },
+		}, "dañada"},
+		{"Fila de más", map[string]func([]byte) []byte{
+			"tables/staff_roles.ndjson": func(b []byte) []byte { return append(b, []byte(`{"email":"x@y.z"}`+"\n")...) },
+		}, "dañada"},
+		{"Línea que no es JSON", map[string]func([]byte) []byte{
+			"tables/quizzes.ndjson": func(b []byte) []byte { return append(b, []byte("DROP TABLE quizzes;\n")...) },
+		}, "no es un objeto JSON"},
+		{"Falta una tabla", map[string]func([]byte) []byte{"tables/resources.ndjson": nil}, "falta el archivo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Verify(openZip(t, rewrite(t, good, tt.change)))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, esperaba que mencionara %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRestore(t *testing.T) {
+	data := writeBackup(t, sampleDB())
+
+	t.Run("Base vacía", func(t *testing.T) {
+		db := newMemDB()
+		written, err := Restore(openZip(t, data), db, ModeEmpty)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if written["sentences"] != 2 || len(db.rows["sentences"]) != 2 || db.rows["sentences"][0] != `{"id":1,"english":"Good morning","spanish":"Buenos días"}` {
+			t.Errorf("restauró %v: %q", written, db.rows["sentences"])
+		}
+		if db.rows["audit_logs"][0] != sampleDB().rows["audit_logs"][0] {
+			t.Errorf("la auditoría no se restauró tal cual: %q", db.rows["audit_logs"])
+		}
+	})
+
+	t.Run("Base con datos en modo empty", func(t *testing.T) {
+		db := newMemDB()
+		db.rows["staff_roles"] = []string{`{"email":"otro@example.com","role":"editor"}`}
+		if _, err := Restore(openZip(t, data), db, ModeEmpty); err == nil || !strings.Contains(err.Error(), "staff_roles") {
+			t.Errorf("esperaba rechazo por staff_roles, obtuvo %v", err)
+		}
+		if len(db.rows["sentences"]) != 0 {
+			t.Error("no debería escribir nada si la base no está vacía")
+		}
+	})
+
+	t.Run("Fusión", func(t *testing.T) {
+		db := newMemDB()
+		db.rows["sentences"] = []string{`{"id":2,"english":"Edited","spanish":"Editada"}`, `{"id":5,"english":"Only here","spanish":"Sólo aquí"}`}
+		if _, err := Restore(openZip(t, data), db, ModeMerge); err != nil {
+			t.Fatal(err)
+		}
+		got := strings.Join(db.rows["sentences"], "\n")
+		if len(db.rows["sentences"]) != 3 || !strings.Contains(got, "See you") || strings.Contains(got, "Edited") || !strings.Contains(got, "Only here") {
+			t.Errorf("fusión inesperada:\n%s", got)
+		}
+	})
+
+	t.Run("Copia dañada no escribe nada", func(t *testing.T) {
+		bad := rewrite(t, data, map[string]func([]byte) []byte{
+			"tables/audit_logs.ndjson": func(b []byte) []byte { return bytes.ToUpper(b) },
+		})
+		db := newMemDB()
+		if _, err := Restore(openZip(t, bad), db, ModeEmpty); err == nil {
+			t.Fatal("esperaba error")
+		}
+		if len(db.rows["sentences"]) != 0 {
+			t.Error("se escribieron tablas antes de detectar el daño")
+		}
+	})
+}
+
+func TestRestoreBatches(t *testing.T) {
+	src := newMemDB()
+	for i := 1; i <= 2*batchSize+1; i++ {
+		src.rows["lessons"] = append(src.rows["lessons"], fmt.Sprintf(`{"id":%d}`, i))
+	}
+	db := newMemDB()
+	if _, err := Restore(openZip(t, writeBackup(t, src)), db, ModeEmpty); err != nil {
+		t.Fatal(err)
+	}
+	if got := db.puts["lessons"]; len(got) != 3 || got[0] != batchSize || got[2] != 1 {
+		t.Errorf("lotes de %v", got)
+	}
+}
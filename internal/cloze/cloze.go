@@ -0,0 +1,270 @@
+// Package cloze genera quizzes de completar el hueco y de opción única a
+// partir de las frases del banco: elige una palabra de la frase, la cambia
+// por ___ y busca distractores de la misma categoría gramatical o de la
+// misma banda de frecuencia en el resto de frases.
+package cloze
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"english-at-lima-cms/internal/models"
+	"english-at-lima-cms/internal/quiz"
+)
+
+// Distractors es cuántas opciones incorrectas lleva el quiz de opción única
+const Distractors = 3
+
+// MaxBatch es el máximo de frases por generación en lote
+const MaxBatch = 50
+
+// Categorías gramaticales aproximadas (no hay etiquetador: se deducen de
+// listas cerradas de palabras funcionales y de los sufijos)
+const (
+	Determiner  = "det"
+	Pronoun     = "pron"
+	Preposition = "prep"
+	Auxiliary   = "aux"
+	Conjunction = "conj"
+	Adverb      = "adv"
+	Verb        = "verb"
+	Noun        = "noun"
+	Adjective   = "adj"
+	Other       = "other" // palabra de contenido sin sufijo reconocible
+)
+
+var closedClasses = map[string][]string{
+	Determiner:  strings.Fields("a an the this that these those my your his her its our their some any no every each"),
+	Pronoun:     strings.Fields("i you he she it we they me him us them mine yours hers ours theirs myself yourself himself herself itself ourselves themselves"),
+	Preposition: strings.Fields("in on at to from with by for of about under over between into through after before during without near behind above below across"),
+	Auxiliary:   strings.Fields("am is are was were be been being do does did have has had can could will would shall should may might must"),
+	Conjunction: strings.Fields("and but or so because if when while although than"),
+}
+
+var wordClass = func() map[string]string {
+	m := map[string]string{}
+	for class, words := range closedClasses {
+		for _, w := range words {
+			m[w] = class
+		}
+	}
+	return m
+}()
+
+// suffixes se prueban en orden; la primera que coincide decide la categoría
+var suffixes = []struct{ suffix, class string }{
+	{"ly", Adverb},
+	{"ing", Verb}, {"ed", Verb}, {"ize", Verb}, {"ise", Verb},
+	{"tion", Noun}, {"sion", Noun}, {"ness", Noun}, {"ment", Noun}, {"ity", Noun}, {"ship", Noun}, {"ism", Noun},
+	{"ous", Adjective}, {"ful", Adjective}, {"less", Adjective}, {"able", Adjective}, {"ible", Adjective}, {"ive", Adjective}, {"ic", Adjective},
+}
+
+// Class devuelve la categoría aproximada de la palabra
+func Class(word string) string {
+	w := strings.ToLower(word)
+	if c, ok := wordClass[w]; ok {
+		return c
+	}
+	for _, s := range suffixes {
+		if len(w) > len(s.suffix)+2 && strings.HasSuffix(w, s.suffix) {
+			return s.class
+		}
+	}
+	return Other
+}
+
+// content indica si la palabra sirve de hueco: se evitan artículos,
+// pronombres y conjunciones porque casi nunca son el punto a practicar
+func content(class string) bool {
+	return class != Determiner && class != Pronoun && class != Conjunction
+}
+
+var reWord = regexp.MustCompile(`[A-Za-z]+(?:'[A-Za-z]+)?`)
+
+// Token es una palabra de la frase con su posición en bytes
+type Token struct {
+	Word       string
+	Start, End int
+}
+
+// Tokens devuelve las palabras de la frase (las contracciones son una sola)
+func Tokens(s string) []Token {
+	var tokens []Token
+	for _, m := range reWord.FindAllStringIndex(s, -1) {
+		tokens = append(tokens, Token{Word: s[m[0]:m[1]], Start: m[0], End: m[1]})
+	}
+	return tokens
+}
+
+// Vocabulary cuenta cuántas veces aparece cada palabra en el banco de
+// frases y las agrupa por categoría y banda de frecuencia
+type Vocabulary struct {
+	counts map[string]int
+	groups map[string][]string // "categoría/banda" → palabras ordenadas
+}
+
+// NewVocabulary prepara el vocabulario con el inglés de todas las frases
+func NewVocabulary(texts []string) *Vocabulary {
+	v := &Vocabulary{counts: map[string]int{}, groups: map[string][]string{}}
+	for _, t := range texts {
+		for _, tok := range Tokens(t) {
+			v.counts[strings.ToLower(tok.Word)]++
+		}
+	}
+	for w := range v.counts {
+		if len(w) < 2 || strings.Contains(w, "'") {
+			continue
+		}
+		key := groupKey(Class(w), v.Band(w))
+		v.groups[key] = append(v.groups[key], w)
+	}
+	for _, words := range v.groups {
+		sort.Strings(words)
+	}
+	return v
+}
+
+// Band es la banda de frecuencia de la palabra en el banco: 0 para las que
+// aparecen una vez, 1 para 2–3 veces, 2 para 4–7… hasta 5
+func (v *Vocabulary) Band(word string) int {
+	n := v.counts[strings.ToLower(word)]
+	if n <= 1 {
+		return 0
+	}
+	return min(int(math.Log2(float64(n))), 5)
+}
+
+func groupKey(class string, band int) string { return fmt.Sprintf("%s/%d", class, band) }
+
+// Target elige la palabra a ocultar: la palabra de contenido menos frecuente
+// del banco (la más informativa); si empatan, la más larga y luego la primera
+func (v *Vocabulary) Target(sentence string) (Token, bool) {
+	var best Token
+	found := false
+	for _, tok := range Tokens(sentence) {
+		if len(tok.Word) < 3 || strings.Contains(tok.Word, "'") || !content(Class(tok.Word)) {
+			continue
+		}
+		if !found || v.better(tok, best) {
+			best, found = tok, true
+		}
+	}
+	return best, found
+}
+
+func (v *Vocabulary) better(a, b Token) bool {
+	ca, cb := v.counts[strings.ToLower(a.Word)], v.counts[strings.ToLower(b.Word)]
+	if ca != cb {
+		return ca < cb
+	}
+	return len(a.Word) > len(b.Word)
+}
+
+// Distractors busca n palabras de la misma categoría y banda que el hueco;
+// si no alcanzan, completa con la misma categoría y luego con la misma banda.
+// Nunca repite palabras de la frase.
+func (v *Vocabulary) Distractors(target string, sentence string, n int, rng *rand.Rand) []string {
+	skip := map[string]bool{}
+	for _, tok := range Tokens(sentence) {
+		skip[strings.ToLower(tok.Word)] = true
+	}
+	class, band := Class(target), v.Band(target)
+
+	var picked []string
+	take := func(words []string) {
+		pool := slices.Clone(words)
+		rng.Shuffle(len(pool), func(i, j int) { pool[i], pool[j] = pool[j], pool[i] })
+		for _, w := range pool {
+			if len(picked) == n {
+				return
+			}
+			if !skip[w] {
+				skip[w] = true
+				picked = append(picked, w)
+			}
+		}
+	}
+
+	take(v.groups[groupKey(class, band)])
+	for b := 0; b <= 5 && len(picked) < n; b++ {
+		take(v.groups[groupKey(class, b)])
+	}
+	if len(picked) < n && content(class) {
+		// Misma banda de cualquier categoría de contenido
+		var same []string
+		for _, c := range []string{Adverb, Verb, Noun, Adjective, Other} {
+			same = append(same, v.groups[groupKey(c, band)]...)
+		}
+		take(same)
+	}
+	for i, w := range picked {
+		picked[i] = matchCase(w, target)
+	}
+	return picked
+}
+
+// matchCase copia la mayúscula inicial del hueco (p. ej. al inicio de la frase)
+func matchCase(word, like string) string {
+	r := []rune(like)
+	if len(r) > 0 && unicode.IsUpper(r[0]) {
+		w := []rune(word)
+		w[0] = unicode.ToUpper(w[0])
+		return string(w)
+	}
+	return word
+}
+
+// Generate crea el quiz en borrador a partir de la frase. kind es
+// models.QuizFill o models.QuizSingle; target fuerza la palabra a ocultar
+// (vacío = la elige el generador).
+func Generate(s models.Sentence, v *Vocabulary, kind, target string) (models.Quiz, error) {
+	if kind != models.QuizFill && kind != models.QuizSingle {
+		return models.Quiz{}, fmt.Errorf("sólo se generan quizzes de completar o de opción única")
+	}
+
+	var tok Token
+	found := false
+	if target != "" {
+		for _, t := range Tokens(s.English) {
+			if strings.EqualFold(t.Word, target) {
+				tok, found = t, true
+				break
+			}
+		}
+	} else {
+		tok, found = v.Target(s.English)
+	}
+	if !found {
+		return models.Quiz{}, fmt.Errorf("la frase no tiene una palabra adecuada para el hueco")
+	}
+
+	q := models.Quiz{
+		Question: s.English[:tok.Start] + quiz.Blank + s.English[tok.End:],
+		Type:     kind,
+		Answers:  []string{tok.Word},
+		Taxonomy: s.Taxonomy,
+		// El alumno ve la frase completa y su traducción al corregir
+		Explanation:      models.Note{ES: fmt.Sprintf("«%s» — %s", s.English, s.Spanish)},
+		SourceSentenceID: s.ID,
+	}
+	q.Status = models.StatusDraft
+
+	if kind == models.QuizSingle {
+		rng := rand.New(rand.NewPCG(uint64(s.ID), uint64(len(s.English))))
+		options := append(v.Distractors(tok.Word, s.English, Distractors, rng), tok.Word)
+		if len(options) < Distractors+1 {
+			return models.Quiz{}, fmt.Errorf("no hay suficientes palabras parecidas a «%s» para los distractores", tok.Word)
+		}
+		rng.Shuffle(len(options), func(i, j int) { options[i], options[j] = options[j], options[i] })
+		q.Options = options
+	}
+
+	quiz.Normalize(&q)
+	return q, quiz.Validate(q)
+}
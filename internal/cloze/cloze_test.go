@@ -0,0 +1,164 @@
+package cloze
+
+import (
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+
+	"english-at-lima-cms/internal/models"
+	"english-at-lima-cms/internal/quiz"
+)
+
+var bank = []string{
+	"I usually drink coffee in the morning.",
+	"She quickly finished her homework.",
+	"They are playing football in the park.",
+	"We visited the museum yesterday.",
+	"He slowly opened the door.",
+	"The teacher explained the lesson carefully.",
+	"My brother is reading a book.",
+	"Our neighbours are cooking dinner.",
+	"She drinks tea every afternoon.",
+	"I drink water after running.",
+	"The children are watching a movie.",
+	"They happily accepted the invitation.",
+}
+
+func TestClass(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"the", Determiner},
+		{"They", Pronoun},
+		{"in", Preposition},
+		{"could", Auxiliary},
+		{"because", Conjunction},
+		{"quickly", Adverb},
+		{"playing", Verb},
+		{"visited", Verb},
+		{"invitation", Noun},
+		{"careful", Adjective},
+		{"coffee", Other},
+		{"red", Other}, // demasiado corta para deducir por el sufijo
+	}
+	for _, tt := range tests {
+		if got := Class(tt.word); got != tt.want {
+			t.Errorf("Class(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestBand(t *testing.T) {
+	v := NewVocabulary(bank)
+	tests := []struct {
+		word string
+		want int
+	}{
+		{"museum", 0},  // 1 vez
+		{"drink", 1},   // 2 veces
+		{"are", 1},     // 3 veces
+		{"the", 3},     // 8 veces
+		{"unknown", 0}, // no está en el banco
+	}
+	for _, tt := range tests {
+		if got := v.Band(tt.word); got != tt.want {
+			t.Errorf("Band(%q) = %d, want %d", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestTarget(t *testing.T) {
+	v := NewVocabulary(bank)
+	tests := []struct {
+		sentence string
+		want     string
+		ok       bool
+	}{
+		{"I usually drink coffee in the morning.", "usually", true}, // "drink" es más frecuente; entre las únicas gana la más larga y luego la primera
+		{"She quickly finished her homework.", "finished", true},
+		{"I don't know.", "know", true}, // las contracciones nunca son el hueco
+		{"It is.", "", false},
+	}
+	for _, tt := range tests {
+		tok, ok := v.Target(tt.sentence)
+		if ok != tt.ok || tok.Word != tt.want {
+			t.Errorf("Target(%q) = %q, %v; want %q, %v", tt.sentence, tok.Word, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestDistractors(t *testing.T) {
+	v := NewVocabulary(bank)
+	rng := rand.New(rand.NewPCG(1, 2))
+	sentence := "She quickly finished her homework."
+	got := v.Distractors("quickly", sentence, 3, rng)
+	if len(got) != 3 {
+		t.Fatalf("Distractors() = %v, want 3", got)
+	}
+	for _, d := range got {
+		if Class(d) != Adverb {
+			t.Errorf("distractor %q no es adverbio como «quickly»", d)
+		}
+		if strings.Contains(strings.ToLower(sentence), strings.ToLower(d)) {
+			t.Errorf("distractor %q aparece en la frase", d)
+		}
+	}
+
+	// La mayúscula del hueco se copia a los distractores
+	for _, d := range v.Distractors("Usually", "Usually I walk.", 2, rng) {
+		if d[0] < 'A' || d[0] > 'Z' {
+			t.Errorf("distractor %q debería empezar en mayúscula", d)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	v := NewVocabulary(bank)
+	s := models.Sentence{
+		ID: 7, English: "She quickly finished her homework.", Spanish: "Ella terminó rápido su tarea.",
+		Taxonomy: models.Taxonomy{Level: "A2", Tags: []string{"school"}},
+	}
+
+	fill, err := Generate(s, v, models.QuizFill, "")
+	if err != nil {
+		t.Fatalf("Generate(fill) error = %v", err)
+	}
+	if fill.Question != "She quickly ___ her homework." || !slices.Equal(fill.Answers, []string{"finished"}) {
+		t.Errorf("fill = %q %v", fill.Question, fill.Answers)
+	}
+	if fill.Status != models.StatusDraft || fill.SourceSentenceID != 7 || fill.Level != "A2" {
+		t.Errorf("fill debe ser borrador con la taxonomía y el origen de la frase: %+v", fill)
+	}
+
+	single, err := Generate(s, v, models.QuizSingle, "quickly")
+	if err != nil {
+		t.Fatalf("Generate(single) error = %v", err)
+	}
+	if single.Question != "She ___ finished her homework." {
+		t.Errorf("single.Question = %q", single.Question)
+	}
+	if len(single.Options) != Distractors+1 || !slices.Contains(single.Options, "quickly") {
+		t.Errorf("single.Options = %v", single.Options)
+	}
+	if err := quiz.Validate(single); err != nil {
+		t.Errorf("el quiz generado no es válido: %v", err)
+	}
+
+	// Misma frase, mismas opciones: el orden no cambia entre ejecuciones
+	again, _ := Generate(s, v, models.QuizSingle, "quickly")
+	if !slices.Equal(again.Options, single.Options) {
+		t.Errorf("Generate no es determinista: %v / %v", again.Options, single.Options)
+	}
+
+	if _, err := Generate(s, v, models.QuizSingle, "absent"); err == nil {
+		t.Error("se esperaba error con una palabra que no está en la frase")
+	}
+	if _, err := Generate(s, v, models.QuizOrdering, ""); err == nil {
+		t.Error("se esperaba error con un tipo no generable")
+	}
+	if _, err := Generate(models.Sentence{English: "Hi."}, v, models.QuizFill, ""); err == nil {
+		t.Error("se esperaba error sin palabra adecuada")
+	}
+}
@@ -0,0 +1,249 @@
+// Package course recorre la jerarquía Curso → Unidad → Lección: orden,
+// navegación del reproductor público y progreso del alumno.
+package course
+
+import (
+	"english-at-lima-cms/internal/models"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// ContentTypes son los tipos de contenido que puede contener una lección
+var ContentTypes = map[string]string{
+	"sentence": "sentences",
+	"quiz":     "quizzes",
+	"resource": "resources",
+}
+
+// ValidContentType indica si el tipo se puede añadir a una lección
+func ValidContentType(t string) bool {
+	_, ok := ContentTypes[t]
+	return ok
+}
+
+// Sort ordena unidades, lecciones y elementos por posición (y por id si empatan)
+func Sort(c *models.Course) {
+	sort.SliceStable(c.Units, func(i, j int) bool {
+		return before(c.Units[i].Position, c.Units[i].ID, c.Units[j].Position, c.Units[j].ID)
+	})
+	for u := range c.Units {
+		lessons := c.Units[u].Lessons
+		sort.SliceStable(lessons, func(i, j int) bool {
+			return before(lessons[i].Position, lessons[i].ID, lessons[j].Position, lessons[j].ID)
+		})
+		for l := range lessons {
+			items := lessons[l].Items
+			sort.SliceStable(items, func(i, j int) bool {
+				return before(items[i].Position, items[i].ID, items[j].Position, items[j].ID)
+			})
+		}
+	}
+}
+
+func before(posA, idA, posB, idB int) bool {
+	if posA != posB {
+		return posA < posB
+	}
+	return idA < idB
+}
+
+// Lessons aplana el curso en el orden en que lo recorre el alumno
+func Lessons(c models.Course) []models.Lesson {
+	var out []models.Lesson
+	for _, u := range c.Units {
+		out = append(out, u.Lessons...)
+	}
+	return out
+}
+
+// LessonIDs devuelve los ids de todas las lecciones del curso
+func LessonIDs(c models.Course) []int {
+	var ids []int
+	for _, l := range Lessons(c) {
+		ids = append(ids, l.ID)
+	}
+	return ids
+}
+
+// Step es la posición de una lección dentro del recorrido del curso
+type Step struct {
+	Lesson models.Lesson
+	Unit   models.Unit
+	Number int // 1-based
+	Total  int
+	Prev   *models.Lesson
+	Next   *models.Lesson
+}
+
+// Find ubica la lección con sus vecinas; ok es false si no pertenece al curso
+func Find(c models.Course, lessonID int) (Step, bool) {
+	all := Lessons(c)
+	for i, l := range all {
+		if l.ID != lessonID {
+			continue
+		}
+		step := Step{Lesson: l, Number: i + 1, Total: len(all)}
+		if i > 0 {
+			step.Prev = &all[i-1]
+		}
+		if i < len(all)-1 {
+			step.Next = &all[i+1]
+		}
+		for _, u := range c.Units {
+			if u.ID == l.UnitID {
+				step.Unit = u
+			}
+		}
+		return step, true
+	}
+	return Step{}, false
+}
+
+// MarkCompleted marca las lecciones que el alumno ya terminó
+func MarkCompleted(c *models.Course, done map[int]bool) {
+	for u := range c.Units {
+		for l := range c.Units[u].Lessons {
+			lesson := &c.Units[u].Lessons[l]
+			lesson.Completed = done[lesson.ID]
+		}
+	}
+}
+
+// SetCompletions guarda cuántos alumnos completaron cada lección (vista admin)
+func SetCompletions(c *models.Course, counts map[int]int) {
+	for u := range c.Units {
+		for l := range c.Units[u].Lessons {
+			lesson := &c.Units[u].Lessons[l]
+			lesson.Completions = counts[lesson.ID]
+		}
+	}
+}
+
+// Progress cuenta las lecciones completadas sobre el total y el porcentaje
+func Progress(c models.Course) (done, total, percent int) {
+	for _, l := range Lessons(c) {
+		total++
+		if l.Completed {
+			done++
+		}
+	}
+	if total > 0 {
+		percent = done * 100 / total
+	}
+	return done, total, percent
+}
+
+// Resume devuelve la primera lección sin completar (o la primera si ya
+// terminó todo); nil si el curso no tiene lecciones
+func Resume(c models.Course) *models.Lesson {
+	all := Lessons(c)
+	if len(all) == 0 {
+		return nil
+	}
+	for i := range all {
+		if !all[i].Completed {
+			return &all[i]
+		}
+	}
+	return &all[0]
+}
+
+// ParseOrder valida la lista de ids que envía el drag-and-drop: todos
+// numéricos y sin repetir
+func ParseOrder(values []string) ([]int, error) {
+	if len(values) == 0 {
+		return nil, fmt.Errorf("no se recibió ningún elemento para ordenar")
+	}
+	seen := make(map[int]bool, len(values))
+	ids := make([]int, 0, len(values))
+	for _, v := range values {
+		id, err := strconv.Atoi(v)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("id inválido: %q", v)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("id repetido: %d", id)
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// SameSet comprueba que el nuevo orden contiene exactamente los mismos
+// elementos que había, para que nadie mueva filas de otra lección
+func SameSet(ids, current []int) bool {
+	if len(ids) != len(current) {
+		return false
+	}
+	have := make(map[int]bool, len(current))
+	for _, id := range current {
+		have[id] = true
+	}
+	for _, id := range ids {
+		if !have[id] {
+			return false
+		}
+	}
+	return true
+}
+
+// Tables traduce el tipo de nodo de la URL a su tabla en Supabase
+var Tables = map[string]string{
+	"units":   "units",
+	"lessons": "lessons",
+	"items":   "lesson_items",
+}
+
+// Children devuelve los ids actuales bajo parent para el tipo de nodo:
+// las unidades del curso, las lecciones de una unidad o los elementos de una
+// lección. ok es false si parent no pertenece al curso.
+func Children(c models.Course, kind string, parent int) (ids []int, ok bool) {
+	switch kind {
+	case "units":
+		for _, u := range c.Units {
+			ids = append(ids, u.ID)
+		}
+		return ids, true
+	case "lessons":
+		for _, u := range c.Units {
+			if u.ID == parent {
+				for _, l := range u.Lessons {
+					ids = append(ids, l.ID)
+				}
+				return ids, true
+			}
+		}
+	case "items":
+		for _, l := range Lessons(c) {
+			if l.ID == parent {
+				for _, it := range l.Items {
+					ids = append(ids, it.ID)
+				}
+				return ids, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Owns indica si el nodo (unidad, lección o elemento) pertenece al curso
+func Owns(c models.Course, kind string, id int) bool {
+	for _, u := range c.Units {
+		if kind == "units" && u.ID == id {
+			return true
+		}
+		for _, l := range u.Lessons {
+			if kind == "lessons" && l.ID == id {
+				return true
+			}
+			for _, it := range l.Items {
+				if kind == "items" && it.ID == id {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package course
+
+import (
+	"english-at-lima-cms/internal/models"
+	"reflect"
+	"testing"
+)
+
+// sample arma un curso desordenado, tal como puede llegar de PostgREST
+func sample() models.Course {
+	return models.Course{ID: 1, Title: "Inglés para viajar", Units: []models.Unit{
+		{ID: 20, Position: 1, Lessons: []models.Lesson{
+			{ID: 202, UnitID: 20, Position: 1},
+			{ID: 201, UnitID: 20, Position: 0},
+		}},
+		{ID: 10, Position: 0, Lessons: []models.Lesson{
+			{ID: 101, UnitID: 10, Position: 0, Items: []models.LessonItem{
+				{ID: 3, Position: 2}, {ID: 1, Position: 0}, {ID: 2, Position: 1},
+			}},
+		}},
+	}}
+}
+
+func TestSortAndFind(t *testing.T) {
+	c := sample()
+	Sort(&c)
+
+	if got, want := LessonIDs(c), []int{101, 201, 202}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("orden de lecciones %v, esperaba %v", got, want)
+	}
+	items, _ := Children(c, "items", 101)
+	if want := []int{1, 2, 3}; !reflect.DeepEqual(items, want) {
+		t.Errorf("orden de elementos %v, esperaba %v", items, want)
+	}
+
+	step, ok := Find(c, 201)
+	if !ok || step.Number != 2 || step.Total != 3 || step.Unit.ID != 20 {
+		t.Fatalf("paso mal calculado: %+v", step)
+	}
+	if step.Prev == nil || step.Prev.ID != 101 || step.Next == nil || step.Next.ID != 202 {
+		t.Errorf("vecinas incorrectas: prev=%v next=%v", step.Prev, step.Next)
+	}
+	if _, ok := Find(c, 999); ok {
+		t.Error("❌ Se encontró una lección de otro curso")
+	}
+}
+
+func TestProgressAndResume(t *testing.T) {
+	c := sample()
+	Sort(&c)
+
+	if Resume(c).ID != 101 {
+		t.Error("sin progreso se debe empezar por la primera lección")
+	}
+	MarkCompleted(&c, map[int]bool{101: true, 999: true})
+	done, total, percent := Progress(c)
+	if done != 1 || total != 3 || percent != 33 {
+		t.Errorf("progreso %d/%d (%d%%), esperaba 1/3 (33%%)", done, total, percent)
+	}
+	if Resume(c).ID != 201 {
+		t.Errorf("se debe continuar por la primera lección pendiente, no %d", Resume(c).ID)
+	}
+	if Resume(models.Course{}) != nil {
+		t.Error("un curso vacío no tiene lección para continuar")
+	}
+}
+
+func TestReorderGuards(t *testing.T) {
+	c := sample()
+	Sort(&c)
+
+	tests := []struct {
+		name    string
+		kind    string
+		parent  int
+		order   []string
+		wantErr bool
+	}{
+		{"Unidades", "units", 0, []string{"20", "10"}, false},
+		{"Lecciones de la unidad", "lessons", 20, []string{"202", "201"}, false},
+		{"Lección de otra unidad", "lessons", 20, []string{"202", "101"}, true},
+		{"Falta un elemento", "items", 101, []string{"3", "1"}, true},
+		{"Id repetido", "items", 101, []string{"1", "1", "2"}, true},
+		{"Id no numérico", "units", 0, []string{"10", "x"}, true},
+		{"Padre ajeno", "items", 999, []string{"1"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, err := ParseOrder(tt.order)
+			current, ok := Children(c, tt.kind, tt.parent)
+			valid := err == nil && ok && SameSet(ids, current)
+			if valid == tt.wantErr {
+				t.Errorf("válido=%v, se esperaba error=%v", valid, tt.wantErr)
+			}
+		})
+	}
+
+	if !Owns(c, "items", 2) || Owns(c, "lessons", 20) || Owns(c, "units", 999) {
+		t.Error("❌ Owns no distingue los nodos del curso")
+	}
+}
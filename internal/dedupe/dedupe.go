@@ -0,0 +1,261 @@
+// Package dedupe detecta contenido casi duplicado ("How are you?" frente a
+// "How are you ?") comparando el texto normalizado por trigramas y por
+// distancia de Levenshtein.
+package dedupe
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"english-at-lima-cms/internal/models"
+)
+
+// Threshold es la similitud a partir de la cual se avisa al guardar
+const Threshold = 0.85
+
+// MaxMatches es cuántos parecidos se muestran como máximo en el aviso
+const MaxMatches = 5
+
+var accents = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ä", "a", "ã", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "ö", "o", "õ", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ñ", "n", "ç", "c",
+)
+
+// Normalize pasa a minúsculas, quita tildes y puntuación y junta los espacios.
+// Los apóstrofos se eliminan para que "don't" y "dont" coincidan.
+func Normalize(s string) string {
+	s = accents.Replace(strings.ToLower(s))
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		switch {
+		case r == '\'' || r == '’':
+			continue
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		default:
+			space = true
+		}
+	}
+	return b.String()
+}
+
+// trigrams devuelve el conjunto de trigramas del texto normalizado, con
+// relleno para que las palabras cortas también cuenten
+func trigrams(norm string) map[string]struct{} {
+	runes := []rune("  " + norm + " ")
+	set := make(map[string]struct{}, len(runes))
+	for i := 0; i+3 <= len(runes); i++ {
+		set[string(runes[i:i+3])] = struct{}{}
+	}
+	return set
+}
+
+// Levenshtein es el número mínimo de inserciones, borrados y sustituciones de runas
+func Levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// dice es el coeficiente de Dice entre dos conjuntos de trigramas
+func dice(a, b map[string]struct{}) float64 {
+	if len(a)+len(b) == 0 {
+		return 1
+	}
+	shared := 0
+	for g := range a {
+		if _, ok := b[g]; ok {
+			shared++
+		}
+	}
+	return 2 * float64(shared) / float64(len(a)+len(b))
+}
+
+// similarity combina, a partes iguales, la distancia de edición y los trigramas
+// compartidos de dos textos ya normalizados
+func similarity(a, b string, ga, gb map[string]struct{}) float64 {
+	if a == b {
+		return 1
+	}
+	longest := max(len([]rune(a)), len([]rune(b)))
+	edit := 1 - float64(Levenshtein(a, b))/float64(longest)
+	return (edit + dice(ga, gb)) / 2
+}
+
+// Score compara dos textos; 1 significa iguales tras normalizar
+func Score(a, b string) float64 {
+	na, nb := Normalize(a), Normalize(b)
+	return similarity(na, nb, trigrams(na), trigrams(nb))
+}
+
+// Match es un contenido existente parecido al que se quiere guardar
+type Match struct {
+	ID    int
+	Text  string
+	Score float64
+}
+
+// Percent es la similitud redondeada para mostrar
+func (m Match) Percent() int { return int(m.Score*100 + 0.5) }
+
+type entry struct {
+	models.ContentText
+	norm  string
+	grams map[string]struct{}
+}
+
+// Index guarda los textos existentes con sus trigramas para no comparar
+// cada texto nuevo contra todo el banco
+type Index struct {
+	entries []entry
+	posting map[string][]int // trigrama → posiciones en entries
+}
+
+// NewIndex prepara el índice de los textos existentes
+func NewIndex(texts []models.ContentText) *Index {
+	idx := &Index{posting: map[string][]int{}}
+	for _, t := range texts {
+		norm := Normalize(t.Text)
+		if norm == "" {
+			continue
+		}
+		e := entry{ContentText: t, norm: norm, grams: trigrams(norm)}
+		for g := range e.grams {
+			idx.posting[g] = append(idx.posting[g], len(idx.entries))
+		}
+		idx.entries = append(idx.entries, e)
+	}
+	return idx
+}
+
+// Find devuelve los textos con similitud ≥ threshold, del más al menos
+// parecido. exclude es el id del propio contenido al editarlo (0 si es nuevo).
+func (idx *Index) Find(text string, threshold float64, exclude int) []Match {
+	norm := Normalize(text)
+	if norm == "" {
+		return nil
+	}
+	return idx.find(norm, trigrams(norm), threshold, func(e entry) bool { return e.ID != exclude })
+}
+
+func (idx *Index) find(norm string, grams map[string]struct{}, threshold float64, keep func(entry) bool) []Match {
+	shared := map[int]int{}
+	for g := range grams {
+		for _, i := range idx.posting[g] {
+			shared[i]++
+		}
+	}
+
+	var matches []Match
+	for i, n := range shared {
+		e := idx.entries[i]
+		if !keep(e) {
+			continue
+		}
+		// Con tan pocos trigramas en común ni una edición perfecta alcanza el umbral
+		upper := (1 + 2*float64(n)/float64(len(grams)+len(e.grams))) / 2
+		if upper < threshold {
+			continue
+		}
+		if s := similarity(norm, e.norm, grams, e.grams); s >= threshold {
+			matches = append(matches, Match{ID: e.ID, Text: e.Text, Score: s})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].ID < matches[j].ID
+	})
+	return matches
+}
+
+// Cluster es un grupo de contenidos parecidos entre sí, ordenado por id
+type Cluster struct {
+	Items []models.ContentText
+}
+
+// IDs devuelve los ids del grupo
+func (c Cluster) IDs() []int {
+	ids := make([]int, len(c.Items))
+	for i, it := range c.Items {
+		ids[i] = it.ID
+	}
+	return ids
+}
+
+// Clusters agrupa los textos del índice que superan el umbral entre sí
+// (transitivamente) y devuelve los grupos de dos o más, los más grandes primero
+func (idx *Index) Clusters(threshold float64) []Cluster {
+	parent := make([]int, len(idx.entries))
+	for i := range parent {
+		parent[i] = i
+	}
+	var root func(int) int
+	root = func(i int) int {
+		if parent[i] != i {
+			parent[i] = root(parent[i])
+		}
+		return parent[i]
+	}
+
+	pos := make(map[int]int, len(idx.entries)) // id → posición
+	for i, e := range idx.entries {
+		pos[e.ID] = i
+	}
+	for i, e := range idx.entries {
+		// Cada par se compara una sola vez: sólo contra los de id mayor
+		for _, m := range idx.find(e.norm, e.grams, threshold, func(o entry) bool { return o.ID > e.ID }) {
+			a, b := root(i), root(pos[m.ID])
+			if a != b {
+				parent[b] = a
+			}
+		}
+	}
+
+	groups := map[int][]models.ContentText{}
+	for i, e := range idx.entries {
+		r := root(i)
+		groups[r] = append(groups[r], e.ContentText)
+	}
+	var clusters []Cluster
+	for _, items := range groups {
+		if len(items) < 2 {
+			continue
+		}
+		sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+		clusters = append(clusters, Cluster{Items: items})
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		if len(clusters[i].Items) != len(clusters[j].Items) {
+			return len(clusters[i].Items) > len(clusters[j].Items)
+		}
+		return clusters[i].Items[0].ID < clusters[j].Items[0].ID
+	})
+	return clusters
+}
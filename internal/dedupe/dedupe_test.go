@@ -0,0 +1,123 @@
+package dedupe
+
+import (
+	"reflect"
+	"testing"
+
+	"english-at-lima-cms/internal/models"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"How are you ?", "how are you"},
+		{"  How   are\tyou?! ", "how are you"},
+		{"I don't know", "i dont know"},
+		{"I don’t know", "i dont know"},
+		{"¿Cómo estás?", "como estas"},
+		{"well-known", "well known"},
+		{"?!...", ""},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.in); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"año", "ano", 1}, // cuenta runas, no bytes
+	}
+	for _, tt := range tests {
+		if got := Levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("Levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		a, b string
+		dup  bool
+	}{
+		{"How are you?", "How are you ?", true},
+		{"I don't like coffee.", "I dont like coffee", true},
+		{"Could you pass me the salt, please?", "Could you pass me the salt please", true},
+		{"What time is it?", "What time it is?", true},
+		{"How are you?", "How old are you?", false},
+		{"I don't like coffee.", "I don't like tea.", false},
+		{"Good morning", "Good evening", false},
+	}
+	for _, tt := range tests {
+		if got := Score(tt.a, tt.b) >= Threshold; got != tt.dup {
+			t.Errorf("Score(%q, %q) = %.2f, duplicado = %v, want %v", tt.a, tt.b, Score(tt.a, tt.b), got, tt.dup)
+		}
+	}
+}
+
+func TestIndexFind(t *testing.T) {
+	idx := NewIndex([]models.ContentText{
+		{ID: 1, Text: "How are you?"},
+		{ID: 2, Text: "How are you doing?"},
+		{ID: 3, Text: "Where is the station?"},
+		{ID: 4, Text: "how are you"},
+		{ID: 5, Text: "???"},
+	})
+
+	got := idx.Find("How are you ?", Threshold, 0)
+	if ids := matchIDs(got); !reflect.DeepEqual(ids, []int{1, 4}) {
+		t.Fatalf("Find() = %v, want [1 4]", ids)
+	}
+	if got[0].Percent() != 100 {
+		t.Errorf("Percent() = %d, want 100", got[0].Percent())
+	}
+
+	// Al editar, el propio contenido no cuenta como duplicado
+	if ids := matchIDs(idx.Find("How are you?", Threshold, 1)); !reflect.DeepEqual(ids, []int{4}) {
+		t.Errorf("Find() excluyendo 1 = %v, want [4]", ids)
+	}
+	if got := idx.Find("Completely different sentence", Threshold, 0); len(got) != 0 {
+		t.Errorf("Find() = %v, want ninguno", got)
+	}
+	if got := idx.Find("...", Threshold, 0); got != nil {
+		t.Errorf("Find() de texto vacío = %v, want nil", got)
+	}
+}
+
+func TestClusters(t *testing.T) {
+	idx := NewIndex([]models.ContentText{
+		{ID: 7, Text: "Where is the bathroom?"},
+		{ID: 1, Text: "How are you?"},
+		{ID: 2, Text: "Good morning"},
+		{ID: 3, Text: "how are you"},
+		{ID: 4, Text: "Where is the bathroom ?"},
+		{ID: 5, Text: "How are you ?!"},
+		{ID: 6, Text: "Good evening"},
+	})
+
+	var got [][]int
+	for _, c := range idx.Clusters(Threshold) {
+		got = append(got, c.IDs())
+	}
+	want := [][]int{{1, 3, 5}, {4, 7}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Clusters() = %v, want %v", got, want)
+	}
+}
+
+func matchIDs(ms []Match) []int {
+	ids := []int{}
+	for _, m := range ms {
+		ids = append(ids, m.ID)
+	}
+	return ids
+}
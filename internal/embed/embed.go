@@ -0,0 +1,120 @@
+package embed
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Themes disponibles para el widget (el colegio puede forzar uno por query)
+var Themes = map[string]bool{"light": true, "dark": true, "brand": true}
+
+var reAccent = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// NewKey genera una clave pública de 32 caracteres hexadecimales
+func NewKey() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// ParseOrigins convierte el textarea del admin (uno por línea o separados
+// por comas) en una lista de orígenes normalizados tipo https://colegio.pe
+func ParseOrigins(raw string) ([]string, error) {
+	fields := strings.FieldsFunc(raw, func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r' || r == ' '
+	})
+
+	var origins []string
+	seen := map[string]bool{}
+	for _, f := range fields {
+		origin, err := normalizeOrigin(f)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[origin] {
+			seen[origin] = true
+			origins = append(origins, origin)
+		}
+	}
+	if len(origins) == 0 {
+		return nil, fmt.Errorf("debe indicar al menos un dominio autorizado")
+	}
+	return origins, nil
+}
+
+func normalizeOrigin(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return "", fmt.Errorf("dominio inválido: %q (use https://dominio.com)", raw)
+	}
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.User != nil {
+		return "", fmt.Errorf("el dominio %q no debe incluir rutas ni credenciales", raw)
+	}
+	// Evita que un origen rompa la cabecera CSP con ; o comillas
+	if strings.ContainsAny(u.Host, ";'\" ") {
+		return "", fmt.Errorf("dominio inválido: %q", raw)
+	}
+	return strings.ToLower(u.Scheme + "://" + u.Host), nil
+}
+
+// FrameAncestors arma la política CSP que sólo permite incrustar el iframe
+// desde los dominios registrados del socio
+func FrameAncestors(origins []string) string {
+	if len(origins) == 0 {
+		return "frame-ancestors 'none'"
+	}
+	return "frame-ancestors " + strings.Join(origins, " ")
+}
+
+// FromAllowedOrigin indica si la carga del iframe viene de una página de
+// los dominios registrados, según Origin o, si falta, Referer. Sin ninguno
+// de los dos (abrir la URL a mano, un bot) no cuenta como visita.
+func FromAllowedOrigin(allowed []string, origin, referer string) bool {
+	source := origin
+	if source == "" || source == "null" {
+		source = referer
+	}
+	u, err := url.Parse(source)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	from := strings.ToLower(u.Scheme + "://" + u.Host)
+	for _, o := range allowed {
+		if o == from {
+			return true
+		}
+	}
+	return false
+}
+
+// Theme resuelve el tema final: el de la query si es válido, si no el de la clave
+func Theme(requested, fallback string) string {
+	if Themes[requested] {
+		return requested
+	}
+	if Themes[fallback] {
+		return fallback
+	}
+	return "light"
+}
+
+// Accent sólo acepta colores #rrggbb para que no se pueda inyectar CSS
+func Accent(raw string) string {
+	if reAccent.MatchString(raw) {
+		return raw
+	}
+	return "#6366f1"
+}
+
+var reKey = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// ValidKey evita que una clave manipulada llegue al filtro de PostgREST
+func ValidKey(key string) bool {
+	return reKey.MatchString(key)
+}
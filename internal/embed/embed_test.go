@@ -0,0 +1,76 @@
+package embed
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseOrigins(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    []string
+		wantErr bool
+	}{
+		{"Un dominio", "https://colegio.edu.pe", []string{"https://colegio.edu.pe"}, false},
+		{"Varios y duplicados", "https://a.pe\nhttps://A.pe/, http://b.pe:8080", []string{"https://a.pe", "http://b.pe:8080"}, false},
+		{"Vacío", "  ", nil, true},
+		{"Con ruta", "https://a.pe/admin", nil, true},
+		{"Sin esquema", "colegio.edu.pe", nil, true},
+		{"Inyección CSP", "https://a.pe;script-src", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseOrigins(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error esperado %v, obtenido %v", tt.wantErr, err)
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("obtuve %v, esperaba %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFrameAncestors(t *testing.T) {
+	if got := FrameAncestors(nil); got != "frame-ancestors 'none'" {
+		t.Errorf("sin dominios el iframe debe bloquearse: %s", got)
+	}
+	if got := FrameAncestors([]string{"https://a.pe", "https://b.pe"}); got != "frame-ancestors https://a.pe https://b.pe" {
+		t.Errorf("política inesperada: %s", got)
+	}
+}
+
+func TestFromAllowedOrigin(t *testing.T) {
+	allowed := []string{"https://colegio.pe", "http://intranet.colegio.pe:8080"}
+	tests := []struct {
+		name, origin, referer string
+		want                  bool
+	}{
+		{"referer del socio", "", "https://colegio.pe/aula/ingles?x=1", true},
+		{"sólo el origen", "", "https://colegio.pe/", true},
+		{"mayúsculas y puerto", "", "http://INTRANET.colegio.pe:8080/", true},
+		{"cabecera Origin", "https://colegio.pe", "", true},
+		{"Origin manda sobre Referer", "https://otro.pe", "https://colegio.pe/", false},
+		{"Origin null", "null", "https://colegio.pe/", true},
+		{"otro dominio", "", "https://otro.pe/", false},
+		{"subdominio no registrado", "", "https://www.colegio.pe/", false},
+		{"otro esquema", "", "http://colegio.pe/", false},
+		{"sin cabeceras", "", "", false},
+		{"referer basura", "", "colegio.pe", false},
+	}
+	for _, tt := range tests {
+		if got := FromAllowedOrigin(allowed, tt.origin, tt.referer); got != tt.want {
+			t.Errorf("%s: FromAllowedOrigin(%q, %q) = %v, esperaba %v", tt.name, tt.origin, tt.referer, got, tt.want)
+		}
+	}
+}
+
+func TestThemeAndAccent(t *testing.T) {
+	if Theme("neon", "dark") != "dark" || Theme("brand", "dark") != "brand" || Theme("", "") != "light" {
+		t.Error("❌ Resolución de tema incorrecta")
+	}
+	if Accent("red;}body{display:none") != "#6366f1" || Accent("#10B981") != "#10B981" {
+		t.Error("❌ El color de acento permite inyectar CSS")
+	}
+}
@@ -0,0 +1,53 @@
+package embed
+
+import (
+	"english-at-lima-cms/internal/repository"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Las visitas se acumulan en RAM y se vuelcan a Supabase cada minuto para no
+// hacer una escritura por cada carga del iframe en las webs de los socios
+var (
+	viewsMu sync.Mutex
+	views   = make(map[string]int)
+)
+
+// RecordView suma una visita a la clave
+func RecordView(key string) {
+	viewsMu.Lock()
+	defer viewsMu.Unlock()
+	views[key]++
+}
+
+// drainViews entrega las visitas pendientes y deja el contador a cero
+func drainViews() map[string]int {
+	viewsMu.Lock()
+	defer viewsMu.Unlock()
+	pending := views
+	views = make(map[string]int)
+	return pending
+}
+
+// FlushViews vuelca las visitas pendientes; si Supabase falla se reintentan
+// en el siguiente ciclo en lugar de perderse
+func FlushViews() {
+	for key, n := range drainViews() {
+		if err := repository.AddEmbedViews(key, n); err != nil {
+			viewsMu.Lock()
+			views[key] += n
+			viewsMu.Unlock()
+		}
+	}
+}
+
+func StartViewFlusher() {
+	ticker := time.NewTicker(time.Minute)
+	go func() {
+		for range ticker.C {
+			FlushViews()
+		}
+	}()
+	fmt.Println("📈 Contador de visitas de widgets activo")
+}
@@ -0,0 +1,141 @@
+package exam
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"time"
+
+	"english-at-lima-cms/internal/models"
+	"english-at-lima-cms/internal/repository"
+	"english-at-lima-cms/internal/taxonomy"
+	"english-at-lima-cms/internal/xapi"
+)
+
+// Assemble sortea las preguntas aplicando las reglas en orden; un quiz que
+// cumple varias reglas nunca sale dos veces
+func Assemble(e models.Exam, rng *rand.Rand) ([]int, error) {
+	taken := map[int]bool{}
+	var ids []int
+	for i, r := range e.Rules {
+		pool, err := repository.ListQuizPool(taxonomy.Filter(r.Tag, r.Level))
+		if err != nil {
+			return nil, err
+		}
+		picked, err := Draw(pool, r.Count, taken, rng)
+		if err != nil {
+			return nil, fmt.Errorf("regla %d: %w", i+1, err)
+		}
+		for _, q := range picked {
+			ids = append(ids, q.ID)
+		}
+	}
+	return ids, nil
+}
+
+// Start crea el intento número attemptNo con la hora límite calculada en el
+// servidor. El índice único (exam_id, student_id, attempt_no) hace que dos
+// peticiones simultáneas no puedan gastar el mismo intento: la segunda
+// recibe repository.ErrAttemptTaken
+func Start(e models.Exam, student string, attemptNo int, now time.Time) (models.ExamAttempt, error) {
+	ids, err := Assemble(e, rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())))
+	if err != nil {
+		return models.ExamAttempt{}, err
+	}
+	a, err := repository.InsertAttempt(models.ExamAttempt{
+		ExamID:    e.ID,
+		Student:   student,
+		AttemptNo: attemptNo,
+		QuizIDs:   ids,
+		Responses: map[string][]string{},
+		HintsUsed: map[string]int{},
+		Deadline:  Deadline(now, e.TimeLimit).UTC().Format(time.RFC3339),
+	})
+	if err == nil {
+		xapi.Emit(xapi.ExamAttempted(xapi.Student(student), e, a))
+	}
+	return a, err
+}
+
+// Finish califica el intento con las respuestas guardadas y lo cierra. Si
+// se cierra por tiempo, la entrega queda registrada a la hora límite. Si el
+// intento ya estaba cerrado devuelve la entrega guardada.
+func Finish(a models.ExamAttempt, e models.Exam, now time.Time) (models.ExamAttempt, error) {
+	// Se califica lo que está en la base, no la copia que trajo el llamador:
+	// pudo llegar otra respuesta después de leerla
+	a, err := repository.GetAttempt(strconv.Itoa(a.ID))
+	if err != nil || a.Submitted() {
+		return a, err
+	}
+	quizzes, err := repository.GetQuizzesByIDs(a.QuizIDs)
+	if err != nil {
+		return a, err
+	}
+	res := Score(quizzes, a.Responses, a.HintsUsed)
+	submitted := now
+	if d, ok := deadline(a); ok && d.Before(now) {
+		submitted = d
+	}
+	a.Score, a.Passed, a.Breakdown = res.Percent, res.Percent >= e.PassingScore, res.ByTag
+	a.SubmittedAt = submitted.UTC().Format(time.RFC3339)
+	closed, err := repository.FinishAttempt(a)
+	if err != nil {
+		return a, err
+	}
+	if closed == 0 {
+		// Otra petición (o el worker) lo cerró antes: vale esa entrega y sus
+		// statements ya se emitieron
+		return repository.GetAttempt(strconv.Itoa(a.ID))
+	}
+	xapi.Emit(xapi.ExamGraded(xapi.Student(a.Student), e, a)...)
+	return a, nil
+}
+
+// CloseExpired califica los intentos vencidos que nadie entregó (el alumno
+// cerró el navegador, se quedó sin conexión…)
+func CloseExpired(now time.Time) (int, error) {
+	attempts, err := repository.ExpiredAttempts(now.Add(-Grace).UTC().Format(time.RFC3339))
+	if err != nil {
+		return 0, err
+	}
+	exams := map[int]models.Exam{}
+	closed := 0
+	for _, a := range attempts {
+		e, ok := exams[a.ExamID]
+		if !ok {
+			if e, err = repository.GetExam(strconv.Itoa(a.ExamID)); err != nil {
+				fmt.Printf("⚠️ No se pudo leer el examen %d: %v\n", a.ExamID, err)
+				continue
+			}
+			exams[a.ExamID] = e
+		}
+		if _, err := Finish(a, e, now); err != nil {
+			fmt.Printf("⚠️ No se pudo cerrar el intento %d: %v\n", a.ID, err)
+			continue
+		}
+		closed++
+	}
+	return closed, nil
+}
+
+// StartCloser cierra los intentos vencidos cada EXAM_SWEEP_INTERVAL (1 min por defecto)
+func StartCloser() {
+	interval := time.Minute
+	if d, err := time.ParseDuration(os.Getenv("EXAM_SWEEP_INTERVAL")); err == nil && d > 0 {
+		interval = d
+	}
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			closed, err := CloseExpired(time.Now())
+			if err != nil {
+				fmt.Println("⚠️ Cierre de exámenes fallido:", err)
+				continue
+			}
+			if closed > 0 {
+				fmt.Printf("⏱️ Intentos de examen cerrados por tiempo: %d\n", closed)
+			}
+		}
+	}()
+}
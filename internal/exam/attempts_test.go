@@ -0,0 +1,76 @@
+package exam
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"english-at-lima-cms/internal/models"
+)
+
+// fakeAttempts es un Supabase mínimo con un intento y dos quizzes de completar
+type fakeAttempts struct {
+	mu      sync.Mutex
+	stored  models.ExamAttempt
+	patches []map[string]interface{}
+}
+
+func (f *fakeAttempts) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/quizzes"):
+		json.NewEncoder(w).Encode([]models.Quiz{
+			{ID: 1, Type: models.QuizFill, Answers: []string{"goes"}},
+			{ID: 2, Type: models.QuizFill, Answers: []string{"went"}},
+		})
+	case r.Method == http.MethodGet:
+		json.NewEncoder(w).Encode([]models.ExamAttempt{f.stored})
+	case r.Method == http.MethodPatch:
+		var data map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&data)
+		f.patches = append(f.patches, data)
+		if f.stored.SubmittedAt != "" {
+			w.Write([]byte("[]"))
+			return
+		}
+		f.stored.SubmittedAt = data["submitted_at"].(string)
+		w.Write([]byte(`[{"id":1}]`))
+	}
+}
+
+func TestFinishGradesStoredResponses(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	f := &fakeAttempts{stored: models.ExamAttempt{
+		ID: 1, ExamID: 3, QuizIDs: []int{1, 2}, Deadline: now.Add(time.Hour).Format(time.RFC3339),
+		// La segunda respuesta llegó después de que el llamador leyera el intento
+		Responses: map[string][]string{"1": {"goes"}, "2": {"went"}},
+	}}
+	srv := httptest.NewServer(http.HandlerFunc(f.serve))
+	defer srv.Close()
+	t.Setenv("SUPABASE_URL", srv.URL)
+
+	stale := f.stored
+	stale.Responses = map[string][]string{"1": {"goes"}}
+	e := models.Exam{ID: 3, PassingScore: 60}
+
+	a, err := Finish(stale, e, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Score != 100 || len(f.patches) != 1 || f.patches[0]["score"] != float64(100) {
+		t.Fatalf("debería calificar las respuestas guardadas: nota %d, %+v", a.Score, f.patches)
+	}
+
+	// Un intento ya entregado no se vuelve a calificar
+	if _, err := Finish(stale, e, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.patches) != 1 {
+		t.Errorf("el intento cerrado no debería cerrarse otra vez: %d PATCH", len(f.patches))
+	}
+}
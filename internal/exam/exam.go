@@ -0,0 +1,278 @@
+package exam
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"english-at-lima-cms/internal/models"
+	"english-at-lima-cms/internal/quiz"
+	"english-at-lima-cms/internal/taxonomy"
+)
+
+// Límites de un examen
+const (
+	MaxRules     = 10
+	MaxRuleCount = 50
+	MaxQuestions = 100
+	MaxTimeLimit = 240 // minutos
+	MaxAttempts  = 10
+
+	// Grace tolera la latencia de la respuesta enviada en el último segundo
+	Grace = 10 * time.Second
+
+	// Untagged agrupa en el desglose los quizzes sin etiquetas
+	Untagged = "sin etiqueta"
+
+	// HintPenalty es la fracción de la pregunta que cuesta cada pista usada
+	HintPenalty = 0.25
+)
+
+// ErrPoolTooSmall indica que una regla pide más preguntas de las que hay
+var ErrPoolTooSmall = errors.New("no hay suficientes quizzes para la regla")
+
+// Normalize limpia título y reglas; las filas del formulario sin cantidad se descartan
+func Normalize(e *models.Exam) {
+	e.Title = strings.TrimSpace(e.Title)
+	e.Description = strings.TrimSpace(e.Description)
+	rules := make([]models.ExamRule, 0, len(e.Rules))
+	for _, r := range e.Rules {
+		if r.Count <= 0 {
+			continue
+		}
+		r.Tag = taxonomy.NormalizeTag(r.Tag)
+		r.Level = taxonomy.NormalizeLevel(r.Level)
+		rules = append(rules, r)
+	}
+	e.Rules = rules
+}
+
+// Validate comprueba reglas, tiempo, intentos y nota mínima
+func Validate(e models.Exam) error {
+	if len(e.Title) < 3 || len(e.Title) > 100 {
+		return fmt.Errorf("título debe tener entre 3 y 100 caracteres")
+	}
+	if len(e.Description) > 500 {
+		return fmt.Errorf("la descripción excede el límite de 500 caracteres")
+	}
+	if len(e.Rules) == 0 || len(e.Rules) > MaxRules {
+		return fmt.Errorf("el examen necesita entre 1 y %d reglas", MaxRules)
+	}
+	for i, r := range e.Rules {
+		if r.Count < 1 || r.Count > MaxRuleCount {
+			return fmt.Errorf("regla %d: la cantidad debe estar entre 1 y %d", i+1, MaxRuleCount)
+		}
+		if r.Level != "" && !taxonomy.ValidLevel(r.Level) {
+			return fmt.Errorf("regla %d: nivel %q no válido", i+1, r.Level)
+		}
+		if len(r.Tag) > taxonomy.MaxTagLength {
+			return fmt.Errorf("regla %d: la etiqueta supera los %d caracteres", i+1, taxonomy.MaxTagLength)
+		}
+	}
+	if n := e.Questions(); n > MaxQuestions {
+		return fmt.Errorf("el examen no puede tener más de %d preguntas (tiene %d)", MaxQuestions, n)
+	}
+	if e.TimeLimit < 1 || e.TimeLimit > MaxTimeLimit {
+		return fmt.Errorf("el tiempo límite debe estar entre 1 y %d minutos", MaxTimeLimit)
+	}
+	if e.MaxAttempts < 1 || e.MaxAttempts > MaxAttempts {
+		return fmt.Errorf("los intentos permitidos deben estar entre 1 y %d", MaxAttempts)
+	}
+	if e.PassingScore < 0 || e.PassingScore > 100 {
+		return fmt.Errorf("la nota mínima debe estar entre 0 y 100")
+	}
+	return nil
+}
+
+// Draw elige n quizzes al azar del pool que todavía no estén en taken y
+// los marca como usados
+func Draw(pool []models.Quiz, n int, taken map[int]bool, rng *rand.Rand) ([]models.Quiz, error) {
+	var candidates []models.Quiz
+	for _, q := range pool {
+		if !taken[q.ID] {
+			candidates = append(candidates, q)
+		}
+	}
+	if len(candidates) < n {
+		return nil, fmt.Errorf("%w (pide %d, hay %d)", ErrPoolTooSmall, n, len(candidates))
+	}
+	rng.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+	picked := candidates[:n]
+	for _, q := range picked {
+		taken[q.ID] = true
+	}
+	return picked, nil
+}
+
+// Deadline es la hora límite de un intento; la fija el servidor al empezar
+func Deadline(start time.Time, minutes int) time.Time {
+	return start.Add(time.Duration(minutes) * time.Minute)
+}
+
+func deadline(a models.ExamAttempt) (time.Time, bool) {
+	t, err := time.Parse(time.RFC3339, a.Deadline)
+	return t, err == nil
+}
+
+// Open indica si el intento todavía acepta respuestas
+func Open(a models.ExamAttempt, now time.Time) bool {
+	d, ok := deadline(a)
+	return ok && !a.Submitted() && now.Before(d.Add(Grace))
+}
+
+// Remaining es el tiempo que le queda al alumno (nunca negativo)
+func Remaining(a models.ExamAttempt, now time.Time) time.Duration {
+	d, ok := deadline(a)
+	if !ok || !d.After(now) {
+		return 0
+	}
+	return d.Sub(now)
+}
+
+// Result es la nota de un intento con su desglose por etiqueta
+type Result struct {
+	Correct int
+	Total   int
+	Points  float64 // aciertos menos lo descontado por pistas
+	Percent int
+	ByTag   []models.TagScore
+}
+
+// Score corrige las respuestas (indexadas por id de quiz) con quiz.Grade.
+// Cada pista usada descuenta HintPenalty de esa pregunta (sin bajar de 0);
+// el desglose por etiqueta cuenta aciertos sin descuento. Un quiz con
+// varias etiquetas cuenta en cada una de ellas
+func Score(quizzes []models.Quiz, responses map[string][]string, hints map[string]int) Result {
+	var res Result
+	byTag := map[string]*models.TagScore{}
+	for _, q := range quizzes {
+		key := strconv.Itoa(q.ID)
+		ok := quiz.Grade(q, responses[key])
+		res.Total++
+		if ok {
+			res.Correct++
+			res.Points += max(0, 1-HintPenalty*float64(hints[key]))
+		}
+		tags := q.Tags
+		if len(tags) == 0 {
+			tags = []string{Untagged}
+		}
+		for _, t := range tags {
+			ts, found := byTag[t]
+			if !found {
+				ts = &models.TagScore{Tag: t}
+				byTag[t] = ts
+			}
+			ts.Total++
+			if ok {
+				ts.Correct++
+			}
+		}
+	}
+	if res.Total > 0 {
+		res.Percent = int(res.Points * 100 / float64(res.Total))
+	}
+	res.ByTag = sortedScores(byTag)
+	return res
+}
+
+// Aggregate suma el desglose de todos los intentos entregados
+func Aggregate(attempts []models.ExamAttempt) []models.TagScore {
+	byTag := map[string]*models.TagScore{}
+	for _, a := range attempts {
+		if !a.Submitted() {
+			continue
+		}
+		for _, s := range a.Breakdown {
+			ts, found := byTag[s.Tag]
+			if !found {
+				ts = &models.TagScore{Tag: s.Tag}
+				byTag[s.Tag] = ts
+			}
+			ts.Correct += s.Correct
+			ts.Total += s.Total
+		}
+	}
+	return sortedScores(byTag)
+}
+
+func sortedScores(byTag map[string]*models.TagScore) []models.TagScore {
+	out := make([]models.TagScore, 0, len(byTag))
+	for _, ts := range byTag {
+		out = append(out, *ts)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Tag < out[j].Tag })
+	return out
+}
+
+// Question es una pregunta del intento tal como la ve el alumno
+type Question struct {
+	Number    int
+	Quiz      models.Quiz
+	Options   []string
+	Response  []string
+	HintsUsed int
+}
+
+// Hints son las pistas que el alumno ya pidió
+func (q Question) Hints() []models.Note {
+	return q.Quiz.Hints[:min(q.HintsUsed, len(q.Quiz.Hints))]
+}
+
+// HintsLeft indica cuántas pistas quedan por pedir
+func (q Question) HintsLeft() int { return max(len(q.Quiz.Hints)-q.HintsUsed, 0) }
+
+// Correct corrige la pregunta (para la revisión tras entregar)
+func (q Question) Correct() bool { return quiz.Grade(q.Quiz, q.Response) }
+
+// Chosen indica si el alumno ya marcó esa opción
+func (q Question) Chosen(option string) bool {
+	for _, r := range q.Response {
+		if r == option {
+			return true
+		}
+	}
+	return false
+}
+
+// Text es la respuesta escrita (completar el hueco)
+func (q Question) Text() string {
+	if len(q.Response) == 0 {
+		return ""
+	}
+	return q.Response[0]
+}
+
+func (q Question) Answered() bool { return len(q.Response) > 0 }
+
+// Questions arma las preguntas en el orden sorteado. En las de ordenar se
+// muestra el orden que dejó el alumno o, si no respondió, uno mezclado que
+// no cambia al recargar la página
+func Questions(a models.ExamAttempt, quizzes []models.Quiz) []Question {
+	byID := make(map[int]models.Quiz, len(quizzes))
+	for _, q := range quizzes {
+		byID[q.ID] = q
+	}
+	var out []Question
+	for _, id := range a.QuizIDs {
+		q, ok := byID[id]
+		if !ok {
+			continue // el quiz se borró después de sortear
+		}
+		resp := a.Responses[strconv.Itoa(id)]
+		opts := q.Options
+		if q.Type == models.QuizOrdering {
+			if len(resp) == len(q.Options) {
+				opts = resp
+			} else {
+				opts = models.Scramble(q.Options, uint64(a.ID), uint64(id))
+			}
+		}
+		out = append(out, Question{Number: len(out) + 1, Quiz: q, Options: opts, Response: resp, HintsUsed: a.HintsUsed[strconv.Itoa(id)]})
+	}
+	return out
+}
@@ -0,0 +1,196 @@
+package exam
+
+import (
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+
+	"english-at-lima-cms/internal/models"
+)
+
+func TestValidate(t *testing.T) {
+	base := func() models.Exam {
+		return models.Exam{
+			Title: "Examen de ubicación", TimeLimit: 30, MaxAttempts: 2, PassingScore: 60,
+			Rules: []models.ExamRule{{Tag: "grammar", Level: "A2", Count: 10}},
+		}
+	}
+	tests := []struct {
+		name    string
+		edit    func(*models.Exam)
+		wantErr bool
+	}{
+		{"Examen válido", func(e *models.Exam) {}, false},
+		{"Sin reglas", func(e *models.Exam) { e.Rules = nil }, true},
+		{"Filas vacías se ignoran", func(e *models.Exam) { e.Rules = append(e.Rules, models.ExamRule{Tag: "food"}) }, false},
+		{"Regla sin filtros", func(e *models.Exam) { e.Rules = []models.ExamRule{{Count: 5}} }, false},
+		{"Nivel inventado", func(e *models.Exam) { e.Rules[0].Level = "D1" }, true},
+		{"Demasiadas por regla", func(e *models.Exam) { e.Rules[0].Count = 51 }, true},
+		{"Más de 100 preguntas", func(e *models.Exam) {
+			e.Rules = []models.ExamRule{{Count: 50}, {Count: 50}, {Count: 1}}
+		}, true},
+		{"Sin tiempo", func(e *models.Exam) { e.TimeLimit = 0 }, true},
+		{"Tiempo excesivo", func(e *models.Exam) { e.TimeLimit = 241 }, true},
+		{"Sin intentos", func(e *models.Exam) { e.MaxAttempts = 0 }, true},
+		{"Nota mayor a 100", func(e *models.Exam) { e.PassingScore = 101 }, true},
+		{"Título corto", func(e *models.Exam) { e.Title = " Ex " }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := base()
+			tt.edit(&e)
+			Normalize(&e)
+			if err := Validate(e); (err != nil) != tt.wantErr {
+				t.Errorf("error esperado %v, obtenido %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func pool(ids ...int) []models.Quiz {
+	out := make([]models.Quiz, len(ids))
+	for i, id := range ids {
+		out[i] = models.Quiz{ID: id}
+	}
+	return out
+}
+
+func TestDraw(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	taken := map[int]bool{}
+
+	first, err := Draw(pool(1, 2, 3, 4, 5), 3, taken, rng)
+	if err != nil || len(first) != 3 {
+		t.Fatalf("esperaba 3 preguntas: %v %v", first, err)
+	}
+	// La segunda regla comparte quizzes con la primera: no puede repetirlos
+	second, err := Draw(pool(1, 2, 3, 4, 5), 2, taken, rng)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[int]bool{}
+	for _, q := range append(first, second...) {
+		if seen[q.ID] {
+			t.Errorf("❌ El quiz %d salió dos veces", q.ID)
+		}
+		seen[q.ID] = true
+	}
+
+	if _, err := Draw(pool(1, 2, 3, 4, 5), 1, taken, rng); !errors.Is(err, ErrPoolTooSmall) {
+		t.Errorf("esperaba ErrPoolTooSmall con el pool agotado, obtuve %v", err)
+	}
+}
+
+func TestOpenAndRemaining(t *testing.T) {
+	start := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	a := models.ExamAttempt{Deadline: Deadline(start, 20).Format(time.RFC3339)}
+
+	tests := []struct {
+		name      string
+		now       time.Time
+		open      bool
+		remaining time.Duration
+	}{
+		{"Recién empezado", start, true, 20 * time.Minute},
+		{"Dentro del margen", start.Add(20*time.Minute + 5*time.Second), true, 0},
+		{"Vencido", start.Add(21 * time.Minute), false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Open(a, tt.now); got != tt.open {
+				t.Errorf("Open = %v, esperaba %v", got, tt.open)
+			}
+			if got := Remaining(a, tt.now); got != tt.remaining {
+				t.Errorf("Remaining = %v, esperaba %v", got, tt.remaining)
+			}
+		})
+	}
+
+	a.SubmittedAt = start.Format(time.RFC3339)
+	if Open(a, start) {
+		t.Error("❌ Un intento entregado sigue aceptando respuestas")
+	}
+	if Open(models.ExamAttempt{Deadline: "mañana"}, start) {
+		t.Error("❌ Un intento con hora límite ilegible quedó abierto")
+	}
+}
+
+func TestScore(t *testing.T) {
+	quizzes := []models.Quiz{
+		{ID: 1, Type: "single", Options: []string{"a", "b"}, Answers: []string{"a"}, Taxonomy: models.Taxonomy{Tags: []string{"grammar"}}},
+		{ID: 2, Type: "fill", Answers: []string{"went"}, Taxonomy: models.Taxonomy{Tags: []string{"grammar", "past"}}},
+		{ID: 3, Type: "multiple", Options: []string{"x", "y", "z"}, Answers: []string{"x", "z"}, Taxonomy: models.Taxonomy{Tags: []string{"past"}}},
+		{ID: 4, Type: "truefalse", Options: []string{"True", "False"}, Answers: []string{"True"}},
+	}
+	responses := map[string][]string{
+		"1": {"a"},
+		"2": {"Went."},
+		"3": {"x"},
+		// el 4 quedó sin responder
+	}
+	res := Score(quizzes, responses, nil)
+	if res.Correct != 2 || res.Total != 4 || res.Percent != 50 {
+		t.Errorf("nota incorrecta: %+v", res)
+	}
+	want := []models.TagScore{
+		{Tag: "grammar", Correct: 2, Total: 2},
+		{Tag: "past", Correct: 1, Total: 2},
+		{Tag: Untagged, Correct: 0, Total: 1},
+	}
+	if !slices.Equal(res.ByTag, want) {
+		t.Errorf("desglose incorrecto:\n obtuve %+v\n quería %+v", res.ByTag, want)
+	}
+
+	// Las pistas descuentan de la nota pero no del desglose por etiqueta
+	hinted := Score(quizzes, responses, map[string]int{"1": 1, "2": 5, "4": 2})
+	if hinted.Correct != 2 || hinted.Points != 0.75 || hinted.Percent != 18 {
+		t.Errorf("descuento por pistas incorrecto: %+v", hinted)
+	}
+	if !slices.Equal(hinted.ByTag, want) {
+		t.Errorf("las pistas no deberían cambiar el desglose: %+v", hinted.ByTag)
+	}
+
+	agg := Aggregate([]models.ExamAttempt{
+		{SubmittedAt: "x", Breakdown: res.ByTag},
+		{SubmittedAt: "x", Breakdown: []models.TagScore{{Tag: "grammar", Correct: 0, Total: 2}}},
+		{Breakdown: []models.TagScore{{Tag: "grammar", Correct: 9, Total: 9}}}, // en curso: no cuenta
+	})
+	if agg[0] != (models.TagScore{Tag: "grammar", Correct: 2, Total: 4}) || agg[0].Percent() != 50 {
+		t.Errorf("agregado incorrecto: %+v", agg)
+	}
+}
+
+func TestQuestions(t *testing.T) {
+	ordering := models.Quiz{ID: 7, Type: "ordering", Options: []string{"I", "have", "been", "to", "Cusco"}}
+	single := models.Quiz{ID: 3, Type: "single", Options: []string{"a", "b"}, Answers: []string{"a"}}
+	a := models.ExamAttempt{ID: 42, QuizIDs: []int{7, 99, 3}, Responses: map[string][]string{"3": {"b"}}}
+
+	qs := Questions(a, []models.Quiz{single, ordering})
+	if len(qs) != 2 || qs[0].Quiz.ID != 7 || qs[1].Number != 2 {
+		t.Fatalf("orden o numeración incorrectos: %+v", qs)
+	}
+	if !qs[1].Chosen("b") || qs[1].Chosen("a") {
+		t.Error("❌ No se marcó la respuesta guardada")
+	}
+	// La mezcla es la misma en cada recarga
+	again := Questions(a, []models.Quiz{single, ordering})
+	if !slices.Equal(qs[0].Options, again[0].Options) {
+		t.Error("❌ El orden mezclado cambia al recargar")
+	}
+	if !slices.Equal(ordering.Options, []string{"I", "have", "been", "to", "Cusco"}) {
+		t.Error("❌ Mezclar modificó las opciones del quiz")
+	}
+
+	hinted := models.Quiz{ID: 3, Type: "single", Options: []string{"a", "b"}, Hints: []models.Note{{ES: "uno"}, {ES: "dos"}}}
+	a.HintsUsed = map[string]int{"3": 1}
+	if q := Questions(a, []models.Quiz{hinted})[0]; len(q.Hints()) != 1 || q.HintsLeft() != 1 {
+		t.Errorf("pistas mal contadas: %d vistas, %d restantes", len(q.Hints()), q.HintsLeft())
+	}
+
+	a.Responses["7"] = []string{"Cusco", "to", "been", "have", "I"}
+	if got := Questions(a, []models.Quiz{ordering})[0].Options; !slices.Equal(got, a.Responses["7"]) {
+		t.Errorf("esperaba el orden del alumno, obtuve %v", got)
+	}
+}
@@ -0,0 +1,167 @@
+// Package export escribe listados de contenido en CSV, XLSX o JSON fila a
+// fila, sin cargar la tabla entera en memoria.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// Formatos soportados
+const (
+	CSV  = "csv"
+	XLSX = "xlsx"
+	JSON = "json"
+)
+
+// Formats son los formatos en el orden en que se ofrecen en el admin
+var Formats = []string{CSV, XLSX, JSON}
+
+var contentTypes = map[string]string{
+	CSV:  "text/csv; charset=utf-8",
+	XLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	JSON: "application/json; charset=utf-8",
+}
+
+// Valid indica si el formato está soportado
+func Valid(format string) bool { return slices.Contains(Formats, format) }
+
+// ContentType es la cabecera HTTP del formato
+func ContentType(format string) string { return contentTypes[format] }
+
+// Writer recibe las filas de una exportación. CSV y XLSX usan las celdas;
+// JSON escribe el elemento completo tal como lo devuelve la API.
+type Writer interface {
+	Write(cells []string, item interface{}) error
+	// Close termina el archivo; sin él un XLSX o un JSON quedan inválidos
+	Close() error
+}
+
+// New prepara la exportación en w. sheet es el nombre de la hoja en XLSX.
+func New(format string, w io.Writer, sheet string, header []string) (Writer, error) {
+	switch format {
+	case CSV:
+		return newCSV(w, header), nil
+	case XLSX:
+		return newXLSX(w, sheet, header), nil
+	case JSON:
+		return newJSON(w), nil
+	}
+	return nil, fmt.Errorf("formato de exportación desconocido: %s", format)
+}
+
+// --- CSV ---
+
+// utf8BOM hace que Excel (también en configuración regional española) lea
+// los acentos como UTF-8 en vez de Windows-1252
+const utf8BOM = "\xEF\xBB\xBF"
+
+type csvWriter struct {
+	w      *csv.Writer
+	header []string
+	begun  bool
+}
+
+func newCSV(w io.Writer, header []string) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(&bomWriter{w: w}), header: header}
+}
+
+func (cw *csvWriter) begin() error {
+	if cw.begun {
+		return nil
+	}
+	cw.begun = true
+	return cw.w.Write(cw.header)
+}
+
+func (cw *csvWriter) Write(cells []string, _ interface{}) error {
+	if err := cw.begin(); err != nil {
+		return err
+	}
+	safe := make([]string, len(cells))
+	for i, cell := range cells {
+		safe[i] = Neutralize(cell)
+	}
+	return cw.w.Write(safe)
+}
+
+func (cw *csvWriter) Close() error {
+	if err := cw.begin(); err != nil {
+		return err
+	}
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// bomWriter antepone el BOM a lo primero que se escribe
+type bomWriter struct {
+	w    io.Writer
+	done bool
+}
+
+func (b *bomWriter) Write(p []byte) (int, error) {
+	if !b.done {
+		b.done = true
+		if _, err := io.WriteString(b.w, utf8BOM); err != nil {
+			return 0, err
+		}
+	}
+	return b.w.Write(p)
+}
+
+// Neutralize evita la inyección de fórmulas: Excel ejecuta las celdas que
+// empiezan por = + - @ aunque vengan de un CSV. Se les antepone un apóstrofo,
+// que Excel oculta y el importador quita.
+func Neutralize(cell string) string {
+	if cell != "" && isFormulaStart(cell[0]) {
+		return "'" + cell
+	}
+	return cell
+}
+
+func isFormulaStart(b byte) bool {
+	switch b {
+	case '=', '+', '-', '@', '\t', '\r':
+		return true
+	}
+	return false
+}
+
+// --- JSON ---
+
+// jsonWriter escribe un array con un elemento por línea
+type jsonWriter struct {
+	w     io.Writer
+	count int
+}
+
+func newJSON(w io.Writer) *jsonWriter { return &jsonWriter{w: w} }
+
+func (jw *jsonWriter) Write(_ []string, item interface{}) error {
+	b, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	sep := ",\n"
+	if jw.count == 0 {
+		sep = "[\n"
+	}
+	jw.count++
+	if _, err := io.WriteString(jw.w, sep); err != nil {
+		return err
+	}
+	_, err = jw.w.Write(b)
+	return err
+}
+
+func (jw *jsonWriter) Close() error {
+	end := "\n]\n"
+	if jw.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(jw.w, end)
+	return err
+}
@@ -0,0 +1,151 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCSV(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := New(CSV, &buf, "", []string{"English", "Spanish"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := [][]string{
+		{"Good morning", "Buenos días"},
+		{"=HYPERLINK(\"http://x\")", "-5 grados"},
+		{"Say \"hi\", please", "@todos"},
+	}
+	for _, r := range rows {
+		if err := w.Write(r, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := utf8BOM + "English,Spanish\n" +
+		"Good morning,Buenos días\n" +
+		"\"'=HYPERLINK(\"\"http://x\"\")\",'-5 grados\n" +
+		"\"Say \"\"hi\"\", please\",'@todos\n"
+	if buf.String() != want {
+		t.Errorf("CSV:\n%q\nesperaba\n%q", buf.String(), want)
+	}
+
+	// Sin filas sale igualmente la cabecera
+	buf.Reset()
+	w, _ = New(CSV, &buf, "", []string{"ID"})
+	_ = w.Close()
+	if buf.String() != utf8BOM+"ID\n" {
+		t.Errorf("CSV vacío: %q", buf.String())
+	}
+}
+
+func TestJSON(t *testing.T) {
+	type item struct {
+		ID      int    `json:"id"`
+		English string `json:"english"`
+	}
+	tests := []struct {
+		name  string
+		items []item
+	}{
+		{"Vacío", nil},
+		{"Uno", []item{{1, "Hi"}}},
+		{"Varios", []item{{1, "Hi"}, {2, "</script> & more"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, _ := New(JSON, &buf, "", nil)
+			for _, it := range tt.items {
+				if err := w.Write(nil, it); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			var got []item
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("JSON inválido %q: %v", buf.String(), err)
+			}
+			if len(got) != len(tt.items) || (len(got) > 0 && got[len(got)-1] != tt.items[len(tt.items)-1]) {
+				t.Errorf("leyó %v", got)
+			}
+		})
+	}
+}
+
+func readZipFile(t *testing.T, data []byte, name string) string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range zr.File {
+		if f.Name == name {
+			rc, _ := f.Open()
+			b, _ := io.ReadAll(rc)
+			rc.Close()
+			return string(b)
+		}
+	}
+	t.Fatalf("falta %s en el libro", name)
+	return ""
+}
+
+func TestXLSX(t *testing.T) {
+	var buf bytes.Buffer
+	w, _ := New(XLSX, &buf, "Frases: [A1]", []string{"English", "Spanish"})
+	_ = w.Write([]string{"Fish & <chips>", ""}, nil)
+	_ = w.Write([]string{"=1+1", "a\x00b\x1fc\nd"}, nil)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	if wb := readZipFile(t, data, "xl/workbook.xml"); !strings.Contains(wb, `name="Frases A1"`) {
+		t.Errorf("nombre de hoja no saneado: %s", wb)
+	}
+	sheet := readZipFile(t, data, "xl/worksheets/sheet1.xml")
+	for _, want := range []string{
+		`<c r="A1" t="inlineStr" s="1"><is><t xml:space="preserve">English</t>`,
+		`<c r="A2" t="inlineStr"><is><t xml:space="preserve">Fish &amp; &lt;chips&gt;</t>`,
+		`<row r="2"><c r="A2"`,
+		`</c></row><row r="3">`,
+		// En XLSX una celda de texto nunca se evalúa: no hace falta el apóstrofo
+		`<t xml:space="preserve">=1+1</t>`,
+		`<c r="B3" t="inlineStr"><is><t xml:space="preserve">abc&#xA;d</t>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("la hoja no contiene %s:\n%s", want, sheet)
+		}
+	}
+	if strings.Contains(sheet, `r="B2"`) {
+		t.Error("las celdas vacías no deberían escribirse")
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(i); got != want {
+			t.Errorf("columnName(%d) = %s, esperaba %s", i, got, want)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New("pdf", io.Discard, "", nil); err == nil || Valid("pdf") {
+		t.Error("un formato desconocido debería rechazarse")
+	}
+	for _, f := range Formats {
+		if !Valid(f) || ContentType(f) == "" {
+			t.Errorf("%s sin Content-Type", f)
+		}
+	}
+}
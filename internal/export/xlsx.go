@@ -0,0 +1,172 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxCellLen es el máximo de caracteres que Excel admite en una celda
+const maxCellLen = 32767
+
+// Partes fijas del libro: una sola hoja con textos en línea (inlineStr), así
+// no hace falta reunir todos los textos en sharedStrings antes de escribir
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/><Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/></Types>`
+
+	xlsxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/></Relationships>`
+
+	// Estilo 1: negrita para la cabecera
+	xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts><fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills><borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders><cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs><cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs></styleSheet>`
+
+	// La primera fila queda fija al desplazarse
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews><sheetData>`
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+type xlsxWriter struct {
+	out    io.Writer
+	zw     *zip.Writer
+	sheet  *bufio.Writer
+	name   string
+	header []string
+	row    int
+}
+
+func newXLSX(w io.Writer, sheet string, header []string) *xlsxWriter {
+	return &xlsxWriter{out: w, name: sheetName(sheet), header: header}
+}
+
+// begin escribe las partes fijas y abre la hoja; se hace con la primera fila
+// para que un error al leer la base no deje medio ZIP en la respuesta
+func (xw *xlsxWriter) begin() error {
+	if xw.zw != nil {
+		return nil
+	}
+	xw.zw = zip.NewWriter(xw.out)
+	workbook := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="` + escapeXML(xw.name) + `" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	parts := [][2]string{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRels},
+		{"xl/workbook.xml", workbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, p := range parts {
+		f, err := xw.zw.Create(p[0])
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, p[1]); err != nil {
+			return err
+		}
+	}
+	f, err := xw.zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	xw.sheet = bufio.NewWriter(f)
+	if _, err := xw.sheet.WriteString(xlsxSheetStart); err != nil {
+		return err
+	}
+	return xw.writeRow(xw.header, 1)
+}
+
+func (xw *xlsxWriter) Write(cells []string, _ interface{}) error {
+	if err := xw.begin(); err != nil {
+		return err
+	}
+	return xw.writeRow(cells, 0)
+}
+
+// writeRow escribe una fila de textos; style 1 es la cabecera en negrita
+func (xw *xlsxWriter) writeRow(cells []string, style int) error {
+	xw.row++
+	r := strconv.Itoa(xw.row)
+	var b strings.Builder
+	b.WriteString(`<row r="` + r + `">`)
+	for i, cell := range cells {
+		if cell == "" {
+			continue
+		}
+		b.WriteString(`<c r="` + columnName(i) + r + `" t="inlineStr"`)
+		if style > 0 {
+			b.WriteString(` s="` + strconv.Itoa(style) + `"`)
+		}
+		b.WriteString(`><is><t xml:space="preserve">` + escapeXML(cellText(cell)) + `</t></is></c>`)
+	}
+	b.WriteString(`</row>`)
+	_, err := xw.sheet.WriteString(b.String())
+	return err
+}
+
+func (xw *xlsxWriter) Close() error {
+	if err := xw.begin(); err != nil {
+		return err
+	}
+	if _, err := xw.sheet.WriteString(xlsxSheetEnd); err != nil {
+		return err
+	}
+	if err := xw.sheet.Flush(); err != nil {
+		return err
+	}
+	return xw.zw.Close()
+}
+
+// columnName convierte el índice (desde 0) en la letra de columna: A, B… Z, AA…
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// cellText quita los caracteres que XML no admite y recorta al máximo de Excel
+func cellText(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' || (r >= 0x20 && r != 0xFFFE && r != 0xFFFF && r != utf8.RuneError) {
+			return r
+		}
+		return -1
+	}, s)
+	if utf8.RuneCountInString(s) > maxCellLen {
+		s = string([]rune(s)[:maxCellLen])
+	}
+	return s
+}
+
+// sheetName respeta las reglas de Excel: hasta 31 caracteres y sin []:*?/\
+func sheetName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return -1
+		}
+		return r
+	}, s)
+	if utf8.RuneCountInString(s) > 31 {
+		s = string([]rune(s)[:31])
+	}
+	if s == "" {
+		s = "Hoja1"
+	}
+	return s
+}
+
+func escapeXML(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -16,7 +16,8 @@ import (
 
 func GlobalSearch(c *gin.Context) {
 	query := strings.TrimSpace(c.Query("search"))
-	if len(query) < 2 {
+	filter, view := taxonomyQuery(c)
+	if len(query) < 2 && filter == "" {
 		c.Status(http.StatusNoContent)
 		return
 	}
@@ -36,9 +37,15 @@ func GlobalSearch(c *gin.Context) {
 		}
 	}
 
-	go search("sentences", &sentences, fmt.Sprintf("or=(english.ilike.*%s*,spanish.ilike.*%s*)", query, query))
-	go search("quizzes", &quizzes, fmt.Sprintf("question.ilike.*%s*", query))
-	go search("resources", &resources, fmt.Sprintf("title.ilike.*%s*", query))
+	// Sin texto se busca sólo por etiqueta/nivel
+	active := "select=*&" + repository.ActiveFilter
+	sentenceQ, quizQ, resourceQ := active, active, active
+	sentenceQ = withFilter(sentenceQ, searchFilter("sentence", query))
+	quizQ = withFilter(quizQ, searchFilter("quiz", query))
+	resourceQ = withFilter(resourceQ, searchFilter("resource", query))
+	go search("sentences", &sentences, withFilter(sentenceQ, filter))
+	go search("quizzes", &quizzes, withFilter(quizQ, filter))
+	go search("resources", &resources, withFilter(resourceQ, filter))
 
 	wg.Wait()
 
@@ -47,7 +54,7 @@ func GlobalSearch(c *gin.Context) {
 	}
 
 	c.HTML(http.StatusOK, "search-results.html", gin.H{
-		"Sentences": sentences, "Quizzes": quizzes, "Resources": resources, "Query": query,
+		"Sentences": sentences, "Quizzes": quizzes, "Resources": resources, "Query": query, "Filter": view,
 	})
 }
 
@@ -82,6 +89,16 @@ func GetStats(c *gin.Context) {
 	go getTableCount("quizzes", "quizzes")
 	go getTableCount("resources", "resources")
 
+	// Uso de cada etiqueta por tipo de contenido
+	if tags, err := repository.ListTagCounts(); err == nil {
+		counts["tags"] = tags
+	}
+
+	// Visitas de los widgets incrustados por cada colegio socio
+	if keys, err := repository.ListEmbedKeys(); err == nil {
+		counts["embeds"] = keys
+	}
+
 	wg.Wait()
 	c.HTML(http.StatusOK, "stats-panel.html", counts)
 }
@@ -92,6 +109,7 @@ func GetAdminDashboard(c *gin.Context) {
 
 	c.HTML(http.StatusOK, "admin.html", gin.H{
 		"UserEmail": email,
+		"Reviewer":  isReviewer(c),
 	})
 }
 
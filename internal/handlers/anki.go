@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"encoding/json"
+	"english-at-lima-cms/internal/anki"
+	"english-at-lima-cms/internal/models"
+	"english-at-lima-cms/internal/repository"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	ankiRootDeck = "English at Lima"
+	// maxAnkiNotes limita el mazo: la colección se arma en memoria
+	maxAnkiNotes = 10000
+)
+
+var errTooManyNotes = fmt.Errorf("hay más de %d frases con esos filtros: afine la búsqueda", maxAnkiNotes)
+
+// ankiNote convierte la frase en nota. Los mazos cuelgan de «English at
+// Lima», uno por nivel o por etiqueta (la primera, si tiene varias; las
+// demás y el nivel van como etiquetas de Anki).
+func ankiNote(s models.Sentence, byTag bool, now time.Time) anki.Note {
+	deck := s.Level
+	if deck == "" {
+		deck = "Sin nivel"
+	}
+	if byTag {
+		deck = "Sin etiqueta"
+		if len(s.Tags) > 0 {
+			deck = s.Tags[0]
+		}
+	}
+	tags := append([]string{}, s.Tags...)
+	if s.Level != "" {
+		tags = append(tags, "nivel::"+s.Level)
+	}
+	modified, err := time.Parse(time.RFC3339, s.UpdatedAt)
+	if err != nil {
+		modified = now
+	}
+	return anki.Note{
+		SentenceID: s.ID, English: s.English, Spanish: s.Spanish, IPA: s.IPA,
+		Deck: ankiRootDeck + "::" + deck, Tags: tags, Modified: modified,
+	}
+}
+
+// ExportAnki descarga como mazo de Anki (.apkg) las frases del listado con
+// sus filtros (?status, ?level, ?tag, ?search). ?deck=tag reparte las notas
+// por etiqueta en vez de por nivel. Sólo se incluyen los audios subidos al
+// sitio; los enlaces externos no se descargan.
+func ExportAnki(c *gin.Context) {
+	filter, _ := listQuery(c, "sentence")
+	byTag := c.Query("deck") == "tag"
+	now := time.Now()
+
+	var notes []anki.Note
+	var media []anki.Media
+	err := repository.ExportRows("sentences", filter, "id.asc", func(raw json.RawMessage) error {
+		if len(notes) == maxAnkiNotes {
+			return errTooManyNotes
+		}
+		var s models.Sentence
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return err
+		}
+		n := ankiNote(s, byTag, now)
+		if key, ok := mediaKey(s.AudioURL); ok && mediaStore != nil {
+			// Nombre fijo por frase: al reimportar se reutiliza el mismo archivo
+			n.Audio = fmt.Sprintf("english-at-lima-%d%s", s.ID, path.Ext(key))
+			media = append(media, anki.Media{Name: n.Audio, Open: func() (io.ReadCloser, error) { return mediaStore.Open(key) }})
+		}
+		notes = append(notes, n)
+		return nil
+	})
+	switch {
+	case errors.Is(err, errTooManyNotes):
+		c.String(http.StatusRequestEntityTooLarge, err.Error())
+		return
+	case err != nil:
+		c.String(http.StatusInternalServerError, "Error al leer las frases")
+		return
+	case len(notes) == 0:
+		c.String(http.StatusNotFound, "No hay frases con esos filtros")
+		return
+	}
+
+	c.Header("Content-Type", "application/apkg")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="english-at-lima-%s.apkg"`, now.In(models.Lima).Format("20060102")))
+	skipped, err := anki.Write(c.Writer, notes, media, now)
+	if len(skipped) > 0 {
+		fmt.Println("⚠️ Mazo de Anki sin estos audios:", skipped)
+	}
+	if err != nil {
+		fmt.Println("❌ Mazo de Anki interrumpido:", err)
+		if !c.Writer.Written() {
+			c.Header("Content-Disposition", "")
+			c.Header("Content-Type", "text/plain; charset=utf-8")
+			c.String(http.StatusInternalServerError, "Error al armar el mazo")
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"english-at-lima-cms/internal/models"
+)
+
+func TestAnkiNote(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		s        models.Sentence
+		byTag    bool
+		deck     string
+		tags     []string
+		modified time.Time
+	}{
+		{
+			name: "Por nivel",
+			s:    models.Sentence{ID: 3, English: "Hi", Taxonomy: models.Taxonomy{Level: "A1", Tags: []string{"greetings", "small talk"}}, UpdatedAt: "2026-10-01T09:30:00.123456+00:00"},
+			deck: "English at Lima::A1", tags: []string{"greetings", "small talk", "nivel::A1"}, modified: time.Date(2026, 10, 1, 9, 30, 0, 123456000, time.UTC),
+		},
+		{
+			name: "Por etiqueta: la primera", byTag: true,
+			s:    models.Sentence{ID: 3, Taxonomy: models.Taxonomy{Level: "A1", Tags: []string{"greetings", "small talk"}}},
+			deck: "English at Lima::greetings", tags: []string{"greetings", "small talk", "nivel::A1"}, modified: now,
+		},
+		{name: "Sin nivel", s: models.Sentence{ID: 4}, deck: "English at Lima::Sin nivel", tags: []string{}, modified: now},
+		{name: "Sin etiqueta", byTag: true, s: models.Sentence{ID: 4}, deck: "English at Lima::Sin etiqueta", tags: []string{}, modified: now},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := ankiNote(tt.s, tt.byTag, now)
+			if n.Deck != tt.deck || !reflect.DeepEqual(n.Tags, tt.tags) || !n.Modified.Equal(tt.modified) {
+				t.Errorf("mazo %q, etiquetas %q, modificada %v", n.Deck, n.Tags, n.Modified)
+			}
+			if n.GUID() != ankiNote(tt.s, !tt.byTag, now.Add(time.Hour)).GUID() {
+				t.Error("el GUID no debe depender del mazo ni de la fecha")
+			}
+		})
+	}
+}
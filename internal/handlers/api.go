@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"english-at-lima-cms/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Paginación de la API pública de frases
+const (
+	apiDefaultLimit = 50
+	apiMaxLimit     = 100
+)
+
+// apiPage lee limit y offset de la consulta, con valores por defecto seguros
+func apiPage(c *gin.Context) (limit, offset int) {
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit <= 0 {
+		limit = apiDefaultLimit
+	}
+	offset, err = strconv.Atoi(c.Query("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	return min(limit, apiMaxLimit), offset
+}
+
+// APISentences lista las frases publicadas en JSON con filtros ?tag= y ?level=
+func APISentences(c *gin.Context) {
+	filter, _ := taxonomyQuery(c)
+	limit, offset := apiPage(c)
+	sentences, err := repository.ListSentences(filter, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error de conexión"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": sentences, "limit": limit, "offset": offset})
+}
+
+// APISentence devuelve una frase con todos sus datos opcionales
+func APISentence(c *gin.Context) {
+	id := c.Param("id")
+	if n, err := strconv.Atoi(id); err != nil || n <= 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contenido no encontrado"})
+		return
+	}
+	s, err := repository.GetSentence(id)
+	if err == nil && !s.Public() {
+		err = repository.ErrNotFound
+	}
+	if err == repository.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contenido no encontrado"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error de conexión"})
+		return
+	}
+	c.JSON(http.StatusOK, s)
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"english-at-lima-cms/internal/models"
 	"english-at-lima-cms/internal/repository"
 	"net/http"
 
@@ -25,9 +26,16 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	// Quien no figura en staff_roles entra como editor
+	role, err := repository.GetStaffRole(email)
+	if err != nil {
+		role = models.RoleEditor
+	}
+
 	session := sessions.Default(c)
 	session.Set("user_id", email)
 	session.Set("token", token)
+	session.Set("role", role)
 
 	if err := session.Save(); err != nil {
 		SendToast(c, "Error de sesión", "error")
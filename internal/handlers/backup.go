@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"english-at-lima-cms/internal/backup"
+	"english-at-lima-cms/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DownloadBackup envía la copia completa del sitio mientras se va leyendo,
+// tabla a tabla. Lleva correos del equipo y registros de auditoría, así que
+// sólo la descargan los revisores.
+func DownloadBackup(c *gin.Context) {
+	if !isReviewer(c) {
+		c.String(http.StatusForbidden, "Sólo un revisor puede descargar la copia de seguridad")
+		return
+	}
+	now := time.Now()
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=backup-%s.zip", now.Format("20060102-150405")))
+	c.Status(http.StatusOK)
+
+	// Si falla a medias la respuesta ya empezó: el ZIP queda sin manifiesto
+	// y la restauración lo rechaza
+	if _, err := backup.Write(c.Writer, repository.Backup{}, now); err != nil {
+		fmt.Println("❌ Copia de seguridad interrumpida:", err)
+	}
+}
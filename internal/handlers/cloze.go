@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"english-at-lima-cms/internal/cloze"
+	"english-at-lima-cms/internal/models"
+	"english-at-lima-cms/internal/repository"
+	"english-at-lima-cms/internal/taxonomy"
+
+	"github.com/gin-gonic/gin"
+)
+
+// clozeKinds son los tipos de quiz que sabe generar el generador de huecos
+var clozeKinds = map[string]string{
+	models.QuizFill:   "Completar el hueco",
+	models.QuizSingle: "Opción única",
+}
+
+// vocabulary prepara el vocabulario con el inglés de todas las frases activas
+func vocabulary() (*cloze.Vocabulary, error) {
+	texts, err := repository.ListContentTexts("sentences", "english")
+	if err != nil {
+		return nil, err
+	}
+	words := make([]string, len(texts))
+	for i, t := range texts {
+		words[i] = t.Text
+	}
+	return cloze.NewVocabulary(words), nil
+}
+
+// clozeKind lee el tipo de quiz a generar; por defecto, completar el hueco
+func clozeKind(c *gin.Context) (string, error) {
+	kind := c.DefaultPostForm("kind", models.QuizFill)
+	if _, ok := clozeKinds[kind]; !ok {
+		return "", fmt.Errorf("tipo de quiz no válido para el generador")
+	}
+	return kind, nil
+}
+
+// GetClozeDrafts muestra el generador y los borradores generados pendientes de revisar
+func GetClozeDrafts(c *gin.Context) {
+	drafts, err := repository.ListGeneratedDrafts()
+	if err != nil {
+		SendToast(c, "Error al cargar los borradores generados", "error")
+		return
+	}
+	c.HTML(http.StatusOK, "cloze-drafts.html", gin.H{
+		"Drafts":   drafts,
+		"Kinds":    clozeKinds,
+		"Sentence": c.Query("sentence"),
+		"Filter":   models.Taxonomy{},
+		"MaxBatch": cloze.MaxBatch,
+	})
+}
+
+// GenerateCloze crea un borrador a partir de una frase. La palabra del hueco
+// se puede forzar con target; si no, la elige el generador.
+func GenerateCloze(c *gin.Context) {
+	id, err := strconv.Atoi(c.PostForm("sentence"))
+	if err != nil || id <= 0 {
+		SendToast(c, "Indique el número de la frase", "error")
+		return
+	}
+	kind, err := clozeKind(c)
+	if err != nil {
+		SendToast(c, err.Error(), "error")
+		return
+	}
+	s, err := repository.GetSentence(strconv.Itoa(id))
+	if err != nil {
+		SendToast(c, "La frase no existe", "error")
+		return
+	}
+	v, err := vocabulary()
+	if err != nil {
+		SendToast(c, "Error al cargar el banco de frases", "error")
+		return
+	}
+
+	q, err := cloze.Generate(s, v, kind, Sanitize(c.PostForm("target")))
+	if err != nil {
+		SendToast(c, err.Error(), "error")
+		return
+	}
+	if err := repository.InsertGeneratedQuizzes([]models.Quiz{q}); err != nil {
+		SendToast(c, "Error al guardar el quiz generado", "error")
+		return
+	}
+	refreshWithToast(c, fmt.Sprintf("Borrador generado desde la frase #%d", id))
+}
+
+// GenerateClozeBatch genera borradores para las frases de una etiqueta o un
+// nivel. Se saltan las frases que ya tienen un quiz generado del mismo tipo.
+func GenerateClozeBatch(c *gin.Context) {
+	kind, err := clozeKind(c)
+	if err != nil {
+		SendToast(c, err.Error(), "error")
+		return
+	}
+	tag := taxonomy.NormalizeTag(c.PostForm("tag"))
+	level := taxonomy.NormalizeLevel(c.PostForm("level"))
+	if tag == "" && level == "" {
+		SendToast(c, "Elija una etiqueta o un nivel para generar en lote", "error")
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultPostForm("limit", strconv.Itoa(cloze.MaxBatch)))
+	if err != nil || limit < 1 || limit > cloze.MaxBatch {
+		SendToast(c, fmt.Sprintf("El lote debe tener entre 1 y %d frases", cloze.MaxBatch), "error")
+		return
+	}
+
+	sentences, err := repository.ListBankSentences(taxonomy.Filter(tag, level), limit)
+	if err != nil {
+		SendToast(c, "Error al cargar las frases", "error")
+		return
+	}
+	if len(sentences) == 0 {
+		SendToast(c, "No hay frases con ese filtro", "error")
+		return
+	}
+	ids := make([]int, len(sentences))
+	for i, s := range sentences {
+		ids[i] = s.ID
+	}
+	done, err := repository.GeneratedFrom(ids, kind)
+	if err != nil {
+		SendToast(c, "Error al consultar los quizzes generados", "error")
+		return
+	}
+	v, err := vocabulary()
+	if err != nil {
+		SendToast(c, "Error al cargar el banco de frases", "error")
+		return
+	}
+
+	var generated []models.Quiz
+	var failed []string
+	skipped := 0
+	for _, s := range sentences {
+		if done[s.ID] {
+			skipped++
+			continue
+		}
+		q, err := cloze.Generate(s, v, kind, "")
+		if err != nil {
+			failed = append(failed, "#"+strconv.Itoa(s.ID))
+			continue
+		}
+		generated = append(generated, q)
+	}
+	if err := repository.InsertGeneratedQuizzes(generated); err != nil {
+		SendToast(c, "Error al guardar los quizzes generados", "error")
+		return
+	}
+
+	msg := fmt.Sprintf("Borradores generados: %d", len(generated))
+	if skipped > 0 {
+		msg += fmt.Sprintf(" · ya generadas: %d", skipped)
+	}
+	if len(failed) > 0 {
+		msg += " · sin hueco adecuado: " + strings.Join(failed, ", ")
+	}
+	refreshWithToast(c, msg)
+}
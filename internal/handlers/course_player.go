@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"english-at-lima-cms/internal/course"
+	"english-at-lima-cms/internal/models"
+	"english-at-lima-cms/internal/repository"
+	"english-at-lima-cms/internal/seo"
+	"english-at-lima-cms/internal/xapi"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// studentCookie identifica al alumno (anónimo) para guardar su progreso
+const studentCookie = "eal_student"
+
+// studentID devuelve el id anónimo del alumno y lo crea si no existe
+func studentID(c *gin.Context) string {
+	if v, err := c.Cookie(studentCookie); err == nil && validStudentID(v) {
+		return v
+	}
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	id := hex.EncodeToString(b)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(studentCookie, id, 365*24*3600, "/", "", true, true)
+	return id
+}
+
+// validStudentID evita meter en el filtro de PostgREST algo que no generamos nosotros
+func validStudentID(v string) bool {
+	if len(v) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(v)
+	return err == nil
+}
+
+// ShowCourses lista los cursos publicados
+func ShowCourses(c *gin.Context) {
+	courses, err := repository.ListCourses(true)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error de conexión")
+		return
+	}
+	c.HTML(http.StatusOK, "courses-public.html", gin.H{
+		"Courses": courses, "Canonical": seo.SiteURL() + "/cursos",
+	})
+}
+
+// ShowCourse es la portada del curso: temario, progreso y botón para continuar
+func ShowCourse(c *gin.Context) {
+	co, ok := publishedCourse(c)
+	if !ok {
+		return
+	}
+	done, total, percent := course.Progress(co)
+	c.HTML(http.StatusOK, "course-player.html", withLocale(c, gin.H{
+		"Course": co, "Done": done, "Total": total, "Percent": percent,
+		"Resume": course.Resume(co), "Canonical": seo.SiteURL() + seo.CoursePath(co.ID),
+	}))
+}
+
+// ShowLesson reproduce una lección con sus elementos en orden
+func ShowLesson(c *gin.Context) {
+	co, ok := publishedCourse(c)
+	if !ok {
+		return
+	}
+	lessonID, _ := strconv.Atoi(c.Param("lesson"))
+	step, found := course.Find(co, lessonID)
+	if !found {
+		c.String(http.StatusNotFound, "Contenido no encontrado")
+		return
+	}
+	lesson := models.Course{Units: []models.Unit{{Lessons: []models.Lesson{step.Lesson}}}}
+	if err := repository.LoadLessonContent(&lesson, true); err != nil {
+		c.String(http.StatusInternalServerError, "Error de conexión")
+		return
+	}
+	step.Lesson = lesson.Units[0].Lessons[0]
+	for _, it := range step.Lesson.Items {
+		if it.Resource != nil {
+			it.Resource.URL = signedMediaURL(it.Resource.URL)
+		}
+	}
+
+	done, total, percent := course.Progress(co)
+	c.HTML(http.StatusOK, "course-player.html", withLocale(c, gin.H{
+		"Course": co, "Step": step, "Done": done, "Total": total, "Percent": percent,
+		"Canonical": seo.SiteURL() + seo.CoursePath(co.ID),
+	}))
+}
+
+// CompleteLesson marca la lección como terminada y lleva a la siguiente
+func CompleteLesson(c *gin.Context) {
+	co, ok := publishedCourse(c)
+	if !ok {
+		return
+	}
+	lessonID, _ := strconv.Atoi(c.Param("lesson"))
+	step, found := course.Find(co, lessonID)
+	if !found {
+		c.String(http.StatusNotFound, "Contenido no encontrado")
+		return
+	}
+	student := studentID(c)
+	if err := repository.MarkLessonComplete(lessonID, student); err != nil {
+		c.String(http.StatusInternalServerError, "No se pudo guardar tu progreso")
+		return
+	}
+	xapi.Emit(xapi.LessonCompleted(xapi.Student(student), co, step.Lesson))
+
+	next := seo.CoursePath(co.ID)
+	if step.Next != nil {
+		next = fmt.Sprintf("%s/lecciones/%d", next, step.Next.ID)
+	}
+	c.Redirect(http.StatusSeeOther, next)
+}
+
+// publishedCourse trae el curso ordenado con el progreso del alumno; los
+// borradores responden 404 como si no existieran
+func publishedCourse(c *gin.Context) (models.Course, bool) {
+	id, ok := publicID(c)
+	if !ok {
+		return models.Course{}, false
+	}
+	co, err := repository.GetCourseTree(id)
+	if err == nil && !co.Published {
+		err = repository.ErrNotFound
+	}
+	if !renderable(c, err) {
+		return models.Course{}, false
+	}
+	course.Sort(&co)
+	if done, err := repository.CompletedLessons(studentID(c), course.LessonIDs(co)); err == nil {
+		course.MarkCompleted(&co, done)
+	}
+	return co, true
+}
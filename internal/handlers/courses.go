@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"encoding/json"
+	"english-at-lima-cms/internal/course"
+	"english-at-lima-cms/internal/models"
+	"english-at-lima-cms/internal/repository"
+	"english-at-lima-cms/internal/taxonomy"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ValidateCourse comprueba título, descripción y nivel del curso
+func ValidateCourse(title, description, level string) error {
+	title = strings.TrimSpace(title)
+	if len(title) < 3 || len(title) > 100 {
+		return fmt.Errorf("título debe tener entre 3 y 100 caracteres")
+	}
+	if len(description) > 500 {
+		return fmt.Errorf("la descripción excede el límite de 500 caracteres")
+	}
+	if !taxonomy.ValidLevel(level) {
+		return fmt.Errorf("debe elegir un nivel MCER (A1–C2)")
+	}
+	return nil
+}
+
+// validNodeTitle aplica la misma regla de título a unidades y lecciones
+func validNodeTitle(title string) error {
+	if len(title) < 3 || len(title) > 100 {
+		return fmt.Errorf("título debe tener entre 3 y 100 caracteres")
+	}
+	return nil
+}
+
+func GetCourses(c *gin.Context) {
+	courses, err := repository.ListCourses(false)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error de conexión")
+		return
+	}
+	c.HTML(http.StatusOK, "courses-list.html", gin.H{"Courses": courses})
+}
+
+func SaveCourse(c *gin.Context) {
+	co := models.Course{
+		Title:       Sanitize(c.PostForm("title")),
+		Description: Sanitize(c.PostForm("description")),
+		Level:       taxonomy.NormalizeLevel(c.PostForm("level")),
+	}
+	if err := ValidateCourse(co.Title, co.Description, co.Level); err != nil {
+		SendToast(c, err.Error(), "error")
+		return
+	}
+	if err := repository.InsertCourse(co); err != nil {
+		SendToast(c, "Error al crear el curso", "error")
+		return
+	}
+	SendToast(c, "Curso creado como borrador", "success")
+	c.Header("HX-Trigger", "refreshList")
+}
+
+// GetCourseEditor muestra el árbol del curso con drag-and-drop y el número
+// de alumnos que completó cada lección
+func GetCourseEditor(c *gin.Context) {
+	co, ok := loadCourse(c)
+	if !ok {
+		return
+	}
+	if err := repository.LoadLessonContent(&co, false); err != nil {
+		c.String(http.StatusInternalServerError, "Error de conexión")
+		return
+	}
+	if counts, err := repository.CountCompletions(course.LessonIDs(co)); err == nil {
+		course.SetCompletions(&co, counts)
+	}
+	c.HTML(http.StatusOK, "course-editor.html", co)
+}
+
+func PublishCourse(c *gin.Context) {
+	id, ok := publicID(c)
+	if !ok {
+		return
+	}
+	published := c.PostForm("published") == "true"
+	if err := repository.SetCoursePublished(id, published); err != nil {
+		SendToast(c, "Error al cambiar el estado del curso", "error")
+		return
+	}
+	msg := "Curso despublicado"
+	if published {
+		msg = "Curso publicado"
+	}
+	refreshWithToast(c, msg)
+}
+
+func DeleteCourse(c *gin.Context) {
+	id, ok := publicID(c)
+	if !ok {
+		return
+	}
+	if err := repository.DeleteCourse(id); err != nil {
+		SendToast(c, "Error al borrar el curso", "error")
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+func AddUnit(c *gin.Context) {
+	co, ok := loadCourse(c)
+	if !ok {
+		return
+	}
+	title := Sanitize(c.PostForm("title"))
+	if err := validNodeTitle(title); err != nil {
+		SendToast(c, err.Error(), "error")
+		return
+	}
+	if err := repository.InsertUnit(co.ID, title, len(co.Units)); err != nil {
+		SendToast(c, "Error al crear la unidad", "error")
+		return
+	}
+	refreshWithToast(c, "Unidad añadida")
+}
+
+func AddLesson(c *gin.Context) {
+	co, ok := loadCourse(c)
+	if !ok {
+		return
+	}
+	unitID, _ := strconv.Atoi(c.Param("node"))
+	lessons, ok := course.Children(co, "lessons", unitID)
+	if !ok {
+		SendToast(c, "La unidad no pertenece a este curso", "error")
+		return
+	}
+	title := Sanitize(c.PostForm("title"))
+	if err := validNodeTitle(title); err != nil {
+		SendToast(c, err.Error(), "error")
+		return
+	}
+	if err := repository.InsertLesson(unitID, title, len(lessons)); err != nil {
+		SendToast(c, "Error al crear la lección", "error")
+		return
+	}
+	refreshWithToast(c, "Lección añadida")
+}
+
+// AddLessonItem añade una frase, quiz o recurso existente al final de la lección
+func AddLessonItem(c *gin.Context) {
+	co, ok := loadCourse(c)
+	if !ok {
+		return
+	}
+	lessonID, _ := strconv.Atoi(c.Param("node"))
+	items, ok := course.Children(co, "items", lessonID)
+	if !ok {
+		SendToast(c, "La lección no pertenece a este curso", "error")
+		return
+	}
+	contentType := c.PostForm("content_type")
+	contentID, err := strconv.Atoi(c.PostForm("content_id"))
+	if !course.ValidContentType(contentType) || err != nil || contentID <= 0 {
+		SendToast(c, "Elija un contenido válido", "error")
+		return
+	}
+	if !contentExists(contentType, fmt.Sprint(contentID)) {
+		SendToast(c, "El contenido elegido no existe", "error")
+		return
+	}
+	if err := repository.InsertLessonItem(lessonID, contentType, contentID, len(items)); err != nil {
+		SendToast(c, "Error al añadir el contenido", "error")
+		return
+	}
+	refreshWithToast(c, "Contenido añadido a la lección")
+}
+
+// contentExists admite borradores y programados (en el curso sólo aparecen
+// al publicarse) pero no lo archivado
+func contentExists(contentType, id string) bool {
+	var p models.Publication
+	var err error
+	switch contentType {
+	case "sentence":
+		var s models.Sentence
+		s, err = repository.GetSentence(id)
+		p = s.Publication
+	case "quiz":
+		var q models.Quiz
+		q, err = repository.GetQuiz(id)
+		p = q.Publication
+	case "resource":
+		var r models.Resource
+		r, err = repository.GetResource(id)
+		p = r.Publication
+	}
+	return err == nil && p.Status != models.StatusArchived
+}
+
+// DeleteCourseNode borra una unidad, lección o elemento del curso
+func DeleteCourseNode(c *gin.Context) {
+	co, ok := loadCourse(c)
+	if !ok {
+		return
+	}
+	kind := c.Param("kind")
+	nodeID, _ := strconv.Atoi(c.Param("node"))
+	table, known := course.Tables[kind]
+	if !known || !course.Owns(co, kind, nodeID) {
+		SendToast(c, "Elemento no encontrado en este curso", "error")
+		return
+	}
+	if err := repository.DeleteCourseRow(table, nodeID); err != nil {
+		SendToast(c, "Error al borrar", "error")
+		return
+	}
+	refreshWithToast(c, "Eliminado del curso")
+}
+
+// ReorderCourse guarda el nuevo orden tras soltar un elemento arrastrado.
+// Sortable.js reordena los <input> ocultos y HTMX los envía en ese orden.
+func ReorderCourse(c *gin.Context) {
+	co, ok := loadCourse(c)
+	if !ok {
+		return
+	}
+	kind := c.Param("kind")
+	table, known := course.Tables[kind]
+	parent, _ := strconv.Atoi(c.PostForm("parent"))
+	current, owned := course.Children(co, kind, parent)
+	if !known || !owned {
+		SendToast(c, "Orden inválido", "error")
+		return
+	}
+	ids, err := course.ParseOrder(c.PostFormArray(kind))
+	if err != nil || !course.SameSet(ids, current) {
+		SendToast(c, "El orden no coincide con el curso, recargue la página", "error")
+		return
+	}
+	if err := repository.ReorderRows(table, ids); err != nil {
+		SendToast(c, "Error al guardar el orden", "error")
+		return
+	}
+	SendToast(c, "Orden guardado", "success")
+	c.Status(http.StatusOK)
+}
+
+// CoursePicker busca contenido por texto para añadirlo a una lección
+func CoursePicker(c *gin.Context) {
+	contentType := c.Query("content_type")
+	table, ok := course.ContentTypes[contentType]
+	if !ok {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	column := map[string]string{"sentence": "english", "quiz": "question", "resource": "title"}[contentType]
+	filter := fmt.Sprintf("select=id,%s&%s&order=id.desc&limit=20", column, repository.ActiveFilter)
+	if q := strings.TrimSpace(c.Query("q")); len(q) >= 2 {
+		filter += fmt.Sprintf("&%s=ilike.*%s*", column, strings.NewReplacer(",", " ", "(", " ", ")", " ", "&", " ").Replace(q))
+	}
+
+	var rows []map[string]interface{}
+	resp, err := repository.CallSupabase("GET", table, nil, filter)
+	if err == nil && resp != nil {
+		defer resp.Body.Close()
+		_ = json.NewDecoder(resp.Body).Decode(&rows)
+	}
+	options := make([]gin.H, 0, len(rows))
+	for _, r := range rows {
+		options = append(options, gin.H{"ID": r["id"], "Label": r[column]})
+	}
+	c.HTML(http.StatusOK, "course-picker.html", gin.H{"Options": options})
+}
+
+// loadCourse lee el :id del curso y trae su árbol ordenado
+func loadCourse(c *gin.Context) (models.Course, bool) {
+	id, ok := publicID(c)
+	if !ok {
+		return models.Course{}, false
+	}
+	co, err := repository.GetCourseTree(id)
+	if !renderable(c, err) {
+		return models.Course{}, false
+	}
+	course.Sort(&co)
+	return co, true
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"english-at-lima-cms/internal/dedupe"
+	"english-at-lima-cms/internal/models"
+	"english-at-lima-cms/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// duplicateSource es de dónde sale el texto que se compara en cada tipo
+type duplicateSource struct {
+	Table  string
+	Column string
+	Label  string
+}
+
+var duplicateSources = map[string]duplicateSource{
+	"sentence": {"sentences", "english", "Frases"},
+	"quiz":     {"quizzes", "question", "Quizzes"},
+}
+
+// warnDuplicates busca contenido casi igual antes de guardar. Si lo hay,
+// pinta el aviso dentro del formulario y devuelve true: el handler no debe
+// guardar. El aviso reenvía el texto en confirm_duplicate para "guardar igual".
+func warnDuplicates(c *gin.Context, contentType, text string) bool {
+	if c.PostForm("confirm_duplicate") == text {
+		return false
+	}
+	src := duplicateSources[contentType]
+	texts, err := repository.ListContentTexts(src.Table, src.Column)
+	if err != nil {
+		// Sin el banco no se puede comparar; no bloqueamos el guardado por eso
+		return false
+	}
+	matches := dedupe.NewIndex(texts).Find(text, dedupe.Threshold, 0)
+	if len(matches) == 0 {
+		return false
+	}
+	if len(matches) > dedupe.MaxMatches {
+		matches = matches[:dedupe.MaxMatches]
+	}
+
+	c.Header("HX-Retarget", "#duplicate-warning")
+	c.Header("HX-Reswap", "innerHTML")
+	c.HTML(http.StatusOK, "duplicate-warning.html", gin.H{
+		"Type": contentType, "Text": text, "Matches": matches,
+	})
+	return true
+}
+
+// GetDuplicates agrupa el contenido existente que está casi duplicado
+func GetDuplicates(c *gin.Context) {
+	contentType := c.DefaultQuery("type", "sentence")
+	src, ok := duplicateSources[contentType]
+	if !ok {
+		c.String(http.StatusBadRequest, "Tipo de contenido no válido")
+		return
+	}
+	texts, err := repository.ListContentTexts(src.Table, src.Column)
+	if err != nil {
+		SendToast(c, "Error al cargar el contenido", "error")
+		return
+	}
+
+	c.HTML(http.StatusOK, "duplicates.html", gin.H{
+		"Type":     contentType,
+		"Sources":  duplicateSources,
+		"Clusters": dedupe.NewIndex(texts).Clusters(dedupe.Threshold),
+	})
+}
+
+// MergeDuplicates conserva el elemento elegido del grupo y archiva el resto.
+// El grupo se recalcula aquí: sólo se archivan ids del mismo grupo que keep,
+// y archivar algo que no es borrador exige un revisor (publishRule).
+func MergeDuplicates(c *gin.Context) {
+	contentType := c.Param("type")
+	src, ok := duplicateSources[contentType]
+	if !ok {
+		SendToast(c, "Tipo de contenido no válido", "error")
+		return
+	}
+	keep, err := strconv.Atoi(c.PostForm("keep"))
+	if err != nil || keep <= 0 {
+		SendToast(c, "Elija qué elemento conservar", "error")
+		return
+	}
+
+	var ids []int
+	for _, raw := range c.PostFormArray("ids") {
+		id, err := strconv.Atoi(raw)
+		if err != nil || id <= 0 {
+			SendToast(c, "Identificador no válido", "error")
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	texts, err := repository.ListContentTexts(src.Table, src.Column)
+	if err != nil {
+		SendToast(c, "Error al cargar el contenido", "error")
+		return
+	}
+	others, err := mergeGroup(dedupe.NewIndex(texts).Clusters(dedupe.Threshold), keep, ids)
+	if err != nil {
+		SendToast(c, err.Error(), "error")
+		return
+	}
+
+	pubs, err := repository.ListPublications(src.Table, others)
+	if err != nil {
+		SendToast(c, "Error al cargar el contenido", "error")
+		return
+	}
+	for _, id := range others {
+		if err := guardPublish(c, pubs[id], models.Publication{Status: models.StatusArchived}); err != nil {
+			sendGuardError(c, err)
+			return
+		}
+	}
+
+	if err := repository.MergeDuplicates(contentType, src.Table, keep, others); err != nil {
+		SendToast(c, "Error al fusionar los duplicados", "error")
+		return
+	}
+	refreshWithToast(c, "Duplicados fusionados: se conservó #"+strconv.Itoa(keep))
+}
+
+// mergeGroup devuelve los ids a archivar si keep y todos los ids pertenecen
+// al mismo grupo de casi duplicados
+func mergeGroup(clusters []dedupe.Cluster, keep int, ids []int) ([]int, error) {
+	var group []int
+	for _, cl := range clusters {
+		if slices.Contains(cl.IDs(), keep) {
+			group = cl.IDs()
+			break
+		}
+	}
+	if group == nil {
+		return nil, fmt.Errorf("#%d ya no forma parte de un grupo de duplicados", keep)
+	}
+	var others []int
+	for _, id := range ids {
+		if !slices.Contains(group, id) {
+			return nil, fmt.Errorf("#%d no es un duplicado de #%d", id, keep)
+		}
+		if id != keep && !slices.Contains(others, id) {
+			others = append(others, id)
+		}
+	}
+	if len(others) == 0 {
+		return nil, fmt.Errorf("no hay nada que fusionar")
+	}
+	return others, nil
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"english-at-lima-cms/internal/dedupe"
+	"english-at-lima-cms/internal/models"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+)
+
+func TestMergeGroup(t *testing.T) {
+	clusters := []dedupe.Cluster{
+		{Items: []models.ContentText{{ID: 1}, {ID: 4}, {ID: 9}}},
+		{Items: []models.ContentText{{ID: 2}, {ID: 3}}},
+	}
+	tests := []struct {
+		name    string
+		keep    int
+		ids     []int
+		want    []int
+		wantErr bool
+	}{
+		{"grupo completo", 4, []int{1, 4, 9}, []int{1, 9}, false},
+		{"parte del grupo", 1, []int{9}, []int{9}, false},
+		{"id de otro grupo", 1, []int{4, 3}, nil, true},
+		{"keep sin grupo", 7, []int{7, 1}, nil, true},
+		{"sólo keep", 2, []int{2}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mergeGroup(clusters, tt.keep, tt.ids)
+			if (err != nil) != tt.wantErr || !slices.Equal(got, tt.want) {
+				t.Fatalf("mergeGroup = %v, %v; esperaba %v (error %v)", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMergeDuplicatesEditorRefused(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// Supabase falso: dos frases casi iguales, la que se archivaría está
+	// publicada; cualquier escritura es un fallo del test
+	supa := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method != http.MethodGet:
+			t.Errorf("un editor no debería poder escribir: %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusInternalServerError)
+		case strings.Contains(r.URL.RawQuery, "select=id,text"):
+			w.Write([]byte(`[{"id":1,"text":"I am going home"},{"id":2,"text":"I am going home."}]`))
+		default:
+			w.Write([]byte(`[{"id":2,"status":"published"}]`))
+		}
+	}))
+	defer supa.Close()
+	t.Setenv("SUPABASE_URL", supa.URL)
+
+	r := gin.New()
+	r.Use(sessions.Sessions("mysession", cookie.NewStore([]byte("test"))))
+	r.POST("/admin/duplicates/:type/merge", MergeDuplicates)
+
+	form := url.Values{"keep": {"1"}, "ids": {"1", "2"}}
+	req := httptest.NewRequest(http.MethodPost, "/admin/duplicates/sentence/merge", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("un editor no debería archivar lo publicado: estado %d", w.Code)
+	}
+}
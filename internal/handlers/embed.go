@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"encoding/json"
+	"english-at-lima-cms/internal/embed"
+	"english-at-lima-cms/internal/models"
+	"english-at-lima-cms/internal/repository"
+	"english-at-lima-cms/internal/seo"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// embedKeyTTL: cada carga del iframe no debe costar una consulta a Supabase
+const embedKeyTTL = 5 * time.Minute
+
+type cachedEmbedKey struct {
+	key     models.EmbedKey
+	expires time.Time
+}
+
+var embedKeys sync.Map // clave pública -> cachedEmbedKey
+
+// EmbedScript sirve el loader que los colegios pegan en su web:
+// <script src="https://.../embed/widget.js" data-key="..." data-theme="dark" async></script>
+func EmbedScript(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Data(http.StatusOK, "text/javascript; charset=utf-8", []byte(embedLoaderJS))
+}
+
+// EmbedFrame renderiza el widget compacto dentro del iframe del socio
+func EmbedFrame(c *gin.Context) {
+	key, ok := lookupEmbedKey(c.Param("key"))
+	if !ok {
+		c.Header("Content-Security-Policy", embed.FrameAncestors(nil))
+		c.String(http.StatusNotFound, "Widget no disponible")
+		return
+	}
+
+	// Solo los dominios registrados del socio pueden incrustar el iframe
+	c.Header("Content-Security-Policy", embed.FrameAncestors(key.AllowedOrigins)+
+		"; default-src 'none'; style-src 'unsafe-inline'; script-src 'unsafe-inline'; img-src 'self'")
+	c.Header("Cache-Control", "no-store")
+
+	data := gin.H{
+		"Theme":   embed.Theme(c.Query("theme"), key.Theme),
+		"Accent":  embed.Accent(c.Query("accent")),
+		"SiteURL": seo.SiteURL(),
+		"Mode":    key.Mode,
+		"Locale":  publicLocale(c),
+	}
+
+	if key.Mode == "quiz" {
+		q, err := repository.GetQuiz(strconv.Itoa(key.QuizID))
+		if err != nil || !q.Public() {
+			c.String(http.StatusNotFound, "Quiz no disponible")
+			return
+		}
+		data["Quiz"] = q
+	} else {
+		data["Sentences"] = sentenceRotation()
+	}
+
+	// Sólo cuentan las cargas desde los dominios del socio: la URL del iframe
+	// es pública y cualquiera podría inflar las visitas pidiéndola
+	if embed.FromAllowedOrigin(key.AllowedOrigins, c.GetHeader("Origin"), c.Request.Referer()) {
+		embed.RecordView(key.Key)
+	}
+	c.HTML(http.StatusOK, "embed-frame.html", data)
+}
+
+// sentenceRotation devuelve las últimas frases empezando por la "frase del día"
+func sentenceRotation() []models.Sentence {
+	var sentences []models.Sentence
+	resp, err := repository.CallSupabase("GET", "sentences", nil, "select=id,english,spanish,translations&"+repository.PublicFilter+"&order=id.desc&limit=30")
+	if err != nil || resp == nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	_ = json.NewDecoder(resp.Body).Decode(&sentences)
+
+	if len(sentences) == 0 {
+		return nil
+	}
+	start := time.Now().YearDay() % len(sentences)
+	return append(sentences[start:], sentences[:start]...)
+}
+
+func lookupEmbedKey(raw string) (models.EmbedKey, bool) {
+	if !embed.ValidKey(raw) {
+		return models.EmbedKey{}, false
+	}
+	if cached, ok := embedKeys.Load(raw); ok {
+		entry := cached.(cachedEmbedKey)
+		if time.Now().Before(entry.expires) {
+			return entry.key, true
+		}
+	}
+	key, err := repository.GetEmbedKey(raw)
+	if err != nil {
+		return models.EmbedKey{}, false
+	}
+	embedKeys.Store(raw, cachedEmbedKey{key: key, expires: time.Now().Add(embedKeyTTL)})
+	return key, true
+}
+
+// --- ADMINISTRACIÓN DE CLAVES ---
+
+func GetEmbedKeys(c *gin.Context) {
+	keys, err := repository.ListEmbedKeys()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error de conexión")
+		return
+	}
+	c.HTML(http.StatusOK, "embed-keys.html", gin.H{"Keys": keys, "SiteURL": seo.SiteURL()})
+}
+
+// ValidateEmbedKey comprueba los datos del socio antes de emitir una clave
+func ValidateEmbedKey(k models.EmbedKey) error {
+	if len(strings.TrimSpace(k.Partner)) < 3 {
+		return fmt.Errorf("el nombre del colegio es demasiado corto")
+	}
+	if k.Mode != "sentences" && k.Mode != "quiz" {
+		return fmt.Errorf("modo de widget inválido")
+	}
+	if k.Mode == "quiz" && k.QuizID <= 0 {
+		return fmt.Errorf("debe indicar el ID del quiz a incrustar")
+	}
+	if !embed.Themes[k.Theme] {
+		return fmt.Errorf("tema inválido")
+	}
+	return nil
+}
+
+func SaveEmbedKey(c *gin.Context) {
+	origins, err := embed.ParseOrigins(c.PostForm("allowed_origins"))
+	if err != nil {
+		SendToast(c, err.Error(), "error")
+		return
+	}
+	quizID, _ := strconv.Atoi(c.PostForm("quiz_id"))
+	k := models.EmbedKey{
+		Partner:        Sanitize(c.PostForm("partner")),
+		AllowedOrigins: origins,
+		Mode:           c.PostForm("mode"),
+		QuizID:         quizID,
+		Theme:          c.PostForm("theme"),
+	}
+	if err := ValidateEmbedKey(k); err != nil {
+		SendToast(c, err.Error(), "error")
+		return
+	}
+
+	if k.Key, err = embed.NewKey(); err != nil {
+		SendToast(c, "No se pudo generar la clave", "error")
+		return
+	}
+	if err := repository.InsertEmbedKey(k); err != nil {
+		SendToast(c, "Error al guardar la clave", "error")
+		return
+	}
+
+	SendToast(c, "Clave de widget creada", "success")
+	c.Header("HX-Trigger", "refreshList")
+}
+
+func DeleteEmbedKey(c *gin.Context) {
+	if err := repository.DeleteEmbedKey(c.Param("id")); err != nil {
+		SendToast(c, "Error al eliminar la clave", "error")
+		return
+	}
+	// Borramos la caché entera: las claves revocadas dejan de servir al momento
+	embedKeys.Range(func(k, _ interface{}) bool {
+		embedKeys.Delete(k)
+		return true
+	})
+	c.Status(http.StatusOK)
+}
+
+const embedLoaderJS = `(function () {
+  var script = document.currentScript;
+  if (!script || !script.dataset.key) { return; }
+  var base = new URL(script.src).origin;
+  var params = new URLSearchParams();
+  if (script.dataset.theme) { params.set("theme", script.dataset.theme); }
+  if (script.dataset.accent) { params.set("accent", script.dataset.accent); }
+
+  var frame = document.createElement("iframe");
+  frame.src = base + "/embed/frame/" + encodeURIComponent(script.dataset.key) + "?" + params.toString();
+  frame.title = "English At Lima";
+  frame.loading = "lazy";
+  // El origen de la página basta para contar la visita, sin revelar la ruta
+  frame.referrerPolicy = "origin";
+  frame.style.cssText = "width:100%;max-width:480px;height:220px;border:0;border-radius:12px;";
+  script.parentNode.insertBefore(frame, script.nextSibling);
+
+  // El iframe nos avisa de su alto real para evitar barras de scroll
+  window.addEventListener("message", function (e) {
+    if (e.origin !== base || e.source !== frame.contentWindow) { return; }
+    if (e.data && e.data.type === "eal-embed-height") { frame.style.height = e.data.height + "px"; }
+  });
+})();
+`
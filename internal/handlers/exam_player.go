@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"english-at-lima-cms/internal/exam"
+	"english-at-lima-cms/internal/models"
+	"english-at-lima-cms/internal/repository"
+	"english-at-lima-cms/internal/seo"
+	"english-at-lima-cms/internal/xapi"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func examPath(examID int) string { return fmt.Sprintf("/examenes/%d", examID) }
+
+func attemptPath(a models.ExamAttempt) string {
+	return fmt.Sprintf("%s/intentos/%d", examPath(a.ExamID), a.ID)
+}
+
+// ShowExams lista los exámenes publicados
+func ShowExams(c *gin.Context) {
+	exams, err := repository.ListExams(true)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error de conexión")
+		return
+	}
+	c.HTML(http.StatusOK, "exams-public.html", gin.H{
+		"Exams": exams, "Canonical": seo.SiteURL() + "/examenes",
+	})
+}
+
+// ShowExam es la portada del examen: reglas, intentos usados y resultados previos
+func ShowExam(c *gin.Context) {
+	e, ok := publishedExam(c)
+	if !ok {
+		return
+	}
+	attempts, err := repository.ListAttempts(e.ID, studentID(c))
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error de conexión")
+		return
+	}
+	var open *models.ExamAttempt
+	for i := range attempts {
+		if exam.Open(attempts[i], time.Now()) {
+			open = &attempts[i]
+			break
+		}
+	}
+	c.HTML(http.StatusOK, "exam-player.html", gin.H{
+		"Exam": e, "Attempts": attempts, "Open": open,
+		"Left": max(e.MaxAttempts-len(attempts), 0), "Canonical": seo.SiteURL() + examPath(e.ID),
+	})
+}
+
+// StartAttempt sortea las preguntas y arranca el reloj. Si el alumno ya
+// tiene un intento en curso lo retoma en vez de gastar otro. Los intentos se
+// cuentan por cookie (eal_student), es decir, por navegador
+func StartAttempt(c *gin.Context) {
+	e, ok := publishedExam(c)
+	if !ok {
+		return
+	}
+	student := studentID(c)
+	attempts, err := repository.ListAttempts(e.ID, student)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error de conexión")
+		return
+	}
+	for _, a := range attempts {
+		if exam.Open(a, time.Now()) {
+			c.Redirect(http.StatusSeeOther, attemptPath(a))
+			return
+		}
+	}
+	if len(attempts) >= e.MaxAttempts {
+		c.String(http.StatusForbidden, "Ya usaste todos tus intentos para este examen")
+		return
+	}
+
+	a, err := exam.Start(e, student, len(attempts)+1, time.Now())
+	if errors.Is(err, repository.ErrAttemptTaken) {
+		// Doble clic o dos pestañas: la otra petición ya lo empezó
+		c.Redirect(http.StatusSeeOther, examPath(e.ID))
+		return
+	}
+	if errors.Is(err, exam.ErrPoolTooSmall) {
+		c.String(http.StatusConflict, "Este examen no está disponible en este momento")
+		return
+	}
+	if err != nil {
+		c.String(http.StatusInternalServerError, "No se pudo iniciar el examen")
+		return
+	}
+	c.Redirect(http.StatusSeeOther, attemptPath(a))
+}
+
+// ShowAttempt muestra las preguntas mientras quede tiempo y el resultado
+// cuando el intento está cerrado. Un intento vencido se califica aquí mismo
+// si el proceso de cierre todavía no pasó por él
+func ShowAttempt(c *gin.Context) {
+	e, a, ok := loadAttempt(c)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	if !a.Submitted() && !exam.Open(a, now) {
+		var err error
+		if a, err = exam.Finish(a, e, now); err != nil {
+			c.String(http.StatusInternalServerError, "Error de conexión")
+			return
+		}
+	}
+
+	quizzes, err := repository.GetQuizzesByIDs(a.QuizIDs)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error de conexión")
+		return
+	}
+	if a.Submitted() {
+		// La revisión con explicaciones sólo aparece cuando el alumno ya no
+		// puede volver a rendir el examen
+		var review []exam.Question
+		if attempts, err := repository.ListAttempts(e.ID, a.Student); err == nil && len(attempts) >= e.MaxAttempts {
+			review = exam.Questions(a, quizzes)
+		}
+		c.HTML(http.StatusOK, "exam-result.html", gin.H{"Exam": e, "Attempt": a, "Review": review, "Locale": publicLocale(c)})
+		return
+	}
+	c.HTML(http.StatusOK, "exam-attempt.html", gin.H{
+		"Exam": e, "Attempt": a, "Questions": examQuestions(a, quizzes),
+		"Remaining": int(exam.Remaining(a, now).Seconds()), "Path": attemptPath(a),
+	})
+}
+
+// examQuestion agrega la URL del intento para que las plantillas parciales
+// (pistas) puedan armar sus peticiones
+type examQuestion struct {
+	exam.Question
+	Path string
+}
+
+func examQuestions(a models.ExamAttempt, quizzes []models.Quiz) []examQuestion {
+	var out []examQuestion
+	for _, q := range exam.Questions(a, quizzes) {
+		out = append(out, examQuestion{q, attemptPath(a)})
+	}
+	return out
+}
+
+// RequestHint revela la siguiente pista de una pregunta y la registra en el
+// intento: cada pista usada descuenta puntos de esa pregunta
+func RequestHint(c *gin.Context) {
+	_, a, ok := loadAttempt(c)
+	if !ok {
+		return
+	}
+	if !exam.Open(a, time.Now()) {
+		c.Header("HX-Redirect", attemptPath(a))
+		c.Status(http.StatusOK)
+		return
+	}
+	quizID, err := strconv.Atoi(c.PostForm("quiz"))
+	if err != nil || !slices.Contains(a.QuizIDs, quizID) {
+		c.String(http.StatusBadRequest, "Pregunta no válida")
+		return
+	}
+	quizzes, err := repository.GetQuizzesByIDs([]int{quizID})
+	if err != nil || len(quizzes) == 0 {
+		c.String(http.StatusInternalServerError, "Error de conexión")
+		return
+	}
+
+	key := strconv.Itoa(quizID)
+	if a.HintsUsed == nil {
+		a.HintsUsed = map[string]int{}
+	}
+	if a.HintsUsed[key] < len(quizzes[0].Hints) {
+		used, err := repository.UseHint(a.ID, quizID, len(quizzes[0].Hints))
+		if errors.Is(err, repository.ErrNotFound) {
+			// Se cerró mientras tanto
+			c.Header("HX-Redirect", attemptPath(a))
+			c.Status(http.StatusOK)
+			return
+		}
+		if err != nil {
+			c.String(http.StatusInternalServerError, "⚠️ No se pudo pedir la pista")
+			return
+		}
+		a.HintsUsed[key] = used
+	}
+	c.HTML(http.StatusOK, "exam-hints.html", examQuestions(a, quizzes)[0])
+}
+
+// AnswerQuestion guarda la respuesta de una pregunta en cuanto el alumno la
+// cambia, así el cierre por tiempo califica todo lo respondido
+func AnswerQuestion(c *gin.Context) {
+	e, a, ok := loadAttempt(c)
+	if !ok {
+		return
+	}
+	if !exam.Open(a, time.Now()) {
+		c.Header("HX-Redirect", attemptPath(a))
+		c.Status(http.StatusOK)
+		return
+	}
+
+	quizID, err := strconv.Atoi(c.PostForm("quiz"))
+	if err != nil || !slices.Contains(a.QuizIDs, quizID) {
+		c.String(http.StatusBadRequest, "Pregunta no válida")
+		return
+	}
+	response, ok := readResponses(c)
+	if !ok {
+		c.String(http.StatusBadRequest, "Demasiadas respuestas")
+		return
+	}
+
+	if err := repository.SaveResponse(a.ID, quizID, response); err != nil {
+		c.String(http.StatusInternalServerError, "⚠️ No se pudo guardar")
+		return
+	}
+	if len(response) > 0 {
+		xapi.Emit(xapi.ExamAnswered(xapi.Student(a.Student), e, a, quizID, response))
+	}
+	c.String(http.StatusOK, "✓ Guardado")
+}
+
+// SubmitAttempt entrega el intento antes de tiempo
+func SubmitAttempt(c *gin.Context) {
+	e, a, ok := loadAttempt(c)
+	if !ok {
+		return
+	}
+	if !a.Submitted() {
+		if _, err := exam.Finish(a, e, time.Now()); err != nil {
+			c.String(http.StatusInternalServerError, "No se pudo entregar el examen")
+			return
+		}
+	}
+	c.Redirect(http.StatusSeeOther, attemptPath(a))
+}
+
+// publishedExam trae el examen; los borradores responden 404
+func publishedExam(c *gin.Context) (models.Exam, bool) {
+	id, ok := publicID(c)
+	if !ok {
+		return models.Exam{}, false
+	}
+	e, err := repository.GetExam(id)
+	if err == nil && !e.Published {
+		err = repository.ErrNotFound
+	}
+	return e, renderable(c, err)
+}
+
+// loadAttempt sólo deja ver un intento a quien lo empezó
+func loadAttempt(c *gin.Context) (models.Exam, models.ExamAttempt, bool) {
+	e, ok := publishedExam(c)
+	if !ok {
+		return e, models.ExamAttempt{}, false
+	}
+	attemptID := c.Param("attempt")
+	if n, err := strconv.Atoi(attemptID); err != nil || n <= 0 {
+		c.String(http.StatusNotFound, "Contenido no encontrado")
+		return e, models.ExamAttempt{}, false
+	}
+	a, err := repository.GetAttempt(attemptID)
+	if err == nil && (a.ExamID != e.ID || a.Student != studentID(c)) {
+		err = repository.ErrNotFound
+	}
+	return e, a, renderable(c, err)
+}
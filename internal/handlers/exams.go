@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"english-at-lima-cms/internal/exam"
+	"english-at-lima-cms/internal/models"
+	"english-at-lima-cms/internal/repository"
+	"english-at-lima-cms/internal/taxonomy"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// examRuleRows es el número de filas de reglas que ofrece el formulario
+const examRuleRows = 5
+
+func GetExams(c *gin.Context) {
+	exams, err := repository.ListExams(false)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error de conexión")
+		return
+	}
+	c.HTML(http.StatusOK, "exams-list.html", gin.H{
+		"Exams": exams, "Levels": taxonomy.Levels, "RuleRows": make([]struct{}, examRuleRows),
+	})
+}
+
+// examFromForm lee el examen; las reglas llegan como tres arrays paralelos
+func examFromForm(c *gin.Context) models.Exam {
+	e := models.Exam{
+		Title:       Sanitize(c.PostForm("title")),
+		Description: Sanitize(c.PostForm("description")),
+	}
+	e.TimeLimit, _ = strconv.Atoi(c.PostForm("time_limit"))
+	e.MaxAttempts, _ = strconv.Atoi(c.PostForm("max_attempts"))
+	e.PassingScore, _ = strconv.Atoi(c.PostForm("passing_score"))
+
+	tags, levels := c.PostFormArray("rule_tag"), c.PostFormArray("rule_level")
+	for i, raw := range c.PostFormArray("rule_count") {
+		r := models.ExamRule{}
+		r.Count, _ = strconv.Atoi(raw)
+		if i < len(tags) {
+			r.Tag = tags[i]
+		}
+		if i < len(levels) {
+			r.Level = levels[i]
+		}
+		e.Rules = append(e.Rules, r)
+	}
+	exam.Normalize(&e)
+	return e
+}
+
+func SaveExam(c *gin.Context) {
+	e := examFromForm(c)
+	if err := exam.Validate(e); err != nil {
+		SendToast(c, err.Error(), "error")
+		return
+	}
+	// Cada regla por separado tiene que poder cubrirse con los quizzes actuales
+	for i, r := range e.Rules {
+		pool, err := repository.ListQuizPool(taxonomy.Filter(r.Tag, r.Level))
+		if err != nil {
+			SendToast(c, "Error de conexión", "error")
+			return
+		}
+		if len(pool) < r.Count {
+			SendToast(c, fmt.Sprintf("Regla %d: sólo hay %d quizzes con esa etiqueta y nivel", i+1, len(pool)), "error")
+			return
+		}
+	}
+	if err := repository.InsertExam(e); err != nil {
+		SendToast(c, "Error al crear el examen", "error")
+		return
+	}
+	refreshWithToast(c, "Examen creado como borrador")
+}
+
+// GetExamResults muestra los intentos del examen y el desglose por etiqueta
+func GetExamResults(c *gin.Context) {
+	id, ok := publicID(c)
+	if !ok {
+		return
+	}
+	e, err := repository.GetExam(id)
+	if !renderable(c, err) {
+		return
+	}
+	attempts, err := repository.ListAttempts(e.ID, "")
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error de conexión")
+		return
+	}
+	submitted, passed := 0, 0
+	for _, a := range attempts {
+		if a.Submitted() {
+			submitted++
+			if a.Passed {
+				passed++
+			}
+		}
+	}
+	c.HTML(http.StatusOK, "exam-results.html", gin.H{
+		"Exam": e, "Attempts": attempts, "ByTag": exam.Aggregate(attempts),
+		"Submitted": submitted, "Passed": passed,
+	})
+}
+
+func PublishExam(c *gin.Context) {
+	id, ok := publicID(c)
+	if !ok {
+		return
+	}
+	published := c.PostForm("published") == "true"
+	if err := repository.SetExamPublished(id, published); err != nil {
+		SendToast(c, "Error al cambiar el estado del examen", "error")
+		return
+	}
+	msg := "Examen despublicado"
+	if published {
+		msg = "Examen publicado"
+	}
+	refreshWithToast(c, msg)
+}
+
+func DeleteExam(c *gin.Context) {
+	id, ok := publicID(c)
+	if !ok {
+		return
+	}
+	if err := repository.DeleteExam(id); err != nil {
+		SendToast(c, "Error al borrar el examen", "error")
+		return
+	}
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"encoding/json"
+	"english-at-lima-cms/internal/export"
+	"english-at-lima-cms/internal/models"
+	"english-at-lima-cms/internal/repository"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// searchColumns son las columnas en las que busca el cuadro 🔎 de cada listado
+var searchColumns = map[string][]string{
+	"sentence": {"english", "spanish"},
+	"quiz":     {"question"},
+	"resource": {"title"},
+}
+
+// searchFilter arma el filtro ilike de PostgREST para la búsqueda de texto.
+// Comas, paréntesis, comillas y asteriscos tienen significado en la sintaxis
+// de filtros, así que se cambian por espacios antes de armarlo.
+func searchFilter(contentType, query string) string {
+	query = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`,()*"\`, r) {
+			return ' '
+		}
+		return r
+	}, query))
+	cols := searchColumns[contentType]
+	if len([]rune(query)) < 2 || len(cols) == 0 {
+		return ""
+	}
+	pattern := "*" + strings.ReplaceAll(url.QueryEscape(query), "+", "%20") + "*"
+	if len(cols) == 1 {
+		return cols[0] + "=ilike." + pattern
+	}
+	parts := make([]string, len(cols))
+	for i, col := range cols {
+		parts[i] = col + ".ilike." + pattern
+	}
+	return "or=(" + strings.Join(parts, ",") + ")"
+}
+
+// listQuery reúne los filtros de un listado del admin (estado, etiqueta,
+// nivel y búsqueda) y los datos que pinta el formulario de filtros. La
+// exportación usa los mismos para bajar exactamente lo que se ve.
+func listQuery(c *gin.Context, contentType string) (string, gin.H) {
+	filter, view := taxonomyQuery(c)
+	status, statuses := statusQuery(c)
+	search := strings.TrimSpace(c.Query("search"))
+	query := withFilter(withFilter(status, filter), searchFilter(contentType, search))
+	return query, gin.H{"Filter": view, "Statuses": statuses, "Search": search, "Type": contentType, "Formats": export.Formats}
+}
+
+// exportSpec describe la exportación de un tipo de contenido. Las cabeceras
+// coinciden con los campos del importador para que el archivo vuelva a entrar
+// sin tocar la asignación de columnas.
+type exportSpec struct {
+	Table  string
+	Sheet  string
+	File   string
+	Order  string
+	Header []string
+	// Row decodifica la fila de la API y devuelve sus celdas y el elemento
+	Row func(raw json.RawMessage) ([]string, interface{}, error)
+}
+
+var exportSpecs = map[string]exportSpec{
+	"sentence": {
+		Table: "sentences", Sheet: "Frases", File: "frases", Order: "id.asc",
+		Header: []string{"ID", "English", "Spanish", "Nivel", "Etiquetas", "Nota gramatical", "Registro", "Ejemplos", "IPA", "Audio", "Estado"},
+		Row: func(raw json.RawMessage) ([]string, interface{}, error) {
+			var s models.Sentence
+			err := json.Unmarshal(raw, &s)
+			return []string{
+				strconv.Itoa(s.ID), s.English, s.Spanish, s.Level, s.TagsInput(),
+				s.GrammarNote, s.Register, strings.Join(s.Examples, " | "), s.IPA, s.AudioURL, s.Status,
+			}, s, err
+		},
+	},
+	"quiz": {
+		Table: "quizzes", Sheet: "Quizzes", File: "quizzes", Order: "id.asc",
+		Header: quizExportHeader(),
+		Row: func(raw json.RawMessage) ([]string, interface{}, error) {
+			var q models.Quiz
+			err := json.Unmarshal(raw, &q)
+			row := []string{strconv.Itoa(q.ID), q.Question, q.Type}
+			// Una columna por casilla de opción, aunque el quiz tenga menos
+			for i := 0; i < models.MaxQuizOptions; i++ {
+				opt := ""
+				if i < len(q.Options) {
+					opt = q.Options[i]
+				}
+				row = append(row, opt)
+			}
+			row = append(row, strings.Join(q.Answers, " | "), q.Explanation.ES, q.Level, q.TagsInput(), q.Status)
+			return row, q, err
+		},
+	},
+	"resource": {
+		Table: "resources", Sheet: "Recursos", File: "recursos", Order: "title.asc,id.asc",
+		Header: []string{"ID", "Título", "Tipo", "URL", "Descripción", "Nivel", "Etiquetas", "Estado"},
+		Row: func(raw json.RawMessage) ([]string, interface{}, error) {
+			var r models.Resource
+			err := json.Unmarshal(raw, &r)
+			return []string{
+				strconv.Itoa(r.ID), r.Title, r.Type, r.URL, r.Description, r.Level, r.TagsInput(), r.Status,
+			}, r, err
+		},
+	},
+}
+
+func quizExportHeader() []string {
+	header := []string{"ID", "Pregunta", "Tipo"}
+	for i := 1; i <= models.MaxQuizOptions; i++ {
+		header = append(header, "Opción "+strconv.Itoa(i))
+	}
+	return append(header, "Correctas", "Explicación", "Nivel", "Etiquetas", "Estado")
+}
+
+// ExportContent descarga el listado de un tipo de contenido en CSV, XLSX o
+// JSON con los mismos filtros que la pantalla (?status, ?level, ?tag,
+// ?search). Las filas se leen por páginas y se escriben según llegan.
+func ExportContent(c *gin.Context) {
+	contentType := c.Param("type")
+	spec, ok := exportSpecs[contentType]
+	if !ok {
+		c.String(http.StatusNotFound, "Tipo de contenido desconocido")
+		return
+	}
+	format := c.DefaultQuery("format", export.CSV)
+	if !export.Valid(format) {
+		c.String(http.StatusBadRequest, "Formato de exportación no soportado")
+		return
+	}
+	filter, _ := listQuery(c, contentType)
+
+	name := fmt.Sprintf("%s-%s.%s", spec.File, time.Now().In(models.Lima).Format("20060102"), format)
+	c.Header("Content-Type", export.ContentType(format))
+	c.Header("Content-Disposition", `attachment; filename="`+name+`"`)
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Content-Type-Options", "nosniff")
+
+	w, _ := export.New(format, c.Writer, spec.Sheet, spec.Header)
+	err := repository.ExportRows(spec.Table, filter, spec.Order, func(raw json.RawMessage) error {
+		cells, item, err := spec.Row(raw)
+		if err != nil {
+			return err
+		}
+		return w.Write(cells, item)
+	})
+	if err == nil {
+		err = w.Close()
+	}
+	if err == nil {
+		return
+	}
+	fmt.Println("❌ Exportación de", spec.Table, "fallida:", err)
+	// Si aún no salió nada se puede contestar con un error; si no, la
+	// descarga queda cortada y el archivo no abre
+	if !c.Writer.Written() {
+		c.Header("Content-Disposition", "")
+		c.Header("Content-Type", "text/plain; charset=utf-8")
+		c.String(http.StatusInternalServerError, "Error al exportar: no se pudo leer la base de datos")
+	}
+}
+
+// LegacyExport mantiene las rutas antiguas /admin/sentences/export y
+// /admin/quizzes/export (marcadores, scripts) redirigiendo a ExportContent
+// con los mismos parámetros; sin ?format sale CSV como antes
+func LegacyExport(contentType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Redirect(http.StatusMovedPermanently, legacyExportURL(contentType, c.Request.URL.RawQuery))
+	}
+}
+
+func legacyExportURL(contentType, rawQuery string) string {
+	target := "/admin/export/" + contentType
+	if rawQuery != "" {
+		target += "?" + rawQuery
+	}
+	return target
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"english-at-lima-cms/internal/export"
+	"english-at-lima-cms/internal/importer"
+	"english-at-lima-cms/internal/models"
+)
+
+func TestSearchFilter(t *testing.T) {
+	tests := []struct {
+		name, kind, query, want string
+	}{
+		{"Dos columnas", "sentence", "good", "or=(english.ilike.*good*,spanish.ilike.*good*)"},
+		{"Una columna", "quiz", "fruit", "question=ilike.*fruit*"},
+		{"Espacios y acentos", "resource", "  café con leche ", "title=ilike.*caf%C3%A9%20con%20leche*"},
+		{"Sintaxis de filtros", "quiz", "a,b)*(c", "question=ilike.*a%20b%20%20%20c*"},
+		{"Parámetros colados", "quiz", "x&status=eq.archived", "question=ilike.*x%26status%3Deq.archived*"},
+		{"Demasiado corta", "sentence", " a ", ""},
+		{"Sólo símbolos", "sentence", "(*)", ""},
+		{"Tipo sin búsqueda", "media", "hola", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := searchFilter(tt.kind, tt.query); got != tt.want {
+				t.Errorf("searchFilter(%q) = %q, esperaba %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+// Lo exportado en CSV o XLSX vuelve a entrar por el importador sin tocar la
+// asignación de columnas y con los mismos datos
+func TestExportRoundTrip(t *testing.T) {
+	items := map[string]interface{}{
+		"sentence": models.Sentence{
+			ID: 7, English: "Can I have the bill?", Spanish: "¿Me trae la cuenta?",
+			Taxonomy: models.Taxonomy{Level: "A2", Tags: []string{"restaurant", "food"}},
+			Examples: []string{"Can I pay by card?", "Can I see the menu?"}, Register: "informal",
+		},
+		"quiz": models.Quiz{
+			ID: 3, Question: "-3 + 5 = ?", Type: "single", Options: []string{"2", "-2", "8"}, Answers: []string{"2"},
+			Taxonomy: models.Taxonomy{Level: "A1"}, Explanation: models.Note{ES: "Se resta **3** a 5."},
+		},
+		"resource": models.Resource{
+			ID: 1, Title: "Listening: «Ñandú» & more", Type: "audio", URL: "https://example.com/a.mp3",
+			Description: "Audio corto", Taxonomy: models.Taxonomy{Level: "B1", Tags: []string{"listening"}},
+		},
+	}
+	for kind, item := range items {
+		spec, imp := exportSpecs[kind], importKinds[kind]
+		raw, _ := json.Marshal(item)
+		cells, _, err := spec.Row(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cells) != len(spec.Header) {
+			t.Fatalf("%s: %d celdas para %d columnas", kind, len(cells), len(spec.Header))
+		}
+		for _, format := range []string{export.CSV, export.XLSX} {
+			t.Run(kind+"/"+format, func(t *testing.T) {
+				var buf bytes.Buffer
+				w, _ := export.New(format, &buf, spec.Sheet, spec.Header)
+				if err := w.Write(cells, item); err != nil {
+					t.Fatal(err)
+				}
+				if err := w.Close(); err != nil {
+					t.Fatal(err)
+				}
+				table, err := importer.Read(spec.File+"."+format, buf.Bytes())
+				if err != nil {
+					t.Fatal(err)
+				}
+				mapping := importer.Guess(table.Header, imp.Fields)
+				for _, f := range imp.Fields {
+					if f.Required && len(mapping[f.Name]) == 0 {
+						t.Fatalf("la cabecera exportada no se asignó a «%s»", f.Label)
+					}
+				}
+				got, _, err := imp.Build(mapping.Row(table.Lines[0], table.Rows[0]))
+				if err != nil {
+					t.Fatalf("no se pudo volver a importar: %v", err)
+				}
+				gotRaw, _ := json.Marshal(got)
+				gotCells, _, _ := spec.Row(gotRaw)
+				// El ID y el estado no se importan: el resto debe coincidir
+				for i, h := range spec.Header {
+					if h != "ID" && h != "Estado" && gotCells[i] != cells[i] {
+						t.Errorf("columna %s: %q, esperaba %q", h, gotCells[i], cells[i])
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestLegacyExportURL(t *testing.T) {
+	if got := legacyExportURL("sentence", ""); got != "/admin/export/sentence" {
+		t.Errorf("sin filtros: %s", got)
+	}
+	if got := legacyExportURL("quiz", "level=A2&tag=food"); got != "/admin/export/quiz?level=A2&tag=food" {
+		t.Errorf("los filtros deberían conservarse: %s", got)
+	}
+	for _, typ := range []string{"sentence", "quiz"} {
+		if _, ok := exportSpecs[typ]; !ok {
+			t.Errorf("la ruta antigua apunta a un tipo sin exportador: %s", typ)
+		}
+	}
+}
@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"english-at-lima-cms/internal/importer"
+	"english-at-lima-cms/internal/models"
+	"english-at-lima-cms/internal/quiz"
+	"english-at-lima-cms/internal/repository"
+	"english-at-lima-cms/internal/richtext"
+	"english-at-lima-cms/internal/sentence"
+	"english-at-lima-cms/internal/taxonomy"
+
+	"github.com/gin-gonic/gin"
+)
+
+// importKind describe cómo se importa un tipo de contenido
+type importKind struct {
+	Label  string
+	Table  string
+	Key    string // columna que identifica el contenido para detectar los ya existentes
+	Fields []importer.Field
+	Build  func(importer.Row) (interface{}, string, error) // contenido y su clave
+}
+
+// taxonomyFields son los campos comunes de nivel y etiquetas
+var taxonomyFields = []importer.Field{
+	{Name: "level", Label: "Nivel", Required: true, Aliases: []string{"MCER", "CEFR"}},
+	{Name: "tags", Label: "Etiquetas", Aliases: []string{"Tags"}},
+}
+
+var importKinds = map[string]importKind{
+	"sentence": {
+		Label: "Frases", Table: "sentences", Key: "english",
+		Fields: append([]importer.Field{
+			{Name: "english", Label: "Inglés", Required: true, Aliases: []string{"English"}},
+			{Name: "spanish", Label: "Español", Required: true, Aliases: []string{"Spanish", "Traducción"}},
+			{Name: "grammar_note", Label: "Nota gramatical", Aliases: []string{"Grammar note"}},
+			{Name: "register", Label: "Registro", Aliases: []string{"Register"}},
+			{Name: "examples", Label: "Ejemplos", Aliases: []string{"Examples"}},
+			{Name: "ipa", Label: "IPA"},
+			{Name: "audio_url", Label: "Audio"},
+		}, taxonomyFields...),
+		Build: importSentence,
+	},
+	"quiz": {
+		Label: "Quizzes", Table: "quizzes", Key: "question",
+		Fields: append([]importer.Field{
+			{Name: "question", Label: "Pregunta", Required: true, Aliases: []string{"Question"}},
+			{Name: "type", Label: "Tipo", Aliases: []string{"Type"}},
+			{Name: "options", Label: "Opciones", Multi: true, Columns: []string{"options", "option_notes"}, Aliases: []string{"Opción", "Option", "Options"}},
+			{Name: "answers", Label: "Correctas", Required: true, Aliases: []string{"Respuestas", "Answers", "Answer"}},
+			{Name: "explanation", Label: "Explicación", Aliases: []string{"Explanation"}},
+		}, taxonomyFields...),
+		Build: importQuiz,
+	},
+	"resource": {
+		Label: "Recursos", Table: "resources", Key: "url",
+		Fields: append([]importer.Field{
+			{Name: "title", Label: "Título", Required: true, Aliases: []string{"Titulo", "Title"}},
+			{Name: "url", Label: "URL", Required: true, Aliases: []string{"Enlace", "Link"}},
+			{Name: "type", Label: "Tipo", Required: true, Aliases: []string{"Type"}},
+			{Name: "description", Label: "Descripción", Aliases: []string{"Description"}},
+		}, taxonomyFields...),
+		Build: importResource,
+	},
+}
+
+// resourceTypes son los tipos de recurso que se aceptan al importar
+var resourceTypes = []string{"video", "audio", "pdf", "image", "web"}
+
+// importTaxonomy lee y valida nivel y etiquetas de la fila
+func importTaxonomy(r importer.Row) (models.Taxonomy, error) {
+	tx := models.Taxonomy{
+		Level: taxonomy.NormalizeLevel(r.Get("level")),
+		Tags:  taxonomy.ParseTags(r.Get("tags")),
+	}
+	return tx, taxonomy.Validate(tx.Level, tx.Tags)
+}
+
+// Lo importado entra como borrador, igual que lo creado por un editor
+var importPublication = models.Publication{Status: models.StatusDraft}
+
+func importSentence(r importer.Row) (interface{}, string, error) {
+	s := models.Sentence{
+		English:     Sanitize(r.Get("english")),
+		Spanish:     Sanitize(r.Get("spanish")),
+		GrammarNote: richtext.Clean(r.Get("grammar_note")),
+		Register:    r.Get("register"),
+		IPA:         r.Get("ipa"),
+		AudioURL:    r.Get("audio_url"),
+		Publication: importPublication,
+	}
+	for _, e := range r.List("examples") {
+		s.Examples = append(s.Examples, Sanitize(e))
+	}
+	if err := ValidateSentence(s.English, s.Spanish); err != nil {
+		return s, s.English, err
+	}
+	tx, err := importTaxonomy(r)
+	if err != nil {
+		return s, s.English, err
+	}
+	s.Taxonomy = tx
+	sentence.Normalize(&s)
+	return s, s.English, sentence.Validate(s)
+}
+
+func importQuiz(r importer.Row) (interface{}, string, error) {
+	q := models.Quiz{
+		Question:    Sanitize(r.Get("question")),
+		Type:        strings.ToLower(r.Get("type")),
+		Explanation: models.Note{ES: richtext.Clean(r.Get("explanation"))},
+		Publication: importPublication,
+	}
+	for _, o := range r.List("options") {
+		q.Options = append(q.Options, Sanitize(o))
+	}
+	for _, a := range r.List("answers") {
+		q.Answers = append(q.Answers, Sanitize(a))
+	}
+	quiz.Normalize(&q)
+	if err := ValidateQuiz(q); err != nil {
+		return q, q.Question, err
+	}
+	tx, err := importTaxonomy(r)
+	q.Taxonomy = tx
+	return q, q.Question, err
+}
+
+func importResource(r importer.Row) (interface{}, string, error) {
+	res := models.Resource{
+		Title:       Sanitize(r.Get("title")),
+		URL:         r.Get("url"),
+		Type:        strings.ToLower(r.Get("type")),
+		Description: richtext.Clean(r.Get("description")),
+		Publication: importPublication,
+	}
+	if err := ValidateResource(res.Title, res.URL, res.Type); err != nil {
+		return res, res.URL, err
+	}
+	if !slices.Contains(resourceTypes, res.Type) {
+		return res, res.URL, fmt.Errorf("tipo de recurso desconocido: %s (use %s)", res.Type, strings.Join(resourceTypes, ", "))
+	}
+	if err := ValidateResourcePreview(res.Description, "", 0); err != nil {
+		return res, res.URL, err
+	}
+	tx, err := importTaxonomy(r)
+	res.Taxonomy = tx
+	return res, res.URL, err
+}
+
+// importModes son los modos que puede elegir quien importa: actualizar
+// sobrescribe contenido que puede estar publicado, así que es de revisores
+func importModes(c *gin.Context) map[string]string {
+	if isReviewer(c) {
+		return importer.ModeLabels
+	}
+	modes := map[string]string{}
+	for code, label := range importer.ModeLabels {
+		if code != importer.ModeUpdate {
+			modes[code] = label
+		}
+	}
+	return modes
+}
+
+// importMode lee el modo de duplicados del formulario
+func importMode(c *gin.Context) (string, error) {
+	mode := c.PostForm("mode")
+	if _, ok := importer.ModeLabels[mode]; !ok {
+		return "", errors.New("elija qué hacer con los duplicados")
+	}
+	if _, ok := importModes(c)[mode]; !ok {
+		return "", fmt.Errorf("%w: sólo un revisor puede actualizar contenido existente al importar", errNeedsReviewer)
+	}
+	return mode, nil
+}
+
+// GetImport muestra el primer paso del asistente: tipo de contenido y archivo
+func GetImport(c *gin.Context) {
+	c.HTML(http.StatusOK, "import.html", gin.H{
+		"Kinds": importKinds, "MaxMB": importer.MaxFileSize >> 20, "MaxRows": importer.MaxRows,
+		"Levels": taxonomy.Levels, "Modes": importModes(c),
+	})
+}
+
+// UploadImport lee el archivo y propone la asignación de columnas
+func UploadImport(c *gin.Context) {
+	kindName := c.PostForm("type")
+	kind, ok := importKinds[kindName]
+	if !ok {
+		SendToast(c, "Elija qué tipo de contenido importar", "error")
+		return
+	}
+	name, data, err := uploadedFile(c, "Seleccione un archivo CSV o XLSX")
+	if err != nil {
+		SendToast(c, err.Error(), "error")
+		return
+	}
+	table, err := importer.Read(name, data)
+	if err != nil {
+		SendToast(c, err.Error(), "error")
+		return
+	}
+
+	u := importer.Upload{Type: kindName, Name: name, Owner: currentUser(c), Table: table}
+	token := importer.Save(u)
+	mapping := importer.Guess(table.Header, kind.Fields)
+	c.HTML(http.StatusOK, "import-mapping.html", gin.H{
+		"Token":   token,
+		"Upload":  u,
+		"Kind":    kind,
+		"Choices": mapping.Choices(kind.Fields, table.Header),
+		"Sample":  table.Rows[:min(len(table.Rows), 5)],
+		"Modes":   importModes(c),
+	})
+}
+
+// uploadedFile lee el archivo del formulario hasta el límite de la importación
+func uploadedFile(c *gin.Context, missing string) (string, []byte, error) {
+	fh, err := c.FormFile("file")
+	if err != nil {
+		return "", nil, errors.New(missing)
+	}
+	if fh.Size > importer.MaxFileSize {
+		return "", nil, fmt.Errorf("El archivo supera el límite de %d MB", importer.MaxFileSize>>20)
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return "", nil, errors.New("No se pudo leer el archivo")
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, importer.MaxFileSize+1))
+	if err != nil {
+		return "", nil, errors.New("No se pudo leer el archivo")
+	}
+	return fh.Filename, data, nil
+}
+
+// importPlan vuelve a leer el archivo guardado con la asignación y el modo
+// del formulario y decide qué pasa con cada fila
+func importPlan(c *gin.Context) (string, importKind, importer.Mapping, []importer.Result, error) {
+	token := c.PostForm("token")
+	u, ok := importer.Load(token, currentUser(c))
+	if !ok {
+		return "", importKind{}, nil, nil, errors.New("el archivo subido caducó; vuelva a subirlo")
+	}
+	kind := importKinds[u.Type]
+	mode, err := importMode(c)
+	if err != nil {
+		return "", kind, nil, nil, err
+	}
+	mapping, err := importer.ReadMapping(kind.Fields, len(u.Table.Header), c.PostFormArray)
+	if err != nil {
+		return "", kind, nil, nil, err
+	}
+
+	existing, err := existingKeys(kind)
+	if err != nil {
+		return "", kind, nil, nil, err
+	}
+
+	rows := make([]importer.Parsed, len(u.Table.Rows))
+	for i, cells := range u.Table.Rows {
+		row := mapping.Row(u.Table.Lines[i], cells)
+		item, label, err := kind.Build(row)
+		rows[i] = importer.Parsed{Line: row.Line, Label: label, Item: item, Err: err}
+	}
+	return token, kind, mapping, importer.Plan(rows, existing, mode), nil
+}
+
+// existingKeys asocia la clave de cada contenido ya guardado con su id
+func existingKeys(kind importKind) (map[string]int, error) {
+	texts, err := repository.ListContentTexts(kind.Table, kind.Key)
+	if err != nil {
+		return nil, errors.New("error al consultar el contenido existente")
+	}
+	existing := map[string]int{}
+	for _, t := range texts {
+		if k := importer.Key(t.Text); existing[k] == 0 {
+			existing[k] = t.ID
+		}
+	}
+	return existing, nil
+}
+
+// importReport pinta el informe por fila; los errores van primero
+func importReport(c *gin.Context, results []importer.Result, data gin.H) {
+	shown := slices.Clone(results)
+	slices.SortStableFunc(shown, func(a, b importer.Result) int {
+		return boolRank(a.Action != importer.ActionInvalid) - boolRank(b.Action != importer.ActionInvalid)
+	})
+	const maxShown = 500
+	data["Hidden"] = max(len(shown)-maxShown, 0)
+	data["Results"] = shown[:min(len(shown), maxShown)]
+	data["Summary"] = importer.Summarize(results)
+	c.HTML(http.StatusOK, "import-report.html", data)
+}
+
+func boolRank(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// PreviewImport simula la importación sin guardar nada
+func PreviewImport(c *gin.Context) {
+	_, _, _, results, err := importPlan(c)
+	if err != nil {
+		sendGuardError(c, err)
+		return
+	}
+	importReport(c, results, gin.H{"DryRun": true})
+}
+
+// CommitImport guarda las filas válidas por bloques. Si un bloque falla se
+// detiene y lo informa: lo guardado hasta ahí queda, y volver a importar el
+// archivo con «saltar» o «actualizar» completa el resto sin duplicar.
+func CommitImport(c *gin.Context) {
+	token, kind, mapping, results, err := importPlan(c)
+	if err != nil {
+		sendGuardError(c, err)
+		return
+	}
+
+	saved, failure := saveImport(kind.Table, results, mapping.Columns(kind.Fields))
+	if failure == "" {
+		importer.Drop(token)
+	}
+	importReport(c, results, gin.H{"Committed": true, "Saved": saved, "Failure": failure})
+}
+
+// saveImport guarda por bloques las filas que se insertan o actualizan y
+// devuelve cuántas se guardaron y, si un bloque falló, el aviso
+func saveImport(table string, results []importer.Result, columns []string) (int, string) {
+	saved := 0
+	for _, chunk := range importer.Chunks(results, importer.ChunkSize) {
+		var inserts []interface{}
+		updates := map[int]interface{}{}
+		for _, r := range chunk {
+			if r.Action == importer.ActionUpdate {
+				updates[r.ID] = r.Item
+			} else {
+				inserts = append(inserts, r.Item)
+			}
+		}
+		if err := repository.ImportChunk(table, inserts, updates, columns); err != nil {
+			return saved, fmt.Sprintf("Error al guardar el bloque de las filas %d–%d; se guardaron %d filas antes del error", chunk[0].Line, chunk[len(chunk)-1].Line, saved)
+		}
+		saved += len(chunk)
+	}
+	return saved, ""
+}
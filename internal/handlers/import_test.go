@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"testing"
+
+	"english-at-lima-cms/internal/importer"
+	"english-at-lima-cms/internal/models"
+)
+
+// Los CSV de «Exportar» se vuelven a importar sin tocar la asignación
+func TestImportExportedCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		kind    string
+		csv     string
+		wantErr []bool
+	}{
+		{
+			name: "Frases",
+			kind: "sentence",
+			csv: "ID,English,Spanish,Nivel,Etiquetas,Nota gramatical,Registro,Ejemplos,IPA,Audio\n" +
+				"7,Can I have the bill?,¿Me trae la cuenta?,A2,\"restaurant, food\",**Can** pide permiso,informal,Can I pay by card? | Can I see the menu?,,\n" +
+				"8,Hi <script>x</script>,Hola,A1,,,,,,\n" +
+				"9,Good morning,Buenos días,Z9,,,,,,\n",
+			wantErr: []bool{false, true, true},
+		},
+		{
+			name: "Quizzes",
+			kind: "quiz",
+			csv: "Pregunta,Tipo,Opción 1,Opción 2,Opción 3,Correctas,Nivel,Etiquetas\n" +
+				"Which one is a fruit?,single,Apple,Chair,Table,Apple,A1,food\n" +
+				"I ___ to school every day.,fill,,,,go | walk,A1,\n" +
+				"Which one is a colour?,single,Red,Dog,,Blue,A1,\n",
+			wantErr: []bool{false, false, true},
+		},
+		{
+			name: "Recursos",
+			kind: "resource",
+			csv: "ID,Titulo,Tipo,URL,Nivel,Etiquetas\n" +
+				"1,Listening practice,audio,https://example.com/a.mp3,B1,\n" +
+				"2,Old link,web,ftp://example.com,B1,\n" +
+				"3,Podcast,radio,https://example.com/p,B1,\n",
+			wantErr: []bool{false, true, true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind := importKinds[tt.kind]
+			table, err := importer.Read("export.csv", []byte(tt.csv))
+			if err != nil {
+				t.Fatal(err)
+			}
+			mapping := importer.Guess(table.Header, kind.Fields)
+			for _, f := range kind.Fields {
+				if f.Required && len(mapping[f.Name]) == 0 {
+					t.Fatalf("la cabecera exportada no se asignó a «%s»", f.Label)
+				}
+			}
+			for i, cells := range table.Rows {
+				item, _, err := kind.Build(mapping.Row(table.Lines[i], cells))
+				if (err != nil) != tt.wantErr[i] {
+					t.Errorf("fila %d: error = %v, esperaba error: %v", table.Lines[i], err, tt.wantErr[i])
+				}
+				if err != nil {
+					continue
+				}
+				var p models.Publication
+				switch v := item.(type) {
+				case models.Sentence:
+					p = v.Publication
+				case models.Quiz:
+					p = v.Publication
+				case models.Resource:
+					p = v.Publication
+				}
+				if p.Status != models.StatusDraft {
+					t.Errorf("fila %d: entra como %q, esperaba borrador", table.Lines[i], p.Status)
+				}
+			}
+		})
+	}
+}
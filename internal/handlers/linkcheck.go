@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"english-at-lima-cms/internal/linkcheck"
+	"english-at-lima-cms/internal/models"
+	"english-at-lima-cms/internal/repository"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetBrokenLinks muestra el reporte de enlaces rotos con acciones de arreglo
+func GetBrokenLinks(c *gin.Context) {
+	resources, err := repository.ListBrokenResources()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error de conexión")
+		return
+	}
+	c.HTML(http.StatusOK, "broken-links.html", gin.H{"Resources": resources})
+}
+
+// RunLinkCheck lanza una revisión completa sin esperar a que termine
+func RunLinkCheck(c *gin.Context) {
+	if err := linkcheck.Start(linkcheck.NewChecker()); err != nil {
+		SendToast(c, "Ya hay una revisión de enlaces en curso", "error")
+		return
+	}
+	SendToast(c, "Revisión de enlaces iniciada en segundo plano", "success")
+	c.Status(http.StatusAccepted)
+}
+
+// FixResourceURL guarda la URL corregida y la revisa al momento
+func FixResourceURL(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	url := strings.TrimSpace(c.PostForm("url"))
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		SendToast(c, "URL debe ser válida y segura (http/https)", "error")
+		return
+	}
+	r, err := repository.GetResource(c.Param("id"))
+	if err != nil {
+		SendToast(c, "Recurso no encontrado", "error")
+		return
+	}
+	if err := guardPublish(c, r.Publication, r.Publication); err != nil {
+		sendGuardError(c, err)
+		return
+	}
+
+	checker := linkcheck.NewChecker()
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 20*time.Second)
+	defer cancel()
+	res := checker.Check(ctx, linkcheck.Target{ID: id, URL: url})
+
+	streak := 0
+	if res.Broken() {
+		streak = 1
+	}
+	if err := repository.UpdateResourceURL(c.Param("id"), url); err != nil {
+		SendToast(c, "Error al actualizar el recurso", "error")
+		return
+	}
+	_ = repository.UpdateLinkStatus(id, res.Status, res.RedirectTo, res.CheckedAt.UTC().Format(time.RFC3339), streak)
+
+	if res.Broken() {
+		SendToast(c, fmt.Sprintf("URL guardada, pero sigue sin responder (%d)", res.Status), "error")
+		return
+	}
+	SendToast(c, "Enlace reparado", "success")
+	c.Status(http.StatusOK)
+}
+
+// ArchiveResource retira un recurso roto; lo publicado sólo lo archiva un revisor
+func ArchiveResource(c *gin.Context) {
+	if _, err := strconv.Atoi(c.Param("id")); err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	r, err := repository.GetResource(c.Param("id"))
+	if err != nil {
+		SendToast(c, "Recurso no encontrado", "error")
+		return
+	}
+	if err := guardPublish(c, r.Publication, models.Publication{Status: models.StatusArchived}); err != nil {
+		sendGuardError(c, err)
+		return
+	}
+	if err := repository.ArchiveResource(c.Param("id")); err != nil {
+		SendToast(c, "Error al archivar el recurso", "error")
+		return
+	}
+	SendToast(c, "Recurso archivado", "success")
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+)
+
+func TestArchiveResourceEditorRefused(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// Supabase falso: el recurso está publicado; cualquier escritura es un
+	// fallo del test
+	supa := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("un editor no debería poder escribir: %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`[{"id":5,"title":"Podcast","status":"published"}]`))
+	}))
+	defer supa.Close()
+	t.Setenv("SUPABASE_URL", supa.URL)
+
+	r := gin.New()
+	r.Use(sessions.Sessions("mysession", cookie.NewStore([]byte("test"))))
+	r.POST("/admin/resources/:id/archive", ArchiveResource)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/resources/5/archive", nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("un editor no debería archivar un recurso publicado: estado %d", w.Code)
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"english-at-lima-cms/internal/lms"
+	"english-at-lima-cms/internal/models"
+	"english-at-lima-cms/internal/repository"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxLMSQuizzes limita el paquete: se arma en memoria y el reproductor de
+// SCORM pinta todas las preguntas en una sola página
+const maxLMSQuizzes = 500
+
+var errTooManyQuizzes = fmt.Errorf("hay más de %d quizzes con esos filtros: afine la búsqueda", maxLMSQuizzes)
+
+// lmsTitle es el título del paquete en el LMS, con el nivel y la etiqueta
+// del filtro si los hay
+func lmsTitle(tx models.Taxonomy) string {
+	parts := []string{"English at Lima · Quizzes"}
+	if tx.Level != "" {
+		parts = append(parts, tx.Level)
+	}
+	parts = append(parts, tx.Tags...)
+	return strings.Join(parts, " · ")
+}
+
+// ExportLMS descarga los quizzes del listado, con sus filtros, para una
+// plataforma de aprendizaje: ?format=qti (banco de ítems IMS QTI 2.1) o
+// scorm (paquete SCORM 1.2 con su reproductor, que envía la nota al LMS).
+// ?mastery es la nota de aprobado del SCORM, de 0 a 100.
+func ExportLMS(c *gin.Context) {
+	format := c.DefaultQuery("format", "scorm")
+	if format != "qti" && format != "scorm" {
+		c.String(http.StatusBadRequest, "Formato de paquete no soportado")
+		return
+	}
+	mastery := lms.DefaultMastery
+	if v := c.Query("mastery"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > 100 {
+			c.String(http.StatusBadRequest, "La nota de aprobado debe estar entre 0 y 100")
+			return
+		}
+		mastery = n
+	}
+	filter, view := listQuery(c, "quiz")
+
+	var quizzes []models.Quiz
+	err := repository.ExportRows("quizzes", filter, "id.asc", func(raw json.RawMessage) error {
+		if len(quizzes) == maxLMSQuizzes {
+			return errTooManyQuizzes
+		}
+		var q models.Quiz
+		if err := json.Unmarshal(raw, &q); err != nil {
+			return err
+		}
+		quizzes = append(quizzes, q)
+		return nil
+	})
+	switch {
+	case errors.Is(err, errTooManyQuizzes):
+		c.String(http.StatusRequestEntityTooLarge, err.Error())
+		return
+	case err != nil:
+		c.String(http.StatusInternalServerError, "Error al leer los quizzes")
+		return
+	case len(quizzes) == 0:
+		c.String(http.StatusNotFound, "No hay quizzes con esos filtros")
+		return
+	}
+
+	// Se arma entero antes de responder: un quiz que no se puede escribir
+	// da un error limpio en vez de un ZIP cortado
+	var buf bytes.Buffer
+	if format == "qti" {
+		err = lms.WriteQTI(&buf, quizzes)
+	} else {
+		err = lms.WriteSCORM(&buf, lms.Package{Title: lmsTitle(view["Filter"].(models.Taxonomy)), Mastery: mastery, Quizzes: quizzes})
+	}
+	if err != nil {
+		fmt.Println("❌ Paquete para LMS fallido:", err)
+		c.String(http.StatusInternalServerError, "Error al armar el paquete: "+err.Error())
+		return
+	}
+
+	name := fmt.Sprintf("english-at-lima-%s-%s.zip", format, time.Now().In(models.Lima).Format("20060102"))
+	c.Header("Content-Disposition", `attachment; filename="`+name+`"`)
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+
+	"english-at-lima-cms/internal/i18n"
+	"english-at-lima-cms/internal/richtext"
+
+	"github.com/gin-gonic/gin"
+)
+
+// localeCookie recuerda el idioma que el alumno eligió en el selector
+const localeCookie = "eal_locale"
+
+// publicLocale elige el idioma de las traducciones: ?lang= (y se recuerda),
+// la cookie, Accept-Language y por último el español
+func publicLocale(c *gin.Context) string {
+	if lang := c.Query("lang"); i18n.Supported(lang) {
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(localeCookie, lang, 365*24*3600, "/", "", true, true)
+		return lang
+	}
+	if lang, err := c.Cookie(localeCookie); err == nil && i18n.Supported(lang) {
+		return lang
+	}
+	return i18n.Negotiate(c.GetHeader("Accept-Language"))
+}
+
+// withLocale añade a los datos de la página el idioma elegido y las
+// opciones del selector
+func withLocale(c *gin.Context, data gin.H) gin.H {
+	locale := publicLocale(c)
+	data["Locale"] = locale
+	data["Locales"] = i18n.Options(locale)
+	return data
+}
+
+// readTranslations lee los campos prefijo_código de las pestañas de traducción
+func readTranslations(c *gin.Context, prefix string) map[string]string {
+	return collectTranslations(c, prefix, Sanitize)
+}
+
+// readMarkdownTranslations es readTranslations para los campos con Markdown
+func readMarkdownTranslations(c *gin.Context, prefix string) map[string]string {
+	return collectTranslations(c, prefix, richtext.Clean)
+}
+
+func collectTranslations(c *gin.Context, prefix string, clean func(string) string) map[string]string {
+	values := map[string]string{}
+	for _, code := range i18n.Extra() {
+		values[code] = clean(c.PostForm(prefix + "_" + code))
+	}
+	return i18n.Clean(values)
+}
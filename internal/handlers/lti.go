@@ -0,0 +1,398 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"english-at-lima-cms/internal/embed"
+	"english-at-lima-cms/internal/lms"
+	"english-at-lima-cms/internal/lti"
+	"english-at-lima-cms/internal/models"
+	"english-at-lima-cms/internal/quiz"
+	"english-at-lima-cms/internal/repository"
+	"english-at-lima-cms/internal/seo"
+	"english-at-lima-cms/internal/xapi"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// ltiPlatformTTL: cada lanzamiento no debe costar una consulta a Supabase
+	ltiPlatformTTL = 5 * time.Minute
+	// maxDeepLinkQuizzes limita la lista del selector de deep linking
+	maxDeepLinkQuizzes = 500
+	// ltiStateCookie + state ata el lanzamiento al navegador que inició el
+	// login; una cookie por state para que dos actividades abiertas a la vez
+	// en la misma página del curso no se pisen
+	ltiStateCookie = "lti_state_"
+	ltiStateMaxAge = 10 * 60 // segundos, lo mismo que vale el state
+)
+
+var ltiTool *lti.Tool
+
+type cachedLTIPlatform struct {
+	platform models.LTIPlatform
+	expires  time.Time
+}
+
+var ltiPlatforms sync.Map // issuer + "\n" + client_id -> cachedLTIPlatform
+
+// InitLTI carga la clave de la herramienta de LTI_PRIVATE_KEY (PEM; los
+// saltos de línea pueden venir escritos como \n). Sin ella se genera una
+// clave temporal: sirve para probar, pero cambia en cada reinicio y las
+// plataformas dejan de aceptar las notas hasta releer nuestro JWKS.
+func InitLTI() error {
+	var key *rsa.PrivateKey
+	var err error
+	if raw := os.Getenv("LTI_PRIVATE_KEY"); raw != "" {
+		if key, err = lti.ParseKey([]byte(strings.ReplaceAll(raw, `\n`, "\n"))); err != nil {
+			return err
+		}
+	} else {
+		fmt.Println("⚠️ LTI_PRIVATE_KEY no configurada: LTI usa una clave temporal")
+		if key, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+			return err
+		}
+	}
+	ltiTool = lti.New(key, seo.SiteURL(), lookupLTIPlatform)
+	ltiTool.Grades = ltiGrades{}
+	return nil
+}
+
+// ltiGrades guarda en la base los intentos ya calificados: un reinicio no
+// debe permitir cambiar la nota reenviando el formulario
+type ltiGrades struct{}
+
+func (ltiGrades) Claim(attempt string, expires time.Time) (bool, error) {
+	return repository.ClaimLTIGrade(attempt, expires)
+}
+
+func (ltiGrades) Release(attempt string) error {
+	return repository.ReleaseLTIGrade(attempt)
+}
+
+func lookupLTIPlatform(issuer, clientID string) (models.LTIPlatform, error) {
+	cacheKey := issuer + "\n" + clientID
+	if cached, ok := ltiPlatforms.Load(cacheKey); ok {
+		entry := cached.(cachedLTIPlatform)
+		if time.Now().Before(entry.expires) {
+			return entry.platform, nil
+		}
+	}
+	p, err := repository.GetLTIPlatform(issuer, clientID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return p, lti.ErrUnknownPlatform
+	}
+	if err != nil {
+		return p, err
+	}
+	ltiPlatforms.Store(cacheKey, cachedLTIPlatform{platform: p, expires: time.Now().Add(ltiPlatformTTL)})
+	return p, nil
+}
+
+// ltiReady responde 503 si la herramienta no arrancó
+func ltiReady(c *gin.Context) bool {
+	if ltiTool == nil {
+		c.String(http.StatusServiceUnavailable, "LTI no está disponible en este servidor")
+		return false
+	}
+	return true
+}
+
+// ltiFrame deja que sólo la plataforma del lanzamiento incruste la página
+func ltiFrame(c *gin.Context, p models.LTIPlatform) {
+	c.Header("Content-Security-Policy", embed.FrameAncestors(lti.Origins(p)))
+	c.Header("Cache-Control", "no-store")
+	c.Header("Referrer-Policy", "no-referrer")
+}
+
+// LTIJWKS publica la clave pública de la herramienta
+func LTIJWKS(c *gin.Context) {
+	if !ltiReady(c) {
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.JSON(http.StatusOK, ltiTool.JWKS())
+}
+
+// LTILogin es el inicio de sesión de terceros: la plataforma lo llama (GET
+// o POST) y el navegador sigue hacia su endpoint de autorización
+func LTILogin(c *gin.Context) {
+	if !ltiReady(c) {
+		return
+	}
+	if err := c.Request.ParseForm(); err != nil {
+		c.String(http.StatusBadRequest, "Petición LTI ilegible")
+		return
+	}
+	redirect, state, err := ltiTool.Login(c.Request.Form)
+	if err != nil {
+		fmt.Println("❌ Inicio de sesión LTI rechazado:", err)
+		c.String(http.StatusBadRequest, "No se pudo iniciar la actividad: "+err.Error())
+		return
+	}
+	// El login y el launch llegan desde el iframe del LMS: sin SameSite=None
+	// el navegador no devolvería la cookie
+	c.SetSameSite(http.SameSiteNoneMode)
+	c.SetCookie(ltiStateCookie+state, state, ltiStateMaxAge, "/lti", "", true, true)
+	c.Redirect(http.StatusFound, redirect)
+}
+
+// LTILaunch recibe el id_token de la plataforma. Un enlace de recurso abre
+// el quiz de su parámetro quiz_id; un pedido de deep linking, el selector
+// de quizzes para el docente.
+func LTILaunch(c *gin.Context) {
+	if !ltiReady(c) {
+		return
+	}
+	// Sin la cookie del login el formulario no viene del navegador que lo
+	// inició (login CSRF): se rechaza antes de consumir el state
+	state := c.PostForm("state")
+	if saved, err := c.Cookie(ltiStateCookie + state); err != nil || state == "" || saved != state {
+		fmt.Println("❌ Lanzamiento LTI rechazado: el state no coincide con la cookie del navegador")
+		c.String(http.StatusUnauthorized, "Lanzamiento LTI no válido: el navegador no es el que inició la actividad (¿cookies de terceros bloqueadas?)")
+		return
+	}
+	c.SetSameSite(http.SameSiteNoneMode)
+	c.SetCookie(ltiStateCookie+state, "", -1, "/lti", "", true, true)
+	l, err := ltiTool.ValidateLaunch(c.PostForm("id_token"), state)
+	if err != nil {
+		fmt.Println("❌ Lanzamiento LTI rechazado:", err)
+		c.String(http.StatusUnauthorized, "Lanzamiento LTI no válido: "+err.Error())
+		return
+	}
+	ltiFrame(c, l.Platform)
+
+	if l.MessageType == lti.MessageDeepLinking {
+		if !l.IsInstructor() {
+			c.String(http.StatusForbidden, "Sólo los docentes pueden elegir quizzes")
+			return
+		}
+		ltiDeepLinkPicker(c, l)
+		return
+	}
+
+	quizID, err := strconv.Atoi(l.Custom["quiz_id"])
+	if err != nil || quizID <= 0 {
+		c.String(http.StatusBadRequest, "El enlace no indica el quiz (parámetro personalizado quiz_id)")
+		return
+	}
+	q, err := repository.GetQuiz(strconv.Itoa(quizID))
+	if err == nil && !q.Public() {
+		err = repository.ErrNotFound
+	}
+	if !renderable(c, err) {
+		return
+	}
+	s := l.Session(quizID)
+	session, err := ltiTool.SignSession(s)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error al abrir la actividad")
+		return
+	}
+	c.HTML(http.StatusOK, "lti-quiz.html", gin.H{
+		"Quiz": q, "Launch": l, "Session": session, "Graded": s.LineItem != "",
+	})
+}
+
+func ltiDeepLinkPicker(c *gin.Context, l lti.Launch) {
+	session, err := ltiTool.SignSession(l.Session(0))
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error al abrir el selector")
+		return
+	}
+	quizzes, err := repository.ListLTIQuizzes(maxDeepLinkQuizzes)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error de conexión")
+		return
+	}
+	c.HTML(http.StatusOK, "lti-deep-link.html", gin.H{
+		"Quizzes": quizzes, "Session": session, "Multiple": l.DeepLinking.AcceptMultiple,
+	})
+}
+
+// LTIDeepLink devuelve a la plataforma los quizzes elegidos (o la
+// cancelación) en una respuesta de deep linking firmada
+func LTIDeepLink(c *gin.Context) {
+	if !ltiReady(c) {
+		return
+	}
+	s, err := ltiTool.ParseSession(c.PostForm("session"))
+	if err != nil || s.DeepLink == nil {
+		c.String(http.StatusUnauthorized, "Sesión LTI no válida: vuelva a abrir el selector desde el curso")
+		return
+	}
+	var items []lti.ContentItem
+	if c.PostForm("cancel") == "" {
+		for _, raw := range c.PostFormArray("quiz_id") {
+			id, err := strconv.Atoi(raw)
+			if err != nil || id <= 0 {
+				continue
+			}
+			q, err := repository.GetQuiz(raw)
+			if err != nil || !q.Public() {
+				c.String(http.StatusNotFound, "Quiz no disponible: "+raw)
+				return
+			}
+			items = append(items, ltiContentItem(q))
+		}
+		if len(items) == 0 {
+			c.String(http.StatusBadRequest, "Elija al menos un quiz")
+			return
+		}
+	}
+	jwt, err := ltiTool.DeepLinkResponse(s, items)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	if p, err := lookupLTIPlatform(s.Platform, s.ClientID); err == nil {
+		ltiFrame(c, p)
+	}
+	c.HTML(http.StatusOK, "lti-return.html", gin.H{"ReturnURL": s.DeepLink.ReturnURL, "JWT": jwt})
+}
+
+// ltiContentItem es el enlace al quiz con su columna de notas de 1 punto
+func ltiContentItem(q models.Quiz) lti.ContentItem {
+	title := q.Question
+	if r := []rune(title); len(r) > 80 {
+		title = string(r[:79]) + "…"
+	}
+	return lti.ContentItem{
+		Type: lti.TypeResourceLink, Title: title, Text: q.TypeLabel() + " · " + q.Level,
+		URL:      ltiTool.LaunchURL(),
+		Custom:   map[string]string{"quiz_id": strconv.Itoa(q.ID)},
+		LineItem: &lti.LineItem{ScoreMaximum: 1, Label: title, ResourceID: lms.ItemID(q)},
+	}
+}
+
+// LTIAnswer corrige la respuesta del alumno lanzado desde el LMS y, si el
+// enlace lleva notas, envía 1 (correcta) o 0 al libro de calificaciones.
+// Sólo cuenta la primera respuesta de cada lanzamiento.
+func LTIAnswer(c *gin.Context) {
+	if !ltiReady(c) {
+		return
+	}
+	s, err := ltiTool.ParseSession(c.PostForm("session"))
+	if err != nil || s.QuizID <= 0 {
+		c.String(http.StatusOK, "La sesión caducó: vuelve a abrir la actividad desde el curso.")
+		return
+	}
+	q, err := repository.GetQuiz(strconv.Itoa(s.QuizID))
+	if err == nil && !q.Public() {
+		err = repository.ErrNotFound
+	}
+	if !renderable(c, err) {
+		return
+	}
+	response, ok := readResponses(c)
+	if !ok || len(response) == 0 {
+		c.String(http.StatusOK, "Elige o escribe una respuesta primero.")
+		return
+	}
+
+	correct := quiz.Grade(q, response)
+	if s.Subject != "" {
+		xapi.Emit(xapi.QuizAnswered(xapi.LTIUser(s.Platform, s.Subject), q, response, correct))
+	}
+	var chosen []models.QuizOption
+	for _, o := range q.OptionViews() {
+		if slices.Contains(response, o.Text) {
+			chosen = append(chosen, o)
+		}
+	}
+	data := gin.H{"Quiz": q, "Correct": correct, "Chosen": chosen, "Locale": publicLocale(c), "Graded": s.LineItem != ""}
+	if s.LineItem != "" {
+		given := 0.0
+		if correct {
+			given = 1
+		}
+		posted, err := ltiTool.GradeOnce(s, lti.Score{
+			ScoreGiven: given, ScoreMaximum: 1, ActivityProgress: "Completed", GradingProgress: "FullyGraded",
+		})
+		switch {
+		case err != nil:
+			fmt.Println("❌ Nota LTI no enviada:", err)
+			data["ScoreError"] = true
+		case !posted:
+			data["AlreadyGraded"] = true
+		}
+	}
+	c.HTML(http.StatusOK, "lti-feedback.html", data)
+}
+
+// --- ADMINISTRACIÓN DE PLATAFORMAS ---
+
+func GetLTIPlatforms(c *gin.Context) {
+	platforms, err := repository.ListLTIPlatforms()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error de conexión")
+		return
+	}
+	c.HTML(http.StatusOK, "lti-platforms.html", gin.H{"Platforms": platforms, "Tool": ltiTool})
+}
+
+// ValidateLTIPlatform comprueba el registro antes de guardarlo
+func ValidateLTIPlatform(p models.LTIPlatform) error {
+	if len(strings.TrimSpace(p.Name)) < 3 {
+		return fmt.Errorf("el nombre de la plataforma es demasiado corto")
+	}
+	if p.ClientID == "" {
+		return fmt.Errorf("falta el client_id que asignó la plataforma")
+	}
+	if len(p.DeploymentIDs) == 0 {
+		return fmt.Errorf("debe indicar al menos un ID de despliegue")
+	}
+	for field, raw := range map[string]string{
+		"El issuer": p.Issuer, "El endpoint de autorización": p.AuthLoginURL,
+		"El endpoint de token": p.AuthTokenURL, "El JWKS": p.JWKSURL,
+	} {
+		if err := lti.CheckURL(raw, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func SaveLTIPlatform(c *gin.Context) {
+	p := models.LTIPlatform{
+		Name:          Sanitize(c.PostForm("name")),
+		Issuer:        strings.TrimSpace(c.PostForm("issuer")),
+		ClientID:      strings.TrimSpace(c.PostForm("client_id")),
+		DeploymentIDs: lti.ParseDeployments(c.PostForm("deployment_ids")),
+		AuthLoginURL:  strings.TrimSpace(c.PostForm("auth_login_url")),
+		AuthTokenURL:  strings.TrimSpace(c.PostForm("auth_token_url")),
+		JWKSURL:       strings.TrimSpace(c.PostForm("jwks_url")),
+	}
+	if err := ValidateLTIPlatform(p); err != nil {
+		SendToast(c, err.Error(), "error")
+		return
+	}
+	if err := repository.InsertLTIPlatform(p); err != nil {
+		SendToast(c, "Error al guardar la plataforma (¿issuer y client_id ya registrados?)", "error")
+		return
+	}
+	SendToast(c, "Plataforma LTI registrada", "success")
+	c.Header("HX-Trigger", "refreshList")
+}
+
+func DeleteLTIPlatform(c *gin.Context) {
+	if err := repository.DeleteLTIPlatform(c.Param("id")); err != nil {
+		SendToast(c, "Error al eliminar la plataforma", "error")
+		return
+	}
+	// Como con los widgets: lo revocado deja de servir al momento
+	ltiPlatforms.Range(func(k, _ interface{}) bool {
+		ltiPlatforms.Delete(k)
+		return true
+	})
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"english-at-lima-cms/internal/models"
+	"english-at-lima-cms/internal/repository"
+	"english-at-lima-cms/internal/seo"
+	"english-at-lima-cms/internal/storage"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// signedURLTTL es lo que dura un enlace a un archivo privado
+const signedURLTTL = time.Hour
+
+var mediaStore storage.Driver
+
+// InitMedia configura el driver de almacenamiento (se llama desde main)
+func InitMedia(d storage.Driver) {
+	mediaStore = d
+}
+
+// mediaURL es la URL absoluta con la que se guarda el archivo en Resource.URL
+func mediaURL(key string) string {
+	return seo.SiteURL() + "/media/" + key
+}
+
+// pendingUpload es el archivo adjunto ya leído y revisado (tipo y tamaño)
+// pero todavía sin guardar: se guarda con store cuando el formulario pasó la
+// validación y los permisos, así un envío rechazado no deja archivos sueltos
+type pendingUpload struct {
+	*storage.Upload
+	filename string
+	private  bool
+}
+
+// readUpload lee el campo "file" del formulario. Devuelve nil si no se
+// adjuntó ningún archivo (el recurso usa una URL externa).
+func readUpload(c *gin.Context) (*pendingUpload, error) {
+	header, err := c.FormFile("file")
+	if errors.Is(err, http.ErrMissingFile) {
+		return nil, nil
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return nil, fmt.Errorf("el envío supera el límite permitido")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo leer el archivo adjunto")
+	}
+	if mediaStore == nil {
+		return nil, fmt.Errorf("el almacenamiento de archivos no está configurado")
+	}
+
+	src, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	up, err := storage.Spool(src)
+	if err != nil {
+		return nil, err
+	}
+	return &pendingUpload{Upload: up, filename: header.Filename, private: c.PostForm("private") == "on"}, nil
+}
+
+// url es la dirección que tendrá el archivo, para validar el formulario
+// antes de guardarlo
+func (p *pendingUpload) url() string {
+	return mediaURL(p.Key(p.private))
+}
+
+// Close borra el temporal; admite nil para poder diferirlo sin comprobar
+func (p *pendingUpload) Close() {
+	if p != nil {
+		p.Upload.Close()
+	}
+}
+
+// store guarda el archivo y devuelve su URL. Sin archivo adjunto devuelve
+// current tal cual.
+func (p *pendingUpload) store(current string) (string, error) {
+	if p == nil {
+		return current, nil
+	}
+	// De-duplicación: si ya tenemos ese mismo contenido reutilizamos la clave
+	existing, err := repository.FindMedia(p.SHA256, p.private)
+	if err != nil {
+		return "", fmt.Errorf("error al consultar archivos existentes")
+	}
+	if existing != nil {
+		return mediaURL(existing.Key), nil
+	}
+
+	key := p.Key(p.private)
+	if err := mediaStore.Put(key, p.File, p.Size, p.ContentType); err != nil {
+		return "", fmt.Errorf("error al guardar el archivo")
+	}
+	err = repository.InsertMedia(models.Media{
+		SHA256:      p.SHA256,
+		Key:         key,
+		Filename:    Sanitize(path.Base(p.filename)),
+		ContentType: p.ContentType,
+		Size:        p.Size,
+		Private:     p.private,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error al registrar el archivo")
+	}
+	return mediaURL(key), nil
+}
+
+// ServeMedia entrega el archivo con soporte de Range (audio/vídeo seekable).
+// Los archivos bajo private/ exigen una URL firmada y vigente.
+func ServeMedia(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	private := strings.HasPrefix(key, storage.PrivatePrefix)
+	if private && !storage.VerifySignature(key, c.Query("exp"), c.Query("sig")) {
+		c.String(http.StatusForbidden, "Enlace caducado o inválido")
+		return
+	}
+	if mediaStore == nil {
+		c.Status(http.StatusServiceUnavailable)
+		return
+	}
+
+	obj, err := mediaStore.Open(key)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.String(http.StatusNotFound, "Archivo no encontrado")
+		return
+	}
+	if err != nil {
+		c.String(http.StatusBadGateway, "Error al leer el archivo")
+		return
+	}
+	defer obj.Close()
+
+	if obj.ContentType != "" {
+		c.Header("Content-Type", obj.ContentType)
+	}
+	c.Header("X-Content-Type-Options", "nosniff")
+	if private {
+		c.Header("Cache-Control", "private, no-store")
+	} else {
+		// La clave es el hash del contenido: nunca cambia
+		c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	}
+	http.ServeContent(c.Writer, c.Request, path.Base(key), obj.ModTime, obj)
+}
+
+// signPrivateURLs reemplaza las URLs de archivos privados por enlaces firmados
+// antes de mostrarlas (admin o alumnos)
+func signPrivateURLs(resources []models.Resource) {
+	for i := range resources {
+		resources[i].URL = signedMediaURL(resources[i].URL)
+	}
+}
+
+func signedMediaURL(url string) string {
+	prefix := mediaURL(storage.PrivatePrefix)
+	if !strings.HasPrefix(url, prefix) {
+		return url
+	}
+	key := strings.TrimPrefix(url, mediaURL(""))
+	return url + "?" + storage.SignedQuery(key, signedURLTTL)
+}
+
+// mediaKey devuelve la clave de almacenamiento de un archivo subido al sitio;
+// ok=false si la URL apunta fuera
+func mediaKey(url string) (key string, ok bool) {
+	url = unsignedMediaURL(url)
+	if !strings.HasPrefix(url, mediaURL("")) {
+		return "", false
+	}
+	key = strings.TrimPrefix(url, mediaURL(""))
+	return key, key != ""
+}
+
+// unsignedMediaURL quita exp/sig si el formulario devolvió un enlace firmado,
+// para no guardar en la base de datos una URL que caduca
+func unsignedMediaURL(url string) string {
+	if !strings.HasPrefix(url, mediaURL("")) {
+		return url
+	}
+	if i := strings.Index(url, "?"); i >= 0 {
+		return url[:i]
+	}
+	return url
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"bytes"
+	"io/fs"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"english-at-lima-cms/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pngBytes es lo justo para que el tipo se detecte como image/png
+var pngBytes = append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 64)...)
+
+func uploadContext(t *testing.T, fields map[string]string, file []byte) *gin.Context {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for k, v := range fields {
+		w.WriteField(k, v)
+	}
+	part, err := w.CreateFormFile("file", "adjunto.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(file)
+	w.Close()
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/admin/resources/save", &body)
+	c.Request.Header.Set("Content-Type", w.FormDataContentType())
+	return c
+}
+
+func TestRejectedFormStoresNothing(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	local, err := storage.NewLocal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := mediaStore
+	InitMedia(local)
+	t.Cleanup(func() { mediaStore = prev })
+	// Supabase responde que el archivo no existe y acepta todo: si el
+	// handler guardara antes de validar, el archivo llegaría al disco
+	supa := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	}))
+	t.Cleanup(supa.Close)
+	t.Setenv("SUPABASE_URL", supa.URL)
+
+	tests := []struct {
+		name   string
+		fields map[string]string
+		save   gin.HandlerFunc
+	}{
+		{"recurso con título inválido", map[string]string{"title": "ab", "level": "A1"}, SaveResource},
+		{"frase con una imagen en vez de audio", map[string]string{"english": "Hello world", "spanish": "Hola mundo", "level": "A1"}, SaveSentence},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := uploadContext(t, tt.fields, pngBytes)
+			tt.save(c)
+			if c.Writer.Status() < 400 {
+				t.Fatalf("el formulario debería rechazarse, estado %d", c.Writer.Status())
+			}
+			stored := 0
+			filepath.WalkDir(dir, func(_ string, d fs.DirEntry, _ error) error {
+				if d != nil && !d.IsDir() {
+					stored++
+				}
+				return nil
+			})
+			if stored != 0 {
+				t.Errorf("un envío rechazado dejó %d archivos guardados", stored)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"english-at-lima-cms/internal/models"
 	"strings"
 	"testing"
 )
@@ -25,6 +26,35 @@ func TestModuleValidations(t *testing.T) {
 	})
 }
 
+func TestModuleValidationsCourse(t *testing.T) {
+	tests := []struct {
+		name        string
+		title, desc string
+		level       string
+		wantErr     bool
+	}{
+		{"Curso Válido", "Inglés para viajar", "Frases útiles en el aeropuerto", "A2", false},
+		{"Sin Nivel", "Inglés para viajar", "", "", true},
+		{"Título Corto", "Ok", "", "B1", true},
+		{"Descripción Larga", "Business English", strings.Repeat("x", 501), "C1", true},
+	}
+	for _, tt := range tests {
+		if err := ValidateCourse(tt.title, tt.desc, tt.level); (err != nil) != tt.wantErr {
+			t.Errorf("%s: error esperado %v, obtenido %v", tt.name, tt.wantErr, err)
+		}
+	}
+
+	// El id del alumno va a un filtro de PostgREST: sólo aceptamos el que generamos
+	for _, id := range []string{"", "abc", "eq.1&or=(x)", strings.Repeat("z", 32)} {
+		if validStudentID(id) {
+			t.Errorf("❌ Se aceptó un id de alumno inválido: %q", id)
+		}
+	}
+	if !validStudentID(strings.Repeat("a1", 16)) {
+		t.Error("un id hexadecimal de 32 caracteres debería ser válido")
+	}
+}
+
 func TestSQLInjectionPrevention(t *testing.T) {
 	// Intentos de inyección comunes
 	maliciousInputs := []string{
@@ -58,9 +88,15 @@ func TestModuleValidationsQuiz(t *testing.T) {
 			{"Pregunta Corta", "Hi?", []string{"1", "2", "3"}, "1", true},
 			{"Opción Vacía", "Valid question?", []string{"", "2", "3"}, "2", true},
 			{"Sin Correcta", "Valid question?", []string{"1", "2", "3"}, "", true},
+			{"Correcta Inexistente", "What is 'Apple'?", []string{"Manzana", "Pera"}, "Uva", true},
+			{"Dos Opciones", "What is 'Apple'?", []string{"Manzana", "Pera"}, "Manzana", false},
 		}
 		for _, tt := range tests {
-			if err := ValidateQuiz(tt.q, tt.o, tt.c); (err != nil) != tt.wantErr {
+			q := models.Quiz{Question: tt.q, Type: models.QuizSingle, Options: tt.o}
+			if tt.c != "" {
+				q.Answers = []string{tt.c}
+			}
+			if err := ValidateQuiz(q); (err != nil) != tt.wantErr {
 				t.Errorf("%s: error esperado %v, obtenido %v", tt.name, tt.wantErr, err)
 			}
 		}
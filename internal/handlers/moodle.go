@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"encoding/json"
+	"english-at-lima-cms/internal/importer"
+	"english-at-lima-cms/internal/models"
+	"english-at-lima-cms/internal/moodle"
+	"english-at-lima-cms/internal/quiz"
+	"english-at-lima-cms/internal/repository"
+	"english-at-lima-cms/internal/richtext"
+	"english-at-lima-cms/internal/taxonomy"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// moodleColumns son las columnas que reescribe la importación de Moodle al
+// actualizar un quiz existente: todo menos la publicación
+var moodleColumns = []string{"question", "type", "options", "answers", "level", "tags", "explanation", "option_notes", "hints"}
+
+// ExportMoodle descarga los quizzes del listado, con sus filtros, como banco
+// de preguntas de Moodle: ?format=xml (Moodle XML) o gift. Los de ordenar
+// no tienen equivalente en Moodle y se quedan fuera.
+func ExportMoodle(c *gin.Context) {
+	format := c.DefaultQuery("format", moodle.XML)
+	if !moodle.Valid(format) {
+		c.String(http.StatusBadRequest, "Formato de banco de preguntas no soportado")
+		return
+	}
+	filter, _ := listQuery(c, "quiz")
+	filter = withFilter(filter, "type=in.("+strings.Join(moodle.Types, ",")+")")
+
+	name := fmt.Sprintf("english-at-lima-%s-%s%s", format, time.Now().In(models.Lima).Format("20060102"), moodle.Extension(format))
+	c.Header("Content-Type", moodle.ContentType(format))
+	c.Header("Content-Disposition", `attachment; filename="`+name+`"`)
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Content-Type-Options", "nosniff")
+
+	w, _ := moodle.NewWriter(format, c.Writer)
+	// Por nivel, así las preguntas de una misma categoría salen juntas
+	err := repository.ExportRows("quizzes", filter, "level.asc,id.asc", func(raw json.RawMessage) error {
+		var q models.Quiz
+		if err := json.Unmarshal(raw, &q); err != nil {
+			return err
+		}
+		return w.Write(q)
+	})
+	if err == nil {
+		err = w.Close()
+	}
+	if err == nil {
+		return
+	}
+	fmt.Println("❌ Exportación a Moodle fallida:", err)
+	if !c.Writer.Written() {
+		c.Header("Content-Disposition", "")
+		c.Header("Content-Type", "text/plain; charset=utf-8")
+		c.String(http.StatusInternalServerError, "Error al exportar: no se pudo leer la base de datos")
+	}
+}
+
+// moodleQuiz pasa la pregunta leída por las mismas reglas que un quiz creado
+// a mano. level es el nivel para las preguntas cuya categoría no lo indica.
+func moodleQuiz(item moodle.Item, level string) (models.Quiz, error) {
+	q := item.Quiz
+	if item.Err != nil {
+		return q, item.Err
+	}
+	q.Question = Sanitize(q.Question)
+	for i := range q.Options {
+		q.Options[i] = Sanitize(q.Options[i])
+	}
+	for i := range q.Answers {
+		q.Answers[i] = Sanitize(q.Answers[i])
+	}
+	q.Explanation = cleanNote(q.Explanation)
+	for i := range q.OptionNotes {
+		q.OptionNotes[i] = cleanNote(q.OptionNotes[i])
+	}
+	for i := range q.Hints {
+		q.Hints[i] = cleanNote(q.Hints[i])
+	}
+	if q.Level == "" {
+		q.Level = level
+	}
+	q.Publication = importPublication
+	quiz.Normalize(&q)
+	if err := ValidateQuiz(q); err != nil {
+		return q, err
+	}
+	return q, taxonomy.Validate(q.Level, q.Tags)
+}
+
+// cleanNote quita los caracteres de control del Markdown de la nota
+func cleanNote(n models.Note) models.Note {
+	n.ES, n.EN = richtext.Clean(n.ES), richtext.Clean(n.EN)
+	for code, text := range n.Locales {
+		n.Locales[code] = richtext.Clean(text)
+	}
+	return n
+}
+
+// ImportMoodle importa como borradores las preguntas de un banco de Moodle
+// (XML o GIFT). Los duplicados se reconocen por el texto de la pregunta y
+// se tratan según el modo elegido, como en el asistente de importación;
+// con dry_run sólo se muestra el informe.
+func ImportMoodle(c *gin.Context) {
+	mode, err := importMode(c)
+	if err != nil {
+		sendGuardError(c, err)
+		return
+	}
+	level := taxonomy.NormalizeLevel(c.PostForm("level"))
+	if level != "" && !taxonomy.ValidLevel(level) {
+		SendToast(c, "Nivel desconocido", "error")
+		return
+	}
+	_, data, err := uploadedFile(c, "Seleccione un banco de preguntas en Moodle XML o GIFT")
+	if err != nil {
+		SendToast(c, err.Error(), "error")
+		return
+	}
+	items, err := moodle.Read(data)
+	if err != nil {
+		SendToast(c, err.Error(), "error")
+		return
+	}
+	if len(items) == 0 {
+		SendToast(c, "El archivo no tiene preguntas", "error")
+		return
+	}
+	if len(items) > importer.MaxRows {
+		SendToast(c, fmt.Sprintf("El banco supera el límite de %d preguntas", importer.MaxRows), "error")
+		return
+	}
+
+	kind := importKinds["quiz"]
+	existing, err := existingKeys(kind)
+	if err != nil {
+		SendToast(c, err.Error(), "error")
+		return
+	}
+	rows := make([]importer.Parsed, len(items))
+	for i, item := range items {
+		q, err := moodleQuiz(item, level)
+		rows[i] = importer.Parsed{Line: item.Line, Label: q.Question, Item: q, Err: err}
+	}
+	results := importer.Plan(rows, existing, mode)
+	if c.PostForm("dry_run") != "" {
+		importReport(c, results, gin.H{"DryRun": true})
+		return
+	}
+	saved, failure := saveImport(kind.Table, results, moodleColumns)
+	importReport(c, results, gin.H{"Committed": true, "Saved": saved, "Failure": failure})
+}
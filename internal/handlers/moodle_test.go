@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"english-at-lima-cms/internal/models"
+	"english-at-lima-cms/internal/moodle"
+	"english-at-lima-cms/internal/quiz"
+)
+
+// moodleBank tiene un quiz de cada tipo con todo lo que admite: notas en
+// varios idiomas, pistas, etiquetas y los caracteres especiales de GIFT y XML
+func moodleBank() []models.Quiz {
+	bank := []models.Quiz{
+		{
+			ID: 3, Question: "Which one is a fruit? {pick one} #1", Type: models.QuizSingle,
+			Options: []string{"Apple", "Car = vehicle", "Table ~ chair"}, Answers: []string{"Apple"},
+			Taxonomy:    models.Taxonomy{Level: "A1", Tags: []string{"food", "vocabulary"}},
+			Explanation: models.Note{ES: "Las **frutas**: apple, pear…\n\n- Ojo: 5 < 7 & \"comillas\"", EN: "Fruits: apple, pear", Locales: map[string]string{"pt": "Frutas: maçã"}},
+			OptionNotes: []models.Note{{ES: "¡Correcto!"}, {ES: "Un coche no es fruta", EN: "A car is not a fruit"}, {}},
+			Hints:       []models.Note{{ES: "Se come", EN: "You can eat it"}, {ES: "Es roja: C:\\frutas\\manzana"}},
+		},
+		{
+			ID: 4, Question: "Select the past forms: (two are right)", Type: models.QuizMultiple,
+			Options: []string{"went", "goed", "ate", "eated"}, Answers: []string{"went", "ate"},
+			Taxonomy: models.Taxonomy{Level: "A2", Tags: []string{"past simple"}},
+		},
+		{
+			ID: 5, Question: "All of these are colours: red, blue", Type: models.QuizMultiple,
+			Options: []string{"red", "blue"}, Answers: []string{"red", "blue"},
+			Taxonomy:    models.Taxonomy{Level: "A1", Tags: []string{}},
+			OptionNotes: []models.Note{{}, {ES: "Azul"}},
+		},
+		{
+			ID: 6, Question: "London is the capital of the UK.", Type: models.QuizTrueFalse,
+			Answers: []string{"True"}, Taxonomy: models.Taxonomy{Level: "A1", Tags: []string{"geography"}},
+			Explanation: models.Note{ES: "Sí: Londres."},
+		},
+		{
+			ID: 7, Question: "The sun rises in the west.", Type: models.QuizTrueFalse,
+			Answers: []string{"False"}, Taxonomy: models.Taxonomy{Level: "B1", Tags: []string{"geography"}},
+		},
+		{
+			ID: 8, Question: "I ___ to school every day.", Type: models.QuizFill,
+			Answers: []string{"go", "walk", "don't go"}, Taxonomy: models.Taxonomy{Level: "A1", Tags: []string{"present simple"}},
+			Hints: []models.Note{{ES: "Verbo en presente"}},
+		},
+	}
+	for i := range bank {
+		bank[i].Publication = importPublication
+		quiz.Normalize(&bank[i])
+	}
+	return bank
+}
+
+// Un banco exportado en cualquiera de los dos formatos vuelve a entrar con
+// los mismos quizzes (salvo el id, que asigna la base)
+func TestMoodleRoundTrip(t *testing.T) {
+	for _, format := range moodle.Formats {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := moodle.NewWriter(format, &buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			bank := moodleBank()
+			for _, q := range bank {
+				if err := w.Write(q); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			items, err := moodle.Read(buf.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(items) != len(bank) {
+				t.Fatalf("leyó %d preguntas, esperaba %d:\n%s", len(items), len(bank), buf.String())
+			}
+			for i, item := range items {
+				got, err := moodleQuiz(item, "")
+				if err != nil {
+					t.Fatalf("quiz %d (línea %d): %v", bank[i].ID, item.Line, err)
+				}
+				want := bank[i]
+				want.ID = 0
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("quiz %d:\n   obtuvo %#v\n esperaba %#v", bank[i].ID, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMoodleQuiz(t *testing.T) {
+	gift := "$CATEGORY: $course$/top/Grammar\n\n" +
+		"Name the colour of the sky {=blue =Blue sky}\n\n" +
+		"<b>Bold</b> claim: cats bark. {F}\n\n" +
+		"Order the words {=a -> 1 =b -> 2}\n\n" +
+		"::A2::$CATEGORY looks like text {=x ~y}\n"
+	items, err := moodle.Read([]byte(gift))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name, level string
+		item        int
+		wantErr     bool
+		check       func(models.Quiz) bool
+	}{
+		{"Sin nivel en la categoría", "", 0, true, nil},
+		{"Nivel por defecto y hueco añadido", "B1", 0, false, func(q models.Quiz) bool {
+			return q.Level == "B1" && q.Question == "Name the colour of the sky ___" && reflect.DeepEqual(q.Tags, []string{"grammar"})
+		}},
+		{"HTML saneado", "A1", 1, false, func(q models.Quiz) bool {
+			return q.Question == "Bold claim: cats bark." && q.Answers[0] == "False" && q.Status == models.StatusDraft
+		}},
+		{"Tipo no admitido", "A1", 2, true, nil},
+		{"Una sola correcta", "A1", 3, false, func(q models.Quiz) bool {
+			return q.Type == models.QuizSingle && reflect.DeepEqual(q.Answers, []string{"x"})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := moodleQuiz(items[tt.item], tt.level)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, esperaba error: %v", err, tt.wantErr)
+			}
+			if tt.check != nil && !tt.check(q) {
+				t.Errorf("quiz inesperado: %#v", q)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"encoding/json"
+	"english-at-lima-cms/internal/models"
+	"english-at-lima-cms/internal/repository"
+	"english-at-lima-cms/internal/seo"
+	"english-at-lima-cms/internal/xapi"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sitemapTTL evita recorrer todas las tablas en cada visita de un crawler
+const sitemapTTL = time.Hour
+
+var (
+	sitemapMu      sync.Mutex
+	sitemapCache   []seo.Entry
+	sitemapExpires time.Time
+)
+
+// ShowPublicHome renderiza la portada para alumnos con el último contenido
+func ShowPublicHome(c *gin.Context) {
+	var sentences []models.Sentence
+	var quizzes []models.Quiz
+	var resources []models.Resource
+	var wg sync.WaitGroup
+	wg.Add(3)
+
+	load := func(table string, target interface{}, filter string) {
+		defer wg.Done()
+		resp, err := repository.CallSupabase("GET", table, nil, filter)
+		if err == nil && resp != nil {
+			defer resp.Body.Close()
+			_ = json.NewDecoder(resp.Body).Decode(target)
+		}
+	}
+
+	// Los alumnos pueden filtrar por nivel y etiqueta: /public?level=A2&tag=restaurant
+	filter, view := taxonomyQuery(c)
+	published := "select=*&" + repository.PublicFilter
+	go load("sentences", &sentences, withFilter(published+"&order=id.desc&limit=10", filter))
+	go load("quizzes", &quizzes, withFilter(published+"&order=id.desc&limit=10", filter))
+	go load("resources", &resources, withFilter(published+"&order=title.asc&limit=20", filter))
+
+	wg.Wait()
+	signPrivateURLs(resources)
+
+	c.HTML(http.StatusOK, "index.html", withLocale(c, gin.H{
+		"Sentences": sentences, "Quizzes": quizzes, "Resources": resources,
+		"Canonical": seo.SiteURL() + "/public", "Filter": view,
+	}))
+}
+
+func ShowSentencePage(c *gin.Context) {
+	id, ok := publicID(c)
+	if !ok {
+		return
+	}
+	s, err := repository.GetSentence(id)
+	if !renderable(c, visible(c, s.Publication, err)) {
+		return
+	}
+	c.HTML(http.StatusOK, "item-page.html", withLocale(c, gin.H{"Meta": seo.SentenceMeta(s), "Sentence": s}))
+}
+
+func ShowQuizPage(c *gin.Context) {
+	id, ok := publicID(c)
+	if !ok {
+		return
+	}
+	q, err := repository.GetQuiz(id)
+	if !renderable(c, visible(c, q.Publication, err)) {
+		return
+	}
+	c.HTML(http.StatusOK, "item-page.html", withLocale(c, gin.H{"Meta": seo.QuizMeta(q), "Quiz": q}))
+}
+
+func ShowResourcePage(c *gin.Context) {
+	id, ok := publicID(c)
+	if !ok {
+		return
+	}
+	r, err := repository.GetResource(id)
+	if !renderable(c, visible(c, r.Publication, err)) {
+		return
+	}
+	r.URL = signedMediaURL(r.URL)
+	c.HTML(http.StatusOK, "item-page.html", withLocale(c, gin.H{"Meta": seo.ResourceMeta(r), "Resource": r}))
+}
+
+// ReviewSentence registra el repaso de la frase: la página lo pide cuando el
+// alumno lleva un momento con la tarjeta a la vista (los crawlers no lo hacen)
+func ReviewSentence(c *gin.Context) {
+	id, ok := publicID(c)
+	if !ok {
+		return
+	}
+	s, err := repository.GetSentence(id)
+	if err == nil && !s.Public() {
+		err = repository.ErrNotFound
+	}
+	if !renderable(c, err) {
+		return
+	}
+	xapi.Emit(xapi.SentenceReviewed(xapi.Student(studentID(c)), s))
+	c.Status(http.StatusNoContent)
+}
+
+// OpenResource registra que el alumno abrió el recurso y lo lleva a él
+func OpenResource(c *gin.Context) {
+	id, ok := publicID(c)
+	if !ok {
+		return
+	}
+	r, err := repository.GetResource(id)
+	if !renderable(c, visible(c, r.Publication, err)) {
+		return
+	}
+	if r.Public() {
+		xapi.Emit(xapi.ResourceOpened(xapi.Student(studentID(c)), r))
+	}
+	c.Redirect(http.StatusFound, signedMediaURL(r.URL))
+}
+
+// Sitemap sirve un único <urlset> o, pasadas las 50k URLs, un índice de sitemaps
+func Sitemap(c *gin.Context) {
+	entries, err := sitemapEntries()
+	if err != nil {
+		c.String(http.StatusServiceUnavailable, "Sitemap no disponible")
+		return
+	}
+
+	c.Header("Content-Type", "application/xml; charset=utf-8")
+	if seo.PageCount(len(entries)) > 1 {
+		_ = seo.WriteIndex(c.Writer, seo.SiteURL(), entries)
+		return
+	}
+	_ = seo.WriteURLSet(c.Writer, entries)
+}
+
+// SitemapPage sirve /sitemap/N.xml cuando el sitemap está partido
+func SitemapPage(c *gin.Context) {
+	page, err := strconv.Atoi(strings.TrimSuffix(c.Param("page"), ".xml"))
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	entries, err := sitemapEntries()
+	if err != nil {
+		c.String(http.StatusServiceUnavailable, "Sitemap no disponible")
+		return
+	}
+	chunk, err := seo.Page(entries, page)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	c.Header("Content-Type", "application/xml; charset=utf-8")
+	_ = seo.WriteURLSet(c.Writer, chunk)
+}
+
+func Robots(c *gin.Context) {
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	_ = seo.WriteRobots(c.Writer, seo.SiteURL())
+}
+
+// sitemapEntries junta las URLs públicas de las tres tablas (con caché en RAM)
+func sitemapEntries() ([]seo.Entry, error) {
+	sitemapMu.Lock()
+	defer sitemapMu.Unlock()
+
+	if sitemapCache != nil && time.Now().Before(sitemapExpires) {
+		return sitemapCache, nil
+	}
+
+	base := seo.SiteURL()
+	entries := []seo.Entry{{Loc: base + "/public", LastMod: time.Now()}}
+	sources := []struct {
+		table string
+		path  func(int) string
+	}{
+		{"sentences", seo.SentencePath},
+		{"quizzes", seo.QuizPath},
+		{"resources", seo.ResourcePath},
+	}
+	for _, src := range sources {
+		stamps, err := repository.ListStamps(src.table)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range stamps {
+			entries = append(entries, seo.Entry{Loc: base + src.path(s.ID), LastMod: seo.ParseTimestamp(s.UpdatedAt)})
+		}
+	}
+
+	// Sólo los cursos publicados; los borradores responden 404
+	courses, err := repository.ListCourses(true)
+	if err != nil {
+		return nil, err
+	}
+	entries = append(entries, seo.Entry{Loc: base + "/cursos", LastMod: time.Now()})
+	for _, co := range courses {
+		entries = append(entries, seo.Entry{Loc: base + seo.CoursePath(co.ID), LastMod: seo.ParseTimestamp(co.UpdatedAt)})
+	}
+
+	sitemapCache = entries
+	sitemapExpires = time.Now().Add(sitemapTTL)
+	return entries, nil
+}
+
+// publicID valida que el :id sea numérico antes de meterlo en un filtro de PostgREST
+func publicID(c *gin.Context) (string, bool) {
+	id := c.Param("id")
+	if n, err := strconv.Atoi(id); err != nil || n <= 0 {
+		c.String(http.StatusNotFound, "Contenido no encontrado")
+		return "", false
+	}
+	return id, true
+}
+
+func renderable(c *gin.Context, err error) bool {
+	if err == nil {
+		return true
+	}
+	if err == repository.ErrNotFound {
+		c.String(http.StatusNotFound, "Contenido no encontrado")
+		return false
+	}
+	c.String(http.StatusInternalServerError, "Error de conexión")
+	return false
+}
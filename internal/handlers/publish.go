@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	"english-at-lima-cms/internal/models"
+	"english-at-lima-cms/internal/publish"
+	"english-at-lima-cms/internal/repository"
+	"english-at-lima-cms/internal/seo"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// readPublication lee estado y fechas (hora de Lima) del formulario
+func readPublication(c *gin.Context) (models.Publication, error) {
+	var p models.Publication
+	var err error
+	p.Status = c.PostForm("status")
+	if p.PublishAt, err = publish.ParseInput(c.PostForm("publish_at")); err != nil {
+		return p, err
+	}
+	if p.UnpublishAt, err = publish.ParseInput(c.PostForm("unpublish_at")); err != nil {
+		return p, err
+	}
+	publish.Normalize(&p, time.Now())
+	return p, publish.Validate(p)
+}
+
+// statusQuery lee ?status= de las listas del admin. Sin estado se ve todo
+// menos lo archivado.
+func statusQuery(c *gin.Context) (string, []models.StatusOption) {
+	current := c.Query("status")
+	if !slices.Contains(models.Statuses, current) {
+		current = ""
+	}
+	opts := []models.StatusOption{{Code: "", Label: "Todos menos archivados", Selected: current == ""}}
+	for _, o := range (models.Publication{Status: current}).StatusOptions() {
+		o.Selected = o.Code == current
+		opts = append(opts, o)
+	}
+	if current == "" {
+		return repository.ActiveFilter, opts
+	}
+	return "status=eq." + current, opts
+}
+
+// visible devuelve ErrNotFound para el contenido que los alumnos no pueden
+// ver; el admin con sesión abierta sí lo ve (vista previa y quizzes en borrador)
+func visible(c *gin.Context, p models.Publication, err error) error {
+	if err == nil && !p.Public() && !isAdmin(c) {
+		return repository.ErrNotFound
+	}
+	return err
+}
+
+// isAdmin indica si la petición trae la sesión del panel
+func isAdmin(c *gin.Context) bool {
+	return sessions.Default(c).Get("user_id") != nil
+}
+
+// PreviewContent muestra una frase, quiz o recurso en cualquier estado tal
+// como lo verá el alumno en su página pública
+func PreviewContent(c *gin.Context) {
+	id, ok := publicID(c)
+	if !ok {
+		return
+	}
+	data := gin.H{"Preview": true}
+	var err error
+	switch c.Param("type") {
+	case "sentence":
+		var s models.Sentence
+		if s, err = repository.GetSentence(id); err == nil {
+			data["Meta"], data["Sentence"], data["Status"] = seo.SentenceMeta(s), s, s.Publication
+		}
+	case "quiz":
+		var q models.Quiz
+		if q, err = repository.GetQuiz(id); err == nil {
+			data["Meta"], data["Quiz"], data["Status"] = seo.QuizMeta(q), q, q.Publication
+		}
+	case "resource":
+		var r models.Resource
+		if r, err = repository.GetResource(id); err == nil {
+			r.URL = signedMediaURL(r.URL)
+			data["Meta"], data["Resource"], data["Status"] = seo.ResourceMeta(r), r, r.Publication
+		}
+	default:
+		err = repository.ErrNotFound
+	}
+	if !renderable(c, err) {
+		return
+	}
+	c.HTML(http.StatusOK, "item-page.html", withLocale(c, data))
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"english-at-lima-cms/internal/models"
+	"english-at-lima-cms/internal/quiz"
+	"english-at-lima-cms/internal/repository"
+	"english-at-lima-cms/internal/xapi"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readResponses lee las opciones elegidas o el texto escrito por el alumno
+func readResponses(c *gin.Context) ([]string, bool) {
+	var response []string
+	for _, r := range c.PostFormArray("response") {
+		if r = strings.TrimSpace(r); r != "" && len(r) <= quiz.MaxOptionLength {
+			response = append(response, r)
+		}
+	}
+	return response, len(response) <= quiz.MaxOptions
+}
+
+// AnswerQuiz corrige la respuesta en la página pública del quiz y devuelve
+// la explicación general y la nota de cada opción elegida
+func AnswerQuiz(c *gin.Context) {
+	id, ok := publicID(c)
+	if !ok {
+		return
+	}
+	q, err := repository.GetQuiz(id)
+	if !renderable(c, visible(c, q.Publication, err)) {
+		return
+	}
+	response, ok := readResponses(c)
+	if !ok || len(response) == 0 {
+		c.String(http.StatusOK, "Elige o escribe una respuesta primero.")
+		return
+	}
+
+	correct := quiz.Grade(q, response)
+	if q.Public() {
+		xapi.Emit(xapi.QuizAnswered(xapi.Student(studentID(c)), q, response, correct))
+	}
+	var chosen []models.QuizOption
+	for _, o := range q.OptionViews() {
+		if slices.Contains(response, o.Text) {
+			chosen = append(chosen, o)
+		}
+	}
+	c.HTML(http.StatusOK, "quiz-feedback.html", gin.H{
+		"Quiz": q, "Correct": correct, "Chosen": chosen,
+		"Locale": publicLocale(c),
+	})
+}
+
+// QuizHint muestra las pistas 1..n; cada pedido revela una más
+func QuizHint(c *gin.Context) {
+	id, ok := publicID(c)
+	if !ok {
+		return
+	}
+	q, err := repository.GetQuiz(id)
+	if !renderable(c, visible(c, q.Publication, err)) {
+		return
+	}
+	n, err := strconv.Atoi(c.Param("n"))
+	if err != nil || n < 1 || n > len(q.Hints) {
+		c.String(http.StatusNotFound, "Contenido no encontrado")
+		return
+	}
+	next := 0
+	if n < len(q.Hints) {
+		next = n + 1
+	}
+	c.HTML(http.StatusOK, "quiz-hints.html", gin.H{"Quiz": q, "Shown": q.Hints[:n], "Next": next})
+}
@@ -1,67 +1,191 @@
 package handlers
 
 import (
-	"encoding/csv"
 	"encoding/json"
 	"english-at-lima-cms/internal/models"
+	"english-at-lima-cms/internal/quiz"
 	"english-at-lima-cms/internal/repository"
-	"fmt"
+	"english-at-lima-cms/internal/richtext"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
 func NewQuizForm(c *gin.Context) {
-	c.HTML(http.StatusOK, "new-quiz.html", nil)
+	c.HTML(http.StatusOK, "new-quiz.html", models.Quiz{Type: models.QuizSingle})
 }
 
-func ValidateQuiz(question string, options []string, correct string) error {
-	if len(strings.TrimSpace(question)) < 10 {
-		return fmt.Errorf("la pregunta es demasiado corta")
+// QuizFields cambia los campos del formulario al elegir otro tipo de pregunta
+func QuizFields(c *gin.Context) {
+	t := c.Query("type")
+	if !quiz.ValidType(t) {
+		t = models.QuizSingle
 	}
-	if len(options) < 3 {
-		return fmt.Errorf("se requieren al menos 3 opciones")
+	c.HTML(http.StatusOK, "quiz-fields.html", models.Quiz{Type: t})
+}
+
+func EditQuizForm(c *gin.Context) {
+	id, ok := publicID(c)
+	if !ok {
+		return
+	}
+	q, err := repository.GetQuiz(id)
+	if !renderable(c, err) {
+		return
+	}
+	c.HTML(http.StatusOK, "quiz-edit-form.html", q)
+}
+
+// ValidateQuiz comprueba que la pregunta tenga entre 2 y 8 opciones y que las
+// respuestas correctas existan entre ellas (ver quiz.Validate)
+func ValidateQuiz(q models.Quiz) error {
+	return quiz.Validate(q)
+}
+
+// quizFromForm arma el quiz según el tipo elegido. Las casillas de opción
+// llegan siempre en el mismo orden (options[i]) y las marcadas como correctas
+// traen su índice en "correct", así las casillas vacías no descuadran nada.
+func quizFromForm(c *gin.Context) models.Quiz {
+	q := models.Quiz{
+		Question: Sanitize(c.PostForm("question")),
+		Type:     c.PostForm("type"),
 	}
-	for _, opt := range options {
-		if strings.TrimSpace(opt) == "" {
-			return fmt.Errorf("las opciones no pueden estar vacías")
+
+	switch q.Type {
+	case models.QuizTrueFalse:
+		q.Answers = []string{c.PostForm("tf_answer")}
+	case models.QuizFill:
+		for _, line := range strings.Split(c.PostForm("accepted"), "\n") {
+			q.Answers = append(q.Answers, Sanitize(line))
+		}
+	default:
+		correct := map[string]bool{}
+		for _, idx := range c.PostFormArray("correct") {
+			correct[idx] = true
+		}
+		notesES, notesEN := c.PostFormArray("option_note_es"), c.PostFormArray("option_note_en")
+		for i, opt := range c.PostFormArray("options") {
+			opt = Sanitize(opt)
+			if opt == "" {
+				continue
+			}
+			q.Options = append(q.Options, opt)
+			q.OptionNotes = append(q.OptionNotes, formNote(notesES, notesEN, i))
+			if correct[strconv.Itoa(i)] {
+				q.Answers = append(q.Answers, opt)
+			}
 		}
 	}
-	if correct == "" {
-		return fmt.Errorf("debe marcar una respuesta como correcta")
+
+	readFeedback(c, &q)
+	quiz.Normalize(&q)
+	return q
+}
+
+// readFeedback lee la explicación (con sus traducciones) y las pistas (arrays paralelos ES/EN)
+func readFeedback(c *gin.Context, q *models.Quiz) {
+	q.Explanation = models.Note{
+		ES:      richtext.Clean(c.PostForm("explanation_es")),
+		EN:      richtext.Clean(c.PostForm("explanation_en")),
+		Locales: readMarkdownTranslations(c, "explanation"),
+	}
+	hintsES, hintsEN := c.PostFormArray("hint_es"), c.PostFormArray("hint_en")
+	q.Hints = nil
+	for i := 0; i < max(len(hintsES), len(hintsEN)); i++ {
+		q.Hints = append(q.Hints, formNote(hintsES, hintsEN, i))
 	}
-	return nil
+}
+
+// formNote arma la nota i a partir de dos arrays paralelos del formulario
+func formNote(es, en []string, i int) models.Note {
+	var n models.Note
+	if i < len(es) {
+		n.ES = richtext.Clean(es[i])
+	}
+	if i < len(en) {
+		n.EN = richtext.Clean(en[i])
+	}
+	return n
 }
 
 func SaveQuiz(c *gin.Context) {
 	// 1. Captura y Sanitizado
-	question := Sanitize(c.PostForm("question"))
-	options := []string{
-		Sanitize(c.PostForm("opt1")),
-		Sanitize(c.PostForm("opt2")),
-		Sanitize(c.PostForm("opt3")),
-	}
-	correct := Sanitize(c.PostForm("correct"))
+	q := quizFromForm(c)
 
 	// 2. Validación de lógica de negocio
-	if err := ValidateQuiz(question, options, correct); err != nil {
+	if err := ValidateQuiz(q); err != nil {
 		SendToast(c, err.Error(), "error")
 		return
 	}
+	tx, err := readTaxonomy(c)
+	if err != nil {
+		SendToast(c, err.Error(), "error")
+		return
+	}
+	q.Taxonomy = tx
+	if q.Publication, err = readPublication(c); err != nil {
+		SendToast(c, err.Error(), "error")
+		return
+	}
+	if err := guardPublish(c, models.Publication{}, q.Publication); err != nil {
+		sendGuardError(c, err)
+		return
+	}
+	if warnDuplicates(c, "quiz", q.Question) {
+		return
+	}
 
 	// 3. Guardado
-	if err := repository.InsertQuiz(question, options, correct); err != nil {
+	if err := repository.InsertQuiz(q); err != nil {
 		SendToast(c, "Error al crear el Quiz", "error")
 		return
 	}
 
-	SendToast(c, "Quiz creado con éxito", "success")
-	c.Header("HX-Trigger", "refreshList")
+	refreshWithToast(c, "Quiz creado con éxito")
+}
+
+// UpdateQuizFeedback edita sólo la explicación, las pistas y las notas por
+// opción desde la tarjeta del quiz, sin tocar pregunta ni respuestas
+func UpdateQuizFeedback(c *gin.Context) {
+	id, ok := publicID(c)
+	if !ok {
+		return
+	}
+	q, err := repository.GetQuiz(id)
+	if err != nil {
+		SendToast(c, "Quiz no encontrado", "error")
+		return
+	}
+	if err := guardPublish(c, q.Publication, q.Publication); err != nil {
+		sendGuardError(c, err)
+		return
+	}
+
+	readFeedback(c, &q)
+	notesES, notesEN := c.PostFormArray("option_note_es"), c.PostFormArray("option_note_en")
+	q.OptionNotes = nil
+	for i := range q.Options {
+		q.OptionNotes = append(q.OptionNotes, formNote(notesES, notesEN, i))
+	}
+	quiz.Normalize(&q)
+
+	if err := ValidateQuiz(q); err != nil {
+		SendToast(c, err.Error(), "error")
+		return
+	}
+	if err := repository.UpdateQuiz(id, q); err != nil {
+		SendToast(c, "Error al actualizar en la base de datos", "error")
+		return
+	}
+	c.Header("HX-Trigger", `{"showToast": {"message": "Explicación y pistas guardadas", "type": "success"}}`)
+	c.HTML(http.StatusOK, "quiz-item.html", q)
 }
 
 func GetQuizzes(c *gin.Context) {
-	resp, err := repository.CallSupabase("GET", "quizzes", nil, "select=*&order=id.desc")
+	filter, view := listQuery(c, "quiz")
+	resp, err := repository.CallSupabase("GET", "quizzes", nil, "select=*&"+filter+"&order=id.desc")
 	if err != nil {
 		c.String(http.StatusInternalServerError, "Error de conexión")
 		return
@@ -77,7 +201,8 @@ func GetQuizzes(c *gin.Context) {
 			return
 		}
 
-		c.HTML(http.StatusOK, "quizzes.html", gin.H{"Quizzes": quizzes})
+		view["Quizzes"], view["Path"] = quizzes, "/admin/quizzes"
+		c.HTML(http.StatusOK, "quizzes-list.html", view)
 		return
 	}
 
@@ -88,29 +213,36 @@ func UpdateQuiz(c *gin.Context) {
 	id := c.Param("id")
 
 	// 1. Captura y Sanitizado
-	question := Sanitize(c.PostForm("question"))
-	options := []string{
-		Sanitize(c.PostForm("opt1")),
-		Sanitize(c.PostForm("opt1")),
-		Sanitize(c.PostForm("opt1")),
-	}
-	correct := Sanitize(c.PostForm("correct"))
+	q := quizFromForm(c)
 
 	// 2. Validación de la Aduana
-	if err := ValidateQuiz(question, options, correct); err != nil {
+	if err := ValidateQuiz(q); err != nil {
 		SendToast(c, err.Error(), "error")
 		return
 	}
+	tx, err := readTaxonomy(c)
+	if err != nil {
+		SendToast(c, err.Error(), "error")
+		return
+	}
+	q.Taxonomy = tx
+	if q.Publication, err = readPublication(c); err != nil {
+		SendToast(c, err.Error(), "error")
+		return
+	}
+	if err := guardUpdate(c, "quiz", id, q.Publication); err != nil {
+		sendGuardError(c, err)
+		return
+	}
 
 	// 3. Persistencia
-	err := repository.UpdateQuiz(id, question, options, correct)
+	err = repository.UpdateQuiz(id, q)
 	if err != nil {
 		SendToast(c, "Error al actualizar el Quiz en Supabase", "error")
 		return
 	}
 
-	SendToast(c, "Quiz actualizado correctamente", "success")
-	c.Header("HX-Trigger", "refreshList")
+	refreshWithToast(c, "Quiz actualizado correctamente")
 }
 
 func DeleteQuiz(c *gin.Context) {
@@ -121,35 +253,3 @@ func DeleteQuiz(c *gin.Context) {
 	}
 	c.Status(http.StatusOK)
 }
-
-func ExportQuizzesCSV(c *gin.Context) {
-	var data []models.Quiz
-	resp, err := repository.CallSupabase("GET", "quizzes", nil, "select=*")
-	if err != nil || resp == nil {
-		c.String(http.StatusInternalServerError, "Error al obtener datos para exportar")
-		return
-	}
-	defer resp.Body.Close()
-
-	// Decodificamos ignorando el error con _ o manejándolo
-	_ = json.NewDecoder(resp.Body).Decode(&data)
-
-	c.Header("Content-Disposition", "attachment; filename=quizzes_backup.csv")
-	c.Header("Content-Type", "text/csv")
-
-	writer := csv.NewWriter(c.Writer)
-	defer writer.Flush()
-
-	// Todas las escrituras deben ser validadas o silenciadas
-	_ = writer.Write([]string{"Pregunta", "Opción 1", "Opción 2", "Opción 3", "Correcta"})
-
-	for _, q := range data {
-		_ = writer.Write([]string{
-			q.Question,
-			q.Opt1,
-			q.Opt2,
-			q.Opt3,
-			q.Correct,
-		})
-	}
-}
@@ -1,19 +1,35 @@
 package handlers
 
 import (
-	"encoding/csv"
 	"encoding/json"
+	"english-at-lima-cms/internal/i18n"
 	"english-at-lima-cms/internal/models"
 	"english-at-lima-cms/internal/repository"
+	"english-at-lima-cms/internal/richtext"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
 func NewResourceForm(c *gin.Context) {
-	c.HTML(http.StatusOK, "new-resource.html", nil)
+	c.HTML(http.StatusOK, "new-resource.html", models.Resource{})
+}
+
+// EditResourceForm carga el recurso en el panel principal para editarlo
+func EditResourceForm(c *gin.Context) {
+	id, ok := publicID(c)
+	if !ok {
+		return
+	}
+	r, err := repository.GetResource(id)
+	if !renderable(c, err) {
+		return
+	}
+	r.URL = unsignedMediaURL(r.URL)
+	c.HTML(http.StatusOK, "resource-edit-form.html", r)
 }
 
 func ValidateResource(title, url, resType string) error {
@@ -30,20 +46,83 @@ func ValidateResource(title, url, resType string) error {
 	return nil
 }
 
+// ValidateResourcePreview revisa los datos de la vista previa (OpenGraph/oEmbed)
+func ValidateResourcePreview(description, thumbnail string, duration int) error {
+	if len(description) > 500 {
+		return fmt.Errorf("la descripción excede el límite de 500 caracteres")
+	}
+	if thumbnail != "" && !strings.HasPrefix(thumbnail, "https://") && !strings.HasPrefix(thumbnail, "http://") {
+		return fmt.Errorf("la miniatura debe ser una URL http/https")
+	}
+	if duration < 0 || duration > 24*3600 {
+		return fmt.Errorf("duración inválida")
+	}
+	return nil
+}
+
+// resourcePreview lee los campos ocultos que rellena el unfurl vía HTMX
+func resourcePreview(c *gin.Context) (models.Resource, error) {
+	duration, _ := strconv.Atoi(c.PostForm("duration"))
+	res := models.Resource{
+		Description:  richtext.Clean(c.PostForm("description")),
+		ThumbnailURL: strings.TrimSpace(c.PostForm("thumbnail_url")),
+		Duration:     duration,
+		SiteName:     Sanitize(c.PostForm("site_name")),
+	}
+	return res, ValidateResourcePreview(res.Description, res.ThumbnailURL, res.Duration)
+}
+
 func SaveResource(c *gin.Context) {
 	// 1. Auto-Sanitizado
 	title := Sanitize(c.PostForm("title"))
 	url := strings.TrimSpace(c.PostForm("url")) // Las URLs no se sanean igual, solo se limpian espacios
 	resType := Sanitize(c.PostForm("type"))
 
+	up, err := readUpload(c)
+	if err != nil {
+		SendToast(c, err.Error(), "error")
+		return
+	}
+	defer up.Close()
+	url, resType = attachUpload(up, url, resType)
+
 	// 2. Validación Robusta
 	if err := ValidateResource(title, url, resType); err != nil {
 		SendToast(c, err.Error(), "error")
 		return
 	}
 
+	res, err := resourcePreview(c)
+	if err != nil {
+		SendToast(c, err.Error(), "error")
+		return
+	}
+	res.Title, res.URL, res.Type = title, url, resType
+	if res.Taxonomy, err = readTaxonomy(c); err != nil {
+		SendToast(c, err.Error(), "error")
+		return
+	}
+	res.TitleTranslations = readTranslations(c, "title")
+	if err := i18n.CheckLength(res.TitleTranslations, 100); err != nil {
+		SendToast(c, err.Error(), "error")
+		return
+	}
+	if res.Publication, err = readPublication(c); err != nil {
+		SendToast(c, err.Error(), "error")
+		return
+	}
+	if err := guardPublish(c, models.Publication{}, res.Publication); err != nil {
+		sendGuardError(c, err)
+		return
+	}
+
+	if res.URL, err = up.store(res.URL); err != nil {
+		SendToast(c, err.Error(), "error")
+		return
+	}
+
 	// 3. Persistencia en Supabase
-	if err := repository.InsertResource(title, url, resType); err != nil {
+	if err := repository.InsertResource(res); err != nil {
 		SendToast(c, "Error al guardar en la base de datos", "error")
 		return
 	}
@@ -59,24 +138,73 @@ func UpdateResource(c *gin.Context) {
 	url := strings.TrimSpace(c.PostForm("url"))
 	resType := c.PostForm("type")
 
+	up, err := readUpload(c)
+	if err != nil {
+		SendToast(c, err.Error(), "error")
+		return
+	}
+	defer up.Close()
+	url, resType = attachUpload(up, url, resType)
+
 	// LA ADUANA: Validación robusta
 	if err := ValidateResource(title, url, resType); err != nil {
 		SendToast(c, err.Error(), "error")
 		return
 	}
 
-	err := repository.UpdateResource(id, title, url, resType)
+	res, err := resourcePreview(c)
 	if err != nil {
+		SendToast(c, err.Error(), "error")
+		return
+	}
+	res.Title, res.URL, res.Type = title, url, resType
+	if res.Taxonomy, err = readTaxonomy(c); err != nil {
+		SendToast(c, err.Error(), "error")
+		return
+	}
+	res.TitleTranslations = readTranslations(c, "title")
+	if err := i18n.CheckLength(res.TitleTranslations, 100); err != nil {
+		SendToast(c, err.Error(), "error")
+		return
+	}
+	if res.Publication, err = readPublication(c); err != nil {
+		SendToast(c, err.Error(), "error")
+		return
+	}
+	if err := guardUpdate(c, "resource", id, res.Publication); err != nil {
+		sendGuardError(c, err)
+		return
+	}
+
+	if res.URL, err = up.store(res.URL); err != nil {
+		SendToast(c, err.Error(), "error")
+		return
+	}
+
+	if err := repository.UpdateResource(id, res); err != nil {
 		SendToast(c, "Error al actualizar el recurso", "error")
 		return
 	}
 
-	SendToast(c, "Recurso actualizado con éxito", "success")
-	c.Header("HX-Trigger", "refreshList")
+	refreshWithToast(c, "Recurso actualizado con éxito")
+}
+
+// attachUpload sustituye la URL (y el tipo si no se eligió) por los del archivo
+// adjunto, para validar el formulario; el archivo se guarda después. Sin
+// archivo adjunto devuelve los valores tal cual.
+func attachUpload(up *pendingUpload, url, resType string) (string, string) {
+	if up == nil {
+		return unsignedMediaURL(url), resType
+	}
+	if resType == "" {
+		resType = up.Rule.Kind
+	}
+	return up.url(), resType
 }
 
 func GetResources(c *gin.Context) {
-	resp, err := repository.CallSupabase("GET", "resources", nil, "select=*&order=title.asc")
+	filter, view := listQuery(c, "resource")
+	resp, err := repository.CallSupabase("GET", "resources", nil, "select=*&"+filter+"&order=title.asc")
 	if err != nil || resp == nil {
 		c.String(http.StatusInternalServerError, "Error de conexión")
 		return
@@ -85,7 +213,9 @@ func GetResources(c *gin.Context) {
 
 	var data []models.Resource
 	_ = json.NewDecoder(resp.Body).Decode(&data) // <--- SOLUCIONA errcheck
-	c.HTML(http.StatusOK, "resources.html", gin.H{"Resources": data})
+	signPrivateURLs(data)
+	view["Resources"], view["Path"] = data, "/admin/resources"
+	c.HTML(http.StatusOK, "resources-list.html", view)
 }
 
 func DeleteResource(c *gin.Context) {
@@ -96,24 +226,3 @@ func DeleteResource(c *gin.Context) {
 	}
 	c.Status(http.StatusOK)
 }
-
-func ExportResourcesCSV(c *gin.Context) {
-	var data []models.Resource
-	resp, err := repository.CallSupabase("GET", "resources", nil, "select=*&order=title.asc")
-	if err != nil || resp == nil {
-		c.String(http.StatusInternalServerError, "Error al obtener recursos")
-		return
-	}
-	defer resp.Body.Close()
-
-	_ = json.NewDecoder(resp.Body).Decode(&data)
-
-	c.Header("Content-Disposition", "attachment; filename=resources.csv")
-	writer := csv.NewWriter(c.Writer)
-	defer writer.Flush()
-
-	_ = writer.Write([]string{"ID", "Titulo", "Tipo", "URL"})
-	for _, r := range data {
-		_ = writer.Write([]string{fmt.Sprint(r.ID), r.Title, r.Type, r.URL})
-	}
-}
@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"english-at-lima-cms/internal/course"
+	"english-at-lima-cms/internal/models"
+	"english-at-lima-cms/internal/repository"
+	"english-at-lima-cms/internal/review"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// currentUser es el correo de quien tiene la sesión del panel
+func currentUser(c *gin.Context) string {
+	email, _ := sessions.Default(c).Get("user_id").(string)
+	return email
+}
+
+// currentRole es el rol guardado en la sesión al iniciarla
+func currentRole(c *gin.Context) string {
+	if role, ok := sessions.Default(c).Get("role").(string); ok && role != "" {
+		return role
+	}
+	return models.RoleEditor
+}
+
+func isReviewer(c *gin.Context) bool { return currentRole(c) == models.RoleReviewer }
+
+// errNeedsReviewer es un guardado que sólo puede hacer un revisor (403)
+var errNeedsReviewer = errors.New("sólo un revisor puede publicar; envíe el contenido a revisión")
+
+// guardPublish impide que un editor publique o programe desde el formulario:
+// debe enviar el contenido a revisión
+func guardPublish(c *gin.Context, prev, next models.Publication) error {
+	return publishRule(currentRole(c), prev, next)
+}
+
+// publishRule es la regla de guardPublish: un editor nunca deja contenido
+// publicado ni programado, tampoco al editar lo que ya lo está (el cambio
+// saldría sin revisar). Lo guarda como borrador y lo envía a revisión.
+// Tampoco archiva lo publicado o programado: eso lo retira del sitio.
+func publishRule(role string, prev, next models.Publication) error {
+	if role == models.RoleReviewer {
+		return nil
+	}
+	if live(prev) && next.Status == models.StatusArchived {
+		return fmt.Errorf("%w: sólo un revisor puede archivar contenido publicado o programado", errNeedsReviewer)
+	}
+	if !live(next) {
+		return nil
+	}
+	if live(prev) {
+		return fmt.Errorf("%w: para modificar contenido publicado o programado, guárdelo como borrador", errNeedsReviewer)
+	}
+	return errNeedsReviewer
+}
+
+func live(p models.Publication) bool {
+	return p.Status == models.StatusPublished || p.Status == models.StatusScheduled
+}
+
+// sendGuardError responde el error del formulario; los de permisos con 403
+func sendGuardError(c *gin.Context, err error) {
+	SendToast(c, err.Error(), "error")
+	if errors.Is(err, errNeedsReviewer) {
+		c.Status(http.StatusForbidden)
+	}
+}
+
+// guardUpdate aplica guardPublish contra el estado guardado del contenido
+func guardUpdate(c *gin.Context, contentType, id string, next models.Publication) error {
+	t, _, err := loadReviewTarget(contentType, id)
+	if err != nil {
+		return err
+	}
+	return guardPublish(c, t.Publication, next)
+}
+
+// reviewField es un campo del contenido tal como lo lee el revisor
+type reviewField struct {
+	Name  string
+	Label string
+	Value string
+}
+
+// loadReviewTarget carga el contenido con los campos que se pueden comentar
+func loadReviewTarget(contentType, id string) (review.Target, []reviewField, error) {
+	table, ok := course.ContentTypes[contentType]
+	if !ok {
+		return review.Target{}, nil, repository.ErrNotFound
+	}
+	t := review.Target{Type: contentType, Table: table}
+	var fields []reviewField
+	switch contentType {
+	case "sentence":
+		s, err := repository.GetSentence(id)
+		if err != nil {
+			return t, nil, err
+		}
+		t.ID, t.Label, t.Publication = s.ID, s.English, s.Publication
+		fields = []reviewField{
+			{"english", "Inglés", s.English},
+			{"spanish", "Español", s.Spanish},
+			{"grammar_note", "Nota gramatical", s.GrammarNote},
+			{"examples", "Ejemplos", strings.Join(s.Examples, "\n")},
+			{"ipa", "IPA", s.IPA},
+		}
+	case "quiz":
+		q, err := repository.GetQuiz(id)
+		if err != nil {
+			return t, nil, err
+		}
+		t.ID, t.Label, t.Publication = q.ID, q.Question, q.Publication
+		fields = []reviewField{
+			{"question", "Pregunta", q.Question},
+			{"options", "Opciones", strings.Join(q.Options, "\n")},
+			{"answers", "Respuestas", strings.Join(q.Answers, "\n")},
+			{"explanation", "Explicación", q.Explanation.ES},
+		}
+	case "resource":
+		r, err := repository.GetResource(id)
+		if err != nil {
+			return t, nil, err
+		}
+		t.ID, t.Label, t.Publication = r.ID, r.Title, r.Publication
+		fields = []reviewField{
+			{"title", "Título", r.Title},
+			{"url", "URL", r.URL},
+			{"description", "Descripción", r.Description},
+		}
+	}
+	return t, fields, nil
+}
+
+// queueItem es una fila de la cola de revisión
+type queueItem struct {
+	Type  string
+	ID    int
+	Label string
+	models.Publication
+}
+
+func (q queueItem) Path() string { return fmt.Sprintf("/admin/review/%s/%d", q.Type, q.ID) }
+
+// ReviewQueue lista lo que espera revisión y lo que tiene cambios pedidos
+func ReviewQueue(c *gin.Context) {
+	var sentences []models.Sentence
+	var quizzes []models.Quiz
+	var resources []models.Resource
+	errs := []error{
+		repository.ListForReview("sentences", &sentences),
+		repository.ListForReview("quizzes", &quizzes),
+		repository.ListForReview("resources", &resources),
+	}
+	if err := errors.Join(errs...); err != nil {
+		SendToast(c, "Error al cargar la cola de revisión", "error")
+		return
+	}
+
+	pending, changes := []queueItem{}, []queueItem{}
+	add := func(item queueItem) {
+		if item.ReviewState == models.ReviewInReview {
+			pending = append(pending, item)
+		} else {
+			changes = append(changes, item)
+		}
+	}
+	for _, s := range sentences {
+		add(queueItem{"sentence", s.ID, s.English, s.Publication})
+	}
+	for _, q := range quizzes {
+		add(queueItem{"quiz", q.ID, q.Question, q.Publication})
+	}
+	for _, r := range resources {
+		add(queueItem{"resource", r.ID, r.Title, r.Publication})
+	}
+
+	c.HTML(http.StatusOK, "review-queue.html", gin.H{
+		"Pending": pending, "Changes": changes, "Reviewer": isReviewer(c),
+	})
+}
+
+// ShowReview muestra el contenido, el formulario de decisión y todo el historial
+func ShowReview(c *gin.Context) {
+	t, fields, err := loadReviewTarget(c.Param("type"), c.Param("id"))
+	if !renderable(c, err) {
+		return
+	}
+	history, err := repository.ListReviewEvents(t.Type, c.Param("id"))
+	if err != nil {
+		SendToast(c, "Error al cargar el historial", "error")
+		return
+	}
+
+	// Los comentarios muestran la etiqueta del campo, no su nombre interno
+	labels := map[string]string{review.General: "General"}
+	for _, f := range fields {
+		labels[f.Name] = f.Label
+	}
+	for i := range history {
+		for j := range history[i].Comments {
+			history[i].Comments[j].Field = labels[history[i].Comments[j].Field]
+		}
+	}
+
+	c.HTML(http.StatusOK, "review-item.html", gin.H{
+		"Target":   t,
+		"Fields":   fields,
+		"History":  history,
+		"Reviewer": isReviewer(c),
+		"Author":   review.Author(history),
+		"User":     currentUser(c),
+	})
+}
+
+// reviewAction devuelve el handler de una acción del flujo de revisión
+func reviewAction(action, done string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		t, fields, err := loadReviewTarget(c.Param("type"), c.Param("id"))
+		if errors.Is(err, repository.ErrNotFound) {
+			SendToast(c, "El contenido no existe", "error")
+			return
+		}
+		if err != nil {
+			SendToast(c, "Error al cargar el contenido", "error")
+			return
+		}
+
+		var comments []models.ReviewComment
+		names := make([]string, len(fields))
+		for i, f := range fields {
+			names[i] = f.Name
+			comments = append(comments, models.ReviewComment{Field: f.Name, Text: c.PostForm("comment_" + f.Name)})
+		}
+		if comments, err = review.CleanComments(comments, names); err != nil {
+			SendToast(c, err.Error(), "error")
+			return
+		}
+
+		note := strings.TrimSpace(c.PostForm("note"))
+		err = review.Apply(t, action, currentUser(c), currentRole(c), note, comments, time.Now())
+		if err != nil {
+			SendToast(c, err.Error(), "error")
+			return
+		}
+		refreshWithToast(c, done)
+	}
+}
+
+var (
+	SubmitForReview = reviewAction(review.ActionSubmit, "Enviado a revisión")
+	ApproveContent  = reviewAction(review.ActionApprove, "Aprobado y publicado")
+	RequestChanges  = reviewAction(review.ActionRequestChanges, "Cambios pedidos al autor")
+)
+
+// GetNotifications lista los avisos del usuario y los marca como leídos
+func GetNotifications(c *gin.Context) {
+	user := currentUser(c)
+	ns, err := repository.ListNotifications(user)
+	if err != nil {
+		SendToast(c, "Error al cargar los avisos", "error")
+		return
+	}
+	if err := repository.MarkNotificationsRead(user); err == nil {
+		c.Header("HX-Trigger", "notificationsRead")
+	}
+	c.HTML(http.StatusOK, "notifications.html", gin.H{"Notifications": ns})
+}
+
+// NotificationCount devuelve el contador de la campana del menú
+func NotificationCount(c *gin.Context) {
+	n, err := repository.CountUnread(currentUser(c))
+	if err != nil || n == 0 {
+		c.String(http.StatusOK, "🔔")
+		return
+	}
+	c.String(http.StatusOK, "🔔 %d", n)
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+
+	"english-at-lima-cms/internal/models"
+)
+
+func TestPublishRule(t *testing.T) {
+	draft := models.Publication{Status: models.StatusDraft}
+	published := models.Publication{Status: models.StatusPublished}
+	scheduled := models.Publication{Status: models.StatusScheduled, PublishAt: "2026-11-01T10:00:00Z"}
+	moved := models.Publication{Status: models.StatusScheduled, PublishAt: "2026-10-20T08:00:00Z"}
+	archived := models.Publication{Status: models.StatusArchived}
+
+	tests := []struct {
+		name      string
+		role      string
+		prev      models.Publication
+		next      models.Publication
+		forbidden bool
+	}{
+		{"editor crea borrador", models.RoleEditor, models.Publication{}, draft, false},
+		{"editor publica al crear", models.RoleEditor, models.Publication{}, published, true},
+		{"editor edita lo publicado", models.RoleEditor, published, published, true},
+		{"editor mueve la fecha programada", models.RoleEditor, scheduled, moved, true},
+		{"editor edita lo programado", models.RoleEditor, scheduled, scheduled, true},
+		{"editor retira lo publicado a borrador", models.RoleEditor, published, draft, false},
+		{"editor archiva lo publicado", models.RoleEditor, published, archived, true},
+		{"editor archiva lo programado", models.RoleEditor, scheduled, archived, true},
+		{"editor archiva un borrador", models.RoleEditor, draft, archived, false},
+		{"revisor archiva lo publicado", models.RoleReviewer, published, archived, false},
+		{"editor edita un borrador", models.RoleEditor, draft, draft, false},
+		{"revisor edita lo publicado", models.RoleReviewer, published, published, false},
+		{"revisor programa", models.RoleReviewer, draft, scheduled, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := publishRule(tt.role, tt.prev, tt.next)
+			if got := errors.Is(err, errNeedsReviewer); got != tt.forbidden {
+				t.Fatalf("prohibido = %v (%v), esperaba %v", got, err, tt.forbidden)
+			}
+		})
+	}
+}
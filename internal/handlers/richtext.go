@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"html/template"
+	"net/http"
+
+	"english-at-lima-cms/internal/i18n"
+	"english-at-lima-cms/internal/richtext"
+	"english-at-lima-cms/internal/taxonomy"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PreviewMarkdown pinta en vivo el campo Markdown indicado en ?field= tal
+// como lo verá el alumno
+func PreviewMarkdown(c *gin.Context) {
+	c.HTML(http.StatusOK, "markdown.html", richtext.Render(c.PostForm(c.Query("field"))))
+}
+
+// TemplateFuncs son las funciones de las plantillas: los modelos sólo
+// guardan datos y la presentación (Markdown, selectores, pestañas de
+// idiomas) se resuelve aquí
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		// markdown convierte el Markdown del profesor en HTML seguro
+		"markdown": richtext.Render,
+		// levelOptions lista A1–C2 para un <select> marcando el nivel dado
+		"levelOptions": taxonomy.LevelOptions,
+		// localeFields son las pestañas de traducción de un campo del formulario
+		"localeFields": i18n.Fields,
+	}
+}
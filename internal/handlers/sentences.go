@@ -1,10 +1,11 @@
 package handlers
 
 import (
-	"encoding/csv"
 	"encoding/json"
 	"english-at-lima-cms/internal/models"
 	"english-at-lima-cms/internal/repository"
+	"english-at-lima-cms/internal/richtext"
+	"english-at-lima-cms/internal/sentence"
 	"fmt"
 	"net/http"
 	"strings"
@@ -14,7 +15,7 @@ import (
 
 // Renderiza el formulario
 func NewSentenceForm(c *gin.Context) {
-	c.HTML(http.StatusOK, "new-sentence.html", nil)
+	c.HTML(http.StatusOK, "new-sentence.html", models.Sentence{})
 }
 
 // ValidateSentence comprueba la integridad de la frase (Separada para Testeo)
@@ -31,59 +32,133 @@ func ValidateSentence(english, spanish string) error {
 	return nil
 }
 
-// Procesa el guardado
-func SaveSentence(c *gin.Context) {
-
-	var s models.Sentence
-
-	// PASO 1: Auto-Sanitizado (Magia automática)
-	s.Spanish = Sanitize(c.PostForm("spanish"))
-	s.English = Sanitize(c.PostForm("english"))
+// sentenceFromForm lee la frase y sus datos opcionales; los ejemplos llegan
+// uno por línea y el audio puede subirse o conservar la URL anterior. El
+// audio subido se devuelve sin guardar: el handler lo guarda (store) después
+// de comprobar los permisos y siempre lo cierra.
+func sentenceFromForm(c *gin.Context) (models.Sentence, *pendingUpload, error) {
+	s := models.Sentence{
+		English:     Sanitize(c.PostForm("english")),
+		Spanish:     Sanitize(c.PostForm("spanish")),
+		GrammarNote: richtext.Clean(c.PostForm("grammar_note")),
+		Register:    c.PostForm("register"),
+		IPA:         c.PostForm("ipa"),
+		AudioURL:    unsignedMediaURL(c.PostForm("audio_url")),
+
+		Translations: readTranslations(c, "translation"),
+	}
+	for _, line := range strings.Split(c.PostForm("examples"), "\n") {
+		s.Examples = append(s.Examples, Sanitize(line))
+	}
+	if c.PostForm("remove_audio") == "on" {
+		s.AudioURL = ""
+	}
 
 	// PASO 2: Validación (Sobre el texto ya limpio)
 	if err := ValidateSentence(s.English, s.Spanish); err != nil {
+		return s, nil, err
+	}
+	tx, err := readTaxonomy(c)
+	if err != nil {
+		return s, nil, err
+	}
+	s.Taxonomy = tx
+	if s.Publication, err = readPublication(c); err != nil {
+		return s, nil, err
+	}
+
+	up, err := readUpload(c)
+	if err != nil {
+		return s, nil, err
+	}
+	if up != nil {
+		if up.Rule.Kind != "audio" {
+			return s, up, fmt.Errorf("el archivo adjunto debe ser un audio")
+		}
+		s.AudioURL = up.url()
+	}
+
+	sentence.Normalize(&s)
+	return s, up, sentence.Validate(s)
+}
+
+// Procesa el guardado
+func SaveSentence(c *gin.Context) {
+	// PASO 1: Auto-Sanitizado (Magia automática)
+	s, up, err := sentenceFromForm(c)
+	defer up.Close()
+	if err == nil {
+		err = guardPublish(c, models.Publication{}, s.Publication)
+	}
+	if err != nil {
+		sendGuardError(c, err)
+		return
+	}
+	if warnDuplicates(c, "sentence", s.English) {
+		return
+	}
+	if s.AudioURL, err = up.store(s.AudioURL); err != nil {
 		SendToast(c, err.Error(), "error")
 		return
 	}
 
-	resp, err := repository.CallSupabase("POST", "sentences", s, "")
-	if err == nil && resp != nil {
-		defer resp.Body.Close()
+	if err := repository.InsertSentence(s); err != nil {
+		SendToast(c, "Error al guardar en la base de datos", "error")
+		return
 	}
 	c.Redirect(http.StatusSeeOther, "/admin/sentences")
 }
 
 func GetSentences(c *gin.Context) {
-	resp, _ := repository.CallSupabase("GET", "sentences", nil, "select=*&order=id.desc")
+	filter, view := listQuery(c, "sentence")
+	resp, _ := repository.CallSupabase("GET", "sentences", nil, "select=*&"+filter+"&order=id.desc")
 	if resp != nil {
 		defer resp.Body.Close() // <--- SOLUCIONA bodyclose
 		var data []models.Sentence
 		_ = json.NewDecoder(resp.Body).Decode(&data)
-		c.HTML(http.StatusOK, "sentences.html", gin.H{"Sentences": data})
+		view["Sentences"], view["Path"] = data, "/admin/sentences"
+		c.HTML(http.StatusOK, "sentences-list.html", view)
 	}
 }
 
+// EditSentenceForm carga la frase completa en el panel principal
+func EditSentenceForm(c *gin.Context) {
+	id, ok := publicID(c)
+	if !ok {
+		return
+	}
+	s, err := repository.GetSentence(id)
+	if !renderable(c, err) {
+		return
+	}
+	c.HTML(http.StatusOK, "sentence-edit-form.html", s)
+}
+
 func UpdateSentence(c *gin.Context) {
-	var s models.Sentence
 	id := c.Param("id")
-	s.Spanish = Sanitize(c.PostForm("spanish"))
-	s.English = Sanitize(c.PostForm("english"))
 
 	// LA ADUANA: Validación robusta
-	if err := ValidateSentence(s.English, s.Spanish); err != nil {
+	s, up, err := sentenceFromForm(c)
+	defer up.Close()
+	if err == nil {
+		err = guardUpdate(c, "sentence", id, s.Publication)
+	}
+	if err != nil {
+		sendGuardError(c, err)
+		return
+	}
+	if s.AudioURL, err = up.store(s.AudioURL); err != nil {
 		SendToast(c, err.Error(), "error")
 		return
 	}
 
 	// Si pasa, actualizamos en el repositorio
-	err := repository.UpdateSentence(id, s.English, s.Spanish)
-	if err != nil {
+	if err := repository.UpdateSentence(id, s); err != nil {
 		SendToast(c, "Error al actualizar en la base de datos", "error")
 		return
 	}
 
-	SendToast(c, "Frase actualizada correctamente", "success")
-	c.Header("HX-Trigger", "refreshList") // Dispara recarga en el front
+	refreshWithToast(c, "Frase actualizada correctamente") // Dispara recarga en el front
 }
 
 func DeleteSentence(c *gin.Context) {
@@ -94,24 +169,3 @@ func DeleteSentence(c *gin.Context) {
 	}
 	c.Status(http.StatusOK)
 }
-
-func ExportSentencesCSV(c *gin.Context) {
-	var data []models.Sentence
-	resp, err := repository.CallSupabase("GET", "sentences", nil, "select=*&order=id.asc")
-	if err != nil || resp == nil {
-		c.String(http.StatusInternalServerError, "Error al obtener frases")
-		return
-	}
-	defer resp.Body.Close()
-
-	_ = json.NewDecoder(resp.Body).Decode(&data)
-
-	c.Header("Content-Disposition", "attachment; filename=sentences.csv")
-	writer := csv.NewWriter(c.Writer)
-	defer writer.Flush()
-
-	_ = writer.Write([]string{"ID", "English", "Spanish"})
-	for _, s := range data {
-		_ = writer.Write([]string{fmt.Sprintf("%d", s.ID), s.English, s.Spanish})
-	}
-}
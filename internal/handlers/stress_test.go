@@ -30,6 +30,23 @@ func TestExtremeValidation(t *testing.T) {
 		}
 	})
 
+	// El texto con entidades no debe convertirse en etiquetas al guardarse
+	t.Run("Entidades Codificadas", func(t *testing.T) {
+		for _, evil := range []string{
+			"&lt;script&gt;alert(1)&lt;/script&gt;",
+			"&amp;lt;script&amp;gt;alert(1)",
+			"<scr<script>ipt>alert(1)",
+			"&#60;img src=x onerror=alert(1)&#62;",
+		} {
+			if clean := Sanitize(evil); strings.Contains(clean, "<") {
+				t.Errorf("❌ FALLO: %q quedó como %q", evil, clean)
+			}
+		}
+		if clean := Sanitize("Tom &amp; Jerry"); clean != "Tom & Jerry" {
+			t.Errorf("❌ FALLO: las entidades normales deben decodificarse, got %q", clean)
+		}
+	})
+
 	// 3. PRUEBA DE CARACTERES NULOS Y CONTROL
 	t.Run("Caracteres Invisibles", func(t *testing.T) {
 		inputConNulos := "Frase\x00Peligrosa\n\r"
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"english-at-lima-cms/internal/models"
+	"english-at-lima-cms/internal/repository"
+	"english-at-lima-cms/internal/taxonomy"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readTaxonomy lee el nivel MCER y las etiquetas (separadas por comas) del formulario
+func readTaxonomy(c *gin.Context) (models.Taxonomy, error) {
+	tx := models.Taxonomy{
+		Level: taxonomy.NormalizeLevel(c.PostForm("level")),
+		Tags:  taxonomy.ParseTags(c.PostForm("tags")),
+	}
+	return tx, taxonomy.Validate(tx.Level, tx.Tags)
+}
+
+// taxonomyQuery lee los filtros ?tag=&level= de un listado. Devuelve el
+// filtro PostgREST y los valores elegidos para repintar la barra de filtros.
+func taxonomyQuery(c *gin.Context) (string, models.Taxonomy) {
+	tag := taxonomy.NormalizeTag(c.Query("tag"))
+	view := models.Taxonomy{Level: taxonomy.NormalizeLevel(c.Query("level"))}
+	if tag != "" {
+		view.Tags = []string{tag}
+	}
+	return taxonomy.Filter(tag, view.Level), view
+}
+
+// withFilter añade el filtro de etiqueta/nivel a una consulta base
+func withFilter(base, filter string) string {
+	if filter == "" {
+		return base
+	}
+	return base + "&" + filter
+}
+
+// GetTags muestra la gestión de etiquetas con su uso por tipo de contenido
+func GetTags(c *gin.Context) {
+	counts, err := repository.ListTagCounts()
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error de conexión")
+		return
+	}
+	c.HTML(http.StatusOK, "tags.html", gin.H{"Counts": counts})
+}
+
+// RenameTag cambia el nombre de una etiqueta en todo el contenido. Si el
+// nombre nuevo ya existe el resultado es el mismo que fusionarlas.
+func RenameTag(c *gin.Context) {
+	old := taxonomy.NormalizeTag(c.PostForm("tag"))
+	name := taxonomy.NormalizeTag(c.PostForm("new_name"))
+	if old == "" || name == "" || old == name {
+		SendToast(c, "Escriba un nombre nuevo distinto al actual", "error")
+		return
+	}
+	replaceTag(c, old, name, fmt.Sprintf("Etiqueta renombrada a '%s'", name))
+}
+
+// MergeTag mueve todo el contenido de una etiqueta a otra existente
+func MergeTag(c *gin.Context) {
+	old := taxonomy.NormalizeTag(c.PostForm("tag"))
+	into := taxonomy.NormalizeTag(c.PostForm("into"))
+	if old == "" || into == "" || old == into {
+		SendToast(c, "Elija otra etiqueta con la que fusionar", "error")
+		return
+	}
+	replaceTag(c, old, into, fmt.Sprintf("'%s' fusionada en '%s'", old, into))
+}
+
+// DeleteTag quita la etiqueta de todo el contenido, reasignándolo a otra
+// etiqueta si se eligió una
+func DeleteTag(c *gin.Context) {
+	old := taxonomy.NormalizeTag(c.PostForm("tag"))
+	into := taxonomy.NormalizeTag(c.PostForm("into"))
+	if old == "" || old == into {
+		SendToast(c, "Etiqueta inválida", "error")
+		return
+	}
+	msg := fmt.Sprintf("Etiqueta '%s' eliminada", old)
+	if into != "" {
+		msg += fmt.Sprintf(" (contenido reasignado a '%s')", into)
+	}
+	replaceTag(c, old, into, msg)
+}
+
+func replaceTag(c *gin.Context, old, replacement, msg string) {
+	if err := repository.ReplaceTag(old, replacement); err != nil {
+		SendToast(c, "Error al actualizar las etiquetas", "error")
+		return
+	}
+	refreshWithToast(c, msg)
+}
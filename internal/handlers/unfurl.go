@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"context"
+	"english-at-lima-cms/internal/models"
+	"english-at-lima-cms/internal/unfurl"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+var unfurler = unfurl.New()
+
+// UnfurlResource devuelve la vista previa de la URL pegada en el formulario.
+// Si el profesor aún no escribió título o tipo, también se los prellena.
+func UnfurlResource(c *gin.Context) {
+	raw := strings.TrimSpace(c.Query("url"))
+	if raw == "" {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	meta, err := unfurler.Fetch(ctx, raw)
+	if err != nil {
+		c.HTML(http.StatusOK, "unfurl-preview.html", gin.H{"Error": "No se pudo obtener la vista previa"})
+		return
+	}
+
+	meta.Title = clip(Sanitize(meta.Title), 100)
+	meta.Description = clip(Sanitize(meta.Description), 500)
+	meta.SiteName = clip(Sanitize(meta.SiteName), 100)
+	if !strings.HasPrefix(meta.ThumbnailURL, "https://") && !strings.HasPrefix(meta.ThumbnailURL, "http://") {
+		meta.ThumbnailURL = ""
+	}
+
+	data := gin.H{
+		"Meta":      meta,
+		"FillTitle": strings.TrimSpace(c.Query("title")) == "" && meta.Title != "",
+		"FillType":  c.Query("type") == "",
+	}
+	// La descripción es editable (Markdown): sólo se prellena si está vacía
+	if strings.TrimSpace(c.Query("description")) == "" && meta.Description != "" {
+		data["FillDescription"] = models.Resource{Description: meta.Description}
+	}
+	c.HTML(http.StatusOK, "unfurl-preview.html", data)
+}
+
+// clip recorta a max caracteres sin partir un carácter UTF-8
+func clip(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max])
+}
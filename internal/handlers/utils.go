@@ -10,13 +10,25 @@ import (
 	"strings"
 )
 
+var (
+	reControl = regexp.MustCompile(`[\x00-\x1F\x7F]`)
+	reHTML    = regexp.MustCompile(`<[^>]*>`)
+)
+
+// Sanitize deja texto plano: sin caracteres de control ni etiquetas. Las
+// entidades se decodifican ANTES de quitar etiquetas (y se repite hasta que
+// no cambie) para que "&lt;script&gt;" no reviva como "<script>". Los
+// campos con formato usan richtext.
 func Sanitize(input string) string {
-	reControl := regexp.MustCompile(`[\x00-\x1F\x7F]`)
 	clean := reControl.ReplaceAllString(input, "")
-	const htmlRegex = `<[^>]*>`
-	reHTML := regexp.MustCompile(htmlRegex)
-	clean = reHTML.ReplaceAllString(clean, "")
-	clean = html.UnescapeString(clean)
+	for {
+		next := reHTML.ReplaceAllString(html.UnescapeString(clean), "")
+		next = reControl.ReplaceAllString(next, "")
+		if next == clean {
+			break
+		}
+		clean = next
+	}
 	return strings.TrimSpace(clean)
 }
 
@@ -33,3 +45,10 @@ func SendToast(c *gin.Context, message string, msgType string) {
 	c.Header("HX-Trigger", headerValue)
 	c.Status(http.StatusUnprocessableEntity)
 }
+
+// refreshWithToast muestra un toast de éxito y recarga la lista en el mismo HX-Trigger
+func refreshWithToast(c *gin.Context, message string) {
+	headerValue := fmt.Sprintf(`{"showToast": {"message": "%s", "type": "success"}, "refreshList": true}`, message)
+	c.Header("HX-Trigger", headerValue)
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,182 @@
+// Package i18n gestiona los idiomas de las traducciones (además del español,
+// que sigue siendo el idioma base de frases, explicaciones y títulos).
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Default es el idioma base y el de respaldo cuando falta una traducción
+const Default = "es"
+
+// names son los nombres que ven profesores y alumnos; un código sin nombre
+// se muestra en mayúsculas
+var names = map[string]string{
+	"es": "Español",
+	"pt": "Português",
+	"en": "English",
+	"fr": "Français",
+	"it": "Italiano",
+	"de": "Deutsch",
+	"qu": "Runasimi",
+}
+
+var (
+	localesOnce sync.Once
+	locales     []string
+)
+
+// Locales son los idiomas configurados en SUPPORTED_LOCALES (por defecto
+// "es,pt"), siempre con el español primero
+func Locales() []string {
+	localesOnce.Do(func() {
+		raw := os.Getenv("SUPPORTED_LOCALES")
+		if raw == "" {
+			raw = "es,pt"
+		}
+		locales = Parse(raw)
+	})
+	return locales
+}
+
+// Parse lee una lista de códigos separados por comas. Descarta lo que no
+// parezca un código ISO 639 (2 o 3 letras) y los repetidos.
+func Parse(raw string) []string {
+	list := []string{Default}
+	for _, code := range strings.Split(raw, ",") {
+		code = strings.ToLower(strings.TrimSpace(code))
+		if validCode(code) && !slices.Contains(list, code) {
+			list = append(list, code)
+		}
+	}
+	return list
+}
+
+func validCode(code string) bool {
+	if len(code) < 2 || len(code) > 3 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// Extra son los idiomas configurados que se traducen aparte del español
+func Extra() []string {
+	return Locales()[1:]
+}
+
+// Supported indica si el idioma está configurado
+func Supported(code string) bool {
+	return slices.Contains(Locales(), code)
+}
+
+// Name es el nombre del idioma en su propia lengua
+func Name(code string) string {
+	if n, ok := names[code]; ok {
+		return n
+	}
+	return strings.ToUpper(code)
+}
+
+// Negotiate elige el idioma configurado que más prefiere la cabecera
+// Accept-Language ("pt-BR,pt;q=0.9,en;q=0.8"); sin coincidencias devuelve Default
+func Negotiate(header string) string {
+	type pref struct {
+		code string
+		q    float64
+	}
+	var prefs []pref
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		primary, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if q > 0 && Supported(primary) {
+			prefs = append(prefs, pref{primary, q})
+		}
+	}
+	// Orden estable: a igual peso manda el orden de la cabecera
+	sort.SliceStable(prefs, func(i, j int) bool { return prefs[i].q > prefs[j].q })
+	if len(prefs) == 0 {
+		return Default
+	}
+	return prefs[0].code
+}
+
+// Clean recorta las traducciones y quita las vacías o de idiomas no configurados
+func Clean(values map[string]string) map[string]string {
+	out := map[string]string{}
+	for code, text := range values {
+		if text = strings.TrimSpace(text); text != "" && code != Default && Supported(code) {
+			out[code] = text
+		}
+	}
+	return out
+}
+
+// CheckLength comprueba que ninguna traducción pase de max bytes
+func CheckLength(values map[string]string, max int) error {
+	for code, text := range values {
+		if len(text) > max {
+			return fmt.Errorf("la traducción al %s excede el límite de %d caracteres", Name(code), max)
+		}
+	}
+	return nil
+}
+
+// Field es una pestaña de traducción en los formularios del admin
+type Field struct {
+	Locale string
+	Label  string
+	Name   string // nombre del campo: prefijo + "_" + código
+	Value  string
+	Long   bool // textarea en vez de input
+}
+
+// Missing activa el aviso de "falta traducción" en la pestaña
+func (f Field) Missing() bool { return f.Value == "" }
+
+// Fields arma una pestaña por cada idioma extra, saltando los que ya tienen
+// un campo propio en el formulario (p. ej. "en" en las explicaciones)
+func Fields(prefix string, values map[string]string, long bool, skip ...string) []Field {
+	var fields []Field
+	for _, code := range Extra() {
+		if slices.Contains(skip, code) {
+			continue
+		}
+		fields = append(fields, Field{Locale: code, Label: Name(code), Name: prefix + "_" + code, Value: values[code], Long: long})
+	}
+	return fields
+}
+
+// Option es un idioma en el selector de las páginas públicas
+type Option struct {
+	Code     string
+	Label    string
+	Selected bool
+}
+
+// Options lista los idiomas configurados marcando el actual
+func Options(current string) []Option {
+	var opts []Option
+	for _, code := range Locales() {
+		opts = append(opts, Option{Code: code, Label: Name(code), Selected: code == current})
+	}
+	return opts
+}
@@ -0,0 +1,69 @@
+package i18n
+
+import (
+	"slices"
+	"testing"
+)
+
+// configure fija los idiomas sin depender de SUPPORTED_LOCALES
+func configure(raw string) {
+	localesOnce.Do(func() {})
+	locales = Parse(raw)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want []string
+	}{
+		{"", []string{"es"}},
+		{"es,pt", []string{"es", "pt"}},
+		{" PT , fr,pt", []string{"es", "pt", "fr"}},
+		{"pt-BR,e,1a,qu", []string{"es", "qu"}},
+	}
+	for _, tt := range tests {
+		if got := Parse(tt.raw); !slices.Equal(got, tt.want) {
+			t.Errorf("Parse(%q) = %v, se esperaba %v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	configure("es,pt,fr")
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", "es"},
+		{"pt-BR,pt;q=0.9,en;q=0.8", "pt"},
+		{"en-US,en;q=0.9", "es"},
+		{"de;q=1, fr;q=0.4, pt;q=0.6", "pt"},
+		{"fr;q=0, pt;q=0.2", "pt"},
+		{"fr;q=abc, pt;q=0.5", "pt"},
+		{"FR-ca", "fr"},
+	}
+	for _, tt := range tests {
+		if got := Negotiate(tt.header); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, se esperaba %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestCleanAndFields(t *testing.T) {
+	configure("es,pt,en")
+	got := Clean(map[string]string{"pt": "  Como vai? ", "es": "¿Cómo estás?", "de": "Wie geht's?", "en": " "})
+	if len(got) != 1 || got["pt"] != "Como vai?" {
+		t.Errorf("Clean = %v", got)
+	}
+	if err := CheckLength(map[string]string{"pt": "Olá"}, 3); err == nil {
+		t.Error("se esperaba error por longitud (Olá ocupa 4 bytes)")
+	}
+
+	fields := Fields("explanation", got, true, "en")
+	if len(fields) != 1 || fields[0].Name != "explanation_pt" || fields[0].Label != "Português" || fields[0].Missing() {
+		t.Errorf("Fields = %+v", fields)
+	}
+	if f := Fields("title", nil, false); len(f) != 2 || !f[1].Missing() {
+		t.Errorf("sin traducciones todas las pestañas deben avisar: %+v", f)
+	}
+}
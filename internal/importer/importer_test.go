@@ -0,0 +1,263 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func TestReadCSV(t *testing.T) {
+	latin1, _ := charmap.Windows1252.NewEncoder().String("English;Spanish\nGood morning;Buenos días\n")
+	utf16, _ := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder().String("English\tSpanish\nGood morning\tBuenos días\n")
+
+	tests := []struct {
+		name      string
+		data      string
+		encoding  string
+		delimiter string
+		header    []string
+		rows      [][]string
+		lines     []int
+	}{
+		{
+			name: "Coma con BOM", data: "\xEF\xBB\xBFEnglish,Spanish\nHello there,Hola\n",
+			encoding: "UTF-8", delimiter: "coma",
+			header: []string{"English", "Spanish"}, rows: [][]string{{"Hello there", "Hola"}}, lines: []int{2},
+		},
+		{
+			name: "Punto y coma en Windows-1252", data: latin1,
+			encoding: "Windows-1252", delimiter: "punto y coma",
+			header: []string{"English", "Spanish"}, rows: [][]string{{"Good morning", "Buenos días"}}, lines: []int{2},
+		},
+		{
+			name: "Tabulador en UTF-16", data: utf16,
+			encoding: "UTF-16", delimiter: "tabulador",
+			header: []string{"English", "Spanish"}, rows: [][]string{{"Good morning", "Buenos días"}}, lines: []int{2},
+		},
+		{
+			name: "Comas dentro de comillas no engañan al separador", data: "a;b\n\"x, y\";z\n\"1, 2\";3\n",
+			encoding: "UTF-8", delimiter: "punto y coma",
+			header: []string{"a", "b"}, rows: [][]string{{"x, y", "z"}, {"1, 2", "3"}}, lines: []int{2, 3},
+		},
+		{
+			name: "Filas vacías y cortas", data: "a,b,c\n\n1,2\n,,\n4,5,6\n",
+			encoding: "UTF-8", delimiter: "coma",
+			header: []string{"a", "b", "c"}, rows: [][]string{{"1", "2", ""}, {"4", "5", "6"}}, lines: []int{3, 5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Read("datos.csv", []byte(tt.data))
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			if got.Format != "CSV" || got.Encoding != tt.encoding || got.Delimiter != tt.delimiter {
+				t.Errorf("formato %s/%s/%s, esperaba CSV/%s/%s", got.Format, got.Encoding, got.Delimiter, tt.encoding, tt.delimiter)
+			}
+			if !reflect.DeepEqual(got.Header, tt.header) || !reflect.DeepEqual(got.Rows, tt.rows) || !reflect.DeepEqual(got.Lines, tt.lines) {
+				t.Errorf("leyó %q %q %v", got.Header, got.Rows, got.Lines)
+			}
+		})
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		data []byte
+	}{
+		{"Sólo cabecera", "a.csv", []byte("English,Spanish\n")},
+		{"Vacío", "a.csv", nil},
+		{"XLSX que no es ZIP", "a.xlsx", []byte("English,Spanish\nx,y\n")},
+		{"ZIP roto", "a.xlsx", []byte("PK\x03\x04basura")},
+		{"Demasiado grande", "a.csv", make([]byte, MaxFileSize+1)},
+		{"Demasiadas filas", "a.csv", []byte("a,b\n" + strings.Repeat("1,2\n", MaxRows+1))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Read(tt.file, tt.data); err == nil {
+				t.Error("esperaba un error")
+			}
+		})
+	}
+}
+
+// buildXLSX arma un libro mínimo como los que guarda Excel
+func buildXLSX(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadXLSX(t *testing.T) {
+	data := buildXLSX(t, map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+			<sheets><sheet name="Frases" sheetId="1" r:id="rId7"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+			<Relationship Id="rId7" Target="worksheets/frases.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+			<si><t>English</t></si><si><t>Spanish</t></si><si><r><t>Good </t></r><r><t>morning</t></r></si></sst>`,
+		"xl/worksheets/frases.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+			<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="inlineStr"><is><t>Nivel</t></is></c></row>
+			<row r="3"><c r="A3" t="s"><v>2</v></c><c r="B3" t="str"><v>Buenos días</v></c><c r="C3" t="inlineStr"><is><t>A1</t></is></c></row>
+			<row r="4"><c r="C4"><v>42</v></c><c r="A4" t="b"><v>1</v></c></row>
+		</sheetData></worksheet>`,
+	})
+
+	got, err := Read("libro.xlsx", data)
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	wantRows := [][]string{{"Good morning", "Buenos días", "A1"}, {"true", "", "42"}}
+	if got.Format != "XLSX" || !reflect.DeepEqual(got.Header, []string{"English", "Spanish", "Nivel"}) ||
+		!reflect.DeepEqual(got.Rows, wantRows) || !reflect.DeepEqual(got.Lines, []int{3, 4}) {
+		t.Errorf("leyó %s %q %q %v", got.Format, got.Header, got.Rows, got.Lines)
+	}
+
+	bad := buildXLSX(t, map[string]string{
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData><row><c t="s"><v>9</v></c></row></sheetData></worksheet>`,
+	})
+	if _, err := Read("roto.xlsx", bad); err == nil {
+		t.Error("un texto compartido inexistente debería dar error")
+	}
+}
+
+var testFields = []Field{
+	{Name: "english", Label: "Inglés", Required: true},
+	{Name: "options", Label: "Opciones", Multi: true, Columns: []string{"options", "option_notes"}, Aliases: []string{"Opción"}},
+	{Name: "level", Label: "Nivel"},
+}
+
+func TestGuess(t *testing.T) {
+	header := []string{"ID", "ENGLISH", "Opción 1", "Opción 2", "nivel", "Opción 3", "Inglés"}
+	want := Mapping{"english": {1}, "options": {2, 3, 5}, "level": {4}}
+	if got := Guess(header, testFields); !reflect.DeepEqual(got, want) {
+		t.Errorf("Guess = %v, esperaba %v", got, want)
+	}
+}
+
+func TestReadMapping(t *testing.T) {
+	tests := []struct {
+		name    string
+		form    map[string][]string
+		want    Mapping
+		wantErr bool
+	}{
+		{"Completa", map[string][]string{"map_english": {"0"}, "map_options": {"1", "2"}, "map_level": {""}}, Mapping{"english": {0}, "options": {1, 2}}, false},
+		{"Falta un obligatorio", map[string][]string{"map_options": {"1"}}, nil, true},
+		{"Columna fuera de rango", map[string][]string{"map_english": {"7"}}, nil, true},
+		{"Un campo simple toma sólo la primera", map[string][]string{"map_english": {"0", "1"}}, Mapping{"english": {0}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadMapping(testFields, 3, func(k string) []string { return tt.form[k] })
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, esperaba error: %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadMapping = %v, esperaba %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRow(t *testing.T) {
+	m := Mapping{"english": {0}, "options": {1, 2}}
+	r := m.Row(4, []string{"  Hello there ", "red | blue", "", "ignored"})
+	if r.Line != 4 || r.Get("english") != "Hello there" || r.Get("level") != "" {
+		t.Errorf("Get: %d %q %q", r.Line, r.Get("english"), r.Get("level"))
+	}
+	if got := r.List("options"); !reflect.DeepEqual(got, []string{"red", "blue"}) {
+		t.Errorf("List = %q", got)
+	}
+	if got := m.Columns(testFields); !reflect.DeepEqual(got, []string{"english", "options", "option_notes"}) {
+		t.Errorf("Columns = %q", got)
+	}
+}
+
+func TestPlan(t *testing.T) {
+	rows := []Parsed{
+		{Line: 2, Label: "Good morning", Item: 1},
+		{Line: 3, Label: "See you later", Item: 2},
+		{Line: 4, Label: "good  MORNING", Item: 3},
+		{Line: 5, Label: "x", Err: errors.New("la frase es demasiado corta")},
+	}
+	existing := map[string]int{"see you later": 40}
+
+	tests := []struct {
+		mode    string
+		actions []string
+		ids     []int
+	}{
+		{ModeSkip, []string{ActionInsert, ActionSkip, ActionSkip, ActionInvalid}, []int{0, 40, 0, 0}},
+		{ModeUpdate, []string{ActionInsert, ActionUpdate, ActionSkip, ActionInvalid}, []int{0, 40, 0, 0}},
+		{ModeInsert, []string{ActionInsert, ActionInsert, ActionInsert, ActionInvalid}, []int{0, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			results := Plan(rows, existing, tt.mode)
+			for i, r := range results {
+				if r.Action != tt.actions[i] || r.ID != tt.ids[i] {
+					t.Errorf("fila %d: %s #%d (%s), esperaba %s #%d", r.Line, r.Action, r.ID, r.Message, tt.actions[i], tt.ids[i])
+				}
+			}
+		})
+	}
+
+	// Con todo ya importado, volver a importar no guarda nada
+	done := map[string]int{"good morning": 1, "see you later": 40}
+	if s := Summarize(Plan(rows, done, ModeSkip)); s.Insert+s.Update != 0 || s.Skip != 3 || s.Invalid != 1 {
+		t.Errorf("reimportar: %+v", s)
+	}
+}
+
+func TestChunks(t *testing.T) {
+	var results []Result
+	for i := 0; i < 7; i++ {
+		action := ActionInsert
+		if i%3 == 2 {
+			action = ActionSkip
+		}
+		results = append(results, Result{Line: i, Action: action})
+	}
+	chunks := Chunks(results, 2)
+	var sizes []int
+	for _, c := range chunks {
+		sizes = append(sizes, len(c))
+	}
+	if !reflect.DeepEqual(sizes, []int{2, 2, 1}) || chunks[1][0].Line != 3 {
+		t.Errorf("bloques de %v", sizes)
+	}
+}
+
+func TestStore(t *testing.T) {
+	token := Save(Upload{Type: "sentence", Owner: "ana@example.com"})
+	if _, ok := Load(token, "otro@example.com"); ok {
+		t.Error("otro usuario no debería ver el archivo")
+	}
+	if u, ok := Load(token, "ana@example.com"); !ok || u.Type != "sentence" {
+		t.Error("el dueño debería recuperar el archivo")
+	}
+	Drop(token)
+	if _, ok := Load(token, "ana@example.com"); ok {
+		t.Error("tras Drop no debería quedar nada")
+	}
+}
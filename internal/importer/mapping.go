@@ -0,0 +1,174 @@
+package importer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Field es un campo del contenido al que se le asigna una columna del archivo
+type Field struct {
+	Name     string // nombre en el formulario de asignación
+	Label    string
+	Required bool
+	Multi    bool     // admite varias columnas (p. ej. «Opción 1» … «Opción 8»)
+	Columns  []string // columnas de la tabla que escribe; vacío = Name
+	Aliases  []string // otras cabeceras que se reconocen solas
+}
+
+// DBColumns son las columnas de la tabla que se actualizan si el campo está asignado
+func (f Field) DBColumns() []string {
+	if len(f.Columns) > 0 {
+		return f.Columns
+	}
+	return []string{f.Name}
+}
+
+// Mapping asigna a cada campo los índices de las columnas del archivo
+type Mapping map[string][]int
+
+var headerAccents = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n")
+
+// headerKey normaliza una cabecera para compararla: minúsculas, sin tildes,
+// sin el número final («Opción 3» → «opcion») y con _ en vez de espacios
+func headerKey(h string) string {
+	h = headerAccents.Replace(strings.ToLower(strings.TrimSpace(h)))
+	h = strings.TrimRight(h, "0123456789 ")
+	return strings.Join(strings.FieldsFunc(h, func(r rune) bool {
+		return r == ' ' || r == '_' || r == '-' || r == '.'
+	}), "_")
+}
+
+// Guess propone la asignación comparando cada cabecera con el nombre, la
+// etiqueta y los alias de los campos. Cada columna va a un solo campo.
+func Guess(header []string, fields []Field) Mapping {
+	m := Mapping{}
+	used := map[int]bool{}
+	for _, f := range fields {
+		names := map[string]bool{headerKey(f.Name): true, headerKey(f.Label): true}
+		for _, a := range f.Aliases {
+			names[headerKey(a)] = true
+		}
+		for i, h := range header {
+			if used[i] || !names[headerKey(h)] {
+				continue
+			}
+			m[f.Name] = append(m[f.Name], i)
+			used[i] = true
+			if !f.Multi {
+				break
+			}
+		}
+	}
+	return m
+}
+
+// ReadMapping lee la asignación del formulario (map_<campo> = índice de columna)
+func ReadMapping(fields []Field, width int, values func(key string) []string) (Mapping, error) {
+	m := Mapping{}
+	for _, f := range fields {
+		for _, raw := range values("map_" + f.Name) {
+			if raw == "" {
+				continue
+			}
+			i, err := strconv.Atoi(raw)
+			if err != nil || i < 0 || i >= width {
+				return nil, fmt.Errorf("columna no válida para «%s»", f.Label)
+			}
+			m[f.Name] = append(m[f.Name], i)
+			if !f.Multi {
+				break
+			}
+		}
+		if f.Required && len(m[f.Name]) == 0 {
+			return nil, fmt.Errorf("asigne una columna a «%s»", f.Label)
+		}
+	}
+	return m, nil
+}
+
+// Columns son las columnas de la tabla que escribe la importación con esta
+// asignación: las de los campos que tienen alguna columna del archivo
+func (m Mapping) Columns(fields []Field) []string {
+	var cols []string
+	for _, f := range fields {
+		if len(m[f.Name]) > 0 {
+			cols = append(cols, f.DBColumns()...)
+		}
+	}
+	return cols
+}
+
+// Row es una fila del archivo leída a través de la asignación
+type Row struct {
+	Line   int
+	values map[string][]string
+}
+
+// Row lee la fila: line es su número en la hoja (la cabecera es la 1)
+func (m Mapping) Row(line int, cells []string) Row {
+	r := Row{Line: line, values: map[string][]string{}}
+	for name, cols := range m {
+		for _, i := range cols {
+			if i < len(cells) {
+				r.values[name] = append(r.values[name], cells[i])
+			}
+		}
+	}
+	return r
+}
+
+// Get es el valor del campo (el de la primera columna si tiene varias)
+func (r Row) Get(name string) string {
+	if v := r.values[name]; len(v) > 0 {
+		return strings.TrimSpace(v[0])
+	}
+	return ""
+}
+
+// List reparte el campo en valores: cada columna asignada y, dentro de
+// cada celda, lo separado por «|» (como en la exportación). Sin vacíos.
+func (r Row) List(name string) []string {
+	var out []string
+	for _, cell := range r.values[name] {
+		for _, part := range strings.Split(cell, "|") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+	}
+	return out
+}
+
+// ColumnOption es una columna del archivo en el desplegable de un campo
+type ColumnOption struct {
+	Index    int
+	Header   string
+	Selected bool
+}
+
+// Choice es un campo con sus columnas posibles para el formulario de asignación
+type Choice struct {
+	Field
+	Options []ColumnOption
+}
+
+// Choices prepara el formulario de asignación marcando lo ya asignado
+func (m Mapping) Choices(fields []Field, header []string) []Choice {
+	choices := make([]Choice, len(fields))
+	for i, f := range fields {
+		selected := map[int]bool{}
+		for _, col := range m[f.Name] {
+			selected[col] = true
+		}
+		opts := make([]ColumnOption, len(header))
+		for j, h := range header {
+			if h == "" {
+				h = "Columna " + strconv.Itoa(j+1)
+			}
+			opts[j] = ColumnOption{Index: j, Header: h, Selected: selected[j]}
+		}
+		choices[i] = Choice{Field: f, Options: opts}
+	}
+	return choices
+}
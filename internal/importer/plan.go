@@ -0,0 +1,141 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Qué hacer con una fila que ya existe en la base (o repetida en el archivo)
+const (
+	ModeSkip   = "skip"   // se deja como está
+	ModeUpdate = "update" // se sobrescriben las columnas asignadas
+	ModeInsert = "insert" // se crea otra igualmente
+)
+
+// ModeLabels son los modos de duplicados tal como los ve el profesor
+var ModeLabels = map[string]string{
+	ModeSkip:   "Saltar las que ya existen",
+	ModeUpdate: "Actualizar las que ya existen",
+	ModeInsert: "Insertar siempre (puede duplicar)",
+}
+
+// Lo que pasa con cada fila
+const (
+	ActionInsert  = "insert"
+	ActionUpdate  = "update"
+	ActionSkip    = "skip"
+	ActionInvalid = "invalid"
+)
+
+// ChunkSize es cuántas filas se guardan por petición al confirmar
+const ChunkSize = 100
+
+// Key normaliza el texto que identifica el contenido (el inglés de la
+// frase, la pregunta, la URL del recurso) para reconocer los ya existentes
+func Key(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// Parsed es una fila ya convertida en contenido, o el error que lo impidió
+type Parsed struct {
+	Line  int
+	Label string // texto que identifica la fila en el informe (y su clave)
+	Item  interface{}
+	Err   error
+}
+
+// Result es lo que pasará con la fila (o pasó, tras confirmar)
+type Result struct {
+	Line    int
+	Label   string
+	Action  string
+	ID      int // contenido existente que se actualiza o por el que se salta
+	Message string
+	Item    interface{}
+}
+
+// ActionLabel es la acción para el informe
+func (r Result) ActionLabel() string {
+	return map[string]string{
+		ActionInsert: "➕ Nueva", ActionUpdate: "✏️ Actualiza", ActionSkip: "⏭️ Se salta", ActionInvalid: "❌ Error",
+	}[r.Action]
+}
+
+// Plan decide la acción de cada fila según lo que ya existe (clave → id) y
+// el modo elegido. Con los modos skip y update, la misma clave repetida en el
+// archivo sólo cuenta la primera vez, así que importar dos veces el mismo
+// archivo no crea nada nuevo.
+func Plan(rows []Parsed, existing map[string]int, mode string) []Result {
+	results := make([]Result, len(rows))
+	firstLine := map[string]int{}
+	for i, p := range rows {
+		r := Result{Line: p.Line, Label: p.Label, Item: p.Item}
+		key := Key(p.Label)
+		switch {
+		case p.Err != nil:
+			r.Action, r.Message = ActionInvalid, p.Err.Error()
+		case firstLine[key] > 0 && mode != ModeInsert:
+			r.Action, r.Message = ActionSkip, fmt.Sprintf("repetida en el archivo (fila %d)", firstLine[key])
+		case existing[key] > 0 && mode == ModeSkip:
+			r.Action, r.ID, r.Message = ActionSkip, existing[key], "ya existe"
+		case existing[key] > 0 && mode == ModeUpdate:
+			r.Action, r.ID = ActionUpdate, existing[key]
+		default:
+			r.Action = ActionInsert
+			if existing[key] > 0 {
+				r.Message = fmt.Sprintf("ya existe #%d; se crea otra", existing[key])
+			}
+		}
+		if p.Err == nil && firstLine[key] == 0 {
+			firstLine[key] = p.Line
+		}
+		results[i] = r
+	}
+	return results
+}
+
+// Summary cuenta las filas por acción
+type Summary struct {
+	Insert, Update, Skip, Invalid int
+}
+
+// Total es el número de filas del archivo
+func (s Summary) Total() int { return s.Insert + s.Update + s.Skip + s.Invalid }
+
+// Summarize cuenta las acciones del plan
+func Summarize(results []Result) Summary {
+	var s Summary
+	for _, r := range results {
+		switch r.Action {
+		case ActionInsert:
+			s.Insert++
+		case ActionUpdate:
+			s.Update++
+		case ActionSkip:
+			s.Skip++
+		case ActionInvalid:
+			s.Invalid++
+		}
+	}
+	return s
+}
+
+// Chunks reparte en bloques de size las filas que hay que guardar
+func Chunks(results []Result, size int) [][]Result {
+	var chunks [][]Result
+	var current []Result
+	for _, r := range results {
+		if r.Action != ActionInsert && r.Action != ActionUpdate {
+			continue
+		}
+		current = append(current, r)
+		if len(current) == size {
+			chunks = append(chunks, current)
+			current = nil
+		}
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
@@ -0,0 +1,397 @@
+// Package importer lee hojas de cálculo (CSV o XLSX) para la importación
+// masiva: detecta la codificación y el separador del CSV, asigna columnas a
+// campos y decide qué filas se insertan, se actualizan o se saltan.
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// Límites del archivo subido
+const (
+	MaxFileSize = 5 << 20  // 5 MB
+	MaxRows     = 5000     // filas de datos, sin la cabecera
+	maxUnzipped = 50 << 20 // lo más que se descomprime de un XLSX
+)
+
+// Table es la hoja leída: la primera fila no vacía es la cabecera
+type Table struct {
+	Header []string
+	Rows   [][]string
+	Lines  []int // número de línea en la hoja de cada fila de Rows
+	// Cómo se leyó el archivo, para mostrarlo en el asistente
+	Format    string // "CSV" o "XLSX"
+	Encoding  string
+	Delimiter string
+}
+
+// Read lee el archivo según su contenido: los XLSX son ZIP y empiezan por "PK"
+func Read(name string, data []byte) (Table, error) {
+	if len(data) > MaxFileSize {
+		return Table{}, fmt.Errorf("el archivo supera el límite de %d MB", MaxFileSize>>20)
+	}
+	var t Table
+	var err error
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		t, err = readXLSX(data)
+	} else if strings.EqualFold(path.Ext(name), ".xlsx") {
+		return Table{}, errors.New("el archivo .xlsx está dañado")
+	} else {
+		t, err = readCSV(data)
+	}
+	if err != nil {
+		return Table{}, err
+	}
+	return t.trim()
+}
+
+// trim quita las filas vacías, separa la cabecera e iguala el ancho de las filas
+func (t Table) trim() (Table, error) {
+	var rows [][]string
+	var lines []int
+	for n, r := range t.Rows {
+		line := n + 1
+		if n < len(t.Lines) {
+			line = t.Lines[n]
+		}
+		for _, cell := range r {
+			if strings.TrimSpace(cell) != "" {
+				rows = append(rows, r)
+				lines = append(lines, line)
+				break
+			}
+		}
+	}
+	if len(rows) < 2 {
+		return Table{}, errors.New("el archivo no tiene filas de datos debajo de la cabecera")
+	}
+	if len(rows)-1 > MaxRows {
+		return Table{}, fmt.Errorf("el archivo tiene más de %d filas; divídalo en partes", MaxRows)
+	}
+
+	t.Header = make([]string, len(rows[0]))
+	for i, h := range rows[0] {
+		t.Header[i] = strings.TrimSpace(strings.TrimPrefix(h, "\ufeff"))
+	}
+	t.Rows = make([][]string, 0, len(rows)-1)
+	for _, r := range rows[1:] {
+		row := make([]string, len(t.Header))
+		copy(row, r)
+		t.Rows = append(t.Rows, row)
+	}
+	t.Lines = lines[1:]
+	return t, nil
+}
+
+// --- CSV ---
+
+// delimiters son los separadores que se prueban, en orden de preferencia
+var delimiters = []rune{',', ';', '\t', '|'}
+
+func readCSV(data []byte) (Table, error) {
+	text, enc, err := decode(data)
+	if err != nil {
+		return Table{}, err
+	}
+	delim := detectDelimiter(text)
+
+	r := csv.NewReader(strings.NewReader(text))
+	r.Comma = delim
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	t := Table{Format: "CSV", Encoding: enc, Delimiter: delimiterName(delim)}
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Table{}, fmt.Errorf("no se pudo leer el CSV: %w", err)
+		}
+		// El lector se salta las líneas en blanco: la línea real la da FieldPos
+		line, _ := r.FieldPos(0)
+		for i := range rec {
+			rec[i] = unquoteFormula(rec[i])
+		}
+		t.Rows = append(t.Rows, rec)
+		t.Lines = append(t.Lines, line)
+	}
+	return t, nil
+}
+
+// unquoteFormula quita el apóstrofo que la exportación CSV antepone a las
+// celdas que Excel tomaría por fórmulas («'=…», «'-…»)
+func unquoteFormula(cell string) string {
+	if len(cell) > 1 && cell[0] == '\'' && strings.ContainsRune("=+-@\t\r", rune(cell[1])) {
+		return cell[1:]
+	}
+	return cell
+}
+
+// decode pasa el texto a UTF-8. Con BOM se respeta; sin BOM, si no es UTF-8
+// válido se asume Windows-1252, que es lo que guarda Excel en Windows.
+func decode(data []byte) (string, string, error) {
+	var dec *encoding.Decoder
+	name := "UTF-8"
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return string(data[3:]), name, nil
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		dec, name = unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM).NewDecoder(), "UTF-16"
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		dec, name = unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM).NewDecoder(), "UTF-16"
+	case utf8.Valid(data):
+		return string(data), name, nil
+	default:
+		dec, name = charmap.Windows1252.NewDecoder(), "Windows-1252"
+	}
+	out, err := dec.Bytes(data)
+	if err != nil {
+		return "", name, fmt.Errorf("no se pudo leer el archivo como %s", name)
+	}
+	return string(out), name, nil
+}
+
+// detectDelimiter elige el separador con el que las primeras líneas tienen
+// más columnas y el mismo número de columnas en todas
+func detectDelimiter(text string) rune {
+	best, bestScore := delimiters[0], 0
+	for _, d := range delimiters {
+		r := csv.NewReader(strings.NewReader(text))
+		r.Comma = d
+		r.FieldsPerRecord = -1
+		r.LazyQuotes = true
+
+		width, consistent := 0, 0
+		for i := 0; i < 10; i++ {
+			rec, err := r.Read()
+			if err != nil {
+				break
+			}
+			if i == 0 {
+				width = len(rec)
+			}
+			if len(rec) == width {
+				consistent++
+			}
+		}
+		if width < 2 {
+			continue
+		}
+		if score := consistent*100 + width; score > bestScore {
+			best, bestScore = d, score
+		}
+	}
+	return best
+}
+
+func delimiterName(d rune) string {
+	switch d {
+	case '\t':
+		return "tabulador"
+	case ';':
+		return "punto y coma"
+	case '|':
+		return "barra vertical"
+	}
+	return "coma"
+}
+
+// --- XLSX ---
+
+// readXLSX lee la primera hoja del libro: sólo los valores, sin formatos ni
+// fórmulas (de una fórmula se toma el último valor calculado)
+func readXLSX(data []byte) (Table, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return Table{}, errors.New("el archivo .xlsx está dañado")
+	}
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	sheet, err := firstSheet(files)
+	if err != nil {
+		return Table{}, err
+	}
+	var shared []string
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		if shared, err = readSharedStrings(f); err != nil {
+			return Table{}, err
+		}
+	}
+	f, ok := files[sheet]
+	if !ok {
+		return Table{}, errors.New("el archivo .xlsx no tiene hojas")
+	}
+	rows, err := readSheet(f, shared)
+	if err != nil {
+		return Table{}, err
+	}
+	return Table{Rows: rows, Format: "XLSX", Encoding: "UTF-8"}, nil
+}
+
+func unmarshalZip(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(io.LimitReader(rc, maxUnzipped)).Decode(v)
+}
+
+// firstSheet busca la ruta de la primera hoja a través de las relaciones del libro
+func firstSheet(files map[string]*zip.File) (string, error) {
+	const fallback = "xl/worksheets/sheet1.xml"
+	wbFile, ok1 := files["xl/workbook.xml"]
+	relFile, ok2 := files["xl/_rels/workbook.xml.rels"]
+	if !ok1 || !ok2 {
+		return fallback, nil
+	}
+	var wb struct {
+		Sheets []struct {
+			RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	var rels struct {
+		Items []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if unmarshalZip(wbFile, &wb) != nil || unmarshalZip(relFile, &rels) != nil {
+		return "", errors.New("el archivo .xlsx está dañado")
+	}
+	if len(wb.Sheets) == 0 {
+		return "", errors.New("el archivo .xlsx no tiene hojas")
+	}
+	for _, r := range rels.Items {
+		if r.ID == wb.Sheets[0].RID {
+			if strings.HasPrefix(r.Target, "/") {
+				return strings.TrimPrefix(r.Target, "/"), nil
+			}
+			return path.Join("xl", r.Target), nil
+		}
+	}
+	return fallback, nil
+}
+
+// richText es el texto de una celda: directo en <t> o repartido en tramos <r><t>
+type richText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (r richText) String() string {
+	if len(r.Runs) == 0 {
+		return r.T
+	}
+	var b strings.Builder
+	for _, run := range r.Runs {
+		b.WriteString(run.T)
+	}
+	return b.String()
+}
+
+func readSharedStrings(f *zip.File) ([]string, error) {
+	var sst struct {
+		Items []richText `xml:"si"`
+	}
+	if err := unmarshalZip(f, &sst); err != nil {
+		return nil, errors.New("el archivo .xlsx está dañado")
+	}
+	out := make([]string, len(sst.Items))
+	for i, si := range sst.Items {
+		out[i] = si.String()
+	}
+	return out, nil
+}
+
+func readSheet(f *zip.File, shared []string) ([][]string, error) {
+	var ws struct {
+		Rows []struct {
+			Ref   int `xml:"r,attr"`
+			Cells []struct {
+				Ref    string   `xml:"r,attr"`
+				Type   string   `xml:"t,attr"`
+				Value  string   `xml:"v"`
+				Inline richText `xml:"is"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := unmarshalZip(f, &ws); err != nil {
+		return nil, errors.New("el archivo .xlsx está dañado")
+	}
+
+	rows := make([][]string, 0, len(ws.Rows))
+	for _, r := range ws.Rows {
+		if r.Ref > 2*MaxRows {
+			return nil, fmt.Errorf("el archivo tiene más de %d filas; divídalo en partes", MaxRows)
+		}
+		// Las filas vacías no vienen en el XML; se rellenan para numerar bien
+		for len(rows) < r.Ref-1 {
+			rows = append(rows, nil)
+		}
+		var row []string
+		for i, c := range r.Cells {
+			col := i
+			if c.Ref != "" {
+				if col = columnIndex(c.Ref); col < 0 {
+					return nil, fmt.Errorf("celda no válida: %s", c.Ref)
+				}
+			}
+			if col > 1000 {
+				return nil, errors.New("la hoja tiene demasiadas columnas")
+			}
+			for len(row) <= col {
+				row = append(row, "")
+			}
+			switch c.Type {
+			case "s":
+				n, err := strconv.Atoi(c.Value)
+				if err != nil || n < 0 || n >= len(shared) {
+					return nil, fmt.Errorf("celda %s: texto compartido inexistente", c.Ref)
+				}
+				row[col] = shared[n]
+			case "inlineStr":
+				row[col] = c.Inline.String()
+			case "b":
+				row[col] = map[string]string{"1": "true", "0": "false"}[c.Value]
+			default: // números, "str" (resultado de fórmula) y "e" (error)
+				row[col] = c.Value
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// columnIndex convierte la referencia "C12" en el índice de columna 2
+func columnIndex(ref string) int {
+	col := 0
+	i := 0
+	for ; i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z'; i++ {
+		col = col*26 + int(ref[i]-'A'+1)
+	}
+	if i == 0 || i > 3 {
+		return -1
+	}
+	return col - 1
+}
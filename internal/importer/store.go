@@ -0,0 +1,63 @@
+package importer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// UploadTTL es cuánto se guarda un archivo subido mientras se asignan columnas
+const UploadTTL = 30 * time.Minute
+
+// Upload es un archivo ya leído que espera la asignación y la confirmación
+type Upload struct {
+	Type  string // "sentence", "quiz" o "resource"
+	Name  string
+	Owner string // correo de quien lo subió
+	Table Table
+
+	expires time.Time
+}
+
+var (
+	uploadsMu sync.Mutex
+	uploads   = map[string]Upload{}
+)
+
+// Save guarda el archivo en memoria y devuelve el token para los pasos siguientes
+func Save(u Upload) string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	token := hex.EncodeToString(buf)
+
+	uploadsMu.Lock()
+	defer uploadsMu.Unlock()
+	now := time.Now()
+	for t, old := range uploads {
+		if now.After(old.expires) {
+			delete(uploads, t)
+		}
+	}
+	u.expires = now.Add(UploadTTL)
+	uploads[token] = u
+	return token
+}
+
+// Load recupera el archivo si sigue vigente y lo subió owner
+func Load(token, owner string) (Upload, bool) {
+	uploadsMu.Lock()
+	defer uploadsMu.Unlock()
+	u, ok := uploads[token]
+	if !ok || u.Owner != owner || time.Now().After(u.expires) {
+		return Upload{}, false
+	}
+	return u, true
+}
+
+// Drop olvida el archivo (tras confirmar la importación)
+func Drop(token string) {
+	uploadsMu.Lock()
+	defer uploadsMu.Unlock()
+	delete(uploads, token)
+}
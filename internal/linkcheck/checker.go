@@ -0,0 +1,163 @@
+package linkcheck
+
+import (
+	"context"
+	"english-at-lima-cms/internal/unfurl"
+	"errors"
+	"io"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Target es una URL a revisar junto al id del recurso al que pertenece
+type Target struct {
+	ID  int
+	URL string
+}
+
+// Result es lo que se guarda en el recurso tras cada revisión
+type Result struct {
+	ID         int
+	Status     int    // código HTTP final (0 si no hubo respuesta)
+	RedirectTo string // URL final si el enlace redirige a otro sitio
+	Err        string
+	CheckedAt  time.Time
+}
+
+// Broken indica si el enlace debe marcarse como roto
+func (r Result) Broken() bool {
+	return r.Err != "" || r.Status >= 400 || r.Status == 0
+}
+
+// Checker revisa URLs con concurrencia limitada y sin martillear a ningún
+// servidor: entre dos peticiones al mismo host espera al menos HostDelay
+type Checker struct {
+	Client      *http.Client
+	Concurrency int
+	HostDelay   time.Duration
+	UserAgent   string
+
+	mu    sync.Mutex
+	hosts map[string]*hostSlot
+}
+
+type hostSlot struct {
+	mu   sync.Mutex
+	last time.Time
+}
+
+// NewChecker crea un Checker que sólo se conecta a IPs públicas: las URLs las
+// escriben los editores y no deben servir para sondear la red interna
+func NewChecker() *Checker {
+	return newChecker(unfurl.IsPublicIP)
+}
+
+func newChecker(allow func(netip.Addr) bool) *Checker {
+	return &Checker{
+		Client: &http.Client{
+			Transport: unfurl.NewTransport(allow),
+			Timeout:   15 * time.Second,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= 5 {
+					return errors.New("demasiadas redirecciones")
+				}
+				return nil
+			},
+		},
+		Concurrency: 8,
+		HostDelay:   time.Second,
+		UserAgent:   "EnglishAtLima-LinkChecker/1.0",
+	}
+}
+
+// Run revisa todos los objetivos y devuelve un resultado por cada uno
+func (c *Checker) Run(ctx context.Context, targets []Target) []Result {
+	results := make([]Result, len(targets))
+	sem := make(chan struct{}, max(c.Concurrency, 1))
+	var wg sync.WaitGroup
+
+	for i, t := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, t Target) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = c.Check(ctx, t)
+		}(i, t)
+	}
+	wg.Wait()
+	return results
+}
+
+// Check hace HEAD y, si el servidor no lo soporta, repite con GET
+func (c *Checker) Check(ctx context.Context, t Target) Result {
+	res := Result{ID: t.ID}
+
+	u, err := url.Parse(t.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		res.Err = "URL inválida"
+		res.CheckedAt = time.Now()
+		return res
+	}
+
+	resp, err := c.request(ctx, http.MethodHead, u)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented || resp.StatusCode == http.StatusForbidden) {
+		resp.Body.Close()
+		resp, err = c.request(ctx, http.MethodGet, u)
+	}
+	res.CheckedAt = time.Now()
+	if errors.Is(err, unfurl.ErrBlockedAddress) {
+		res.Err = unfurl.ErrBlockedAddress.Error()
+		return res
+	}
+	if err != nil {
+		res.Err = err.Error()
+		return res
+	}
+	defer resp.Body.Close()
+	_, _ = io.CopyN(io.Discard, resp.Body, 4096)
+
+	res.Status = resp.StatusCode
+	if final := resp.Request.URL.String(); final != u.String() {
+		res.RedirectTo = final
+	}
+	return res
+}
+
+func (c *Checker) request(ctx context.Context, method string, u *url.URL) (*http.Response, error) {
+	c.wait(u.Host)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", c.UserAgent)
+	if method == http.MethodGet {
+		// Basta con los primeros bytes para saber si el enlace responde
+		req.Header.Set("Range", "bytes=0-4095")
+	}
+	return c.Client.Do(req)
+}
+
+// wait bloquea hasta que haya pasado HostDelay desde la última petición al host
+func (c *Checker) wait(host string) {
+	c.mu.Lock()
+	if c.hosts == nil {
+		c.hosts = make(map[string]*hostSlot)
+	}
+	slot, ok := c.hosts[host]
+	if !ok {
+		slot = &hostSlot{}
+		c.hosts[host] = slot
+	}
+	c.mu.Unlock()
+
+	slot.mu.Lock()
+	defer slot.mu.Unlock()
+	if wait := c.HostDelay - time.Since(slot.last); wait > 0 {
+		time.Sleep(wait)
+	}
+	slot.last = time.Now()
+}
@@ -0,0 +1,161 @@
+package linkcheck
+
+import (
+	"context"
+	"english-at-lima-cms/internal/unfurl"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// allowAll deja pasar 127.0.0.1 para que los tests usen httptest
+func allowAll(netip.Addr) bool { return true }
+
+// newStandIn simula los distintos sitios a los que apuntan los recursos
+func newStandIn(t *testing.T, inFlight, peak *int32) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/no-head", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(inFlight, 1)
+		defer atomic.AddInt32(inFlight, -1)
+		for {
+			p := atomic.LoadInt32(peak)
+			if n <= p || atomic.CompareAndSwapInt32(peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestCheckStatuses(t *testing.T) {
+	var inFlight, peak int32
+	srv := newStandIn(t, &inFlight, &peak)
+	c := newChecker(allowAll)
+	c.HostDelay = 0
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantBroken bool
+		redirected bool
+	}{
+		{"Enlace sano", "/ok", 200, false, false},
+		{"Enlace roto", "/gone", 404, true, false},
+		{"Redirección", "/moved", 200, false, true},
+		{"Servidor sin HEAD", "/no-head", 200, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := c.Check(context.Background(), Target{ID: 1, URL: srv.URL + tt.path})
+			if res.Status != tt.wantStatus || res.Broken() != tt.wantBroken {
+				t.Errorf("estado %d roto=%v, esperaba %d roto=%v (%s)", res.Status, res.Broken(), tt.wantStatus, tt.wantBroken, res.Err)
+			}
+			if (res.RedirectTo != "") != tt.redirected {
+				t.Errorf("redirección inesperada: %q", res.RedirectTo)
+			}
+		})
+	}
+
+	t.Run("Servidor caído", func(t *testing.T) {
+		res := c.Check(context.Background(), Target{URL: "http://127.0.0.1:1/nada"})
+		if !res.Broken() || res.Err == "" {
+			t.Error("❌ Un servidor caído no se marcó como roto")
+		}
+	})
+	t.Run("Esquema no permitido", func(t *testing.T) {
+		if res := c.Check(context.Background(), Target{URL: "ftp://lima.com/x"}); !res.Broken() {
+			t.Error("❌ Se aceptó una URL ftp://")
+		}
+	})
+}
+
+func TestRunBoundedConcurrency(t *testing.T) {
+	var inFlight, peak int32
+	srv := newStandIn(t, &inFlight, &peak)
+	c := newChecker(allowAll)
+	c.Concurrency = 3
+	c.HostDelay = 0
+
+	targets := make([]Target, 12)
+	for i := range targets {
+		targets[i] = Target{ID: i + 1, URL: srv.URL + "/slow"}
+	}
+	results := c.Run(context.Background(), targets)
+
+	if len(results) != len(targets) {
+		t.Fatalf("esperaba %d resultados, obtuve %d", len(targets), len(results))
+	}
+	for i, r := range results {
+		if r.ID != targets[i].ID || r.Broken() {
+			t.Errorf("resultado %d incorrecto: %+v", i, r)
+		}
+	}
+	if peak > 3 {
+		t.Errorf("❌ Se superó la concurrencia máxima: %d peticiones simultáneas", peak)
+	}
+}
+
+func TestHostPoliteness(t *testing.T) {
+	var inFlight, peak int32
+	srv := newStandIn(t, &inFlight, &peak)
+	c := newChecker(allowAll)
+	c.Concurrency = 4
+	c.HostDelay = 50 * time.Millisecond
+
+	start := time.Now()
+	c.Run(context.Background(), []Target{{1, srv.URL + "/ok"}, {2, srv.URL + "/ok"}, {3, srv.URL + "/ok"}})
+
+	// Tres peticiones al mismo host => al menos dos esperas de HostDelay
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("❌ No se respetó la pausa entre peticiones al mismo host (%s)", elapsed)
+	}
+}
+
+func TestBlockedAddresses(t *testing.T) {
+	var inFlight, peak int32
+	srv := newStandIn(t, &inFlight, &peak)
+	c := NewChecker()
+	c.HostDelay = 0
+
+	for _, u := range []string{srv.URL + "/ok", "http://169.254.169.254/latest/meta-data/", "http://[::1]:8080/"} {
+		res := c.Check(context.Background(), Target{ID: 1, URL: u})
+		if !res.Broken() || res.Err != unfurl.ErrBlockedAddress.Error() {
+			t.Errorf("❌ %s debería marcarse como bloqueado: estado %d (%s)", u, res.Status, res.Err)
+		}
+	}
+}
+
+func TestStartWhileRunning(t *testing.T) {
+	running.Store(true)
+	defer running.Store(false)
+	if err := Start(NewChecker()); err != ErrRunning {
+		t.Fatalf("con una revisión en curso se esperaba ErrRunning, obtuve %v", err)
+	}
+	if _, _, err := RunOnce(context.Background(), NewChecker()); err != ErrRunning {
+		t.Fatalf("RunOnce: se esperaba ErrRunning, obtuve %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package linkcheck
+
+import (
+	"context"
+	"english-at-lima-cms/internal/repository"
+	"english-at-lima-cms/internal/seo"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// running evita que el cron y el botón "Revisar ahora" se pisen
+var running atomic.Bool
+
+// ErrRunning se devuelve cuando ya hay una revisión en marcha
+var ErrRunning = errors.New("ya hay una revisión de enlaces en curso")
+
+// RunOnce revisa todos los recursos activos y guarda estado, redirección,
+// fecha y racha de fallos de cada uno
+func RunOnce(ctx context.Context, c *Checker) (checked, broken int, err error) {
+	if !running.CompareAndSwap(false, true) {
+		return 0, 0, ErrRunning
+	}
+	defer running.Store(false)
+	return run(ctx, c)
+}
+
+// Start lanza una revisión en segundo plano; si ya hay una en curso devuelve
+// ErrRunning al momento, para poder avisar a quien pulsó el botón
+func Start(c *Checker) error {
+	if !running.CompareAndSwap(false, true) {
+		return ErrRunning
+	}
+	go func() {
+		defer running.Store(false)
+		report(run(context.Background(), c))
+	}()
+	return nil
+}
+
+func report(checked, broken int, err error) {
+	if err != nil {
+		fmt.Println("⚠️ Revisión de enlaces fallida:", err)
+		return
+	}
+	fmt.Printf("🔗 Enlaces revisados: %d (%d rotos)\n", checked, broken)
+}
+
+func run(ctx context.Context, c *Checker) (checked, broken int, err error) {
+	resources, err := repository.ListCheckableResources()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	streaks := make(map[int]int, len(resources))
+	var targets []Target
+	for _, r := range resources {
+		// Los archivos privados necesitan firma: no tiene sentido revisarlos
+		if strings.HasPrefix(r.URL, seo.SiteURL()+"/media/private/") {
+			continue
+		}
+		streaks[r.ID] = r.LinkFailStreak
+		targets = append(targets, Target{ID: r.ID, URL: r.URL})
+	}
+
+	for _, res := range c.Run(ctx, targets) {
+		streak := 0
+		if res.Broken() {
+			streak = streaks[res.ID] + 1
+			broken++
+		}
+		err := repository.UpdateLinkStatus(res.ID, res.Status, res.RedirectTo, res.CheckedAt.UTC().Format(time.RFC3339), streak)
+		if err != nil {
+			fmt.Printf("⚠️ No se pudo guardar el estado del recurso %d: %v\n", res.ID, err)
+			continue
+		}
+		checked++
+	}
+	return checked, broken, nil
+}
+
+// StartScheduler revisa los enlaces cada LINKCHECK_INTERVAL (24h por defecto)
+func StartScheduler() {
+	interval := 24 * time.Hour
+	if d, err := time.ParseDuration(os.Getenv("LINKCHECK_INTERVAL")); err == nil && d > 0 {
+		interval = d
+	}
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			report(RunOnce(context.Background(), NewChecker()))
+		}
+	}()
+}
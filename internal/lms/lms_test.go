@@ -0,0 +1,199 @@
+package lms
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"english-at-lima-cms/internal/models"
+)
+
+func sampleQuizzes() []models.Quiz {
+	return []models.Quiz{
+		{ID: 1, Type: models.QuizSingle, Question: "Choose <the> right one", Options: []string{"look after", "look for"}, Answers: []string{"look after"},
+			Explanation: models.Note{ES: "**Cuidar** <script>x</script>"}, OptionNotes: []models.Note{{ES: "Bien"}, {}}},
+		{ID: 2, Type: models.QuizMultiple, Question: "Pick the verbs", Options: []string{"run", "table", "eat"}, Answers: []string{"run", "eat"}},
+		{ID: 3, Type: models.QuizTrueFalse, Question: "Lima is in Peru", Options: []string{"True", "False"}, Answers: []string{"True"}},
+		{ID: 4, Type: models.QuizFill, Question: "I ___ to Cusco last year.", Answers: []string{"went", "travelled"}},
+		{ID: 5, Type: models.QuizOrdering, Question: "Order the words", Options: []string{"I", "am", "here"}},
+	}
+}
+
+// rewrite copia el ZIP cambiando (o quitando, con nil) y añadiendo archivos
+func rewrite(t *testing.T, data []byte, files map[string][]byte) []byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	write := func(name string, body []byte) {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write(body)
+	}
+	for _, f := range zr.File {
+		body, ok := files[f.Name]
+		if !ok {
+			if body, err = readZipFile(f); err != nil {
+				t.Fatal(err)
+			}
+		}
+		delete(files, f.Name)
+		if body != nil {
+			write(f.Name, body)
+		}
+	}
+	for name, body := range files {
+		write(name, body)
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+func zipFile(t *testing.T, data []byte, name string) string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range zr.File {
+		if f.Name == name {
+			body, err := readZipFile(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return string(body)
+		}
+	}
+	t.Fatalf("falta %s en el paquete", name)
+	return ""
+}
+
+func TestValidate(t *testing.T) {
+	var scorm, qti bytes.Buffer
+	if err := WriteSCORM(&scorm, Package{Title: "English at Lima · Quizzes", Mastery: 80, Quizzes: sampleQuizzes()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteQTI(&qti, sampleQuizzes()); err != nil {
+		t.Fatal(err)
+	}
+	manifest := zipFile(t, scorm.Bytes(), ManifestName)
+	qtiManifest := zipFile(t, qti.Bytes(), ManifestName)
+
+	tests := []struct {
+		name  string
+		pkg   []byte
+		files map[string][]byte
+		err   string
+	}{
+		{"scorm", scorm.Bytes(), nil, ""},
+		{"qti", qti.Bytes(), nil, ""},
+		{"sin manifiesto", scorm.Bytes(), map[string][]byte{ManifestName: nil}, "falta imsmanifest.xml"},
+		{"archivo declarado que falta", scorm.Bytes(), map[string][]byte{"player.js": nil}, "player.js no está en el paquete"},
+		{"archivo sin declarar", scorm.Bytes(), map[string][]byte{"extra.js": []byte("x")}, "extra.js no está declarado"},
+		{"organización por defecto", scorm.Bytes(), map[string][]byte{ManifestName: []byte(strings.Replace(manifest, `default="eal-org"`, `default="otra"`, 1))}, "por defecto otra no existe"},
+		{"espacio de nombres", scorm.Bytes(), map[string][]byte{ManifestName: []byte(strings.Replace(manifest, nsSCORMCP+`"`, `http://example.com/cp"`, 1))}, "no un manifest"},
+		{"versión de SCORM", scorm.Bytes(), map[string][]byte{ManifestName: []byte(strings.Replace(manifest, "<schemaversion>1.2<", "<schemaversion>2004 4th Edition<", 1))}, "schemaversion 1.2"},
+		{"recurso inexistente", scorm.Bytes(), map[string][]byte{ManifestName: []byte(strings.Replace(manifest, `identifierref="eal-sco"`, `identifierref="nada"`, 1))}, "el recurso nada no existe"},
+		{"sin scormtype", scorm.Bytes(), map[string][]byte{ManifestName: []byte(strings.Replace(manifest, ` adlcp:scormtype="sco"`, "", 1))}, "necesita adlcp:scormtype"},
+		{"nota de aprobado", scorm.Bytes(), map[string][]byte{ManifestName: []byte(strings.Replace(manifest, "<adlcp:masteryscore>80<", "<adlcp:masteryscore>120<", 1))}, "masteryscore"},
+		{"identificador repetido", scorm.Bytes(), map[string][]byte{ManifestName: []byte(strings.Replace(manifest, `<item identifier="eal-item"`, `<item identifier="eal-sco"`, 1))}, "identifier repetido: eal-sco"},
+		{"ítem QTI roto", qti.Bytes(), map[string][]byte{"items/eal-quiz-4.xml": []byte(`<assessmentItem identifier="x"/>`)}, "no es un assessmentItem"},
+		{"tipo de recurso", qti.Bytes(), map[string][]byte{ManifestName: []byte(strings.Replace(qtiManifest, resourceQTIItem, "imsqti_item_xmlv1p2", 1))}, "tipo desconocido"},
+		{"no es ZIP", []byte("hola"), nil, "no es un ZIP"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkg := tt.pkg
+			if tt.files != nil {
+				pkg = rewrite(t, pkg, tt.files)
+			}
+			err := Validate(pkg)
+			switch {
+			case tt.err == "" && err != nil:
+				t.Fatalf("paquete válido rechazado: %v", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Fatalf("esperaba %q, obtuvo %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestQTIItems(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteQTI(&buf, sampleQuizzes()); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		id   int
+		want []string
+	}{
+		{1, []string{`cardinality="single"`, `<value>choice-1</value>`, `maxChoices="1"`, "Choose &lt;the&gt; right one", rpMatchCorrect}},
+		{2, []string{`cardinality="multiple"`, "<value>choice-1</value>\n      <value>choice-3</value>", `maxChoices="0"`}},
+		{3, []string{`shuffle="false"`, `<simpleChoice identifier="choice-1">True</simpleChoice>`}},
+		{4, []string{`baseType="string"`, `<mapEntry mapKey="travelled" mappedValue="1" caseSensitive="false"/>`, `<p>I <textEntryInteraction responseIdentifier="RESPONSE" expectedLength="11"/> to Cusco last year.</p>`, rpMapResponse}},
+		{5, []string{`cardinality="ordered"`, "<orderInteraction", "<value>choice-1</value>\n      <value>choice-2</value>\n      <value>choice-3</value>"}},
+	}
+	for _, tt := range tests {
+		item := zipFile(t, buf.Bytes(), "items/"+ItemID(models.Quiz{ID: tt.id})+".xml")
+		for _, w := range tt.want {
+			if !strings.Contains(item, w) {
+				t.Errorf("quiz %d: falta %q en\n%s", tt.id, w, item)
+			}
+		}
+	}
+	if err := WriteQTI(&bytes.Buffer{}, []models.Quiz{{ID: 9, Type: "essay"}}); err == nil {
+		t.Error("un tipo desconocido debería fallar")
+	}
+}
+
+func TestSCORMData(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteSCORM(&buf, Package{Title: "</script>", Mastery: 60, Quizzes: sampleQuizzes()}); err != nil {
+		t.Fatal(err)
+	}
+	js := zipFile(t, buf.Bytes(), dataFile)
+	if strings.Contains(js, "</script>") || strings.Contains(js, "<script>") {
+		t.Fatalf("quizzes.js sin escapar: %s", js)
+	}
+	raw, ok := strings.CutPrefix(strings.TrimSpace(js), "window.EAL_PACKAGE = ")
+	if !ok {
+		t.Fatalf("quizzes.js inesperado: %s", js)
+	}
+	var data playerData
+	if err := json.Unmarshal([]byte(strings.TrimSuffix(raw, ";")), &data); err != nil {
+		t.Fatal(err)
+	}
+	if data.Title != "</script>" || data.Mastery != 60 || len(data.Quizzes) != 5 {
+		t.Fatalf("datos inesperados: %+v", data)
+	}
+	first := data.Quizzes[0]
+	if first.ID != "eal-quiz-1" || !strings.Contains(string(first.Explanation), "<strong>Cuidar</strong>") || strings.Contains(string(first.Explanation), "<script>") {
+		t.Errorf("explicación sin convertir o sin sanear: %q", first.Explanation)
+	}
+	if len(first.OptionNotes) != 2 {
+		t.Errorf("notas por opción: %q", first.OptionNotes)
+	}
+	order := data.Quizzes[4]
+	if strings.Join(order.Answers, " ") != "I am here" || strings.Join(order.Options, " ") == "I am here" {
+		t.Errorf("ordenar debe llevar las opciones mezcladas y el orden correcto como respuesta: %+v", order)
+	}
+	for _, name := range playerAssets {
+		if zipFile(t, buf.Bytes(), name) == "" {
+			t.Errorf("%s vacío", name)
+		}
+	}
+	if !strings.Contains(zipFile(t, buf.Bytes(), ManifestName), "<adlcp:masteryscore>60</adlcp:masteryscore>") {
+		t.Error("falta la nota de aprobado en el manifiesto")
+	}
+
+	if err := WriteSCORM(&bytes.Buffer{}, Package{Title: "x", Mastery: 101}); err == nil {
+		t.Error("una nota de aprobado mayor que 100 debería fallar")
+	}
+}
@@ -0,0 +1,306 @@
+package lms
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+)
+
+// Espacios de nombres de los manifiestos
+const (
+	nsCP      = "http://www.imsglobal.org/xsd/imscp_v1p1"        // IMS Content Packaging 1.1 (QTI)
+	nsSCORMCP = "http://www.imsproject.org/xsd/imscp_rootv1p1p2" // el que exige SCORM 1.2
+	nsADLCP   = "http://www.adlnet.org/xsd/adlcp_rootv1p2"       // extensiones ADL de SCORM 1.2
+	nsXSI     = "http://www.w3.org/2001/XMLSchema-instance"
+	nsQTI     = "http://www.imsglobal.org/xsd/imsqti_v2p1" // ítems QTI 2.1
+	qtiSchema = "http://www.imsglobal.org/xsd/qti/qtiv2p1/imsqti_v2p1.xsd"
+)
+
+// Tipos de recurso del manifiesto
+const (
+	resourceQTIItem = "imsqti_item_xmlv2p1"
+	resourceWeb     = "webcontent"
+)
+
+// ManifestName es el nombre del manifiesto en la raíz del paquete
+const ManifestName = "imsmanifest.xml"
+
+// Estructuras para escribir el manifiesto: los prefijos van tal cual en los
+// nombres porque encoding/xml no los genera solo
+
+type cpManifest struct {
+	XMLName        xml.Name        `xml:"manifest"`
+	Xmlns          string          `xml:"xmlns,attr"`
+	XmlnsADLCP     string          `xml:"xmlns:adlcp,attr,omitempty"`
+	XmlnsXSI       string          `xml:"xmlns:xsi,attr"`
+	SchemaLocation string          `xml:"xsi:schemaLocation,attr"`
+	Identifier     string          `xml:"identifier,attr"`
+	Version        string          `xml:"version,attr"`
+	Metadata       cpMetadata      `xml:"metadata"`
+	Organizations  cpOrganizations `xml:"organizations"`
+	Resources      []cpResource    `xml:"resources>resource"`
+}
+
+type cpMetadata struct {
+	Schema        string `xml:"schema"`
+	SchemaVersion string `xml:"schemaversion"`
+}
+
+type cpOrganizations struct {
+	Default       string           `xml:"default,attr,omitempty"`
+	Organizations []cpOrganization `xml:"organization"`
+}
+
+type cpOrganization struct {
+	Identifier string   `xml:"identifier,attr"`
+	Title      string   `xml:"title"`
+	Items      []cpItem `xml:"item"`
+}
+
+type cpItem struct {
+	Identifier    string `xml:"identifier,attr"`
+	IdentifierRef string `xml:"identifierref,attr"`
+	IsVisible     string `xml:"isvisible,attr"`
+	Title         string `xml:"title"`
+	MasteryScore  string `xml:"adlcp:masteryscore,omitempty"`
+}
+
+type cpResource struct {
+	Identifier string   `xml:"identifier,attr"`
+	Type       string   `xml:"type,attr"`
+	SCORMType  string   `xml:"adlcp:scormtype,attr,omitempty"`
+	Href       string   `xml:"href,attr"`
+	Files      []cpFile `xml:"file"`
+}
+
+type cpFile struct {
+	Href string `xml:"href,attr"`
+}
+
+// writeXML escribe la cabecera y el documento con sangría
+func writeXML(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// Estructuras para leer y validar: aquí los nombres van con su espacio de
+// nombres completo, así un prefijo mal declarado no pasa la validación
+
+type readManifest struct {
+	XMLName       xml.Name
+	Identifier    string `xml:"identifier,attr"`
+	Schema        string `xml:"metadata>schema"`
+	SchemaVersion string `xml:"metadata>schemaversion"`
+	Organizations struct {
+		Default       string `xml:"default,attr"`
+		Organizations []struct {
+			Identifier string     `xml:"identifier,attr"`
+			Title      string     `xml:"title"`
+			Items      []readItem `xml:"item"`
+		} `xml:"organization"`
+	} `xml:"organizations"`
+	Resources []struct {
+		Identifier string `xml:"identifier,attr"`
+		Type       string `xml:"type,attr"`
+		SCORMType  string `xml:"http://www.adlnet.org/xsd/adlcp_rootv1p2 scormtype,attr"`
+		Href       string `xml:"href,attr"`
+		Files      []struct {
+			Href string `xml:"href,attr"`
+		} `xml:"file"`
+	} `xml:"resources>resource"`
+}
+
+type readItem struct {
+	Identifier    string     `xml:"identifier,attr"`
+	IdentifierRef string     `xml:"identifierref,attr"`
+	Title         string     `xml:"title"`
+	MasteryScore  string     `xml:"http://www.adlnet.org/xsd/adlcp_rootv1p2 masteryscore"`
+	Items         []readItem `xml:"item"`
+}
+
+// Validate revisa la estructura de un paquete (SCORM 1.2 o banco QTI 2.1):
+// el manifiesto en la raíz con su espacio de nombres, identificadores
+// únicos, organización por defecto existente, ítems que apuntan a recursos
+// declarados (SCO con página de inicio en SCORM), cada archivo declarado
+// presente en el ZIP y ningún archivo del ZIP sin declarar.
+func Validate(data []byte) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("el paquete no es un ZIP válido: %v", err)
+	}
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	mf, ok := files[ManifestName]
+	if !ok {
+		return errors.New("falta imsmanifest.xml en la raíz del paquete")
+	}
+	raw, err := readZipFile(mf)
+	if err != nil {
+		return err
+	}
+	var m readManifest
+	if err := xml.Unmarshal(raw, &m); err != nil {
+		return fmt.Errorf("imsmanifest.xml no es XML válido: %v", err)
+	}
+	if m.XMLName.Local != "manifest" || (m.XMLName.Space != nsCP && m.XMLName.Space != nsSCORMCP) {
+		return fmt.Errorf("la raíz del manifiesto es {%s}%s, no un manifest de IMS Content Packaging", m.XMLName.Space, m.XMLName.Local)
+	}
+	if m.Identifier == "" {
+		return errors.New("el manifiesto no tiene identifier")
+	}
+	scorm := m.Schema == "ADL SCORM"
+	if scorm && (m.SchemaVersion != "1.2" || m.XMLName.Space != nsSCORMCP) {
+		return fmt.Errorf("un paquete SCORM 1.2 debe declarar schemaversion 1.2 y el espacio %s", nsSCORMCP)
+	}
+	if m.Schema == "" || m.SchemaVersion == "" {
+		return errors.New("faltan metadata/schema y schemaversion")
+	}
+
+	ids := map[string]bool{m.Identifier: true}
+	unique := func(id, what string) error {
+		if id == "" {
+			return fmt.Errorf("%s sin identifier", what)
+		}
+		if ids[id] {
+			return fmt.Errorf("identifier repetido: %s", id)
+		}
+		ids[id] = true
+		return nil
+	}
+
+	resources := map[string]int{}
+	declared := map[string]bool{ManifestName: true}
+	for i, r := range m.Resources {
+		if err := unique(r.Identifier, "recurso"); err != nil {
+			return err
+		}
+		resources[r.Identifier] = i
+		if r.Type != resourceWeb && r.Type != resourceQTIItem {
+			return fmt.Errorf("recurso %s: tipo desconocido %q", r.Identifier, r.Type)
+		}
+		listed := false
+		for _, f := range r.Files {
+			if _, ok := files[f.Href]; !ok || path.Clean(f.Href) != f.Href {
+				return fmt.Errorf("recurso %s: el archivo %s no está en el paquete", r.Identifier, f.Href)
+			}
+			declared[f.Href] = true
+			listed = listed || f.Href == r.Href
+		}
+		if r.Href != "" && !listed {
+			return fmt.Errorf("recurso %s: la página de inicio %s no está entre sus archivos", r.Identifier, r.Href)
+		}
+		if r.Type == resourceQTIItem {
+			if err := validateQTIItem(files[r.Href]); err != nil {
+				return fmt.Errorf("recurso %s: %v", r.Identifier, err)
+			}
+		}
+	}
+	for name, f := range files {
+		if !declared[name] && !f.FileInfo().IsDir() {
+			return fmt.Errorf("el archivo %s no está declarado en el manifiesto", name)
+		}
+	}
+
+	orgs := m.Organizations
+	if scorm && len(orgs.Organizations) == 0 {
+		return errors.New("un paquete SCORM necesita al menos una organización")
+	}
+	defaultFound := orgs.Default == ""
+	var checkItems func([]readItem) error
+	checkItems = func(items []readItem) error {
+		for _, it := range items {
+			if err := unique(it.Identifier, "ítem"); err != nil {
+				return err
+			}
+			if it.Title == "" {
+				return fmt.Errorf("ítem %s sin título", it.Identifier)
+			}
+			if it.MasteryScore != "" {
+				if n, err := strconv.Atoi(it.MasteryScore); err != nil || n < 0 || n > 100 {
+					return fmt.Errorf("ítem %s: masteryscore debe ser un entero entre 0 y 100", it.Identifier)
+				}
+			}
+			if it.IdentifierRef != "" {
+				i, ok := resources[it.IdentifierRef]
+				if !ok {
+					return fmt.Errorf("ítem %s: el recurso %s no existe", it.Identifier, it.IdentifierRef)
+				}
+				if r := m.Resources[i]; scorm && ((r.SCORMType != "sco" && r.SCORMType != "asset") || r.Href == "") {
+					return fmt.Errorf("ítem %s: el recurso %s necesita adlcp:scormtype y href", it.Identifier, r.Identifier)
+				}
+			}
+			if err := checkItems(it.Items); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, o := range orgs.Organizations {
+		if err := unique(o.Identifier, "organización"); err != nil {
+			return err
+		}
+		defaultFound = defaultFound || o.Identifier == orgs.Default
+		if scorm && len(o.Items) == 0 {
+			return fmt.Errorf("la organización %s no tiene ítems", o.Identifier)
+		}
+		if err := checkItems(o.Items); err != nil {
+			return err
+		}
+	}
+	if !defaultFound {
+		return fmt.Errorf("la organización por defecto %s no existe", orgs.Default)
+	}
+	return nil
+}
+
+// validateQTIItem comprueba que el archivo sea un assessmentItem de QTI 2.1
+func validateQTIItem(f *zip.File) error {
+	if f == nil {
+		return errors.New("ítem QTI sin archivo")
+	}
+	raw, err := readZipFile(f)
+	if err != nil {
+		return err
+	}
+	var item struct {
+		XMLName    xml.Name
+		Identifier string `xml:"identifier,attr"`
+		Responses  []struct {
+			Identifier string `xml:"identifier,attr"`
+		} `xml:"responseDeclaration"`
+	}
+	if err := xml.Unmarshal(raw, &item); err != nil {
+		return fmt.Errorf("%s no es XML válido: %v", f.Name, err)
+	}
+	if item.XMLName.Space != nsQTI || item.XMLName.Local != "assessmentItem" || item.Identifier == "" {
+		return fmt.Errorf("%s no es un assessmentItem de QTI 2.1", f.Name)
+	}
+	if len(item.Responses) == 0 {
+		return fmt.Errorf("%s no declara la respuesta", f.Name)
+	}
+	return nil
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
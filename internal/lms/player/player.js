@@ -0,0 +1,251 @@
+// Reproductor de quizzes de English at Lima para SCORM 1.2. Corrige en el
+// navegador con las mismas reglas que quiz.Grade y envía al LMS la nota
+// (cmi.core.score.raw, de 0 a 100), el estado (passed/failed según la nota
+// de aprobado) y el resultado de cada pregunta (cmi.interactions).
+(function () {
+    "use strict";
+
+    var data = window.EAL_PACKAGE || { title: "", mastery: 70, quizzes: [] };
+    var form = document.getElementById("quiz-form");
+    var container = document.getElementById("questions");
+    var result = document.getElementById("result");
+    var submit = document.getElementById("submit");
+    var retry = document.getElementById("retry");
+    var started = new Date().getTime();
+    var finished = false;
+
+    // --- API de SCORM 1.2: window.API en esta ventana, sus padres o quien la abrió ---
+
+    function findAPI(win) {
+        for (var tries = 0; win && tries < 10; tries++) {
+            try {
+                if (win.API) {
+                    return win.API;
+                }
+            } catch (e) {
+                return null; // otra procedencia: no hay API accesible
+            }
+            if (!win.parent || win.parent === win) {
+                break;
+            }
+            win = win.parent;
+        }
+        return null;
+    }
+
+    var api = findAPI(window) || (window.opener ? findAPI(window.opener) : null);
+
+    function set(key, value) {
+        if (api) {
+            api.LMSSetValue(key, String(value));
+        }
+    }
+
+    if (api && String(api.LMSInitialize("")) === "true") {
+        var status = api.LMSGetValue("cmi.core.lesson_status");
+        if (status === "" || status === "not attempted") {
+            set("cmi.core.lesson_status", "incomplete");
+            api.LMSCommit("");
+        }
+        document.getElementById("lms-status").textContent = "Conectado a la plataforma: la nota se guardará al enviar.";
+    } else {
+        api = null;
+        document.getElementById("lms-status").textContent = "Sin plataforma (LMS): la nota sólo se muestra aquí.";
+    }
+
+    // sessionTime da el formato HHHH:MM:SS de SCORM 1.2
+    function sessionTime() {
+        var s = Math.round((new Date().getTime() - started) / 1000);
+        function pad(n, size) {
+            n = String(n);
+            while (n.length < size) {
+                n = "0" + n;
+            }
+            return n;
+        }
+        return pad(Math.floor(s / 3600), 4) + ":" + pad(Math.floor(s / 60) % 60, 2) + ":" + pad(s % 60, 2);
+    }
+
+    function finish() {
+        if (!api || finished) {
+            return;
+        }
+        finished = true;
+        set("cmi.core.session_time", sessionTime());
+        api.LMSCommit("");
+        api.LMSFinish("");
+    }
+    window.addEventListener("pagehide", finish);
+    window.addEventListener("beforeunload", finish);
+
+    // --- Corrección (la misma que quiz.Canonical y quiz.Grade) ---
+
+    // canonical compara sin mayúsculas, espacios repetidos ni puntuación final
+    function canonical(s) {
+        s = String(s).toLowerCase().split(/\s+/).filter(Boolean).join(" ");
+        while (s.length && s.charAt(s.length - 1) !== "'" && /\p{P}$/u.test(s)) {
+            s = s.slice(0, -1);
+        }
+        return s;
+    }
+
+    function grade(q, response) {
+        if (q.type === "fill") {
+            if (response.length !== 1) {
+                return false;
+            }
+            return q.answers.some(function (a) { return canonical(a) === canonical(response[0]); });
+        }
+        if (q.type === "ordering") {
+            return response.length === q.answers.length &&
+                response.every(function (r, i) { return r === q.answers[i]; });
+        }
+        var chosen = {};
+        var count = 0;
+        response.forEach(function (r) {
+            if (!chosen[r]) {
+                chosen[r] = true;
+                count++;
+            }
+        });
+        return count === q.answers.length && q.answers.every(function (a) { return chosen[a]; });
+    }
+
+    // --- Pintado ---
+
+    var labels = { True: "Verdadero", False: "Falso" };
+
+    function el(tag, attrs, text) {
+        var node = document.createElement(tag);
+        Object.keys(attrs || {}).forEach(function (k) { node.setAttribute(k, attrs[k]); });
+        if (text !== undefined) {
+            node.textContent = text;
+        }
+        return node;
+    }
+
+    function moveButton(li, delta, text) {
+        var b = el("button", { type: "button", "aria-label": delta < 0 ? "Subir" : "Bajar" }, text);
+        b.addEventListener("click", function () {
+            var list = li.parentNode;
+            if (delta < 0 && li.previousElementSibling) {
+                list.insertBefore(li, li.previousElementSibling);
+            } else if (delta > 0 && li.nextElementSibling) {
+                list.insertBefore(li.nextElementSibling, li);
+            }
+        });
+        return b;
+    }
+
+    function render() {
+        document.title = data.title || document.title;
+        document.getElementById("title").textContent = data.title || "English at Lima";
+        container.textContent = "";
+        data.quizzes.forEach(function (q, i) {
+            var fs = el("fieldset", { "data-index": i });
+            fs.appendChild(el("legend", {}, (i + 1) + ". " + q.question));
+            var name = "q" + i;
+            if (q.type === "fill") {
+                fs.appendChild(el("input", { type: "text", name: name, autocomplete: "off", "aria-label": "Respuesta" }));
+            } else if (q.type === "ordering") {
+                var list = el("ol", { "class": "order" });
+                q.options.forEach(function (opt) {
+                    var li = el("li", { "data-value": opt });
+                    li.appendChild(el("span", {}, opt));
+                    li.appendChild(moveButton(li, -1, "↑"));
+                    li.appendChild(moveButton(li, 1, "↓"));
+                    list.appendChild(li);
+                });
+                fs.appendChild(list);
+            } else {
+                var kind = q.type === "multiple" ? "checkbox" : "radio";
+                q.options.forEach(function (opt) {
+                    var label = el("label", { "class": "option" });
+                    label.appendChild(el("input", { type: kind, name: name, value: opt }));
+                    label.appendChild(document.createTextNode(" " + (labels[opt] && q.type === "truefalse" ? labels[opt] : opt)));
+                    fs.appendChild(label);
+                });
+            }
+            fs.appendChild(el("div", { "class": "feedback", hidden: "" }));
+            container.appendChild(fs);
+        });
+        result.hidden = true;
+        submit.disabled = false;
+        retry.hidden = true;
+    }
+
+    function responseOf(fs, q) {
+        if (q.type === "fill") {
+            return [fs.querySelector("input").value];
+        }
+        if (q.type === "ordering") {
+            return Array.prototype.map.call(fs.querySelectorAll("li"), function (li) { return li.getAttribute("data-value"); });
+        }
+        return Array.prototype.filter.call(fs.querySelectorAll("input"), function (input) { return input.checked; })
+            .map(function (input) { return input.value; });
+    }
+
+    function showFeedback(fs, q, response, ok) {
+        var box = fs.querySelector(".feedback");
+        box.className = "feedback " + (ok ? "correct" : "wrong");
+        box.textContent = ok ? "✅ Correcto" : "❌ Respuesta correcta: " + q.answers.map(function (a) {
+            return q.type === "truefalse" && labels[a] ? labels[a] : a;
+        }).join(q.type === "ordering" ? " → " : " / ");
+        // Las notas llegan ya saneadas por el CMS (richtext.Render)
+        (q.option_notes || []).forEach(function (note, i) {
+            if (note && response.indexOf(q.options[i]) >= 0) {
+                var div = el("div");
+                div.innerHTML = note;
+                box.appendChild(div);
+            }
+        });
+        if (q.explanation) {
+            var exp = el("div");
+            exp.innerHTML = q.explanation;
+            box.appendChild(exp);
+        }
+        box.hidden = false;
+        Array.prototype.forEach.call(fs.querySelectorAll("input, button"), function (input) { input.disabled = true; });
+    }
+
+    var interactionTypes = { single: "choice", multiple: "choice", truefalse: "true-false", fill: "fill-in", ordering: "sequencing" };
+
+    form.addEventListener("submit", function (ev) {
+        ev.preventDefault();
+        var right = 0;
+        var base = api ? parseInt(api.LMSGetValue("cmi.interactions._count"), 10) || 0 : 0;
+        data.quizzes.forEach(function (q, i) {
+            var fs = container.querySelector('fieldset[data-index="' + i + '"]');
+            var response = responseOf(fs, q);
+            var ok = grade(q, response);
+            if (ok) {
+                right++;
+            }
+            showFeedback(fs, q, response, ok);
+            var prefix = "cmi.interactions." + (base + i) + ".";
+            set(prefix + "id", q.id);
+            set(prefix + "type", interactionTypes[q.type] || "choice");
+            set(prefix + "result", ok ? "correct" : "wrong");
+        });
+
+        var score = data.quizzes.length ? Math.round(100 * right / data.quizzes.length) : 0;
+        var passed = score >= data.mastery;
+        set("cmi.core.score.min", 0);
+        set("cmi.core.score.max", 100);
+        set("cmi.core.score.raw", score);
+        set("cmi.core.lesson_status", passed ? "passed" : "failed");
+        set("cmi.core.session_time", sessionTime());
+        if (api) {
+            api.LMSCommit("");
+        }
+
+        result.textContent = "Puntuación: " + score + " / 100 (" + right + " de " + data.quizzes.length + " correctas) · " +
+            (passed ? "Aprobado" : "Necesitas " + data.mastery + " para aprobar");
+        result.hidden = false;
+        submit.disabled = true;
+        retry.hidden = false;
+    });
+
+    retry.addEventListener("click", render);
+    render();
+})();
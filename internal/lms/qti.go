@@ -0,0 +1,166 @@
+// Package lms arma paquetes para plataformas de aprendizaje: bancos de ítems
+// IMS QTI 2.1 y paquetes SCORM 1.2 con un reproductor HTML/JS que corrige
+// los quizzes en el navegador y envía la nota al LMS por la API de SCORM.
+package lms
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"english-at-lima-cms/internal/models"
+	"english-at-lima-cms/internal/quiz"
+)
+
+// Plantillas de corrección estándar de QTI 2.1
+const (
+	rpMatchCorrect = "http://www.imsglobal.org/question/qti_v2p1/rptemplates/match_correct"
+	rpMapResponse  = "http://www.imsglobal.org/question/qti_v2p1/rptemplates/map_response"
+)
+
+// ItemID es el identificador del quiz en los paquetes
+func ItemID(q models.Quiz) string { return fmt.Sprintf("eal-quiz-%d", q.ID) }
+
+// WriteQTI escribe el banco de ítems: un assessmentItem de QTI 2.1 por quiz
+// en items/ y el manifiesto IMS Content Packaging que los declara
+func WriteQTI(w io.Writer, quizzes []models.Quiz) error {
+	zw := zip.NewWriter(w)
+	m := cpManifest{
+		Xmlns:          nsCP,
+		XmlnsXSI:       nsXSI,
+		SchemaLocation: nsCP + " http://www.imsglobal.org/xsd/imscp_v1p1.xsd",
+		Identifier:     "english-at-lima-qti",
+		Version:        "1.0",
+		Metadata:       cpMetadata{Schema: "QTIv2.1 Package", SchemaVersion: "1.0.0"},
+	}
+	items := map[string][]byte{}
+	for _, q := range quizzes {
+		id := ItemID(q)
+		href := "items/" + id + ".xml"
+		body, err := qtiItem(q)
+		if err != nil {
+			return fmt.Errorf("quiz %d: %v", q.ID, err)
+		}
+		items[href] = body
+		m.Resources = append(m.Resources, cpResource{Identifier: id, Type: resourceQTIItem, Href: href, Files: []cpFile{{Href: href}}})
+	}
+
+	f, err := zw.Create(ManifestName)
+	if err != nil {
+		return err
+	}
+	if err := writeXML(f, m); err != nil {
+		return err
+	}
+	for _, r := range m.Resources {
+		f, err := zw.Create(r.Href)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(items[r.Href]); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// qtiItem escribe el quiz como assessmentItem. Elegir (única, múltiple,
+// verdadero/falso) es una choiceInteraction, ordenar una orderInteraction y
+// completar el hueco una textEntryInteraction en el lugar del ___ que
+// acepta cualquiera de las variantes sin distinguir mayúsculas.
+func qtiItem(q models.Quiz) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	fmt.Fprintf(&b, `<assessmentItem xmlns="%s" xmlns:xsi="%s" xsi:schemaLocation="%s %s" identifier="%s" title="%s" adaptive="false" timeDependent="false" xml:lang="en">`+"\n",
+		nsQTI, nsXSI, nsQTI, qtiSchema, ItemID(q), esc(itemTitle(q.Question)))
+
+	choice := func(i int) string { return fmt.Sprintf("choice-%d", i+1) }
+	switch q.Type {
+	case models.QuizSingle, models.QuizTrueFalse, models.QuizMultiple, models.QuizOrdering:
+		cardinality := map[string]string{models.QuizMultiple: "multiple", models.QuizOrdering: "ordered"}[q.Type]
+		if cardinality == "" {
+			cardinality = "single"
+		}
+		fmt.Fprintf(&b, `  <responseDeclaration identifier="RESPONSE" cardinality="%s" baseType="identifier">`+"\n    <correctResponse>\n", cardinality)
+		for i, opt := range q.Options {
+			if q.Type == models.QuizOrdering || slices.Contains(q.Answers, opt) {
+				fmt.Fprintf(&b, "      <value>%s</value>\n", choice(i))
+			}
+		}
+		b.WriteString("    </correctResponse>\n  </responseDeclaration>\n")
+		writeScore(&b)
+
+		b.WriteString("  <itemBody>\n")
+		switch q.Type {
+		case models.QuizOrdering:
+			b.WriteString(`    <orderInteraction responseIdentifier="RESPONSE" shuffle="true">` + "\n")
+		default:
+			maxChoices := 1
+			if q.Type == models.QuizMultiple {
+				maxChoices = 0 // sin límite
+			}
+			fmt.Fprintf(&b, `    <choiceInteraction responseIdentifier="RESPONSE" shuffle="%t" maxChoices="%d">`+"\n", q.Type != models.QuizTrueFalse, maxChoices)
+		}
+		fmt.Fprintf(&b, "      <prompt>%s</prompt>\n", esc(q.Question))
+		for i, opt := range q.Options {
+			fmt.Fprintf(&b, `      <simpleChoice identifier="%s">%s</simpleChoice>`+"\n", choice(i), esc(opt))
+		}
+		if q.Type == models.QuizOrdering {
+			b.WriteString("    </orderInteraction>\n")
+		} else {
+			b.WriteString("    </choiceInteraction>\n")
+		}
+		b.WriteString("  </itemBody>\n")
+		fmt.Fprintf(&b, `  <responseProcessing template="%s"/>`+"\n", rpMatchCorrect)
+
+	case models.QuizFill:
+		if len(q.Answers) == 0 {
+			return nil, fmt.Errorf("completar el hueco sin respuestas aceptadas")
+		}
+		b.WriteString(`  <responseDeclaration identifier="RESPONSE" cardinality="single" baseType="string">` + "\n")
+		fmt.Fprintf(&b, "    <correctResponse>\n      <value>%s</value>\n    </correctResponse>\n", esc(q.Answers[0]))
+		b.WriteString(`    <mapping defaultValue="0">` + "\n")
+		longest := 0
+		for _, a := range q.Answers {
+			fmt.Fprintf(&b, `      <mapEntry mapKey="%s" mappedValue="1" caseSensitive="false"/>`+"\n", esc(a))
+			longest = max(longest, utf8.RuneCountInString(a))
+		}
+		b.WriteString("    </mapping>\n  </responseDeclaration>\n")
+		writeScore(&b)
+		before, after, _ := strings.Cut(q.Question, quiz.Blank)
+		fmt.Fprintf(&b, `  <itemBody>`+"\n"+`    <p>%s<textEntryInteraction responseIdentifier="RESPONSE" expectedLength="%d"/>%s</p>`+"\n  </itemBody>\n",
+			esc(before), longest+2, esc(after))
+		fmt.Fprintf(&b, `  <responseProcessing template="%s"/>`+"\n", rpMapResponse)
+
+	default:
+		return nil, fmt.Errorf("tipo de quiz desconocido: %s", q.Type)
+	}
+	b.WriteString("</assessmentItem>\n")
+	return b.Bytes(), nil
+}
+
+func writeScore(b *bytes.Buffer) {
+	b.WriteString(`  <outcomeDeclaration identifier="SCORE" cardinality="single" baseType="float">` + "\n")
+	b.WriteString("    <defaultValue>\n      <value>0</value>\n    </defaultValue>\n  </outcomeDeclaration>\n")
+}
+
+// itemTitle es el título corto del ítem en el banco del LMS
+func itemTitle(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= 60 {
+		return text
+	}
+	return string([]rune(text)[:59]) + "…"
+}
+
+// esc escapa el texto para XML (también las comillas, vale para atributos)
+func esc(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,149 @@
+package lms
+
+import (
+	"archive/zip"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+
+	"english-at-lima-cms/internal/models"
+	"english-at-lima-cms/internal/richtext"
+)
+
+// DefaultMastery es la nota mínima (0–100) para aprobar el SCO
+const DefaultMastery = 70
+
+// playerFiles son los archivos fijos del reproductor; quizzes.js se genera
+//
+//go:embed player/*
+var playerFiles embed.FS
+
+// playerAssets son los archivos del reproductor en el orden del manifiesto
+var playerAssets = []string{"index.html", "player.css", "player.js"}
+
+// dataFile es el archivo con los quizzes que lee el reproductor
+const dataFile = "quizzes.js"
+
+// Package describe el paquete SCORM: un único SCO con todos los quizzes
+type Package struct {
+	Title   string
+	Mastery int
+	Quizzes []models.Quiz
+}
+
+// playerQuiz es el quiz tal como lo necesita el reproductor. En ordenar,
+// Options va mezclado y Answers es el orden correcto; las notas ya van
+// convertidas en HTML seguro.
+type playerQuiz struct {
+	ID          string          `json:"id"`
+	Question    string          `json:"question"`
+	Type        string          `json:"type"`
+	Options     []string        `json:"options"`
+	Answers     []string        `json:"answers"`
+	Explanation template.HTML   `json:"explanation,omitempty"`
+	OptionNotes []template.HTML `json:"option_notes,omitempty"`
+}
+
+type playerData struct {
+	Title   string       `json:"title"`
+	Mastery int          `json:"mastery"`
+	Quizzes []playerQuiz `json:"quizzes"`
+}
+
+func toPlayer(q models.Quiz) playerQuiz {
+	pq := playerQuiz{ID: ItemID(q), Question: q.Question, Type: q.Type, Options: q.Options, Answers: q.Answers}
+	if q.Type == models.QuizOrdering {
+		pq.Options, pq.Answers = q.Scrambled(), q.Options
+	}
+	if q.Explanation.ES != "" {
+		pq.Explanation = richtext.Render(q.Explanation.ES)
+	}
+	for _, n := range q.OptionNotes {
+		pq.OptionNotes = append(pq.OptionNotes, richtext.Render(n.ES))
+	}
+	return pq
+}
+
+// scormManifest declara el SCO con su nota de aprobado y todos sus archivos
+func scormManifest(p Package) cpManifest {
+	files := []cpFile{}
+	for _, name := range append(playerAssets, dataFile) {
+		files = append(files, cpFile{Href: name})
+	}
+	return cpManifest{
+		Xmlns:      nsSCORMCP,
+		XmlnsADLCP: nsADLCP,
+		XmlnsXSI:   nsXSI,
+		SchemaLocation: nsSCORMCP + " imscp_rootv1p1p2.xsd " +
+			nsADLCP + " adlcp_rootv1p2.xsd",
+		Identifier: "english-at-lima-scorm",
+		Version:    "1.0",
+		Metadata:   cpMetadata{Schema: "ADL SCORM", SchemaVersion: "1.2"},
+		Organizations: cpOrganizations{
+			Default: "eal-org",
+			Organizations: []cpOrganization{{
+				Identifier: "eal-org",
+				Title:      p.Title,
+				Items: []cpItem{{
+					Identifier: "eal-item", IdentifierRef: "eal-sco", IsVisible: "true",
+					Title: p.Title, MasteryScore: strconv.Itoa(p.Mastery),
+				}},
+			}},
+		},
+		Resources: []cpResource{{
+			Identifier: "eal-sco", Type: resourceWeb, SCORMType: "sco", Href: playerAssets[0], Files: files,
+		}},
+	}
+}
+
+// WriteSCORM escribe el ZIP de SCORM 1.2: imsmanifest.xml, el reproductor
+// y quizzes.js con las preguntas y sus respuestas. La corrección ocurre en
+// el navegador, así que las respuestas viajan en el paquete.
+func WriteSCORM(w io.Writer, p Package) error {
+	if p.Mastery < 0 || p.Mastery > 100 {
+		return fmt.Errorf("la nota de aprobado debe estar entre 0 y 100")
+	}
+	zw := zip.NewWriter(w)
+	f, err := zw.Create(ManifestName)
+	if err != nil {
+		return err
+	}
+	if err := writeXML(f, scormManifest(p)); err != nil {
+		return err
+	}
+
+	for _, name := range playerAssets {
+		data, err := playerFiles.ReadFile("player/" + name)
+		if err != nil {
+			return err
+		}
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(data); err != nil {
+			return err
+		}
+	}
+
+	data := playerData{Title: p.Title, Mastery: p.Mastery, Quizzes: []playerQuiz{}}
+	for _, q := range p.Quizzes {
+		data.Quizzes = append(data.Quizzes, toPlayer(q))
+	}
+	// json.Marshal escapa < > &, así ningún texto puede cerrar el <script>
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	f, err = zw.Create(dataFile)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(f, "window.EAL_PACKAGE = %s;\n", raw); err != nil {
+		return err
+	}
+	return zw.Close()
+}
@@ -0,0 +1,246 @@
+package lti
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"english-at-lima-cms/internal/models"
+)
+
+// ScopeScore es el permiso de AGS para publicar notas
+const ScopeScore = "https://purl.imsglobal.org/spec/lti-ags/scope/score"
+
+const (
+	contentTypeScore = "application/vnd.ims.lis.v1.score+json"
+	assertionType    = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+)
+
+// Score es la nota de un alumno para el lineitem del enlace
+type Score struct {
+	UserID           string  `json:"userId"`
+	ScoreGiven       float64 `json:"scoreGiven"`
+	ScoreMaximum     float64 `json:"scoreMaximum"`
+	Comment          string  `json:"comment,omitempty"`
+	Timestamp        string  `json:"timestamp"`
+	ActivityProgress string  `json:"activityProgress"`
+	GradingProgress  string  `json:"gradingProgress"`
+}
+
+type cachedToken struct {
+	token   string
+	expires time.Time
+}
+
+// tokenCache guarda el token OAuth2 de cada plataforma hasta poco antes de
+// que caduque: cada respuesta del alumno no debe pedir uno nuevo
+type tokenCache struct {
+	mu     sync.Mutex
+	tokens map[string]cachedToken
+}
+
+func (c *tokenCache) get(key string, now time.Time) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t, ok := c.tokens[key]; ok && now.Before(t.expires) {
+		return t.token
+	}
+	return ""
+}
+
+func (c *tokenCache) put(key, token string, expires time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.tokens == nil {
+		c.tokens = map[string]cachedToken{}
+	}
+	c.tokens[key] = cachedToken{token: token, expires: expires}
+}
+
+func (c *tokenCache) drop(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.tokens, key)
+}
+
+func tokenKey(p models.LTIPlatform, scope string) string {
+	return p.Issuer + " " + p.ClientID + " " + scope
+}
+
+// accessToken pide el token de servicio con client_credentials, firmando
+// la aserción con la clave de la herramienta (la plataforma la verifica
+// con nuestro JWKS)
+func (t *Tool) accessToken(p models.LTIPlatform, scope string) (string, error) {
+	key := tokenKey(p, scope)
+	now := t.Now()
+	if tok := t.tokens.get(key, now); tok != "" {
+		return tok, nil
+	}
+	assertion, err := signJWT(t.Key, t.KeyID, map[string]interface{}{
+		"iss": p.ClientID, "sub": p.ClientID, "aud": p.AuthTokenURL,
+		"iat": now.Unix(), "exp": now.Add(5 * time.Minute).Unix(), "jti": randomID(),
+	})
+	if err != nil {
+		return "", err
+	}
+	resp, err := t.Client.PostForm(p.AuthTokenURL, url.Values{
+		"grant_type":            {"client_credentials"},
+		"client_assertion_type": {assertionType},
+		"client_assertion":      {assertion},
+		"scope":                 {scope},
+	})
+	if err != nil {
+		return "", fmt.Errorf("no se pudo pedir el token a la plataforma: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("la plataforma rechazó el pedido de token: %s %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var out struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&out); err != nil || out.AccessToken == "" {
+		return "", errors.New("respuesta de token de la plataforma ilegible")
+	}
+	if out.ExpiresIn <= 0 {
+		out.ExpiresIn = 3600
+	}
+	// Un margen para no usar un token que caduca a medio camino
+	t.tokens.put(key, out.AccessToken, now.Add(time.Duration(out.ExpiresIn)*time.Second-30*time.Second))
+	return out.AccessToken, nil
+}
+
+// scoresURL añade /scores a la ruta del lineitem, que puede traer query
+func scoresURL(lineItem string) (string, error) {
+	u, err := url.Parse(lineItem)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return "", fmt.Errorf("lineitem inválido: %q", lineItem)
+	}
+	u.Path = strings.TrimRight(u.Path, "/") + "/scores"
+	return u.String(), nil
+}
+
+// GradeStore recuerda qué intentos ya tienen nota. Sin él la herramienta lo
+// recuerda en memoria y un reinicio permitiría calificar de nuevo.
+type GradeStore interface {
+	// Claim reserva el intento; devuelve false si ya tenía nota
+	Claim(attempt string, expires time.Time) (bool, error)
+	// Release libera el intento cuando la nota no llegó al LMS
+	Release(attempt string) error
+}
+
+// GradeOnce publica la nota sólo la primera vez que se responde con la
+// sesión: reenviar el formulario no cambia la nota del LMS. Devuelve false
+// sin error si el intento ya tenía nota. Si el envío falla, el intento
+// queda libre para reintentarlo.
+func (t *Tool) GradeOnce(s Session, score Score) (bool, error) {
+	if s.Attempt == "" {
+		return false, errors.New("la sesión no admite notas")
+	}
+	claimed, err := t.claim(s.Attempt, time.Unix(s.Expires, 0))
+	if err != nil || !claimed {
+		return false, err
+	}
+	if err := t.PostScore(s, score); err != nil {
+		if rerr := t.release(s.Attempt); rerr != nil {
+			return false, fmt.Errorf("%v (y el intento no se pudo liberar: %v)", err, rerr)
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (t *Tool) claim(attempt string, expires time.Time) (bool, error) {
+	if t.Grades != nil {
+		return t.Grades.Claim(attempt, expires)
+	}
+	t.sweepGraded()
+	_, used := t.graded.LoadOrStore(attempt, expires)
+	return !used, nil
+}
+
+func (t *Tool) release(attempt string) error {
+	if t.Grades != nil {
+		return t.Grades.Release(attempt)
+	}
+	t.graded.Delete(attempt)
+	return nil
+}
+
+// sweepGraded olvida, como mucho una vez por minuto, los intentos de
+// sesiones ya caducadas: esas sesiones no pasan ParseSession
+func (t *Tool) sweepGraded() {
+	now := t.Now()
+	t.mu.Lock()
+	if now.Sub(t.swept) < time.Minute {
+		t.mu.Unlock()
+		return
+	}
+	t.swept = now
+	t.mu.Unlock()
+	t.graded.Range(func(k, v interface{}) bool {
+		if now.After(v.(time.Time)) {
+			t.graded.Delete(k)
+		}
+		return true
+	})
+}
+
+// PostScore publica la nota del alumno en el libro de calificaciones del
+// LMS. Si la plataforma rechaza el token guardado, pide otro una vez.
+func (t *Tool) PostScore(s Session, score Score) error {
+	if s.LineItem == "" {
+		return errors.New("este enlace no admite notas")
+	}
+	p, err := t.Platform(s.Platform, s.ClientID)
+	if err != nil {
+		return err
+	}
+	target, err := scoresURL(s.LineItem)
+	if err != nil {
+		return err
+	}
+	score.UserID = s.Subject
+	if score.Timestamp == "" {
+		score.Timestamp = t.Now().UTC().Format("2006-01-02T15:04:05.000Z07:00")
+	}
+	body, err := json.Marshal(score)
+	if err != nil {
+		return err
+	}
+
+	for retry := 0; ; retry++ {
+		token, err := t.accessToken(p, ScopeScore)
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", contentTypeScore)
+		resp, err := t.Client.Do(req)
+		if err != nil {
+			return fmt.Errorf("no se pudo enviar la nota: %v", err)
+		}
+		resp.Body.Close()
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized && retry == 0:
+			t.tokens.drop(tokenKey(p, ScopeScore))
+		case resp.StatusCode >= 300:
+			return fmt.Errorf("la plataforma rechazó la nota: %s", resp.Status)
+		default:
+			return nil
+		}
+	}
+}
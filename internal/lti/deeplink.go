@@ -0,0 +1,70 @@
+package lti
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+// ContentItem es un enlace a un quiz devuelto por deep linking. El quiz va
+// en el parámetro personalizado quiz_id, que la plataforma repite en cada
+// lanzamiento; LineItem pide a la plataforma una columna de notas.
+type ContentItem struct {
+	Type     string            `json:"type"`
+	Title    string            `json:"title,omitempty"`
+	Text     string            `json:"text,omitempty"`
+	URL      string            `json:"url,omitempty"`
+	Custom   map[string]string `json:"custom,omitempty"`
+	LineItem *LineItem         `json:"lineItem,omitempty"`
+}
+
+// LineItem es la columna del libro de notas de un enlace
+type LineItem struct {
+	ScoreMaximum float64 `json:"scoreMaximum"`
+	Label        string  `json:"label"`
+	ResourceID   string  `json:"resourceId,omitempty"`
+	Tag          string  `json:"tag,omitempty"`
+}
+
+// TypeResourceLink es el único tipo de contenido que devuelve la herramienta
+const TypeResourceLink = "ltiResourceLink"
+
+type deepLinkResponse struct {
+	Issuer       string        `json:"iss"`
+	Audience     string        `json:"aud"`
+	IssuedAt     int64         `json:"iat"`
+	Expires      int64         `json:"exp"`
+	Nonce        string        `json:"nonce"`
+	MessageType  string        `json:"https://purl.imsglobal.org/spec/lti/claim/message_type"`
+	Version      string        `json:"https://purl.imsglobal.org/spec/lti/claim/version"`
+	DeploymentID string        `json:"https://purl.imsglobal.org/spec/lti/claim/deployment_id"`
+	Data         string        `json:"https://purl.imsglobal.org/spec/lti-dl/claim/data,omitempty"`
+	Items        []ContentItem `json:"https://purl.imsglobal.org/spec/lti-dl/claim/content_items"`
+	Message      string        `json:"https://purl.imsglobal.org/spec/lti-dl/claim/msg,omitempty"`
+}
+
+// DeepLinkResponse firma la respuesta de deep linking con los quizzes
+// elegidos. El navegador la devuelve a DeepLink.ReturnURL en el campo JWT
+// de un formulario; sin ítems, la plataforma entiende que se canceló.
+func (t *Tool) DeepLinkResponse(s Session, items []ContentItem) (string, error) {
+	if s.DeepLink == nil {
+		return "", errors.New("la sesión no viene de un pedido de deep linking")
+	}
+	if len(items) > 1 && !s.DeepLink.AcceptMultiple {
+		return "", errors.New("la plataforma sólo acepta un quiz por enlace")
+	}
+	if len(items) > 0 && len(s.DeepLink.AcceptTypes) > 0 && !slices.Contains(s.DeepLink.AcceptTypes, TypeResourceLink) {
+		return "", errors.New("la plataforma no acepta enlaces LTI en este lugar")
+	}
+	now := t.Now()
+	msg := ""
+	if len(items) > 0 {
+		msg = "Quizzes de English at Lima añadidos"
+	}
+	return signJWT(t.Key, t.KeyID, deepLinkResponse{
+		Issuer: s.ClientID, Audience: s.Platform,
+		IssuedAt: now.Unix(), Expires: now.Add(5 * time.Minute).Unix(), Nonce: randomID(),
+		MessageType: messageDeepLinkResp, Version: version, DeploymentID: s.DeploymentID,
+		Data: s.DeepLink.Data, Items: append([]ContentItem{}, items...), Message: msg,
+	})
+}
@@ -0,0 +1,180 @@
+package lti
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// LTI 1.3 sólo admite RS256 (RSA con SHA-256) para firmar los JWT
+const algRS256 = "RS256"
+
+var b64 = base64.RawURLEncoding
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// signJWT firma los claims con la clave de la herramienta
+func signJWT(key *rsa.PrivateKey, kid string, claims interface{}) (string, error) {
+	header, err := json.Marshal(jwtHeader{Alg: algRS256, Typ: "JWT", Kid: kid})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := b64.EncodeToString(header) + "." + b64.EncodeToString(payload)
+	sum := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + b64.EncodeToString(sig), nil
+}
+
+// rawJWT es un JWT partido pero todavía sin verificar
+type rawJWT struct {
+	header  jwtHeader
+	payload []byte
+	signed  string
+	sig     []byte
+}
+
+func parseJWT(token string) (rawJWT, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return rawJWT{}, errors.New("el token no es un JWT")
+	}
+	var t rawJWT
+	header, err := b64.DecodeString(parts[0])
+	if err != nil {
+		return t, errors.New("cabecera del JWT ilegible")
+	}
+	if err := json.Unmarshal(header, &t.header); err != nil {
+		return t, errors.New("cabecera del JWT ilegible")
+	}
+	if t.header.Alg != algRS256 {
+		return t, fmt.Errorf("algoritmo de firma no admitido: %q", t.header.Alg)
+	}
+	if t.payload, err = b64.DecodeString(parts[1]); err != nil {
+		return t, errors.New("contenido del JWT ilegible")
+	}
+	if t.sig, err = b64.DecodeString(parts[2]); err != nil {
+		return t, errors.New("firma del JWT ilegible")
+	}
+	t.signed = parts[0] + "." + parts[1]
+	return t, nil
+}
+
+func (t rawJWT) verify(pub *rsa.PublicKey) error {
+	sum := sha256.Sum256([]byte(t.signed))
+	if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, sum[:], t.sig); err != nil {
+		return errors.New("la firma del JWT no es válida")
+	}
+	return nil
+}
+
+// audience acepta aud como texto o como lista, las dos formas de la norma
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*a = audience{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return errors.New("aud debe ser texto o lista")
+	}
+	*a = many
+	return nil
+}
+
+// --- Claves ---
+
+// JWK es una clave pública RSA publicada en un JWKS
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKS es el conjunto de claves que publica la plataforma (y la herramienta)
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicJWK describe la clave pública para el JWKS
+func PublicJWK(pub *rsa.PublicKey, kid string) JWK {
+	e := make([]byte, 8)
+	binary.BigEndian.PutUint64(e, uint64(pub.E))
+	for len(e) > 1 && e[0] == 0 {
+		e = e[1:]
+	}
+	return JWK{Kty: "RSA", Kid: kid, Use: "sig", Alg: algRS256, N: b64.EncodeToString(pub.N.Bytes()), E: b64.EncodeToString(e)}
+}
+
+func (k JWK) publicKey() (*rsa.PublicKey, error) {
+	if k.Kty != "RSA" {
+		return nil, fmt.Errorf("clave %s: tipo %q no admitido", k.Kid, k.Kty)
+	}
+	n, err := b64.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("clave %s ilegible", k.Kid)
+	}
+	e, err := b64.DecodeString(k.E)
+	if err != nil || len(e) == 0 || len(e) > 4 {
+		return nil, fmt.Errorf("clave %s ilegible", k.Kid)
+	}
+	exp := 0
+	for _, b := range e {
+		exp = exp<<8 | int(b)
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exp}, nil
+}
+
+// KeyID es la huella RFC 7638 de la clave: no cambia mientras la clave sea
+// la misma, así las plataformas no tienen que volver a registrarla
+func KeyID(pub *rsa.PublicKey) string {
+	k := PublicJWK(pub, "")
+	sum := sha256.Sum256([]byte(`{"e":"` + k.E + `","kty":"RSA","n":"` + k.N + `"}`))
+	return b64.EncodeToString(sum[:])
+}
+
+// ParseKey lee la clave privada de la herramienta en PEM (PKCS#1 o PKCS#8)
+func ParseKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("la clave LTI no está en formato PEM")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("clave LTI ilegible: %v", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("la clave LTI debe ser RSA")
+	}
+	return key, nil
+}
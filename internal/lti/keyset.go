@@ -0,0 +1,88 @@
+package lti
+
+import (
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// keySetTTL: las plataformas rotan sus claves pocas veces; cada lanzamiento
+	// no debe costar una petición a su JWKS
+	keySetTTL = time.Hour
+	// keySetRefresh es lo mínimo entre dos descargas por un kid desconocido,
+	// para que un token inventado no sirva para martillear a la plataforma
+	keySetRefresh = time.Minute
+)
+
+type cachedKeySet struct {
+	keys    map[string]*rsa.PublicKey
+	fetched time.Time
+}
+
+// keySets guarda el JWKS de cada plataforma por su URL
+type keySets struct {
+	mu   sync.Mutex
+	sets map[string]cachedKeySet
+}
+
+// key devuelve la clave kid del JWKS. Si no la conoce vuelve a descargar el
+// JWKS (la plataforma pudo rotar su clave), como mucho una vez por minuto.
+func (k *keySets) key(client *http.Client, url, kid string, now time.Time) (*rsa.PublicKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.sets == nil {
+		k.sets = map[string]cachedKeySet{}
+	}
+	set, ok := k.sets[url]
+	fresh := ok && now.Sub(set.fetched) < keySetTTL
+	if pub := set.keys[kid]; pub != nil && fresh {
+		return pub, nil
+	}
+	if !ok || !fresh || now.Sub(set.fetched) >= keySetRefresh {
+		keys, err := fetchKeySet(client, url)
+		if err != nil {
+			if set.keys[kid] != nil {
+				// Mejor una clave algo vieja que cortar todos los lanzamientos
+				fmt.Println("⚠️ JWKS de la plataforma no disponible, se usa el guardado:", err)
+				return set.keys[kid], nil
+			}
+			return nil, err
+		}
+		set = cachedKeySet{keys: keys, fetched: now}
+		k.sets[url] = set
+	}
+	if pub := set.keys[kid]; pub != nil {
+		return pub, nil
+	}
+	return nil, fmt.Errorf("la plataforma no publica la clave %q", kid)
+}
+
+func fetchKeySet(client *http.Client, url string) (map[string]*rsa.PublicKey, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo leer el JWKS de la plataforma: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JWKS de la plataforma: %s", resp.Status)
+	}
+	var set JWKS
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&set); err != nil {
+		return nil, fmt.Errorf("JWKS de la plataforma ilegible: %v", err)
+	}
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if pub, err := k.publicKey(); err == nil {
+			keys[k.Kid] = pub
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,373 @@
+package lti
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"english-at-lima-cms/internal/models"
+)
+
+func TestLaunchAndScore(t *testing.T) {
+	f, tool := newFakePlatform(t)
+	idToken, state, err := f.launch(tool, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := tool.ValidateLaunch(idToken, state)
+	if err != nil {
+		t.Fatalf("lanzamiento válido rechazado: %v", err)
+	}
+	if l.MessageType != MessageResourceLink || l.Subject != "student-42" || l.Custom["quiz_id"] != "12" || l.ResourceLinkID != "link-1" || l.IsInstructor() {
+		t.Fatalf("lanzamiento inesperado: %+v", l)
+	}
+	if _, err := tool.ValidateLaunch(idToken, state); err == nil {
+		t.Fatal("el mismo state no debería valer dos veces")
+	}
+
+	raw, err := tool.SignSession(l.Session(12))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := tool.ParseSession(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.QuizID != 12 || s.LineItem != f.srv.URL+fakeLineItem {
+		t.Fatalf("sesión inesperada: %+v", s)
+	}
+
+	for _, given := range []float64{0, 1} {
+		if err := tool.PostScore(s, Score{ScoreGiven: given, ScoreMaximum: 1, ActivityProgress: "Completed", GradingProgress: "FullyGraded"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(f.scores) != 2 || f.scores[1].UserID != "student-42" || f.scores[1].ScoreGiven != 1 || f.scores[1].Timestamp == "" {
+		t.Fatalf("notas recibidas: %+v", f.scores)
+	}
+	if f.tokenCalls != 1 {
+		t.Errorf("el token debería reutilizarse: %d pedidos", f.tokenCalls)
+	}
+
+	// La plataforma revoca el token: se pide otro y se reintenta una vez
+	f.mu.Lock()
+	f.tokens = map[string]bool{}
+	f.mu.Unlock()
+	if err := tool.PostScore(s, Score{ScoreGiven: 1, ScoreMaximum: 1, ActivityProgress: "Completed", GradingProgress: "FullyGraded"}); err != nil {
+		t.Fatalf("no reintentó con un token nuevo: %v", err)
+	}
+	if f.tokenCalls != 2 || len(f.scores) != 3 {
+		t.Errorf("pedidos de token %d, notas %d", f.tokenCalls, len(f.scores))
+	}
+}
+
+func TestGradeOnce(t *testing.T) {
+	f, tool := newFakePlatform(t)
+	idToken, state, err := f.launch(tool, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := tool.ValidateLaunch(idToken, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := tool.SignSession(l.Session(12))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := tool.ParseSession(raw)
+	if err != nil || s.Attempt == "" {
+		t.Fatalf("sesión sin intento: %+v %v", s, err)
+	}
+	score := Score{ScoreGiven: 1, ScoreMaximum: 1, ActivityProgress: "Completed", GradingProgress: "FullyGraded"}
+
+	// Si el envío falla el intento queda libre para la siguiente respuesta
+	broken := s
+	broken.LineItem = f.srv.URL + "/lineitems/otra"
+	if posted, err := tool.GradeOnce(broken, score); posted || err == nil {
+		t.Fatalf("el envío a una columna inexistente debería fallar: %v %v", posted, err)
+	}
+	if posted, err := tool.GradeOnce(s, score); !posted || err != nil {
+		t.Fatalf("la primera respuesta debería enviarse: %v %v", posted, err)
+	}
+	score.ScoreGiven = 0
+	if posted, err := tool.GradeOnce(s, score); posted || err != nil {
+		t.Fatalf("la segunda respuesta no debería enviarse: %v %v", posted, err)
+	}
+	if len(f.scores) != 1 || f.scores[0].ScoreGiven != 1 {
+		t.Fatalf("notas recibidas: %+v", f.scores)
+	}
+
+	if _, err := tool.GradeOnce(Session{QuizID: 12}, score); err == nil {
+		t.Error("una sesión sin columna de notas no debería calificarse")
+	}
+}
+
+// gradeLog es un GradeStore en memoria que sobrevive al Tool, como la tabla
+type gradeLog map[string]time.Time
+
+func (g gradeLog) Claim(attempt string, expires time.Time) (bool, error) {
+	if _, used := g[attempt]; used {
+		return false, nil
+	}
+	g[attempt] = expires
+	return true, nil
+}
+
+func (g gradeLog) Release(attempt string) error {
+	delete(g, attempt)
+	return nil
+}
+
+func TestGradeOnceStore(t *testing.T) {
+	f, tool := newFakePlatform(t)
+	idToken, state, err := f.launch(tool, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := tool.ValidateLaunch(idToken, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := l.Session(12)
+	score := Score{ScoreGiven: 1, ScoreMaximum: 1, ActivityProgress: "Completed", GradingProgress: "FullyGraded"}
+
+	// El intento se calificó antes de reiniciar el servidor
+	log := gradeLog{s.Attempt: time.Unix(s.Expires, 0)}
+	tool.Grades = log
+	if posted, err := tool.GradeOnce(s, score); posted || err != nil || len(f.scores) != 0 {
+		t.Fatalf("un intento ya guardado no debería calificarse de nuevo: %v %v", posted, err)
+	}
+
+	// Un envío fallido libera el intento en el almacén
+	other := l.Session(12)
+	other.LineItem = f.srv.URL + "/lineitems/otra"
+	if posted, err := tool.GradeOnce(other, score); posted || err == nil {
+		t.Fatalf("el envío a una columna inexistente debería fallar: %v %v", posted, err)
+	}
+	if _, kept := log[other.Attempt]; kept {
+		t.Fatal("el intento fallido debería quedar libre")
+	}
+}
+
+func TestValidateLaunchRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+		other  bool // firma con una clave que la plataforma no publica
+		err    string
+	}{
+		{"firma ajena", nil, true, "firma"},
+		{"emisor", map[string]interface{}{"iss": "https://otro.lms"}, false, "emisor"},
+		{"audiencia", map[string]interface{}{"aud": "otra-herramienta"}, false, "aud"},
+		{"varias audiencias sin azp", map[string]interface{}{"aud": []string{fakeClientID, "otra"}}, false, "azp"},
+		{"caducado", map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix()}, false, "caducó"},
+		{"del futuro", map[string]interface{}{"iat": time.Now().Add(time.Hour).Unix()}, false, "iat"},
+		{"nonce", map[string]interface{}{"nonce": "otro"}, false, "nonce"},
+		{"versión", map[string]interface{}{"https://purl.imsglobal.org/spec/lti/claim/version": "1.1"}, false, "versión"},
+		{"despliegue", map[string]interface{}{"https://purl.imsglobal.org/spec/lti/claim/deployment_id": "dep-9"}, false, "despliegue"},
+		{"sin resource_link", map[string]interface{}{"https://purl.imsglobal.org/spec/lti/claim/resource_link": nil}, false, "resource_link"},
+		{"tipo de mensaje", map[string]interface{}{"https://purl.imsglobal.org/spec/lti/claim/message_type": "LtiSubmissionReviewRequest"}, false, "tipo de mensaje"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, tool := newFakePlatform(t)
+			f.claims = tt.claims
+			if tt.other {
+				f.signWith = newKey(t)
+			}
+			idToken, state, err := f.launch(tool, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = tool.ValidateLaunch(idToken, state)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("esperaba %q, obtuvo %v", tt.err, err)
+			}
+		})
+	}
+
+	f, tool := newFakePlatform(t)
+	idToken, _, _ := f.launch(tool, "")
+	if _, err := tool.ValidateLaunch(idToken, "state-inventado"); err == nil {
+		t.Error("un state que no emitimos no debería valer")
+	}
+	_, state, _ := f.launch(tool, "")
+	header, _, _ := strings.Cut(idToken, ".")
+	if _, err := tool.ValidateLaunch(header+".e30.", state); err == nil {
+		t.Error("un token sin firma no debería valer")
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	f, tool := newFakePlatform(t)
+	idToken, state, _ := f.launch(tool, "")
+	if _, err := tool.ValidateLaunch(idToken, state); err != nil {
+		t.Fatal(err)
+	}
+	// La plataforma cambia de clave: el kid nuevo obliga a releer el JWKS,
+	// pero no más de una vez por minuto
+	f.key, f.kid = newKey(t), "platform-2"
+	idToken, state, _ = f.launch(tool, "")
+	if _, err := tool.ValidateLaunch(idToken, state); err == nil || !strings.Contains(err.Error(), "platform-2") {
+		t.Fatalf("esperaba kid desconocido dentro del minuto, obtuvo %v", err)
+	}
+	tool.Now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	idToken, state, _ = f.launch(tool, "")
+	if _, err := tool.ValidateLaunch(idToken, state); err != nil {
+		t.Fatalf("no releyó el JWKS tras la rotación: %v", err)
+	}
+}
+
+func TestDeepLinking(t *testing.T) {
+	f, tool := newFakePlatform(t)
+	idToken, state, err := f.launch(tool, "deep-link")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := tool.ValidateLaunch(idToken, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.DeepLinking == nil || !l.IsInstructor() {
+		t.Fatalf("pedido de deep linking inesperado: %+v", l)
+	}
+	raw, _ := tool.SignSession(l.Session(0))
+	s, err := tool.ParseSession(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.LineItem != "" {
+		t.Errorf("el deep linking no lleva lineitem: %q", s.LineItem)
+	}
+
+	items := []ContentItem{
+		{Type: TypeResourceLink, Title: "Phrasal verbs", URL: tool.LaunchURL(), Custom: map[string]string{"quiz_id": "12"}, LineItem: &LineItem{ScoreMaximum: 1, Label: "Phrasal verbs", ResourceID: "eal-quiz-12"}},
+		{Type: TypeResourceLink, Title: "Past simple", URL: tool.LaunchURL(), Custom: map[string]string{"quiz_id": "13"}},
+	}
+	jwt, err := tool.DeepLinkResponse(s, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := tool.Client.PostForm(s.DeepLink.ReturnURL, url.Values{"JWT": {jwt}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(f.deepLinks) != 1 {
+		t.Fatalf("la plataforma no aceptó la respuesta: %s", resp.Status)
+	}
+	claims := f.deepLinks[0]
+	got, _ := claims["https://purl.imsglobal.org/spec/lti-dl/claim/content_items"].([]interface{})
+	if claims["iss"] != fakeClientID || claims["aud"] != f.platform.Issuer || len(got) != 2 ||
+		claims["https://purl.imsglobal.org/spec/lti-dl/claim/data"] != "opaque-123" ||
+		claims["https://purl.imsglobal.org/spec/lti/claim/message_type"] != "LtiDeepLinkingResponse" ||
+		claims["https://purl.imsglobal.org/spec/lti/claim/deployment_id"] != fakeDeployment {
+		t.Fatalf("respuesta de deep linking inesperada: %v", claims)
+	}
+	first := got[0].(map[string]interface{})
+	if first["custom"].(map[string]interface{})["quiz_id"] != "12" || first["lineItem"].(map[string]interface{})["resourceId"] != "eal-quiz-12" {
+		t.Errorf("ítem inesperado: %v", first)
+	}
+
+	s.DeepLink.AcceptMultiple = false
+	if _, err := tool.DeepLinkResponse(s, items); err == nil {
+		t.Error("con accept_multiple=false no debería devolver dos quizzes")
+	}
+	if _, err := tool.DeepLinkResponse(l.Session(12), nil); err != nil {
+		t.Errorf("cancelar (sin ítems) debería valer: %v", err)
+	}
+	if _, err := tool.DeepLinkResponse(Session{}, items); err == nil {
+		t.Error("una sesión de quiz no sirve para deep linking")
+	}
+}
+
+func TestLoginRejects(t *testing.T) {
+	f, tool := newFakePlatform(t)
+	base := url.Values{"iss": {f.platform.Issuer}, "login_hint": {"student-42"}, "target_link_uri": {tool.LaunchURL()}}
+	tests := []struct {
+		name string
+		set  map[string]string
+		err  string
+	}{
+		{"sin iss", map[string]string{"iss": ""}, "iss"},
+		{"sin login_hint", map[string]string{"login_hint": ""}, "login_hint"},
+		{"plataforma desconocida", map[string]string{"iss": "https://otro.lms"}, "no registrada"},
+		{"client_id ajeno", map[string]string{"client_id": "otro"}, "no registrada"},
+		{"destino ajeno", map[string]string{"target_link_uri": "https://evil.example/lti/launch"}, "target_link_uri"},
+		{"despliegue", map[string]string{"lti_deployment_id": "dep-9"}, "despliegue"},
+	}
+	for _, tt := range tests {
+		params := url.Values{}
+		for k, v := range base {
+			params[k] = v
+		}
+		for k, v := range tt.set {
+			params.Set(k, v)
+		}
+		if _, _, err := tool.Login(params); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: esperaba %q, obtuvo %v", tt.name, tt.err, err)
+		}
+	}
+
+	redirect, _, err := tool.Login(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(redirect)
+	if u.Query().Get("client_id") != fakeClientID || u.Query().Get("redirect_uri") != tool.LaunchURL() {
+		t.Errorf("redirección inesperada: %s", redirect)
+	}
+}
+
+func TestSessionRejects(t *testing.T) {
+	_, tool := newFakePlatform(t)
+	raw, _ := tool.SignSession(Session{Platform: "x", QuizID: 1})
+
+	header, rest, _ := strings.Cut(raw, ".")
+	_, sig, _ := strings.Cut(rest, ".")
+	forged := header + "." + b64.EncodeToString([]byte(`{"iss":"`+tool.BaseURL+`","aud":"`+tool.BaseURL+`","exp":9999999999,"quiz_id":2}`)) + "." + sig
+	if _, err := tool.ParseSession(forged); err == nil {
+		t.Error("una sesión alterada no debería valer")
+	}
+
+	tool.Now = func() time.Time { return time.Now().Add(3 * time.Hour) }
+	if _, err := tool.ParseSession(raw); err == nil || !strings.Contains(err.Error(), "caducó") {
+		t.Errorf("esperaba sesión caducada, obtuvo %v", err)
+	}
+
+	other := New(newKey(t), tool.BaseURL, tool.Platform)
+	raw, _ = other.SignSession(Session{QuizID: 1})
+	if _, err := tool.ParseSession(raw); err == nil {
+		t.Error("una sesión firmada con otra clave no debería valer")
+	}
+}
+
+func TestKeyIDAndParseKey(t *testing.T) {
+	key := newKey(t)
+	if KeyID(&key.PublicKey) != KeyID(&key.PublicKey) || KeyID(&key.PublicKey) == KeyID(&newKey(t).PublicKey) {
+		t.Error("el kid debe depender sólo de la clave")
+	}
+	pub, err := PublicJWK(&key.PublicKey, "k").publicKey()
+	if err != nil || pub.N.Cmp(key.N) != 0 || pub.E != key.E {
+		t.Errorf("el JWK no devuelve la misma clave: %v", err)
+	}
+	if _, err := ParseKey([]byte("no es PEM")); err == nil {
+		t.Error("esperaba error con una clave que no es PEM")
+	}
+}
+
+func TestPlatformHelpers(t *testing.T) {
+	if got := ParseDeployments(" 1,2\n2\r\n deployment-x \n"); strings.Join(got, "|") != "1|2|deployment-x" {
+		t.Errorf("despliegues: %q", got)
+	}
+	p := models.LTIPlatform{Issuer: "https://LMS.empresa.pe", AuthLoginURL: "https://auth.empresa.pe/oidc"}
+	if got := Origins(p); strings.Join(got, " ") != "https://lms.empresa.pe https://auth.empresa.pe" {
+		t.Errorf("orígenes: %q", got)
+	}
+	p.AuthLoginURL = "https://lms.empresa.pe/mod/lti/auth.php"
+	if got := Origins(p); len(got) != 1 {
+		t.Errorf("orígenes repetidos: %q", got)
+	}
+}
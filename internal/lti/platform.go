@@ -0,0 +1,54 @@
+package lti
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"english-at-lima-cms/internal/models"
+)
+
+// CheckURL exige https en los endpoints de la plataforma; http sólo se
+// acepta para un LMS de pruebas en la propia máquina
+func CheckURL(raw, field string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || u.User != nil || u.Fragment != "" {
+		return fmt.Errorf("%s no es una URL válida", field)
+	}
+	local := u.Hostname() == "localhost" || u.Hostname() == "127.0.0.1"
+	if u.Scheme != "https" && !(u.Scheme == "http" && local) {
+		return fmt.Errorf("%s debe usar https", field)
+	}
+	return nil
+}
+
+// ParseDeployments convierte el textarea del admin (uno por línea o
+// separados por comas) en la lista de despliegues, sin repetidos
+func ParseDeployments(raw string) []string {
+	var ids []string
+	seen := map[string]bool{}
+	for _, f := range strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == '\n' || r == '\r' }) {
+		if f = strings.TrimSpace(f); f != "" && !seen[f] {
+			seen[f] = true
+			ids = append(ids, f)
+		}
+	}
+	return ids
+}
+
+// Origins son los orígenes desde los que la plataforma incrusta la
+// herramienta en un iframe: el del issuer y el de su autorización
+func Origins(p models.LTIPlatform) []string {
+	var origins []string
+	for _, raw := range []string{p.Issuer, p.AuthLoginURL} {
+		u, err := url.Parse(raw)
+		if err != nil || u.Host == "" || strings.ContainsAny(u.Host, ";'\" ") {
+			continue
+		}
+		origin := strings.ToLower(u.Scheme + "://" + u.Host)
+		if len(origins) == 0 || origins[0] != origin {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
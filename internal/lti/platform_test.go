@@ -0,0 +1,258 @@
+package lti
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"english-at-lima-cms/internal/models"
+)
+
+// fakePlatform es un LMS mínimo en memoria: publica su JWKS, atiende la
+// autorización OIDC devolviendo el id_token por form_post, emite tokens
+// OAuth2 verificando la aserción firmada por la herramienta y recibe las
+// notas y las respuestas de deep linking.
+type fakePlatform struct {
+	t        *testing.T
+	srv      *httptest.Server
+	tool     *httptest.Server // sirve el JWKS de la herramienta
+	key      *rsa.PrivateKey
+	kid      string
+	platform models.LTIPlatform
+
+	mu sync.Mutex
+	// claims que se añaden (o se pisan) en el próximo id_token
+	claims     map[string]interface{}
+	signWith   *rsa.PrivateKey
+	tokens     map[string]bool
+	tokenCalls int
+	scores     []Score
+	deepLinks  []map[string]interface{}
+}
+
+const (
+	fakeClientID   = "eal-client"
+	fakeDeployment = "dep-1"
+	fakeLineItem   = "/courses/7/lineitems/3/lineitem?type_id=2"
+)
+
+func newFakePlatform(t *testing.T) (*fakePlatform, *Tool) {
+	t.Helper()
+	f := &fakePlatform{t: t, key: newKey(t), kid: "platform-1", tokens: map[string]bool{}}
+	f.srv = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.srv.Close)
+	f.platform = models.LTIPlatform{
+		ID: 1, Name: "Moodle del cliente", Issuer: f.srv.URL, ClientID: fakeClientID,
+		DeploymentIDs: []string{fakeDeployment},
+		AuthLoginURL:  f.srv.URL + "/auth", AuthTokenURL: f.srv.URL + "/token", JWKSURL: f.srv.URL + "/jwks",
+	}
+
+	var tool *Tool
+	f.tool = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(tool.JWKS())
+	}))
+	t.Cleanup(f.tool.Close)
+	tool = New(newKey(t), f.tool.URL, func(issuer, clientID string) (models.LTIPlatform, error) {
+		if issuer != f.platform.Issuer || (clientID != "" && clientID != f.platform.ClientID) {
+			return models.LTIPlatform{}, ErrUnknownPlatform
+		}
+		return f.platform, nil
+	})
+	return f, tool
+}
+
+func newKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func (f *fakePlatform) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case r.URL.Path == "/jwks":
+		json.NewEncoder(w).Encode(JWKS{Keys: []JWK{PublicJWK(&f.key.PublicKey, f.kid)}})
+	case r.URL.Path == "/auth":
+		f.authorize(w, r)
+	case r.URL.Path == "/token":
+		f.token(w, r)
+	case strings.HasSuffix(r.URL.Path, "/scores"):
+		f.score(w, r)
+	case r.URL.Path == "/deep_link_return":
+		f.deepLinkReturn(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// authorize hace de endpoint OIDC: comprueba el pedido y responde con el
+// formulario que el navegador enviaría al launch de la herramienta
+func (f *fakePlatform) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	for k, want := range map[string]string{"scope": "openid", "response_type": "id_token", "response_mode": "form_post", "prompt": "none", "client_id": fakeClientID, "login_hint": "student-42"} {
+		if q.Get(k) != want {
+			http.Error(w, "bad "+k, http.StatusBadRequest)
+			return
+		}
+	}
+	if q.Get("state") == "" || q.Get("nonce") == "" {
+		http.Error(w, "missing state/nonce", http.StatusBadRequest)
+		return
+	}
+	claims := map[string]interface{}{
+		"iss": f.platform.Issuer, "aud": fakeClientID, "sub": "student-42", "name": "Ana Quispe",
+		"iat": time.Now().Unix(), "exp": time.Now().Add(5 * time.Minute).Unix(), "nonce": q.Get("nonce"),
+		"https://purl.imsglobal.org/spec/lti/claim/message_type":    MessageResourceLink,
+		"https://purl.imsglobal.org/spec/lti/claim/version":         "1.3.0",
+		"https://purl.imsglobal.org/spec/lti/claim/deployment_id":   fakeDeployment,
+		"https://purl.imsglobal.org/spec/lti/claim/target_link_uri": q.Get("redirect_uri"),
+		"https://purl.imsglobal.org/spec/lti/claim/resource_link":   map[string]string{"id": "link-1"},
+		"https://purl.imsglobal.org/spec/lti/claim/roles":           []string{"http://purl.imsglobal.org/vocab/lis/v2/membership#Learner"},
+		"https://purl.imsglobal.org/spec/lti/claim/custom":          map[string]string{"quiz_id": "12"},
+		"https://purl.imsglobal.org/spec/lti-ags/claim/endpoint": map[string]interface{}{
+			"scope":    []string{ScopeScore},
+			"lineitem": f.srv.URL + fakeLineItem,
+		},
+	}
+	if q.Get("lti_message_hint") == "deep-link" {
+		delete(claims, "https://purl.imsglobal.org/spec/lti/claim/resource_link")
+		delete(claims, "https://purl.imsglobal.org/spec/lti-ags/claim/endpoint")
+		claims["https://purl.imsglobal.org/spec/lti/claim/message_type"] = MessageDeepLinking
+		claims["https://purl.imsglobal.org/spec/lti/claim/roles"] = []string{"http://purl.imsglobal.org/vocab/lis/v2/membership#Instructor"}
+		claims["https://purl.imsglobal.org/spec/lti-dl/claim/deep_linking_settings"] = map[string]interface{}{
+			"deep_link_return_url": f.srv.URL + "/deep_link_return",
+			"accept_types":         []string{"ltiResourceLink"},
+			"accept_multiple":      true,
+			"data":                 "opaque-123",
+		}
+	}
+	for k, v := range f.claims {
+		if v == nil {
+			delete(claims, k)
+		} else {
+			claims[k] = v
+		}
+	}
+	key := f.key
+	if f.signWith != nil {
+		key = f.signWith
+	}
+	token, err := signJWT(key, f.kid, claims)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	fmt.Fprintf(w, `<form method="post" action="%s"><input type="hidden" name="id_token" value="%s"><input type="hidden" name="state" value="%s"></form>`,
+		html.EscapeString(q.Get("redirect_uri")), token, html.EscapeString(q.Get("state")))
+}
+
+// toolClaims verifica un JWT firmado por la herramienta con su JWKS publicado
+func (f *fakePlatform) toolClaims(token string) (map[string]interface{}, error) {
+	tok, err := parseJWT(token)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := fetchKeySet(http.DefaultClient, f.tool.URL)
+	if err != nil {
+		return nil, err
+	}
+	pub := keys[tok.header.Kid]
+	if pub == nil {
+		return nil, fmt.Errorf("kid desconocido %q", tok.header.Kid)
+	}
+	if err := tok.verify(pub); err != nil {
+		return nil, err
+	}
+	var claims map[string]interface{}
+	return claims, json.Unmarshal(tok.payload, &claims)
+}
+
+func (f *fakePlatform) token(w http.ResponseWriter, r *http.Request) {
+	f.tokenCalls++
+	if r.PostFormValue("grant_type") != "client_credentials" || r.PostFormValue("client_assertion_type") != assertionType || r.PostFormValue("scope") != ScopeScore {
+		http.Error(w, `{"error":"invalid_request"}`, http.StatusBadRequest)
+		return
+	}
+	claims, err := f.toolClaims(r.PostFormValue("client_assertion"))
+	if err != nil || claims["iss"] != fakeClientID || claims["sub"] != fakeClientID || claims["aud"] != f.platform.AuthTokenURL || claims["jti"] == "" {
+		http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+		return
+	}
+	token := fmt.Sprintf("access-%d", f.tokenCalls)
+	f.tokens[token] = true
+	json.NewEncoder(w).Encode(map[string]interface{}{"access_token": token, "token_type": "Bearer", "expires_in": 3600, "scope": ScopeScore})
+}
+
+func (f *fakePlatform) score(w http.ResponseWriter, r *http.Request) {
+	if !f.tokens[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")] {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Header.Get("Content-Type") != contentTypeScore || r.URL.Path+"?"+r.URL.RawQuery != strings.Replace(fakeLineItem, "?", "/scores?", 1) {
+		http.Error(w, "bad score request", http.StatusBadRequest)
+		return
+	}
+	var s Score
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.scores = append(f.scores, s)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *fakePlatform) deepLinkReturn(w http.ResponseWriter, r *http.Request) {
+	claims, err := f.toolClaims(r.PostFormValue("JWT"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	f.deepLinks = append(f.deepLinks, claims)
+}
+
+var reHidden = regexp.MustCompile(`name="(\w+)" value="([^"]*)"`)
+
+// launch recorre el flujo completo como lo haría el navegador: la
+// plataforma llama al login, el alumno sigue la redirección a /auth y
+// envía el formulario al launch
+func (f *fakePlatform) launch(tool *Tool, messageHint string) (string, string, error) {
+	f.t.Helper()
+	redirect, state, err := tool.Login(url.Values{
+		"iss": {f.platform.Issuer}, "login_hint": {"student-42"}, "target_link_uri": {tool.LaunchURL()},
+		"lti_message_hint": {messageHint}, "client_id": {fakeClientID}, "lti_deployment_id": {fakeDeployment},
+	})
+	if err != nil {
+		return "", "", err
+	}
+	resp, err := http.Get(redirect)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		f.t.Fatalf("la plataforma rechazó la autorización: %s", body)
+	}
+	form := map[string]string{}
+	for _, m := range reHidden.FindAllStringSubmatch(string(body), -1) {
+		form[m[1]] = html.UnescapeString(m[2])
+	}
+	if form["state"] != state {
+		f.t.Fatalf("la plataforma devolvió el state %q, el login dio %q", form["state"], state)
+	}
+	return form["id_token"], form["state"], nil
+}
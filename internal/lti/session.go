@@ -0,0 +1,69 @@
+package lti
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// sessionTTL es lo que dura la actividad abierta desde el LMS
+const sessionTTL = 2 * time.Hour
+
+// Session es lo que la herramienta necesita recordar del lanzamiento. Viaja
+// firmada con la clave de la herramienta en los formularios de la página:
+// dentro del iframe del LMS la cookie de sesión (SameSite=Lax) no llega.
+type Session struct {
+	Issuer       string            `json:"iss"`
+	Audience     string            `json:"aud"`
+	Expires      int64             `json:"exp"`
+	Platform     string            `json:"platform"` // issuer de la plataforma
+	ClientID     string            `json:"client_id"`
+	DeploymentID string            `json:"deployment_id"`
+	Subject      string            `json:"sub,omitempty"`
+	QuizID       int               `json:"quiz_id,omitempty"`
+	LineItem     string            `json:"lineitem,omitempty"` // sólo si se puede enviar la nota
+	Attempt      string            `json:"attempt,omitempty"`  // intento de un solo uso para la nota
+	DeepLink     *DeepLinkSettings `json:"deep_link,omitempty"`
+}
+
+// Session resume el lanzamiento para las peticiones siguientes
+func (l Launch) Session(quizID int) Session {
+	s := Session{
+		Platform: l.Platform.Issuer, ClientID: l.Platform.ClientID, DeploymentID: l.DeploymentID,
+		Subject: l.Subject, QuizID: quizID, DeepLink: l.DeepLinking,
+	}
+	if l.AGS.CanScore() && l.Subject != "" {
+		s.LineItem, s.Attempt = l.AGS.LineItem, randomID()
+	}
+	return s
+}
+
+// SignSession firma la sesión; su audiencia es la propia herramienta, así
+// no se confunde con un JWT que la herramienta firma para la plataforma
+func (t *Tool) SignSession(s Session) (string, error) {
+	s.Issuer, s.Audience = t.BaseURL, t.BaseURL
+	s.Expires = t.Now().Add(sessionTTL).Unix()
+	return signJWT(t.Key, t.KeyID, s)
+}
+
+// ParseSession verifica la firma y la vigencia de una sesión
+func (t *Tool) ParseSession(token string) (Session, error) {
+	var s Session
+	tok, err := parseJWT(token)
+	if err != nil {
+		return s, err
+	}
+	if err := tok.verify(&t.Key.PublicKey); err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(tok.payload, &s); err != nil {
+		return s, err
+	}
+	if s.Issuer != t.BaseURL || s.Audience != t.BaseURL {
+		return Session{}, errors.New("la sesión LTI no es de esta herramienta")
+	}
+	if t.Now().Unix() > s.Expires {
+		return Session{}, errors.New("la sesión LTI caducó: vuelva a abrir la actividad desde el curso")
+	}
+	return s, nil
+}
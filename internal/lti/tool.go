@@ -0,0 +1,311 @@
+// Package lti hace del CMS una herramienta LTI 1.3: inicio de sesión OIDC,
+// validación del lanzamiento (id_token firmado por la plataforma y
+// verificado con su JWKS), deep linking para elegir quizzes y envío de
+// notas por Assignment and Grade Services.
+package lti
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"english-at-lima-cms/internal/models"
+)
+
+// Tipos de mensaje
+const (
+	MessageResourceLink = "LtiResourceLinkRequest"
+	MessageDeepLinking  = "LtiDeepLinkingRequest"
+	messageDeepLinkResp = "LtiDeepLinkingResponse"
+	version             = "1.3.0"
+)
+
+const (
+	// loginTTL es lo que tiene el alumno para volver de la plataforma con
+	// el id_token después del inicio de sesión
+	loginTTL = 10 * time.Minute
+	// maxPendingLogins limita la memoria de los inicios sin terminar
+	maxPendingLogins = 10000
+	// leeway tolera relojes algo desfasados entre plataforma y herramienta
+	leeway = time.Minute
+)
+
+var ErrUnknownPlatform = errors.New("plataforma LTI no registrada")
+
+// Tool es la herramienta LTI. Platform busca la plataforma registrada por
+// su issuer y client_id (el client_id puede faltar en el inicio de sesión).
+type Tool struct {
+	Key      *rsa.PrivateKey
+	KeyID    string
+	BaseURL  string
+	Platform func(issuer, clientID string) (models.LTIPlatform, error)
+	Client   *http.Client
+	Now      func() time.Time
+	Grades   GradeStore // intentos ya calificados; nil = en memoria
+
+	pending sync.Map // state -> pendingLogin
+	graded  sync.Map // intento -> vencimiento de la sesión (sin Grades)
+	swept   time.Time
+	count   int
+	mu      sync.Mutex
+	keys    keySets
+	tokens  tokenCache
+}
+
+// New arma la herramienta con su clave y su URL pública
+func New(key *rsa.PrivateKey, baseURL string, platform func(issuer, clientID string) (models.LTIPlatform, error)) *Tool {
+	return &Tool{
+		Key: key, KeyID: KeyID(&key.PublicKey), BaseURL: strings.TrimRight(baseURL, "/"),
+		Platform: platform, Client: &http.Client{Timeout: 10 * time.Second}, Now: time.Now,
+	}
+}
+
+// URLs que se dan al administrador de la plataforma al registrar la herramienta
+func (t *Tool) LoginURL() string  { return t.BaseURL + "/lti/login" }
+func (t *Tool) LaunchURL() string { return t.BaseURL + "/lti/launch" }
+func (t *Tool) JWKSURL() string   { return t.BaseURL + "/lti/jwks" }
+
+// JWKS es el conjunto de claves públicas de la herramienta: con él la
+// plataforma verifica las respuestas de deep linking y los pedidos de token
+func (t *Tool) JWKS() JWKS {
+	return JWKS{Keys: []JWK{PublicJWK(&t.Key.PublicKey, t.KeyID)}}
+}
+
+type pendingLogin struct {
+	platform models.LTIPlatform
+	nonce    string
+	expires  time.Time
+}
+
+func randomID() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// Login atiende el inicio de sesión de terceros (OIDC login initiation):
+// reconoce la plataforma y devuelve la URL de su endpoint de autorización
+// con un state y un nonce de un solo uso. Los params son los de la petición
+// de la plataforma (iss, login_hint, target_link_uri, lti_message_hint…).
+// El state también se devuelve para atarlo al navegador con una cookie.
+func (t *Tool) Login(params url.Values) (string, string, error) {
+	iss, hint := params.Get("iss"), params.Get("login_hint")
+	if iss == "" || hint == "" {
+		return "", "", errors.New("faltan iss o login_hint en el inicio de sesión")
+	}
+	if target := params.Get("target_link_uri"); target != "" && !strings.HasPrefix(target, t.BaseURL+"/") {
+		return "", "", fmt.Errorf("target_link_uri ajeno a la herramienta: %s", target)
+	}
+	p, err := t.Platform(iss, params.Get("client_id"))
+	if err != nil {
+		return "", "", err
+	}
+	if dep := params.Get("lti_deployment_id"); dep != "" && !slices.Contains(p.DeploymentIDs, dep) {
+		return "", "", fmt.Errorf("despliegue %q no registrado para %s", dep, p.Name)
+	}
+
+	now := t.Now()
+	t.mu.Lock()
+	if t.count >= maxPendingLogins {
+		t.sweep(now)
+	}
+	if t.count >= maxPendingLogins {
+		t.mu.Unlock()
+		return "", "", errors.New("demasiados inicios de sesión LTI pendientes")
+	}
+	t.count++
+	t.mu.Unlock()
+
+	state, nonce := randomID(), randomID()
+	t.pending.Store(state, pendingLogin{platform: p, nonce: nonce, expires: now.Add(loginTTL)})
+
+	q := url.Values{
+		"scope":         {"openid"},
+		"response_type": {"id_token"},
+		"response_mode": {"form_post"},
+		"prompt":        {"none"},
+		"client_id":     {p.ClientID},
+		"redirect_uri":  {t.LaunchURL()},
+		"login_hint":    {hint},
+		"state":         {state},
+		"nonce":         {nonce},
+	}
+	if m := params.Get("lti_message_hint"); m != "" {
+		q.Set("lti_message_hint", m)
+	}
+	sep := "?"
+	if strings.Contains(p.AuthLoginURL, "?") {
+		sep = "&"
+	}
+	return p.AuthLoginURL + sep + q.Encode(), state, nil
+}
+
+// sweep borra los inicios caducados; se llama con mu tomado
+func (t *Tool) sweep(now time.Time) {
+	t.pending.Range(func(k, v interface{}) bool {
+		if now.After(v.(pendingLogin).expires) {
+			t.pending.Delete(k)
+			t.count--
+		}
+		return true
+	})
+}
+
+// takeState consume el state: sólo vale una vez
+func (t *Tool) takeState(state string) (pendingLogin, bool) {
+	v, ok := t.pending.LoadAndDelete(state)
+	if !ok {
+		return pendingLogin{}, false
+	}
+	t.mu.Lock()
+	t.count--
+	t.mu.Unlock()
+	login := v.(pendingLogin)
+	return login, t.Now().Before(login.expires)
+}
+
+// Launch es un lanzamiento validado
+type Launch struct {
+	Platform       models.LTIPlatform
+	MessageType    string
+	DeploymentID   string
+	Subject        string // el alumno en la plataforma (sub)
+	Name           string
+	Roles          []string
+	ContextTitle   string
+	ResourceLinkID string
+	Custom         map[string]string
+	AGS            *Endpoint
+	DeepLinking    *DeepLinkSettings
+}
+
+// Endpoint es el claim de AGS con los permisos y el lineitem del enlace
+type Endpoint struct {
+	Scope     []string `json:"scope"`
+	LineItems string   `json:"lineitems,omitempty"`
+	LineItem  string   `json:"lineitem,omitempty"`
+}
+
+// CanScore dice si la plataforma deja enviar la nota de este enlace
+func (e *Endpoint) CanScore() bool {
+	return e != nil && e.LineItem != "" && slices.Contains(e.Scope, ScopeScore)
+}
+
+// DeepLinkSettings son los ajustes del pedido de deep linking
+type DeepLinkSettings struct {
+	ReturnURL      string   `json:"deep_link_return_url"`
+	AcceptTypes    []string `json:"accept_types"`
+	AcceptMultiple bool     `json:"accept_multiple,omitempty"`
+	Title          string   `json:"title,omitempty"`
+	Data           string   `json:"data,omitempty"`
+}
+
+type idToken struct {
+	Issuer       string            `json:"iss"`
+	Audience     audience          `json:"aud"`
+	AuthorizedBy string            `json:"azp"`
+	Subject      string            `json:"sub"`
+	Expires      int64             `json:"exp"`
+	IssuedAt     int64             `json:"iat"`
+	Nonce        string            `json:"nonce"`
+	Name         string            `json:"name"`
+	MessageType  string            `json:"https://purl.imsglobal.org/spec/lti/claim/message_type"`
+	Version      string            `json:"https://purl.imsglobal.org/spec/lti/claim/version"`
+	DeploymentID string            `json:"https://purl.imsglobal.org/spec/lti/claim/deployment_id"`
+	Roles        []string          `json:"https://purl.imsglobal.org/spec/lti/claim/roles"`
+	Custom       map[string]string `json:"https://purl.imsglobal.org/spec/lti/claim/custom"`
+	ResourceLink struct {
+		ID string `json:"id"`
+	} `json:"https://purl.imsglobal.org/spec/lti/claim/resource_link"`
+	Context struct {
+		Title string `json:"title"`
+	} `json:"https://purl.imsglobal.org/spec/lti/claim/context"`
+	AGS         *Endpoint         `json:"https://purl.imsglobal.org/spec/lti-ags/claim/endpoint"`
+	DeepLinking *DeepLinkSettings `json:"https://purl.imsglobal.org/spec/lti-dl/claim/deep_linking_settings"`
+}
+
+// ValidateLaunch comprueba el id_token que la plataforma envía al launch:
+// el state de un inicio de sesión nuestro, la firma con la clave del JWKS
+// de la plataforma, emisor, audiencia, vigencia, nonce, versión, despliegue
+// registrado y los claims obligatorios de cada tipo de mensaje.
+func (t *Tool) ValidateLaunch(rawToken, state string) (Launch, error) {
+	login, ok := t.takeState(state)
+	if !ok {
+		return Launch{}, errors.New("inicio de sesión LTI desconocido o caducado: vuelva a abrir la actividad desde el curso")
+	}
+	p := login.platform
+	tok, err := parseJWT(rawToken)
+	if err != nil {
+		return Launch{}, err
+	}
+	pub, err := t.keys.key(t.Client, p.JWKSURL, tok.header.Kid, t.Now())
+	if err != nil {
+		return Launch{}, err
+	}
+	if err := tok.verify(pub); err != nil {
+		return Launch{}, err
+	}
+	var c idToken
+	if err := json.Unmarshal(tok.payload, &c); err != nil {
+		return Launch{}, fmt.Errorf("claims del lanzamiento ilegibles: %v", err)
+	}
+
+	now := t.Now()
+	switch {
+	case c.Issuer != p.Issuer:
+		return Launch{}, fmt.Errorf("emisor inesperado: %s", c.Issuer)
+	case !slices.Contains(c.Audience, p.ClientID):
+		return Launch{}, errors.New("el token no está dirigido a esta herramienta (aud)")
+	case len(c.Audience) > 1 && c.AuthorizedBy != p.ClientID:
+		return Launch{}, errors.New("azp no coincide con el client_id")
+	case c.Expires == 0 || now.After(time.Unix(c.Expires, 0).Add(leeway)):
+		return Launch{}, errors.New("el token de lanzamiento caducó")
+	case now.Add(leeway).Before(time.Unix(c.IssuedAt, 0)):
+		return Launch{}, errors.New("el token de lanzamiento viene del futuro (iat)")
+	case c.Nonce == "" || c.Nonce != login.nonce:
+		return Launch{}, errors.New("nonce inválido")
+	case c.Version != version:
+		return Launch{}, fmt.Errorf("versión de LTI no admitida: %q", c.Version)
+	case !slices.Contains(p.DeploymentIDs, c.DeploymentID):
+		return Launch{}, fmt.Errorf("despliegue %q no registrado para %s", c.DeploymentID, p.Name)
+	}
+
+	l := Launch{
+		Platform: p, MessageType: c.MessageType, DeploymentID: c.DeploymentID,
+		Subject: c.Subject, Name: c.Name, Roles: c.Roles, ContextTitle: c.Context.Title,
+		ResourceLinkID: c.ResourceLink.ID, Custom: c.Custom, AGS: c.AGS,
+	}
+	switch c.MessageType {
+	case MessageResourceLink:
+		if c.ResourceLink.ID == "" {
+			return Launch{}, errors.New("falta resource_link en el lanzamiento")
+		}
+	case MessageDeepLinking:
+		if c.DeepLinking == nil || c.DeepLinking.ReturnURL == "" {
+			return Launch{}, errors.New("faltan deep_linking_settings en el pedido")
+		}
+		l.DeepLinking = c.DeepLinking
+	default:
+		return Launch{}, fmt.Errorf("tipo de mensaje no admitido: %q", c.MessageType)
+	}
+	return l, nil
+}
+
+// IsInstructor reconoce a docentes y administradores del curso
+func (l Launch) IsInstructor() bool {
+	for _, r := range l.Roles {
+		if strings.HasSuffix(r, "#Instructor") || strings.HasSuffix(r, "#Administrator") || strings.HasSuffix(r, "#ContentDeveloper") {
+			return true
+		}
+	}
+	return false
+}
@@ -8,16 +8,23 @@ import (
 	"strings"
 )
 
+// MaxInspectedBody es el mayor cuerpo (no multipart) que el inspector acepta
+const MaxInspectedBody = 2 << 20
+
 func GlobalSecurityInspector() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if c.Request.Method == "POST" || c.Request.Method == "PATCH" {
-			bodyBytes, _ := io.ReadAll(c.Request.Body)
+		// Los multipart llevan archivos binarios (PDF, audio): no se cargan en RAM
+		multipart := strings.HasPrefix(c.ContentType(), "multipart/form-data")
+		if (c.Request.Method == "POST" || c.Request.Method == "PATCH") && !multipart {
+			// Nunca se lee más del límite: un cuerpo enorme se corta aquí y no
+			// llega entero a la RAM
+			bodyBytes, err := io.ReadAll(io.LimitReader(c.Request.Body, MaxInspectedBody+1))
 			c.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
 
 			bodyString := string(bodyBytes)
 
 			// 1. Protección de hardware (Payload Limit)
-			if len(bodyString) > 2*1024*1024 {
+			if err != nil || len(bodyBytes) > MaxInspectedBody {
 				security.LogIntrusion(c, "MASSIVE_PAYLOAD", "Tamaño excedido")
 				c.AbortWithStatusJSON(413, gin.H{"error": "Payload demasiado grande"})
 				return
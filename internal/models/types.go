@@ -1,23 +1,673 @@
 package models
 
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Taxonomy son las etiquetas libres y el nivel MCER comunes a todo el contenido
+type Taxonomy struct {
+	Tags  []string `json:"tags"`
+	Level string   `json:"level"`
+}
+
+// TagsInput devuelve las etiquetas como las escribe el profesor: "food, travel"
+func (t Taxonomy) TagsInput() string {
+	return strings.Join(t.Tags, ", ")
+}
+
+// Estados editoriales del contenido
+const (
+	StatusDraft     = "draft"     // sólo lo ve el admin (vista previa)
+	StatusScheduled = "scheduled" // se publica sólo al llegar PublishAt
+	StatusPublished = "published"
+	StatusArchived  = "archived"
+)
+
+// Statuses en el orden del <select> del formulario
+var Statuses = []string{StatusDraft, StatusScheduled, StatusPublished, StatusArchived}
+
+var statusLabels = map[string]string{
+	StatusDraft:     "Borrador",
+	StatusScheduled: "Programado",
+	StatusPublished: "Publicado",
+	StatusArchived:  "Archivado",
+}
+
+// Publication es el estado editorial común a frases, quizzes y recursos.
+// Las fechas son timestamps UTC de Supabase ("" = sin fecha).
+type Publication struct {
+	Status      string `json:"status"`
+	PublishAt   string `json:"publish_at"`
+	UnpublishAt string `json:"unpublish_at"`
+
+	// ReviewState sólo cambia con las acciones de revisión (ver review.Next)
+	ReviewState string `json:"review_state,omitempty"`
+}
+
+// Estados de la revisión editorial ("" = nunca enviado)
+const (
+	ReviewInReview = "in_review"
+	ReviewChanges  = "changes_requested"
+	ReviewApproved = "approved"
+)
+
+// ReviewLabel es el estado de la revisión en español
+func (p Publication) ReviewLabel() string {
+	switch p.ReviewState {
+	case ReviewInReview:
+		return "En revisión"
+	case ReviewChanges:
+		return "Cambios pedidos"
+	case ReviewApproved:
+		return "Aprobado"
+	}
+	return ""
+}
+
+// Public indica si los alumnos pueden ver el contenido
+func (p Publication) Public() bool { return p.Status == StatusPublished }
+
+// StatusLabel es el estado en español para las insignias del admin
+func (p Publication) StatusLabel() string {
+	if l, ok := statusLabels[p.Status]; ok {
+		return l
+	}
+	return p.Status
+}
+
+// StatusOption es una opción del <select> de estados
+type StatusOption struct {
+	Code     string
+	Label    string
+	Selected bool
+}
+
+// StatusOptions lista los estados marcando el actual (borrador si no hay ninguno)
+func (p Publication) StatusOptions() []StatusOption {
+	current := p.Status
+	if current == "" {
+		current = StatusDraft
+	}
+	opts := make([]StatusOption, len(Statuses))
+	for i, st := range Statuses {
+		opts[i] = StatusOption{Code: st, Label: statusLabels[st], Selected: st == current}
+	}
+	return opts
+}
+
+// PublishAtInput y UnpublishAtInput rellenan los <input type="datetime-local">
+// en hora de Lima
+func (p Publication) PublishAtInput() string   { return LimaInput(p.PublishAt) }
+func (p Publication) UnpublishAtInput() string { return LimaInput(p.UnpublishAt) }
+
+// PublishAtLabel y UnpublishAtLabel muestran las fechas en hora de Lima
+func (p Publication) PublishAtLabel() string   { return LimaTime(p.PublishAt) }
+func (p Publication) UnpublishAtLabel() string { return LimaTime(p.UnpublishAt) }
+
+// Roles del equipo: los editores escriben y envían a revisión; sólo los
+// revisores aprueban y publican
+const (
+	RoleEditor   = "editor"
+	RoleReviewer = "reviewer"
+)
+
+// ReviewComment es un comentario del revisor sobre un campo concreto
+type ReviewComment struct {
+	Field string `json:"field"`
+	Text  string `json:"text"`
+}
+
+// ReviewEvent es una entrada del historial de revisión de un contenido
+type ReviewEvent struct {
+	ID          int             `json:"id,omitempty"`
+	ContentType string          `json:"content_type"` // "sentence", "quiz" o "resource"
+	ContentID   int             `json:"content_id"`
+	Action      string          `json:"action"` // "submit", "approve" o "request_changes"
+	Actor       string          `json:"actor"`
+	Note        string          `json:"note"`
+	Comments    []ReviewComment `json:"comments"`
+	CreatedAt   string          `json:"created_at,omitempty"`
+}
+
+// ActionLabel describe la acción en el historial
+func (e ReviewEvent) ActionLabel() string {
+	switch e.Action {
+	case "submit":
+		return "📤 Enviado a revisión"
+	case "approve":
+		return "✅ Aprobado y publicado"
+	case "request_changes":
+		return "✏️ Cambios pedidos"
+	}
+	return e.Action
+}
+
+func (e ReviewEvent) CreatedLabel() string { return LimaTime(e.CreatedAt) }
+
+// Notification es un aviso del panel para un miembro del equipo
+type Notification struct {
+	ID        int    `json:"id,omitempty"`
+	Recipient string `json:"recipient"`
+	Message   string `json:"message"`
+	Link      string `json:"link"` // ruta del panel que se carga en #main-panel
+	Read      bool   `json:"read"`
+	CreatedAt string `json:"created_at,omitempty"`
+}
+
+func (n Notification) CreatedLabel() string { return LimaTime(n.CreatedAt) }
+
+// ContentText es el texto principal de una fila (inglés de la frase o
+// pregunta del quiz), para buscar contenido casi duplicado
+type ContentText struct {
+	ID   int    `json:"id"`
+	Text string `json:"text"`
+}
+
+// TagCount es el número de elementos que usan una etiqueta, por tipo
+type TagCount struct {
+	Tag       string `json:"tag"`
+	Sentences int    `json:"sentences"`
+	Quizzes   int    `json:"quizzes"`
+	Resources int    `json:"resources"`
+}
+
+// Total suma los tres tipos de contenido
+func (t TagCount) Total() int {
+	return t.Sentences + t.Quizzes + t.Resources
+}
+
 type Sentence struct {
-	ID      int    `json:"id,omitempty"`
-	English string `json:"english"`
-	Spanish string `json:"spanish"`
+	ID        int    `json:"id,omitempty"`
+	English   string `json:"english"`
+	Spanish   string `json:"spanish"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+	Taxonomy
+	Publication
+
+	// Datos opcionales para que el contexto no acabe dentro de Spanish
+	GrammarNote string   `json:"grammar_note"`
+	Register    string   `json:"register"` // "", "formal" o "informal"
+	Examples    []string `json:"examples"`
+	IPA         string   `json:"ipa"`
+	AudioURL    string   `json:"audio_url"`
+
+	// Traducciones a otros idiomas (i18n.Extra); Spanish es la de respaldo
+	Translations map[string]string `json:"translations"`
+}
+
+// Translation es la traducción de la frase al idioma pedido o, si falta, la española
+func (s Sentence) Translation(locale string) string {
+	if t := s.Translations[locale]; t != "" {
+		return t
+	}
+	return s.Spanish
+}
+
+// Registros de una frase (vacío = neutro)
+const (
+	RegisterFormal   = "formal"
+	RegisterInformal = "informal"
+)
+
+// RegisterLabel es el registro en español para las tarjetas
+func (s Sentence) RegisterLabel() string {
+	switch s.Register {
+	case RegisterFormal:
+		return "Formal"
+	case RegisterInformal:
+		return "Informal"
+	}
+	return ""
+}
+
+// ExamplesInput son los ejemplos extra, uno por línea, para el formulario
+func (s Sentence) ExamplesInput() string {
+	return strings.Join(s.Examples, "\n")
+}
+
+// HasDetails indica si la frase tiene algún dato opcional que mostrar
+func (s Sentence) HasDetails() bool {
+	return s.GrammarNote != "" || s.Register != "" || len(s.Examples) > 0 || s.IPA != "" || s.AudioURL != ""
+}
+
+// Tipos de pregunta del motor de quizzes
+const (
+	QuizSingle    = "single"    // una sola opción correcta
+	QuizMultiple  = "multiple"  // una o más opciones correctas
+	QuizTrueFalse = "truefalse" // verdadero/falso
+	QuizFill      = "fill"      // completar el hueco (___) con variantes aceptadas
+	QuizOrdering  = "ordering"  // las opciones se guardan en el orden correcto
+)
+
+// MaxQuizOptions es el número de casillas de opción del formulario
+const MaxQuizOptions = 8
+
+// MaxQuizHints es el número de pistas progresivas que admite un quiz
+const MaxQuizHints = 3
+
+// Note es un texto opcional en español e inglés (explicaciones y pistas).
+// Locales guarda el texto español traducido a los idiomas extra.
+type Note struct {
+	ES      string            `json:"es,omitempty"`
+	EN      string            `json:"en,omitempty"`
+	Locales map[string]string `json:"locales,omitempty"`
+}
+
+func (n Note) Empty() bool { return n.ES == "" && n.EN == "" && len(n.Locales) == 0 }
+
+// Localized cambia el texto español por su traducción al idioma pedido, si existe
+func (n Note) Localized(locale string) Note {
+	if t := n.Locales[locale]; t != "" {
+		n.ES = t
+	}
+	return n
 }
 
 type Quiz struct {
-	ID       int    `json:"id,omitempty"`
-	Question string `json:"question"`
-	Opt1     string `json:"opt1"`
-	Opt2     string `json:"opt2"`
-	Opt3     string `json:"opt3"`
-	Correct  string `json:"correct"`
+	ID        int      `json:"id,omitempty"`
+	Question  string   `json:"question"`
+	Type      string   `json:"type"`
+	Options   []string `json:"options"`
+	Answers   []string `json:"answers"` // opciones correctas o variantes aceptadas (fill)
+	UpdatedAt string   `json:"updated_at,omitempty"`
+	Taxonomy
+	Publication
+
+	// Retroalimentación: explicación general, una nota por opción (paralela
+	// a Options, sólo en única y múltiple) y pistas de menor a mayor ayuda
+	Explanation Note   `json:"explanation"`
+	OptionNotes []Note `json:"option_notes"`
+	Hints       []Note `json:"hints"`
+
+	// Frase de la que salió el quiz si lo creó el generador de huecos (0 = a mano)
+	SourceSentenceID int `json:"source_sentence_id,omitempty"`
+}
+
+// QuizOption es una opción tal como la pintan las plantillas
+type QuizOption struct {
+	Index   int
+	Number  int // Index + 1, para mostrar
+	Text    string
+	Correct bool
+	Note    Note // por qué la opción es (in)correcta
+}
+
+// OptionViews marca qué opciones son correctas (en ordering lo son todas
+// porque el orden guardado ya es la respuesta)
+func (q Quiz) OptionViews() []QuizOption {
+	views := make([]QuizOption, len(q.Options))
+	for i, o := range q.Options {
+		views[i] = QuizOption{Index: i, Number: i + 1, Text: o, Correct: q.Type == QuizOrdering || q.isAnswer(o)}
+		if i < len(q.OptionNotes) {
+			views[i].Note = q.OptionNotes[i]
+		}
+	}
+	return views
+}
+
+// HintSlot es una casilla de pista del formulario
+type HintSlot struct {
+	Number int
+	Note
+}
+
+// HintSlots rellena las MaxQuizHints casillas de pista del formulario
+func (q Quiz) HintSlots() []HintSlot {
+	slots := make([]HintSlot, MaxQuizHints)
+	for i := range slots {
+		slots[i].Number = i + 1
+		if i < len(q.Hints) {
+			slots[i].Note = q.Hints[i]
+		}
+	}
+	return slots
+}
+
+// HasOptionNotes indica si el tipo de pregunta admite notas por opción
+func (q Quiz) HasOptionNotes() bool {
+	return q.Type == QuizSingle || q.Type == QuizMultiple || q.Type == ""
+}
+
+// Scrambled mezcla las opciones de un "ordenar" siempre igual para el mismo
+// quiz, así la página no cambia al recargar
+func (q Quiz) Scrambled() []string {
+	return Scramble(q.Options, uint64(q.ID), 0)
+}
+
+// Scramble devuelve una copia mezclada de forma determinista según las
+// semillas; nunca devuelve el orden original si hay más de un elemento
+func Scramble(items []string, seed1, seed2 uint64) []string {
+	out := append([]string(nil), items...)
+	rng := rand.New(rand.NewPCG(seed1, seed2))
+	rng.Shuffle(len(out), func(i, j int) { out[i], out[j] = out[j], out[i] })
+	if len(out) > 1 && slices.Equal(out, items) {
+		out = append(out[1:], out[0])
+	}
+	return out
+}
+
+// Slots rellena las MaxQuizOptions casillas del formulario (vacías al final)
+func (q Quiz) Slots() []QuizOption {
+	slots := make([]QuizOption, MaxQuizOptions)
+	for i := range slots {
+		slots[i].Index, slots[i].Number = i, i+1
+	}
+	for i, o := range q.OptionViews() {
+		if i < MaxQuizOptions {
+			slots[i] = o
+		}
+	}
+	return slots
+}
+
+func (q Quiz) isAnswer(option string) bool {
+	for _, a := range q.Answers {
+		if a == option {
+			return true
+		}
+	}
+	return false
+}
+
+// AcceptedInput son las variantes de un fill-in-the-blank, una por línea
+func (q Quiz) AcceptedInput() string {
+	return strings.Join(q.Answers, "\n")
+}
+
+// AnswerIsTrue indica la respuesta de un verdadero/falso
+func (q Quiz) AnswerIsTrue() bool {
+	return len(q.Answers) == 1 && q.Answers[0] == "True"
+}
+
+// CorrectLabel resume la respuesta correcta para listas, CSV y SEO
+func (q Quiz) CorrectLabel() string {
+	switch q.Type {
+	case QuizOrdering:
+		return strings.Join(q.Options, " → ")
+	case QuizFill:
+		return strings.Join(q.Answers, " / ")
+	case QuizTrueFalse:
+		if q.AnswerIsTrue() {
+			return "Verdadero"
+		}
+		return "Falso"
+	}
+	return strings.Join(q.Answers, ", ")
+}
+
+// TypeLabel es el nombre del tipo de pregunta en el panel
+func (q Quiz) TypeLabel() string {
+	switch q.Type {
+	case QuizMultiple:
+		return "Opción múltiple"
+	case QuizTrueFalse:
+		return "Verdadero/Falso"
+	case QuizFill:
+		return "Completar"
+	case QuizOrdering:
+		return "Ordenar"
+	}
+	return "Opción única"
+}
+
+// Choosable indica si el alumno responde eligiendo entre botones
+func (q Quiz) Choosable() bool {
+	return q.Type == QuizSingle || q.Type == QuizMultiple || q.Type == QuizTrueFalse || q.Type == ""
 }
 
 type Resource struct {
-	ID    int    `json:"id,omitempty"`
-	Title string `json:"title"`
-	URL   string `json:"url"`
-	Type  string `json:"type"`
+	ID        int    `json:"id,omitempty"`
+	Title     string `json:"title"`
+	URL       string `json:"url"`
+	Type      string `json:"type"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+	Taxonomy
+	Publication
+
+	// Vista previa obtenida de OpenGraph/oEmbed al pegar la URL
+	Description  string `json:"description,omitempty"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+	Duration     int    `json:"duration,omitempty"` // segundos
+	SiteName     string `json:"site_name,omitempty"`
+
+	// Estado del enlace según el verificador en segundo plano
+	LinkStatus     int    `json:"link_status,omitempty"`
+	LinkRedirect   string `json:"link_redirect,omitempty"`
+	LinkCheckedAt  string `json:"link_checked_at,omitempty"`
+	LinkFailStreak int    `json:"link_fail_streak,omitempty"`
+
+	// Título traducido a los idiomas extra (i18n.Extra)
+	TitleTranslations map[string]string `json:"title_translations,omitempty"`
+}
+
+// LocalTitle es el título en el idioma pedido o el original si falta la traducción
+func (r Resource) LocalTitle(locale string) string {
+	if t := r.TitleTranslations[locale]; t != "" {
+		return t
+	}
+	return r.Title
+}
+
+// LinkBroken es true si la última revisión del enlace falló
+func (r Resource) LinkBroken() bool {
+	return r.LinkFailStreak > 0
+}
+
+// DurationLabel formatea la duración como 4:05 o 1:02:03
+func (r Resource) DurationLabel() string {
+	if r.Duration <= 0 {
+		return ""
+	}
+	h, m, s := r.Duration/3600, r.Duration%3600/60, r.Duration%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
+
+// EmbedKey identifica a un colegio socio que incrusta nuestro contenido
+type EmbedKey struct {
+	ID             int      `json:"id,omitempty"`
+	Key            string   `json:"key"`
+	Partner        string   `json:"partner"`
+	AllowedOrigins []string `json:"allowed_origins"`
+	Mode           string   `json:"mode"` // "sentences" o "quiz"
+	QuizID         int      `json:"quiz_id,omitempty"`
+	Theme          string   `json:"theme"`
+	Views          int      `json:"views,omitempty"`
+}
+
+// LTIPlatform es un LMS registrado que lanza nuestros quizzes por LTI 1.3.
+// Los datos salen del registro de la herramienta en la plataforma.
+type LTIPlatform struct {
+	ID            int      `json:"id,omitempty"`
+	Name          string   `json:"name"`
+	Issuer        string   `json:"issuer"`
+	ClientID      string   `json:"client_id"`
+	DeploymentIDs []string `json:"deployment_ids"`
+	AuthLoginURL  string   `json:"auth_login_url"` // endpoint de autorización OIDC
+	AuthTokenURL  string   `json:"auth_token_url"` // token OAuth2 para los servicios (AGS)
+	JWKSURL       string   `json:"jwks_url"`
+}
+
+// Media es un archivo subido (PDF, audio...) deduplicado por su SHA-256
+type Media struct {
+	ID          int    `json:"id,omitempty"`
+	SHA256      string `json:"sha256"`
+	Key         string `json:"key"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	Private     bool   `json:"private"`
+}
+
+// Course agrupa unidades y lecciones en una ruta de aprendizaje ordenada
+type Course struct {
+	ID          int    `json:"id,omitempty"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Level       string `json:"level"`
+	Published   bool   `json:"published"`
+	UpdatedAt   string `json:"updated_at,omitempty"`
+	Units       []Unit `json:"units,omitempty"`
+}
+
+type Unit struct {
+	ID       int      `json:"id,omitempty"`
+	CourseID int      `json:"course_id"`
+	Title    string   `json:"title"`
+	Position int      `json:"position"`
+	Lessons  []Lesson `json:"lessons,omitempty"`
+}
+
+type Lesson struct {
+	ID       int          `json:"id,omitempty"`
+	UnitID   int          `json:"unit_id"`
+	Title    string       `json:"title"`
+	Position int          `json:"position"`
+	Items    []LessonItem `json:"lesson_items,omitempty"`
+
+	// Se rellenan al mostrar el curso, no vienen de la tabla
+	Completed   bool `json:"-"`
+	Completions int  `json:"-"`
+}
+
+// LessonItem apunta a una frase, quiz o recurso existente dentro de una lección
+type LessonItem struct {
+	ID          int    `json:"id,omitempty"`
+	LessonID    int    `json:"lesson_id"`
+	ContentType string `json:"content_type"` // "sentence", "quiz" o "resource"
+	ContentID   int    `json:"content_id"`
+	Position    int    `json:"position"`
+
+	Sentence *Sentence `json:"-"`
+	Quiz     *Quiz     `json:"-"`
+	Resource *Resource `json:"-"`
+}
+
+// Label es el texto con el que se reconoce el elemento en el editor
+func (i LessonItem) Label() string {
+	switch {
+	case i.Sentence != nil:
+		return i.Sentence.English
+	case i.Quiz != nil:
+		return i.Quiz.Question
+	case i.Resource != nil:
+		return i.Resource.Title
+	}
+	return fmt.Sprintf("%s #%d (no encontrado)", i.ContentType, i.ContentID)
+}
+
+// Lima es la hora oficial de Perú (UTC-5, sin horario de verano)
+var Lima = time.FixedZone("America/Lima", -5*60*60)
+
+// LimaTime muestra un timestamp de Supabase en hora de Lima
+func LimaTime(ts string) string {
+	t, err := time.Parse(time.RFC3339, ts)
+	if err != nil {
+		return ts
+	}
+	return t.In(Lima).Format("02/01/2006 15:04")
+}
+
+// LimaInputLayout es el formato de <input type="datetime-local">
+const LimaInputLayout = "2006-01-02T15:04"
+
+// LimaInput convierte un timestamp de Supabase al valor de un datetime-local en hora de Lima
+func LimaInput(ts string) string {
+	t, err := time.Parse(time.RFC3339, ts)
+	if err != nil {
+		return ""
+	}
+	return t.In(Lima).Format(LimaInputLayout)
+}
+
+// ExamRule toma Count preguntas al azar entre los quizzes con esa etiqueta
+// y nivel (vacíos = cualquiera)
+type ExamRule struct {
+	Tag   string `json:"tag"`
+	Level string `json:"level"`
+	Count int    `json:"count"`
+}
+
+type Exam struct {
+	ID           int        `json:"id,omitempty"`
+	Title        string     `json:"title"`
+	Description  string     `json:"description"`
+	Rules        []ExamRule `json:"rules"`
+	TimeLimit    int        `json:"time_limit"`    // minutos
+	MaxAttempts  int        `json:"max_attempts"`  // por alumno
+	PassingScore int        `json:"passing_score"` // porcentaje mínimo
+	Published    bool       `json:"published"`
+	UpdatedAt    string     `json:"updated_at,omitempty"`
+}
+
+// Questions es el número total de preguntas que sortea el examen
+func (e Exam) Questions() int {
+	n := 0
+	for _, r := range e.Rules {
+		n += r.Count
+	}
+	return n
+}
+
+// ExamAttempt guarda las preguntas sorteadas, las respuestas que el alumno
+// va enviando y, al cerrarse, la nota con el desglose por etiqueta
+type ExamAttempt struct {
+	ID          int                 `json:"id,omitempty"`
+	ExamID      int                 `json:"exam_id"`
+	Student     string              `json:"student_id"`
+	AttemptNo   int                 `json:"attempt_no,omitempty"` // 1, 2…: único por examen y alumno
+	QuizIDs     []int               `json:"quiz_ids"`
+	Responses   map[string][]string `json:"responses"`
+	StartedAt   string              `json:"started_at,omitempty"`
+	Deadline    string              `json:"deadline"`
+	SubmittedAt string              `json:"submitted_at,omitempty"`
+	HintsUsed   map[string]int      `json:"hints_used"` // pistas pedidas por id de quiz
+	Score       int                 `json:"score"`
+	Passed      bool                `json:"passed"`
+	Breakdown   []TagScore          `json:"breakdown"`
+}
+
+// TotalHints es el número de pistas que pidió el alumno en el intento
+func (a ExamAttempt) TotalHints() int {
+	n := 0
+	for _, used := range a.HintsUsed {
+		n += used
+	}
+	return n
+}
+
+func (a ExamAttempt) Submitted() bool { return a.SubmittedAt != "" }
+
+func (a ExamAttempt) StartedLabel() string { return LimaTime(a.StartedAt) }
+
+func (a ExamAttempt) DeadlineLabel() string { return LimaTime(a.Deadline) }
+
+func (a ExamAttempt) SubmittedLabel() string { return LimaTime(a.SubmittedAt) }
+
+// ShortStudent abrevia el id anónimo del alumno para las tablas del panel
+func (a ExamAttempt) ShortStudent() string {
+	if len(a.Student) > 8 {
+		return a.Student[:8]
+	}
+	return a.Student
+}
+
+// TagScore es el resultado de un examen restringido a una etiqueta
+type TagScore struct {
+	Tag     string `json:"tag"`
+	Correct int    `json:"correct"`
+	Total   int    `json:"total"`
+}
+
+func (t TagScore) Percent() int {
+	if t.Total == 0 {
+		return 0
+	}
+	return t.Correct * 100 / t.Total
 }
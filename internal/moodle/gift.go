@@ -0,0 +1,303 @@
+package moodle
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"english-at-lima-cms/internal/models"
+)
+
+// giftEscaper protege los caracteres con significado en GIFT; los saltos de
+// línea van como \n porque una línea en blanco separa preguntas
+var giftEscaper = strings.NewReplacer(`\`, `\\`, "~", `\~`, "=", `\=`, "#", `\#`, "{", `\{`, "}", `\}`, ":", `\:`, "\r", "", "\n", `\n`)
+
+func giftEscape(s string) string { return giftEscaper.Replace(s) }
+
+// giftUnescape deshace giftEscape: \n es un salto y \X es X
+func giftUnescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			if s[i] == 'n' {
+				b.WriteByte('\n')
+			} else {
+				b.WriteByte(s[i])
+			}
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// giftWriter escribe una pregunta por bloque. El id, las etiquetas y las
+// pistas van en comentarios: Moodle lee [id:] y [tag:] e ignora [hint],
+// que sólo recupera el importador del CMS.
+type giftWriter struct {
+	w        io.Writer
+	category string
+}
+
+func (g *giftWriter) Write(q models.Quiz) error {
+	qs, err := toQuestion(q)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	if qs.Category != g.category {
+		g.category = qs.Category
+		fmt.Fprintf(&b, "$CATEGORY: %s\n\n", qs.Category)
+	}
+
+	var meta []string
+	if qs.IDNumber != "" {
+		meta = append(meta, "[id:"+qs.IDNumber+"]")
+	}
+	for _, t := range qs.Tags {
+		meta = append(meta, "[tag:"+t+"]")
+	}
+	if len(meta) > 0 {
+		fmt.Fprintf(&b, "// %s\n", strings.Join(meta, " "))
+	}
+	for _, h := range qs.Hints {
+		fmt.Fprintf(&b, "// [hint] %s\n", giftEscape(noteText(h)))
+	}
+
+	fmt.Fprintf(&b, "::%s::[markdown]%s {\n", giftEscape(qs.Name), giftEscape(qs.Text))
+	switch qs.Type {
+	case typeTrueFalse:
+		if qs.Answers[0].Fraction > 0 {
+			b.WriteString("\tTRUE\n")
+		} else {
+			b.WriteString("\tFALSE\n")
+		}
+	default:
+		for _, a := range qs.Answers {
+			switch {
+			case qs.Type == typeShortAnswer || (qs.Single && a.Fraction == 100):
+				b.WriteString("\t=")
+			case qs.Single:
+				b.WriteString("\t~")
+			default:
+				fmt.Fprintf(&b, "\t~%%%s%%", formatFraction(a.Fraction))
+			}
+			b.WriteString(giftEscape(a.Text))
+			if !a.Feedback.Empty() {
+				b.WriteString("#" + giftEscape(noteText(a.Feedback)))
+			}
+			b.WriteByte('\n')
+		}
+	}
+	if !qs.Explanation.Empty() {
+		fmt.Fprintf(&b, "\t####%s\n", giftEscape(noteText(qs.Explanation)))
+	}
+	b.WriteString("}\n\n")
+	_, err = io.WriteString(g.w, b.String())
+	return err
+}
+
+func (g *giftWriter) Close() error { return nil }
+
+var (
+	reGIFTTag    = regexp.MustCompile(`\[tag:([^\]]+)\]`)
+	reGIFTID     = regexp.MustCompile(`\[id:([^\]]+)\]`)
+	reGIFTHint   = regexp.MustCompile(`^\[hint\]\s?(.*)$`)
+	reGIFTFormat = regexp.MustCompile(`^\[(html|moodle|plain|markdown)\]`)
+)
+
+// readGIFT separa las preguntas por líneas en blanco. Los comentarios (//)
+// de antes de cada pregunta aportan id, etiquetas y pistas; $CATEGORY:
+// cambia la categoría de las siguientes.
+func readGIFT(text string) []Item {
+	var items []Item
+	var block, comments []string
+	category := ""
+	start := 0
+	flush := func() {
+		if len(block) > 0 {
+			q, err := fromGIFT(strings.Join(block, "\n"), comments, category)
+			items = append(items, Item{Line: start, Quiz: q, Err: err})
+		}
+		block, comments = nil, nil
+	}
+
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			flush()
+		case strings.HasPrefix(trimmed, "//"):
+			comments = append(comments, strings.TrimSpace(strings.TrimPrefix(trimmed, "//")))
+		case len(block) == 0 && strings.HasPrefix(trimmed, "$CATEGORY:"):
+			category = strings.TrimSpace(strings.TrimPrefix(trimmed, "$CATEGORY:"))
+			comments = nil
+		default:
+			if len(block) == 0 {
+				start = i + 1
+			}
+			block = append(block, line)
+		}
+	}
+	flush()
+	return items
+}
+
+// indexUnescaped busca sub saltándose lo escapado con \
+func indexUnescaped(s, sub string, from int) int {
+	for i := from; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(s[i:], sub) {
+			return i
+		}
+	}
+	return -1
+}
+
+func fromGIFT(text string, comments []string, category string) (models.Quiz, error) {
+	qs := question{Category: category}
+	for _, c := range comments {
+		for _, m := range reGIFTTag.FindAllStringSubmatch(c, -1) {
+			qs.Tags = append(qs.Tags, strings.TrimSpace(m[1]))
+		}
+		if m := reGIFTID.FindStringSubmatch(c); m != nil {
+			qs.IDNumber = strings.TrimSpace(m[1])
+		}
+	}
+
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "::") {
+		if end := indexUnescaped(text, "::", 2); end > 0 {
+			qs.Name = strings.TrimSpace(giftUnescape(text[2:end]))
+			text = strings.TrimSpace(text[end+2:])
+		}
+	}
+	format := "moodle"
+	if m := reGIFTFormat.FindStringSubmatch(text); m != nil {
+		format = m[1]
+		text = text[len(m[0]):]
+	}
+	for _, c := range comments {
+		if m := reGIFTHint.FindStringSubmatch(c); m != nil {
+			qs.Hints = append(qs.Hints, parseNote(giftUnescape(m[1]), format))
+		}
+	}
+
+	open := indexUnescaped(text, "{", 0)
+	end := -1
+	if open >= 0 {
+		end = indexUnescaped(text, "}", open)
+	}
+	if end < 0 {
+		qs.Text = decodeText(giftUnescape(text), format)
+		return models.Quiz{Question: qs.Text}, fmt.Errorf("la pregunta no tiene bloque de respuestas { }")
+	}
+	// Con texto después de las llaves es una pregunta de «palabra que falta»
+	head, tail := text[:open], text[end+1:]
+	if strings.TrimSpace(tail) != "" {
+		head = head + "___" + tail
+	}
+	qs.Text = strings.TrimSpace(decodeText(giftUnescape(head), format))
+
+	body := text[open+1 : end]
+	if i := indexUnescaped(body, "####", 0); i >= 0 {
+		qs.Explanation = parseNote(giftUnescape(body[i+4:]), format)
+		body = body[:i]
+	}
+	body = strings.TrimSpace(body)
+	if err := giftAnswers(&qs, body, format); err != nil {
+		return models.Quiz{Question: qs.Text}, err
+	}
+	return fromQuestion(qs)
+}
+
+// giftAnswers reconoce el tipo por las respuestas: T/F es verdadero/falso,
+// con alguna ~ es multichoice (de una respuesta si alguna va con =) y sólo
+// con = es respuesta corta
+func giftAnswers(qs *question, body, format string) error {
+	truth := body
+	if i := indexUnescaped(body, "#", 0); i >= 0 {
+		truth = strings.TrimSpace(body[:i])
+	}
+	switch strings.ToUpper(truth) {
+	case "T", "TRUE", "F", "FALSE":
+		right := strings.HasPrefix(strings.ToUpper(truth), "T")
+		qs.Type = typeTrueFalse
+		qs.Answers = []answer{{Text: "true", Fraction: boolFraction(right)}, {Text: "false", Fraction: boolFraction(!right)}}
+		return nil
+	}
+	switch {
+	case body == "":
+		return fmt.Errorf("tipo de pregunta de Moodle no admitido: essay")
+	case strings.HasPrefix(body, "#"):
+		return fmt.Errorf("tipo de pregunta de Moodle no admitido: numerical")
+	}
+
+	type token struct {
+		marker byte
+		text   string
+	}
+	var tokens []token
+	for i := 0; i < len(body); i++ {
+		switch body[i] {
+		case '\\':
+			i++
+			if len(tokens) > 0 && i < len(body) {
+				tokens[len(tokens)-1].text += body[i-1 : i+1]
+			}
+		case '=', '~':
+			tokens = append(tokens, token{marker: body[i]})
+		default:
+			if len(tokens) > 0 {
+				tokens[len(tokens)-1].text += body[i : i+1]
+			}
+		}
+	}
+
+	choice, single := false, false
+	for _, t := range tokens {
+		choice = choice || t.marker == '~'
+		single = single || t.marker == '='
+	}
+	qs.Type, qs.Single = typeShortAnswer, single
+	if choice {
+		qs.Type = typeMultichoice
+	}
+	for _, t := range tokens {
+		raw := strings.TrimSpace(t.text)
+		if indexUnescaped(raw, "->", 0) >= 0 {
+			return fmt.Errorf("tipo de pregunta de Moodle no admitido: matching")
+		}
+		a := answer{}
+		weighted := false
+		if strings.HasPrefix(raw, "%") {
+			if end := strings.Index(raw[1:], "%"); end >= 0 {
+				a.Fraction, _ = strconv.ParseFloat(raw[1:end+1], 64)
+				raw, weighted = strings.TrimSpace(raw[end+2:]), true
+			}
+		}
+		if !weighted && (t.marker == '=') {
+			a.Fraction = 100
+		}
+		if i := indexUnescaped(raw, "#", 0); i >= 0 {
+			a.Feedback = parseNote(giftUnescape(strings.TrimSpace(raw[i+1:])), format)
+			raw = raw[:i]
+		}
+		a.Text = strings.TrimSpace(decodeText(giftUnescape(raw), format))
+		qs.Answers = append(qs.Answers, a)
+	}
+	if len(qs.Answers) == 0 {
+		return fmt.Errorf("la pregunta no tiene respuestas")
+	}
+	return nil
+}
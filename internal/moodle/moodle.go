@@ -0,0 +1,404 @@
+// Package moodle lleva los quizzes al banco de preguntas de Moodle y los trae
+// de vuelta, en Moodle XML y en GIFT. Opción única y múltiple son preguntas
+// multichoice, verdadero/falso es truefalse y completar el hueco es
+// shortanswer; ordenar no tiene equivalente. La categoría sale del nivel y la
+// primera etiqueta, las notas por opción van como retroalimentación de cada
+// respuesta y los textos en inglés u otros idiomas viajan en el formato
+// «multilang» de Moodle.
+package moodle
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"english-at-lima-cms/internal/models"
+	"english-at-lima-cms/internal/quiz"
+	"english-at-lima-cms/internal/taxonomy"
+
+	xhtml "golang.org/x/net/html"
+)
+
+// Formatos de banco de preguntas
+const (
+	XML  = "xml"
+	GIFT = "gift"
+)
+
+// Formats son los formatos en el orden en que se ofrecen
+var Formats = []string{XML, GIFT}
+
+// Types son los tipos de quiz que tienen equivalente en Moodle
+var Types = []string{models.QuizSingle, models.QuizMultiple, models.QuizTrueFalse, models.QuizFill}
+
+// RootCategory es la categoría de Moodle de la que cuelgan las preguntas
+const RootCategory = "English at Lima"
+
+// Tipos de pregunta de Moodle
+const (
+	typeCategory    = "category"
+	typeMultichoice = "multichoice"
+	typeTrueFalse   = "truefalse"
+	typeShortAnswer = "shortanswer"
+)
+
+// ErrUnsupported es el error al exportar un quiz sin equivalente en Moodle
+var ErrUnsupported = errors.New("los quizzes de ordenar no tienen equivalente en Moodle")
+
+// Valid indica si el formato existe
+func Valid(format string) bool { return slices.Contains(Formats, format) }
+
+// ContentType es el tipo MIME de la descarga
+func ContentType(format string) string {
+	if format == XML {
+		return "application/xml; charset=utf-8"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// Extension es la extensión del archivo: Moodle espera los GIFT como .txt
+func Extension(format string) string {
+	if format == XML {
+		return ".xml"
+	}
+	return ".txt"
+}
+
+// Writer escribe el banco pregunta a pregunta, sin tenerlo entero en memoria
+type Writer interface {
+	Write(q models.Quiz) error
+	Close() error
+}
+
+// NewWriter prepara el banco en el formato pedido
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case XML:
+		return newXMLWriter(w), nil
+	case GIFT:
+		return &giftWriter{w: w}, nil
+	}
+	return nil, fmt.Errorf("formato de banco de preguntas desconocido: %s", format)
+}
+
+// Item es una pregunta leída del banco con la línea donde empieza, o el
+// motivo por el que no se puede importar
+type Item struct {
+	Line int
+	Quiz models.Quiz
+	Err  error
+}
+
+// Read lee un banco en Moodle XML o GIFT según el contenido: el XML empieza por «<»
+func Read(data []byte) ([]Item, error) {
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	if !utf8.ValidString(text) {
+		return nil, errors.New("el banco de preguntas debe estar en UTF-8")
+	}
+	if strings.HasPrefix(strings.TrimSpace(text), "<") {
+		return readXML(strings.NewReader(text))
+	}
+	return readGIFT(text), nil
+}
+
+// answer y question describen una pregunta de Moodle sin atarse al formato:
+// los dos lectores y los dos escritores pasan por aquí
+type answer struct {
+	Text     string
+	Fraction float64 // porcentaje de la nota: 100 correcta, 0 o negativo incorrecta
+	Feedback models.Note
+}
+
+type question struct {
+	Type        string
+	Name        string
+	IDNumber    string
+	Text        string
+	Single      bool // multichoice: una sola respuesta
+	Answers     []answer
+	Explanation models.Note
+	Hints       []models.Note
+	Category    string
+	Tags        []string
+}
+
+// Category es la ruta de la categoría en Moodle: la raíz, el nivel y la
+// primera etiqueta (las demás viajan como etiquetas de la pregunta)
+func Category(t models.Taxonomy) string {
+	parts := []string{"$course$", "top", RootCategory}
+	if t.Level != "" {
+		parts = append(parts, t.Level)
+	}
+	if len(t.Tags) > 0 {
+		parts = append(parts, t.Tags[0])
+	}
+	return strings.Join(parts, "/")
+}
+
+// categoryTaxonomy recupera nivel y etiquetas. Las etiquetas de la pregunta
+// mandan; si no trae ninguna, la última categoría que no sea un nivel hace
+// de etiqueta, así los bancos de otros centros llegan clasificados.
+func categoryTaxonomy(path string, tags []string) models.Taxonomy {
+	tx := models.Taxonomy{Tags: []string{}}
+	last := ""
+	// En Moodle «//» es una barra dentro del nombre, no un separador; las
+	// etiquetas no admiten barras y queda un espacio
+	for _, seg := range strings.Split(strings.ReplaceAll(path, "//", " "), "/") {
+		seg = strings.TrimSpace(seg)
+		switch {
+		case seg == "" || seg == "top" || seg == RootCategory || strings.HasPrefix(seg, "Default for ") ||
+			(strings.HasPrefix(seg, "$") && strings.HasSuffix(seg, "$")):
+			// Raíces y categorías automáticas de Moodle («Default for …»)
+		case taxonomy.ValidLevel(taxonomy.NormalizeLevel(seg)):
+			tx.Level = taxonomy.NormalizeLevel(seg)
+		default:
+			last = seg
+		}
+	}
+	if len(tags) == 0 && last != "" {
+		tags = []string{last}
+	}
+	for _, t := range tags {
+		if t = taxonomy.NormalizeTag(t); t != "" && !slices.Contains(tx.Tags, t) {
+			tx.Tags = append(tx.Tags, t)
+		}
+	}
+	return tx
+}
+
+// toQuestion traduce el quiz a pregunta de Moodle. En opción múltiple las
+// correctas se reparten el 100 % y las incorrectas restan, así marcar todas
+// no puntúa, igual que en quiz.Grade.
+func toQuestion(q models.Quiz) (question, error) {
+	qs := question{
+		Name:        questionName(q.Question),
+		Text:        q.Question,
+		Explanation: q.Explanation,
+		Hints:       q.Hints,
+		Category:    Category(q.Taxonomy),
+		Tags:        q.Tags,
+	}
+	if q.ID > 0 {
+		qs.IDNumber = fmt.Sprintf("eal-quiz-%d", q.ID)
+	}
+
+	switch q.Type {
+	case models.QuizSingle, models.QuizMultiple:
+		qs.Type, qs.Single = typeMultichoice, q.Type == models.QuizSingle
+		right := 0
+		for _, opt := range q.Options {
+			if slices.Contains(q.Answers, opt) {
+				right++
+			}
+		}
+		wrong := len(q.Options) - right
+		for i, opt := range q.Options {
+			a := answer{Text: opt}
+			switch {
+			case slices.Contains(q.Answers, opt):
+				a.Fraction = 100
+				if !qs.Single {
+					a.Fraction = 100 / float64(right)
+				}
+			case !qs.Single:
+				a.Fraction = -100 / float64(wrong)
+			}
+			if i < len(q.OptionNotes) {
+				a.Feedback = q.OptionNotes[i]
+			}
+			qs.Answers = append(qs.Answers, a)
+		}
+	case models.QuizTrueFalse:
+		qs.Type = typeTrueFalse
+		truth := len(q.Answers) == 1 && q.Answers[0] == quiz.TrueFalseOptions[0]
+		qs.Answers = []answer{{Text: "true", Fraction: boolFraction(truth)}, {Text: "false", Fraction: boolFraction(!truth)}}
+	case models.QuizFill:
+		qs.Type = typeShortAnswer
+		for _, a := range q.Answers {
+			qs.Answers = append(qs.Answers, answer{Text: a, Fraction: 100})
+		}
+	default:
+		return qs, ErrUnsupported
+	}
+	return qs, nil
+}
+
+func boolFraction(b bool) float64 {
+	if b {
+		return 100
+	}
+	return 0
+}
+
+// fromQuestion traduce la pregunta de Moodle a quiz. En opción única sólo
+// cuenta como correcta la respuesta del 100 %; en múltiple, las que suman.
+func fromQuestion(qs question) (models.Quiz, error) {
+	q := models.Quiz{
+		Question:    qs.Text,
+		Explanation: qs.Explanation,
+		Hints:       qs.Hints,
+		Taxonomy:    categoryTaxonomy(qs.Category, qs.Tags),
+		Options:     []string{},
+		Answers:     []string{},
+	}
+	switch qs.Type {
+	case typeMultichoice:
+		q.Type = models.QuizMultiple
+		if qs.Single {
+			q.Type = models.QuizSingle
+		}
+		for _, a := range qs.Answers {
+			q.Options = append(q.Options, a.Text)
+			q.OptionNotes = append(q.OptionNotes, a.Feedback)
+			if (qs.Single && a.Fraction >= 99.99) || (!qs.Single && a.Fraction > 0) {
+				q.Answers = append(q.Answers, a.Text)
+			}
+		}
+	case typeTrueFalse:
+		q.Type = models.QuizTrueFalse
+		q.Options = slices.Clone(quiz.TrueFalseOptions)
+		for _, a := range qs.Answers {
+			if a.Fraction <= 0 {
+				continue
+			}
+			if strings.EqualFold(strings.TrimSpace(a.Text), "true") {
+				q.Answers = []string{quiz.TrueFalseOptions[0]}
+			} else {
+				q.Answers = []string{quiz.TrueFalseOptions[1]}
+			}
+		}
+	case typeShortAnswer:
+		q.Type = models.QuizFill
+		for _, a := range qs.Answers {
+			if a.Fraction >= 99.99 {
+				q.Answers = append(q.Answers, a.Text)
+			}
+		}
+		// Moodle pone la caja de respuesta al final; aquí el hueco va marcado
+		if !strings.Contains(q.Question, quiz.Blank) {
+			q.Question = strings.TrimSpace(q.Question + " " + quiz.Blank)
+		}
+	default:
+		return q, fmt.Errorf("tipo de pregunta de Moodle no admitido: %s", qs.Type)
+	}
+	return q, nil
+}
+
+// questionName es el nombre corto que Moodle muestra en el banco
+func questionName(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= 60 {
+		return text
+	}
+	return string([]rune(text)[:59]) + "…"
+}
+
+// formatFraction escribe el porcentaje como lo hace Moodle: 100, 50, 33.33333
+func formatFraction(f float64) string {
+	s := strconv.FormatFloat(f, 'f', 5, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+// --- Textos ---
+
+var reMultilang = regexp.MustCompile(`(?s)<span\s+(?:lang="([\w-]+)"\s+class="multilang"|class="multilang"\s+lang="([\w-]+)")\s*>(.*?)</span>`)
+
+// noteText escribe la nota en Markdown (que admite HTML, por eso va
+// escapada). Si además del español tiene inglés u otros idiomas, cada texto
+// va en su <span class="multilang">, que el filtro multilang de Moodle
+// muestra según el idioma del alumno.
+func noteText(n models.Note) string {
+	if n.EN == "" && len(n.Locales) == 0 {
+		return html.EscapeString(n.ES)
+	}
+	var b strings.Builder
+	span := func(lang, text string) {
+		fmt.Fprintf(&b, `<span lang="%s" class="multilang">%s</span>`, lang, html.EscapeString(text))
+	}
+	span("es", n.ES)
+	if n.EN != "" {
+		span("en", n.EN)
+	}
+	codes := make([]string, 0, len(n.Locales))
+	for code := range n.Locales {
+		codes = append(codes, code)
+	}
+	slices.Sort(codes)
+	for _, code := range codes {
+		span(code, n.Locales[code])
+	}
+	return b.String()
+}
+
+// parseNote lee una nota escrita por noteText o por Moodle
+func parseNote(text, format string) models.Note {
+	matches := reMultilang.FindAllStringSubmatch(text, -1)
+	if matches == nil {
+		return models.Note{ES: decodeText(text, format)}
+	}
+	var n models.Note
+	for _, m := range matches {
+		lang := strings.ToLower(m[1] + m[2])
+		value := decodeText(m[3], format)
+		switch lang {
+		case "es":
+			n.ES = value
+		case "en":
+			n.EN = value
+		default:
+			if n.Locales == nil {
+				n.Locales = map[string]string{}
+			}
+			n.Locales[lang] = value
+		}
+	}
+	return n
+}
+
+// decodeText pasa a texto lo que llega de Moodle: el HTML pierde las
+// etiquetas y el resto sólo las entidades
+func decodeText(text, format string) string {
+	if format == "html" {
+		return htmlToText(text)
+	}
+	return html.UnescapeString(text)
+}
+
+var reSpaces = regexp.MustCompile(`[ \t\r\n]+`)
+
+// blockTags son las etiquetas HTML que cortan la línea al pasar a texto
+var blockTags = map[string]bool{"br": true, "p": true, "div": true, "li": true, "tr": true, "h1": true, "h2": true, "h3": true, "h4": true, "blockquote": true}
+
+// htmlToText deja el texto visible del HTML, con un salto por bloque
+func htmlToText(s string) string {
+	z := xhtml.NewTokenizer(strings.NewReader(s))
+	var b strings.Builder
+	for {
+		switch z.Next() {
+		case xhtml.ErrorToken:
+			lines := []string{}
+			for _, line := range strings.Split(b.String(), "\n") {
+				if line = strings.Join(strings.Fields(line), " "); line != "" {
+					lines = append(lines, line)
+				}
+			}
+			return strings.Join(lines, "\n")
+		case xhtml.TextToken:
+			b.WriteString(reSpaces.ReplaceAllString(string(z.Text()), " "))
+		case xhtml.StartTagToken, xhtml.EndTagToken, xhtml.SelfClosingTagToken:
+			if name, _ := z.TagName(); blockTags[string(name)] {
+				b.WriteByte('\n')
+			}
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package moodle
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"english-at-lima-cms/internal/models"
+)
+
+// Un banco escrito a mano, como los que exportan otros centros
+const foreignGIFT = `// Banco de un colegio
+$CATEGORY: $course$/top/B2/Phrasal verbs
+
+::Q1:: Choose the right one {
+	=look after#Cuidar
+	~look for#Buscar
+	~%50%look into
+}
+
+// [tag:Travel] [tag:Airports]
+::Q2:: [html]<p>The plane <b>took off</b> on time.</p>{T#No#Sí####Despegó}
+
+Who wrote "Hamlet"? {=Shakespeare =William Shakespeare =%50%Marlowe}
+
+I {=went ~goed} to Cusco last year.
+
+Escapes\: 50\% \{off\} and a \# sign {~%100%yes ~%-100%no}
+
+Write an essay about Lima. {}
+
+Match the pairs {=cat -> gato =dog -> perro}
+
+How many days in a week? {#7}
+
+No braces at all
+`
+
+func TestReadGIFT(t *testing.T) {
+	items, err := Read([]byte(foreignGIFT))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 9 {
+		t.Fatalf("leyó %d preguntas", len(items))
+	}
+	tests := []struct {
+		line     int
+		question string
+		typ      string
+		options  []string
+		answers  []string
+		level    string
+		tags     []string
+		err      string
+	}{
+		{4, "Choose the right one", models.QuizSingle, []string{"look after", "look for", "look into"}, []string{"look after"}, "B2", []string{"phrasal verbs"}, ""},
+		{11, "The plane took off on time.", models.QuizTrueFalse, []string{"True", "False"}, []string{"True"}, "B2", []string{"travel", "airports"}, ""},
+		{13, `Who wrote "Hamlet"? ___`, models.QuizFill, []string{}, []string{"Shakespeare", "William Shakespeare"}, "B2", []string{"phrasal verbs"}, ""},
+		{15, "I ___ to Cusco last year.", models.QuizSingle, []string{"went", "goed"}, []string{"went"}, "B2", []string{"phrasal verbs"}, ""},
+		{17, "Escapes: 50% {off} and a # sign", models.QuizMultiple, []string{"yes", "no"}, []string{"yes"}, "B2", []string{"phrasal verbs"}, ""},
+		{19, "", "", nil, nil, "", nil, "essay"},
+		{21, "", "", nil, nil, "", nil, "matching"},
+		{23, "", "", nil, nil, "", nil, "numerical"},
+		{25, "", "", nil, nil, "", nil, "bloque de respuestas"},
+	}
+	for i, tt := range tests {
+		it := items[i]
+		if it.Line != tt.line {
+			t.Errorf("pregunta %d: línea %d, esperaba %d", i, it.Line, tt.line)
+		}
+		if tt.err != "" {
+			if it.Err == nil || !strings.Contains(it.Err.Error(), tt.err) {
+				t.Errorf("línea %d: error %v, esperaba %q", tt.line, it.Err, tt.err)
+			}
+			continue
+		}
+		if it.Err != nil {
+			t.Errorf("línea %d: %v", tt.line, it.Err)
+			continue
+		}
+		q := it.Quiz
+		if q.Question != tt.question || q.Type != tt.typ || !reflect.DeepEqual(q.Options, tt.options) ||
+			!reflect.DeepEqual(q.Answers, tt.answers) || q.Level != tt.level || !reflect.DeepEqual(q.Tags, tt.tags) {
+			t.Errorf("línea %d: %#v", tt.line, q)
+		}
+	}
+	if notes := items[0].Quiz.OptionNotes; len(notes) != 3 || notes[0].ES != "Cuidar" || notes[1].ES != "Buscar" {
+		t.Errorf("notas por opción: %#v", notes)
+	}
+	if exp := items[1].Quiz.Explanation; exp.ES != "Despegó" {
+		t.Errorf("explicación: %#v", exp)
+	}
+}
+
+// Un Moodle XML como los que exporta Moodle: HTML en CDATA, <single> con
+// 1/0 y preguntas de tipos que no existen en el CMS
+const foreignXML = `<?xml version="1.0" encoding="UTF-8"?>
+<quiz>
+<!-- question: 0  -->
+  <question type="category">
+    <category><text>$course$/top/Default for Grammar/Reading//Vocab/a1</text></category>
+    <info format="html"><text></text></info>
+  </question>
+  <question type="multichoice">
+    <name><text>Q1</text></name>
+    <questiontext format="html"><text><![CDATA[<p>Pick the <strong>colours</strong>:</p><p>(two)</p>]]></text></questiontext>
+    <generalfeedback format="html"><text><![CDATA[<span lang="en" class="multilang">Colours</span><span class="multilang" lang="es">Colores</span>]]></text></generalfeedback>
+    <single>0</single>
+    <answer fraction="50" format="html"><text><![CDATA[<p>red</p>]]></text><feedback format="html"><text>Yes &amp; more</text></feedback></answer>
+    <answer fraction="50" format="html"><text>blue</text></answer>
+    <answer fraction="-100" format="html"><text>dog</text></answer>
+    <hint format="html"><text>Think of a rainbow</text></hint>
+    <tags><tag><text>Colours</text></tag></tags>
+  </question>
+  <question type="essay">
+    <name><text>Essay</text></name>
+    <questiontext format="html"><text>Describe your city.</text></questiontext>
+  </question>
+  <question type="truefalse">
+    <questiontext format="moodle_auto_format"><text>Water boils at 100 °C.</text></questiontext>
+    <answer fraction="0"><text>true</text></answer>
+    <answer fraction="100"><text>false</text></answer>
+  </question>
+</quiz>`
+
+func TestReadXML(t *testing.T) {
+	items, err := Read([]byte("\ufeff" + foreignXML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("leyó %d preguntas", len(items))
+	}
+	want := models.Quiz{
+		Question: "Pick the colours:\n(two)", Type: models.QuizMultiple,
+		Options: []string{"red", "blue", "dog"}, Answers: []string{"red", "blue"},
+		Taxonomy:    models.Taxonomy{Level: "A1", Tags: []string{"colours"}},
+		Explanation: models.Note{ES: "Colores", EN: "Colours"},
+		OptionNotes: []models.Note{{ES: "Yes & more"}, {}, {}},
+		Hints:       []models.Note{{ES: "Think of a rainbow"}},
+	}
+	if items[0].Err != nil || !reflect.DeepEqual(items[0].Quiz, want) {
+		t.Errorf("multichoice:\n   obtuvo %#v\n esperaba %#v (%v)", items[0].Quiz, want, items[0].Err)
+	}
+	if items[0].Line != 8 {
+		t.Errorf("línea %d, esperaba 8", items[0].Line)
+	}
+	if items[1].Err == nil || !strings.Contains(items[1].Err.Error(), "essay") {
+		t.Errorf("essay: %v", items[1].Err)
+	}
+	if q := items[2].Quiz; items[2].Err != nil || q.Answers[0] != "False" || q.Tags[0] != "reading vocab" {
+		t.Errorf("truefalse: %#v (%v)", q, items[2].Err)
+	}
+
+	for _, bad := range []string{"<html><body>hola</body></html>", "<quiz><question>"} {
+		if _, err := Read([]byte(bad)); err == nil {
+			t.Errorf("%q debería rechazarse", bad)
+		}
+	}
+}
+
+func TestCategoryTaxonomy(t *testing.T) {
+	tests := []struct {
+		path  string
+		tags  []string
+		level string
+		want  []string
+	}{
+		{"$course$/top/English at Lima/A2/food", []string{"food", "Restaurant "}, "A2", []string{"food", "restaurant"}},
+		{"$course$/top/English at Lima/A2/food", nil, "A2", []string{"food"}},
+		{"$system$/top/English at Lima", nil, "", []string{}},
+		{"$course$/Nivel b1/Reading", nil, "", []string{"reading"}},
+		{"$course$/top/c1", []string{"x", "x"}, "C1", []string{"x"}},
+	}
+	for _, tt := range tests {
+		tx := categoryTaxonomy(tt.path, tt.tags)
+		if tx.Level != tt.level || !reflect.DeepEqual(tx.Tags, tt.want) {
+			t.Errorf("categoryTaxonomy(%q, %v) = %q %v", tt.path, tt.tags, tx.Level, tx.Tags)
+		}
+	}
+	if got := Category(models.Taxonomy{Level: "B1", Tags: []string{"travel", "food"}}); got != "$course$/top/English at Lima/B1/travel" {
+		t.Errorf("Category = %s", got)
+	}
+}
+
+func TestWriterRejectsOrdering(t *testing.T) {
+	for _, format := range Formats {
+		var buf bytes.Buffer
+		w, _ := NewWriter(format, &buf)
+		err := w.Write(models.Quiz{Question: "Put in order", Type: models.QuizOrdering, Options: []string{"a", "b"}})
+		if !errors.Is(err, ErrUnsupported) {
+			t.Errorf("%s: error %v", format, err)
+		}
+	}
+	if _, err := NewWriter("qti", &bytes.Buffer{}); err == nil || Valid("qti") {
+		t.Error("un formato desconocido debería rechazarse")
+	}
+}
+
+func TestGIFTEscape(t *testing.T) {
+	for _, s := range []string{`a\b`, "x = ~y # {z}: w", "línea 1\nlínea 2", `\n literal`} {
+		if got := giftUnescape(giftEscape(s)); got != s {
+			t.Errorf("ida y vuelta de %q: %q", s, got)
+		}
+	}
+}
+
+func TestFormatFraction(t *testing.T) {
+	for f, want := range map[float64]string{100: "100", 0: "0", 100.0 / 3: "33.33333", -100.0 / 7: "-14.28571", 12.5: "12.5"} {
+		if got := formatFraction(f); got != want {
+			t.Errorf("formatFraction(%v) = %s, esperaba %s", f, got, want)
+		}
+	}
+}
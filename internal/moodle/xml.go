@@ -0,0 +1,242 @@
+package moodle
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"english-at-lima-cms/internal/models"
+)
+
+// Formatos de texto de Moodle XML: las preguntas y opciones son texto plano,
+// las notas son Markdown como en el resto del CMS
+const (
+	formatPlain    = "plain_text"
+	formatMarkdown = "markdown"
+)
+
+type xmlText struct {
+	Format string `xml:"format,attr,omitempty"`
+	Text   string `xml:"text"`
+}
+
+type xmlAnswer struct {
+	Fraction string   `xml:"fraction,attr"`
+	Format   string   `xml:"format,attr,omitempty"`
+	Text     string   `xml:"text"`
+	Feedback *xmlText `xml:"feedback,omitempty"`
+}
+
+type xmlQuestion struct {
+	XMLName         xml.Name    `xml:"question"`
+	Type            string      `xml:"type,attr"`
+	Category        *xmlText    `xml:"category,omitempty"`
+	Name            *xmlText    `xml:"name,omitempty"`
+	QuestionText    *xmlText    `xml:"questiontext,omitempty"`
+	GeneralFeedback *xmlText    `xml:"generalfeedback,omitempty"`
+	DefaultGrade    string      `xml:"defaultgrade,omitempty"`
+	Hidden          string      `xml:"hidden,omitempty"`
+	IDNumber        string      `xml:"idnumber,omitempty"`
+	Single          string      `xml:"single,omitempty"`
+	ShuffleAnswers  string      `xml:"shuffleanswers,omitempty"`
+	AnswerNumbering string      `xml:"answernumbering,omitempty"`
+	UseCase         string      `xml:"usecase,omitempty"`
+	Answers         []xmlAnswer `xml:"answer"`
+	Hints           []xmlText   `xml:"hint"`
+	Tags            *xmlTags    `xml:"tags,omitempty"`
+}
+
+type xmlTags struct {
+	Tag []xmlText `xml:"tag"`
+}
+
+// xmlWriter escribe <quiz> con una pseudo-pregunta de categoría cada vez
+// que la categoría cambia, como los bancos que exporta Moodle
+type xmlWriter struct {
+	w        io.Writer
+	enc      *xml.Encoder
+	category string
+	started  bool
+}
+
+func newXMLWriter(w io.Writer) *xmlWriter {
+	enc := xml.NewEncoder(w)
+	enc.Indent("  ", "  ")
+	return &xmlWriter{w: w, enc: enc}
+}
+
+func (x *xmlWriter) begin() error {
+	if x.started {
+		return nil
+	}
+	x.started = true
+	_, err := io.WriteString(x.w, xml.Header+"<quiz>\n")
+	return err
+}
+
+func (x *xmlWriter) Write(q models.Quiz) error {
+	qs, err := toQuestion(q)
+	if err != nil {
+		return err
+	}
+	if err := x.begin(); err != nil {
+		return err
+	}
+	if qs.Category != x.category {
+		x.category = qs.Category
+		if err := x.encode(xmlQuestion{Type: typeCategory, Category: &xmlText{Text: qs.Category}}); err != nil {
+			return err
+		}
+	}
+	return x.encode(toXML(qs))
+}
+
+func (x *xmlWriter) encode(v xmlQuestion) error {
+	if err := x.enc.Encode(v); err != nil {
+		return err
+	}
+	if err := x.enc.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(x.w, "\n")
+	return err
+}
+
+func (x *xmlWriter) Close() error {
+	if err := x.begin(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(x.w, "</quiz>\n")
+	return err
+}
+
+func toXML(qs question) xmlQuestion {
+	v := xmlQuestion{
+		Type:         qs.Type,
+		Name:         &xmlText{Text: qs.Name},
+		QuestionText: &xmlText{Format: formatPlain, Text: qs.Text},
+		DefaultGrade: "1",
+		Hidden:       "0",
+		IDNumber:     qs.IDNumber,
+	}
+	if !qs.Explanation.Empty() {
+		v.GeneralFeedback = &xmlText{Format: formatMarkdown, Text: noteText(qs.Explanation)}
+	}
+	switch qs.Type {
+	case typeMultichoice:
+		v.Single = strconv.FormatBool(qs.Single)
+		v.ShuffleAnswers, v.AnswerNumbering = "true", "abc"
+	case typeShortAnswer:
+		v.UseCase = "0"
+	}
+	for _, a := range qs.Answers {
+		xa := xmlAnswer{Fraction: formatFraction(a.Fraction), Text: a.Text}
+		if qs.Type == typeMultichoice {
+			xa.Format = formatPlain
+		}
+		if !a.Feedback.Empty() {
+			xa.Feedback = &xmlText{Format: formatMarkdown, Text: noteText(a.Feedback)}
+		}
+		v.Answers = append(v.Answers, xa)
+	}
+	for _, h := range qs.Hints {
+		v.Hints = append(v.Hints, xmlText{Format: formatMarkdown, Text: noteText(h)})
+	}
+	if len(qs.Tags) > 0 {
+		v.Tags = &xmlTags{}
+		for _, t := range qs.Tags {
+			v.Tags.Tag = append(v.Tags.Tag, xmlText{Text: t})
+		}
+	}
+	return v
+}
+
+// readXML recorre las <question> de un Moodle XML; cada una hereda la
+// última categoría que apareció antes
+func readXML(r io.Reader) ([]Item, error) {
+	d := xml.NewDecoder(r)
+	var items []Item
+	category := ""
+	root := false
+	for {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("el XML no es válido: %v", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "quiz":
+			root = true
+			continue
+		case "question":
+		default:
+			if !root {
+				return nil, errors.New("el XML no es un banco de preguntas de Moodle (falta <quiz>)")
+			}
+			continue
+		}
+
+		line, _ := d.InputPos()
+		var v xmlQuestion
+		if err := d.DecodeElement(&v, &start); err != nil {
+			return nil, fmt.Errorf("pregunta de la línea %d: %v", line, err)
+		}
+		if v.Type == typeCategory {
+			if v.Category != nil {
+				category = v.Category.Text
+			}
+			continue
+		}
+		q, err := fromQuestion(fromXML(v, category))
+		items = append(items, Item{Line: line, Quiz: q, Err: err})
+	}
+	if !root {
+		return nil, errors.New("el XML no es un banco de preguntas de Moodle (falta <quiz>)")
+	}
+	return items, nil
+}
+
+func fromXML(v xmlQuestion, category string) question {
+	qs := question{
+		Type:     v.Type,
+		IDNumber: strings.TrimSpace(v.IDNumber),
+		Category: category,
+		// Moodle escribe <single>true</single>; las versiones antiguas, 1 y 0
+		Single: v.Single == "" || v.Single == "true" || v.Single == "1",
+	}
+	if v.Name != nil {
+		qs.Name = strings.TrimSpace(v.Name.Text)
+	}
+	if v.QuestionText != nil {
+		qs.Text = decodeText(v.QuestionText.Text, v.QuestionText.Format)
+	}
+	if v.GeneralFeedback != nil {
+		qs.Explanation = parseNote(v.GeneralFeedback.Text, v.GeneralFeedback.Format)
+	}
+	for _, xa := range v.Answers {
+		fraction, _ := strconv.ParseFloat(strings.TrimSpace(xa.Fraction), 64)
+		a := answer{Text: decodeText(xa.Text, xa.Format), Fraction: fraction}
+		if xa.Feedback != nil {
+			a.Feedback = parseNote(xa.Feedback.Text, xa.Feedback.Format)
+		}
+		qs.Answers = append(qs.Answers, a)
+	}
+	for _, h := range v.Hints {
+		qs.Hints = append(qs.Hints, parseNote(h.Text, h.Format))
+	}
+	if v.Tags != nil {
+		for _, t := range v.Tags.Tag {
+			qs.Tags = append(qs.Tags, t.Text)
+		}
+	}
+	return qs
+}
@@ -0,0 +1,120 @@
+// Package publish maneja el flujo borrador → programado → publicado →
+// archivado de frases, quizzes y recursos, con fechas en hora de Lima.
+package publish
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"english-at-lima-cms/internal/models"
+	"english-at-lima-cms/internal/repository"
+)
+
+// ParseInput lee un <input type="datetime-local"> en hora de Lima y lo
+// devuelve como timestamp UTC para Supabase ("" si el campo está vacío)
+func ParseInput(v string) (string, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return "", nil
+	}
+	t, err := time.ParseInLocation(models.LimaInputLayout, v, models.Lima)
+	if err != nil {
+		return "", fmt.Errorf("fecha inválida: %s", v)
+	}
+	return t.UTC().Format(time.RFC3339), nil
+}
+
+func parse(ts string) (time.Time, bool) {
+	t, err := time.Parse(time.RFC3339, ts)
+	return t, err == nil
+}
+
+// Due devuelve el estado que corresponde a now: lo programado cuya fecha
+// llegó se publica y lo publicado cuya fecha de retiro pasó se archiva
+func Due(p models.Publication, now time.Time) string {
+	status := p.Status
+	if at, ok := parse(p.PublishAt); ok && status == models.StatusScheduled && !at.After(now) {
+		status = models.StatusPublished
+	}
+	if until, ok := parse(p.UnpublishAt); ok && status == models.StatusPublished && !until.After(now) {
+		status = models.StatusArchived
+	}
+	return status
+}
+
+// Normalize completa el estado al guardar: sin estado es borrador, publicar
+// con fecha futura equivale a programar y lo que ya venció cambia en el acto
+func Normalize(p *models.Publication, now time.Time) {
+	p.Status = strings.ToLower(strings.TrimSpace(p.Status))
+	if p.Status == "" {
+		p.Status = models.StatusDraft
+	}
+	if p.Status == models.StatusPublished {
+		at, ok := parse(p.PublishAt)
+		switch {
+		case !ok:
+			p.PublishAt = now.UTC().Format(time.RFC3339)
+		case at.After(now):
+			p.Status = models.StatusScheduled
+		}
+	}
+	p.Status = Due(*p, now)
+}
+
+// Validate comprueba el estado y que las fechas tengan sentido
+func Validate(p models.Publication) error {
+	if !slices.Contains(models.Statuses, p.Status) {
+		return fmt.Errorf("estado desconocido: %s", p.Status)
+	}
+	at, hasAt := parse(p.PublishAt)
+	if p.Status == models.StatusScheduled && !hasAt {
+		return fmt.Errorf("indique la fecha de publicación para programar")
+	}
+	if until, ok := parse(p.UnpublishAt); ok && hasAt && !until.After(at) {
+		return fmt.Errorf("la fecha de retiro debe ser posterior a la de publicación")
+	}
+	return nil
+}
+
+// RunOnce aplica las transiciones vencidas en las tres tablas de contenido
+func RunOnce(now time.Time) (published, archived int, err error) {
+	ts := now.UTC().Format(time.RFC3339)
+	for _, table := range repository.ContentTables {
+		n, err := repository.Transition(table, "status=eq.scheduled&publish_at=lte."+ts, models.StatusPublished)
+		if err != nil {
+			return published, archived, err
+		}
+		published += n
+
+		n, err = repository.Transition(table, "status=eq.published&unpublish_at=lte."+ts, models.StatusArchived)
+		if err != nil {
+			return published, archived, err
+		}
+		archived += n
+	}
+	return published, archived, nil
+}
+
+// StartPublisher revisa las fechas cada PUBLISH_INTERVAL (1 min por defecto)
+func StartPublisher() {
+	interval := time.Minute
+	if d, err := time.ParseDuration(os.Getenv("PUBLISH_INTERVAL")); err == nil && d > 0 {
+		interval = d
+	}
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			published, archived, err := RunOnce(time.Now())
+			if err != nil {
+				fmt.Println("⚠️ Publicación programada fallida:", err)
+				continue
+			}
+			if published+archived > 0 {
+				fmt.Printf("🗓️ Contenido publicado: %d, retirado: %d\n", published, archived)
+			}
+		}
+	}()
+}
@@ -0,0 +1,86 @@
+package publish
+
+import (
+	"english-at-lima-cms/internal/models"
+	"testing"
+	"time"
+)
+
+func TestParseInput(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{"2026-03-01T08:30", "2026-03-01T13:30:00Z", false}, // Lima es UTC-5
+		{"2026-12-31T22:00", "2027-01-01T03:00:00Z", false},
+		{"01/03/2026 08:30", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseInput(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseInput(%q) = %q, %v; se esperaba %q", tt.in, got, err, tt.want)
+		}
+	}
+	if in := models.LimaInput("2026-03-01T13:30:00Z"); in != "2026-03-01T08:30" {
+		t.Errorf("LimaInput no es el inverso de ParseInput: %q", in)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	past, future := "2026-02-28T12:00:00Z", "2026-03-02T12:00:00Z"
+	tests := []struct {
+		name    string
+		in      models.Publication
+		want    string
+		wantErr bool
+	}{
+		{"Sin estado es borrador", models.Publication{}, models.StatusDraft, false},
+		{"Programado a futuro", models.Publication{Status: "scheduled", PublishAt: future}, models.StatusScheduled, false},
+		{"Programado vencido se publica", models.Publication{Status: "scheduled", PublishAt: past}, models.StatusPublished, false},
+		{"Programado sin fecha", models.Publication{Status: "scheduled"}, models.StatusScheduled, true},
+		{"Publicar con fecha futura programa", models.Publication{Status: "published", PublishAt: future}, models.StatusScheduled, false},
+		{"Publicado con retiro vencido", models.Publication{Status: "published", PublishAt: "2026-02-01T00:00:00Z", UnpublishAt: past}, models.StatusArchived, false},
+		{"Retiro antes de publicar", models.Publication{Status: "scheduled", PublishAt: future, UnpublishAt: "2026-03-02T11:00:00Z"}, models.StatusScheduled, true},
+		{"Estado inventado", models.Publication{Status: "hidden"}, "hidden", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.in
+			Normalize(&p, now)
+			if p.Status != tt.want {
+				t.Errorf("estado %q, se esperaba %q", p.Status, tt.want)
+			}
+			if err := Validate(p); (err != nil) != tt.wantErr {
+				t.Errorf("error esperado %v, obtenido %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	p := models.Publication{Status: "published"}
+	Normalize(&p, now)
+	if p.PublishAt != "2026-03-01T12:00:00Z" {
+		t.Errorf("publicar ahora debe guardar la fecha: %q", p.PublishAt)
+	}
+}
+
+func TestDue(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		p    models.Publication
+		want string
+	}{
+		{models.Publication{Status: "draft", PublishAt: "2026-02-01T00:00:00Z"}, models.StatusDraft},
+		{models.Publication{Status: "scheduled", PublishAt: "2026-03-01T12:00:00Z"}, models.StatusPublished},
+		{models.Publication{Status: "scheduled", PublishAt: "2026-03-01T12:00:01Z"}, models.StatusScheduled},
+		{models.Publication{Status: "scheduled", PublishAt: "2026-02-01T00:00:00+00:00", UnpublishAt: "2026-02-15T00:00:00Z"}, models.StatusArchived},
+		{models.Publication{Status: "published", UnpublishAt: "2026-04-01T00:00:00Z"}, models.StatusPublished},
+	}
+	for _, tt := range tests {
+		if got := Due(tt.p, now); got != tt.want {
+			t.Errorf("Due(%+v) = %q, se esperaba %q", tt.p, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+// Package quiz valida y corrige las preguntas de todos los tipos: opción
+// única, múltiple, verdadero/falso, completar el hueco y ordenar.
+package quiz
+
+import (
+	"english-at-lima-cms/internal/i18n"
+	"english-at-lima-cms/internal/models"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const (
+	MinOptions      = 2
+	MaxOptions      = models.MaxQuizOptions
+	MaxOptionLength = 200
+	MaxHints        = models.MaxQuizHints
+	MaxNoteLength   = 500
+	Blank           = "___"
+)
+
+// TrueFalseOptions son las opciones fijas de un verdadero/falso
+var TrueFalseOptions = []string{"True", "False"}
+
+// ValidType indica si el tipo de pregunta existe
+func ValidType(t string) bool {
+	switch t {
+	case models.QuizSingle, models.QuizMultiple, models.QuizTrueFalse, models.QuizFill, models.QuizOrdering:
+		return true
+	}
+	return false
+}
+
+// Normalize recorta espacios, quita opciones vacías y aplica las reglas fijas
+// de cada tipo (opciones True/False, sin opciones en fill)
+func Normalize(q *models.Quiz) {
+	q.Question = strings.TrimSpace(q.Question)
+	if q.Type == "" {
+		q.Type = models.QuizSingle
+	}
+	q.Options = compact(q.Options)
+	q.Answers = compact(q.Answers)
+
+	switch q.Type {
+	case models.QuizTrueFalse:
+		q.Options = append([]string(nil), TrueFalseOptions...)
+	case models.QuizFill:
+		q.Options = []string{}
+	case models.QuizOrdering:
+		// El orden de las opciones ES la respuesta
+		q.Answers = []string{}
+	}
+	normalizeNotes(q)
+}
+
+// normalizeNotes recorta explicaciones y pistas, descarta las pistas vacías
+// y ajusta las notas por opción al número de opciones
+func normalizeNotes(q *models.Quiz) {
+	q.Explanation = trimNote(q.Explanation)
+
+	hints := []models.Note{}
+	for _, h := range q.Hints {
+		if h = trimNote(h); !h.Empty() {
+			hints = append(hints, h)
+		}
+	}
+	q.Hints = hints
+
+	notes := []models.Note{}
+	if q.HasOptionNotes() {
+		written := false
+		for i := range q.Options {
+			var n models.Note
+			if i < len(q.OptionNotes) {
+				n = trimNote(q.OptionNotes[i])
+			}
+			written = written || !n.Empty()
+			notes = append(notes, n)
+		}
+		if !written {
+			notes = []models.Note{}
+		}
+	}
+	q.OptionNotes = notes
+}
+
+func trimNote(n models.Note) models.Note {
+	return models.Note{ES: strings.TrimSpace(n.ES), EN: strings.TrimSpace(n.EN), Locales: i18n.Clean(n.Locales)}
+}
+
+func compact(values []string) []string {
+	out := []string{}
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Validate garantiza que la pregunta se pueda responder: número de opciones
+// correcto, sin repetidas y con las respuestas correctas entre las opciones
+func Validate(q models.Quiz) error {
+	if len(strings.TrimSpace(q.Question)) < 10 {
+		return fmt.Errorf("la pregunta es demasiado corta")
+	}
+	if len(q.Question) > 500 {
+		return fmt.Errorf("la pregunta excede el límite de 500 caracteres")
+	}
+	if !ValidType(q.Type) {
+		return fmt.Errorf("tipo de pregunta desconocido")
+	}
+	if err := validateNotes(q); err != nil {
+		return err
+	}
+
+	switch q.Type {
+	case models.QuizFill:
+		return validateFill(q)
+	case models.QuizTrueFalse:
+		if len(q.Answers) != 1 || !contains(TrueFalseOptions, q.Answers[0]) {
+			return fmt.Errorf("debe marcar si la afirmación es verdadera o falsa")
+		}
+		return nil
+	}
+
+	if err := validateOptions(q.Options); err != nil {
+		return err
+	}
+	switch q.Type {
+	case models.QuizSingle:
+		if len(q.Answers) != 1 {
+			return fmt.Errorf("debe marcar exactamente una respuesta correcta")
+		}
+	case models.QuizMultiple:
+		if len(q.Answers) == 0 {
+			return fmt.Errorf("debe marcar al menos una respuesta correcta")
+		}
+	}
+	for _, a := range q.Answers {
+		if !contains(q.Options, a) {
+			return fmt.Errorf("la respuesta correcta '%s' no está entre las opciones", a)
+		}
+	}
+	return nil
+}
+
+func validateOptions(options []string) error {
+	if len(options) < MinOptions || len(options) > MaxOptions {
+		return fmt.Errorf("se requieren entre %d y %d opciones", MinOptions, MaxOptions)
+	}
+	seen := map[string]bool{}
+	for _, opt := range options {
+		if strings.TrimSpace(opt) == "" {
+			return fmt.Errorf("las opciones no pueden estar vacías")
+		}
+		if len(opt) > MaxOptionLength {
+			return fmt.Errorf("cada opción admite como máximo %d caracteres", MaxOptionLength)
+		}
+		key := Canonical(opt)
+		if seen[key] {
+			return fmt.Errorf("la opción '%s' está repetida", opt)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+func validateNotes(q models.Quiz) error {
+	if len(q.Hints) > MaxHints {
+		return fmt.Errorf("un quiz admite como máximo %d pistas", MaxHints)
+	}
+	if len(q.OptionNotes) > len(q.Options) {
+		return fmt.Errorf("hay más notas que opciones")
+	}
+	notes := append([]models.Note{q.Explanation}, q.Hints...)
+	notes = append(notes, q.OptionNotes...)
+	for _, n := range notes {
+		if len(n.ES) > MaxNoteLength || len(n.EN) > MaxNoteLength {
+			return fmt.Errorf("explicaciones y pistas admiten como máximo %d caracteres", MaxNoteLength)
+		}
+		if err := i18n.CheckLength(n.Locales, MaxNoteLength); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateFill(q models.Quiz) error {
+	if !strings.Contains(q.Question, Blank) {
+		return fmt.Errorf("la pregunta debe marcar el hueco con %s", Blank)
+	}
+	if len(q.Answers) == 0 || len(q.Answers) > MaxOptions {
+		return fmt.Errorf("indique entre 1 y %d respuestas aceptadas", MaxOptions)
+	}
+	for _, a := range q.Answers {
+		if Canonical(a) == "" {
+			return fmt.Errorf("las respuestas aceptadas no pueden estar vacías")
+		}
+	}
+	return nil
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Canonical compara respuestas escritas sin fijarse en mayúsculas, espacios
+// repetidos ni la puntuación final: "I'm  going." == "i'm going"
+func Canonical(s string) string {
+	s = strings.ToLower(strings.Join(strings.Fields(s), " "))
+	return strings.TrimRightFunc(s, func(r rune) bool {
+		return unicode.IsPunct(r) && r != '\''
+	})
+}
+
+// Grade corrige la respuesta del alumno. response son las opciones elegidas
+// (single, multiple, truefalse), el texto escrito (fill) o las opciones en el
+// orden propuesto (ordering).
+func Grade(q models.Quiz, response []string) bool {
+	switch q.Type {
+	case models.QuizFill:
+		if len(response) != 1 {
+			return false
+		}
+		for _, a := range q.Answers {
+			if Canonical(a) == Canonical(response[0]) {
+				return true
+			}
+		}
+		return false
+	case models.QuizOrdering:
+		if len(response) != len(q.Options) {
+			return false
+		}
+		for i := range response {
+			if response[i] != q.Options[i] {
+				return false
+			}
+		}
+		return true
+	}
+	// Elección: el conjunto elegido debe ser exactamente el correcto
+	chosen := map[string]bool{}
+	for _, r := range response {
+		chosen[r] = true
+	}
+	if len(chosen) != len(q.Answers) {
+		return false
+	}
+	for _, a := range q.Answers {
+		if !chosen[a] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,128 @@
+package quiz
+
+import (
+	"english-at-lima-cms/internal/models"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	opts := func(o ...string) []string { return o }
+	tests := []struct {
+		name    string
+		q       models.Quiz
+		wantErr bool
+	}{
+		{"Única válida", models.Quiz{Question: "What is 'Apple'?", Type: "single", Options: opts("Manzana", "Pera"), Answers: opts("Manzana")}, false},
+		{"Única con dos correctas", models.Quiz{Question: "What is 'Apple'?", Type: "single", Options: opts("Manzana", "Pera"), Answers: opts("Manzana", "Pera")}, true},
+		{"Correcta fuera de las opciones", models.Quiz{Question: "What is 'Apple'?", Type: "single", Options: opts("Manzana", "Pera"), Answers: opts("Uva")}, true},
+		{"Una sola opción", models.Quiz{Question: "What is 'Apple'?", Type: "single", Options: opts("Manzana"), Answers: opts("Manzana")}, true},
+		{"Nueve opciones", models.Quiz{Question: "Pick the vowels", Type: "multiple", Options: opts("a", "b", "c", "d", "e", "f", "g", "h", "i"), Answers: opts("a")}, true},
+		{"Opciones repetidas", models.Quiz{Question: "Pick the vowels", Type: "multiple", Options: opts("a", "A ", "b"), Answers: opts("a")}, true},
+		{"Múltiple válida", models.Quiz{Question: "Pick the vowels", Type: "multiple", Options: opts("a", "b", "e"), Answers: opts("a", "e")}, false},
+		{"Múltiple sin correctas", models.Quiz{Question: "Pick the vowels", Type: "multiple", Options: opts("a", "b"), Answers: nil}, true},
+		{"Verdadero/Falso", models.Quiz{Question: "London is in France", Type: "truefalse", Options: TrueFalseOptions, Answers: opts("False")}, false},
+		{"V/F sin respuesta", models.Quiz{Question: "London is in France", Type: "truefalse", Options: TrueFalseOptions}, true},
+		{"Completar válido", models.Quiz{Question: "I ___ to school every day", Type: "fill", Answers: opts("go", "walk")}, false},
+		{"Completar sin hueco", models.Quiz{Question: "I go to school every day", Type: "fill", Answers: opts("go")}, true},
+		{"Completar sin variantes", models.Quiz{Question: "I ___ to school every day", Type: "fill"}, true},
+		{"Ordenar válido", models.Quiz{Question: "Order the words", Type: "ordering", Options: opts("I", "am", "happy")}, false},
+		{"Tipo inventado", models.Quiz{Question: "What is 'Apple'?", Type: "essay", Options: opts("a", "b"), Answers: opts("a")}, true},
+		{"Pregunta corta", models.Quiz{Question: "Hi?", Type: "single", Options: opts("1", "2"), Answers: opts("1")}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := tt.q
+			Normalize(&q)
+			if err := Validate(q); (err != nil) != tt.wantErr {
+				t.Errorf("error esperado %v, obtenido %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	q := models.Quiz{Question: "  Is it raining? ", Type: "truefalse", Options: []string{"x"}, Answers: []string{" True "}}
+	Normalize(&q)
+	if q.Question != "Is it raining?" || len(q.Options) != 2 || q.Answers[0] != "True" {
+		t.Errorf("verdadero/falso mal normalizado: %+v", q)
+	}
+
+	o := models.Quiz{Type: "ordering", Options: []string{"I", "", "am"}, Answers: []string{"am"}}
+	Normalize(&o)
+	if len(o.Options) != 2 || len(o.Answers) != 0 {
+		t.Errorf("ordering mal normalizado: %+v", o)
+	}
+}
+
+func TestGrade(t *testing.T) {
+	tests := []struct {
+		name     string
+		q        models.Quiz
+		response []string
+		want     bool
+	}{
+		{"Única correcta", models.Quiz{Type: "single", Options: []string{"a", "b"}, Answers: []string{"a"}}, []string{"a"}, true},
+		{"Única incorrecta", models.Quiz{Type: "single", Options: []string{"a", "b"}, Answers: []string{"a"}}, []string{"b"}, false},
+		{"Múltiple completa", models.Quiz{Type: "multiple", Answers: []string{"a", "c"}}, []string{"c", "a"}, true},
+		{"Múltiple incompleta", models.Quiz{Type: "multiple", Answers: []string{"a", "c"}}, []string{"a"}, false},
+		{"Múltiple con extra", models.Quiz{Type: "multiple", Answers: []string{"a", "c"}}, []string{"a", "b", "c"}, false},
+		{"Completar con variante", models.Quiz{Type: "fill", Answers: []string{"going to", "gonna"}}, []string{"  Gonna. "}, true},
+		{"Completar incorrecto", models.Quiz{Type: "fill", Answers: []string{"going to"}}, []string{"went"}, false},
+		{"Ordenar correcto", models.Quiz{Type: "ordering", Options: []string{"I", "am", "happy"}}, []string{"I", "am", "happy"}, true},
+		{"Ordenar incorrecto", models.Quiz{Type: "ordering", Options: []string{"I", "am", "happy"}}, []string{"am", "I", "happy"}, false},
+	}
+	for _, tt := range tests {
+		if got := Grade(tt.q, tt.response); got != tt.want {
+			t.Errorf("%s: Grade = %v, esperaba %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeNotes(t *testing.T) {
+	q := models.Quiz{
+		Question: "What is 'Apple'?", Type: "single",
+		Options:     []string{"Manzana", "Pera", "Uva"},
+		Answers:     []string{"Manzana"},
+		Explanation: models.Note{ES: " Apple = manzana ", EN: ""},
+		OptionNotes: []models.Note{{}, {ES: " Pear es pera "}},
+		Hints:       []models.Note{{}, {EN: " It's red "}, {ES: "Empieza con M"}},
+	}
+	Normalize(&q)
+	if q.Explanation.ES != "Apple = manzana" {
+		t.Errorf("explicación sin recortar: %q", q.Explanation.ES)
+	}
+	if len(q.Hints) != 2 || q.Hints[0].EN != "It's red" {
+		t.Errorf("las pistas vacías deberían descartarse: %+v", q.Hints)
+	}
+	if len(q.OptionNotes) != 3 || q.OptionNotes[1].ES != "Pear es pera" {
+		t.Errorf("las notas deberían alinearse con las opciones: %+v", q.OptionNotes)
+	}
+	if err := Validate(q); err != nil {
+		t.Errorf("quiz con notas válido rechazado: %v", err)
+	}
+
+	// Sin notas escritas no se guarda un array de notas vacías
+	empty := models.Quiz{Type: "multiple", Options: []string{"a", "b"}, OptionNotes: []models.Note{{}, {ES: "  "}}}
+	Normalize(&empty)
+	if len(empty.OptionNotes) != 0 {
+		t.Errorf("esperaba notas vacías descartadas: %+v", empty.OptionNotes)
+	}
+	// Ordenar y completar no tienen notas por opción
+	fill := models.Quiz{Type: "fill", OptionNotes: []models.Note{{ES: "x"}}}
+	Normalize(&fill)
+	if len(fill.OptionNotes) != 0 {
+		t.Error("❌ Un quiz de completar conservó notas por opción")
+	}
+
+	tooMany := q
+	tooMany.Hints = append(tooMany.Hints, models.Note{ES: "3"}, models.Note{ES: "4"})
+	if Validate(tooMany) == nil {
+		t.Error("❌ Se aceptaron más de 3 pistas")
+	}
+	long := q
+	long.Explanation.EN = strings.Repeat("a", MaxNoteLength+1)
+	if Validate(long) == nil {
+		t.Error("❌ Se aceptó una explicación demasiado larga")
+	}
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"encoding/json"
+)
+
+// Backup lee y escribe tablas completas para la copia de seguridad
+// (implementa backup.Source y backup.Store)
+type Backup struct{}
+
+// Rows recorre la tabla entera ordenada por su clave, de mil en mil filas
+func (Backup) Rows(table, key string, each func(json.RawMessage) error) error {
+	return fetchAll(table, "select=*&order="+key+".asc", each)
+}
+
+// Empty indica si la tabla no tiene ninguna fila
+func (Backup) Empty(table string) (bool, error) {
+	var rows []json.RawMessage
+	if err := fetchJSON(table, "select=*&limit=1", &rows); err != nil {
+		return false, err
+	}
+	return len(rows) == 0, nil
+}
+
+// Put inserta las filas con sus claves originales; si la clave ya existe, la
+// fila de la copia sobrescribe la guardada
+func (Backup) Put(table, key string, rows []json.RawMessage) error {
+	return handleResponse(callSupabase("POST", table, rows, "on_conflict="+key, "resolution=merge-duplicates,return=minimal"))
+}
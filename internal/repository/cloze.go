@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"fmt"
+
+	"english-at-lima-cms/internal/models"
+)
+
+// ListBankSentences devuelve hasta limit frases no archivadas con el filtro de taxonomía
+func ListBankSentences(filter string, limit int) ([]models.Sentence, error) {
+	var sentences []models.Sentence
+	query := fmt.Sprintf("select=*&%s&order=id.asc&limit=%d", ActiveFilter, limit)
+	if filter != "" {
+		query += "&" + filter
+	}
+	err := fetchJSON("sentences", query, &sentences)
+	return sentences, err
+}
+
+// GeneratedFrom indica qué frases ya tienen un quiz generado del tipo dado
+// (sin contar los archivados), para no generarlo dos veces
+func GeneratedFrom(sentenceIDs []int, kind string) (map[int]bool, error) {
+	done := map[int]bool{}
+	if len(sentenceIDs) == 0 {
+		return done, nil
+	}
+	var rows []struct {
+		SourceSentenceID int `json:"source_sentence_id"`
+	}
+	filter := fmt.Sprintf("select=source_sentence_id&type=eq.%s&source_sentence_id=in.(%s)&%s", kind, idList(sentenceIDs), ActiveFilter)
+	if err := fetchJSON("quizzes", filter, &rows); err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		done[r.SourceSentenceID] = true
+	}
+	return done, nil
+}
+
+// InsertGeneratedQuizzes guarda en una sola petición los quizzes generados
+func InsertGeneratedQuizzes(qs []models.Quiz) error {
+	if len(qs) == 0 {
+		return nil
+	}
+	rows := make([]map[string]interface{}, len(qs))
+	for i, q := range qs {
+		rows[i] = quizPayload(q)
+		rows[i]["source_sentence_id"] = q.SourceSentenceID
+	}
+	return handleResponse(CallSupabase("POST", "quizzes", rows, ""))
+}
+
+// ListGeneratedDrafts devuelve los borradores generados que nadie ha enviado a revisión
+func ListGeneratedDrafts() ([]models.Quiz, error) {
+	var qs []models.Quiz
+	filter := "select=*&source_sentence_id=not.is.null&status=eq." + models.StatusDraft + "&review_state=eq.&order=id.desc&limit=200"
+	err := fetchJSON("quizzes", filter, &qs)
+	return qs, err
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"english-at-lima-cms/internal/models"
+)
+
+// pageSize coincide con el max-rows por defecto de PostgREST en Supabase
+const pageSize = 1000
+
+// fetchJSON hace un GET sobre la tabla y decodifica la respuesta en target
+func fetchJSON(table, filter string, target interface{}) error {
+	resp, err := CallSupabase("GET", table, nil, filter)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("error supabase: %d - %s", resp.StatusCode, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(target)
+}
+
+// fetchOne obtiene una fila por id y devuelve ErrNotFound si no existe
+func fetchOne(table, id string, target interface{}) error {
+	var rows []json.RawMessage
+	if err := fetchJSON(table, "select=*&limit=1&id=eq."+id, &rows); err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return ErrNotFound
+	}
+	return json.Unmarshal(rows[0], target)
+}
+
+// ErrNotFound indica que la fila pedida no existe en Supabase
+var ErrNotFound = errors.New("registro no encontrado")
+
+// fetchAll recorre la tabla por páginas para no chocar con el límite de filas
+func fetchAll(table, filter string, each func(json.RawMessage) error) error {
+	for offset := 0; ; offset += pageSize {
+		var rows []json.RawMessage
+		paged := fmt.Sprintf("%s&limit=%d&offset=%d", filter, pageSize, offset)
+		if err := fetchJSON(table, paged, &rows); err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if err := each(row); err != nil {
+				return err
+			}
+		}
+		if len(rows) < pageSize {
+			return nil
+		}
+	}
+}
+
+// --- LECTURAS PÚBLICAS ---
+
+func GetSentence(id string) (models.Sentence, error) {
+	var s models.Sentence
+	err := fetchOne("sentences", id, &s)
+	return s, err
+}
+
+// ListSentences devuelve una página de frases publicadas (id ascendente) con el filtro de taxonomía
+func ListSentences(filter string, limit, offset int) ([]models.Sentence, error) {
+	query := fmt.Sprintf("select=*&%s&order=id.asc&limit=%d&offset=%d", PublicFilter, limit, offset)
+	if filter != "" {
+		query += "&" + filter
+	}
+	var sentences []models.Sentence
+	err := fetchJSON("sentences", query, &sentences)
+	return sentences, err
+}
+
+func GetQuiz(id string) (models.Quiz, error) {
+	var q models.Quiz
+	err := fetchOne("quizzes", id, &q)
+	return q, err
+}
+
+func GetResource(id string) (models.Resource, error) {
+	var r models.Resource
+	err := fetchOne("resources", id, &r)
+	return r, err
+}
+
+// ContentStamp es lo mínimo que necesita el sitemap de cada fila
+type ContentStamp struct {
+	ID        int    `json:"id"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// ListStamps devuelve id y updated_at de las filas publicadas de la tabla
+func ListStamps(table string) ([]ContentStamp, error) {
+	filter := "select=id,updated_at&order=id.asc&" + PublicFilter
+	var stamps []ContentStamp
+	err := fetchAll(table, filter, func(row json.RawMessage) error {
+		var s ContentStamp
+		if err := json.Unmarshal(row, &s); err != nil {
+			return err
+		}
+		stamps = append(stamps, s)
+		return nil
+	})
+	return stamps, err
+}
+
+// --- VERIFICADOR DE ENLACES ---
+
+// ListCheckableResources devuelve los recursos activos con su racha de fallos
+func ListCheckableResources() ([]models.Resource, error) {
+	var resources []models.Resource
+	err := fetchAll("resources", "select=id,url,link_fail_streak&"+ActiveFilter+"&order=id.asc", func(row json.RawMessage) error {
+		var r models.Resource
+		if err := json.Unmarshal(row, &r); err != nil {
+			return err
+		}
+		resources = append(resources, r)
+		return nil
+	})
+	return resources, err
+}
+
+func ListBrokenResources() ([]models.Resource, error) {
+	var resources []models.Resource
+	err := fetchJSON("resources", "select=*&"+ActiveFilter+"&link_fail_streak=gt.0&order=link_fail_streak.desc", &resources)
+	return resources, err
+}
+
+func UpdateLinkStatus(id int, status int, redirect, checkedAt string, streak int) error {
+	return patchToSupabase("resources", fmt.Sprint(id), map[string]interface{}{
+		"link_status":      status,
+		"link_redirect":    redirect,
+		"link_checked_at":  checkedAt,
+		"link_fail_streak": streak,
+	})
+}
+
+func ArchiveResource(id string) error {
+	return patchToSupabase("resources", id, map[string]interface{}{"status": models.StatusArchived})
+}
+
+func UpdateResourceURL(id, url string) error {
+	return patchToSupabase("resources", id, map[string]interface{}{"url": url})
+}
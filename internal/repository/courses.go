@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"encoding/json"
+	"english-at-lima-cms/internal/models"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// courseTree trae el curso con unidades, lecciones y elementos en una sola
+// consulta usando los recursos embebidos de PostgREST
+const courseTree = "select=*,units(*,lessons(*,lesson_items(*)))"
+
+func ListCourses(publishedOnly bool) ([]models.Course, error) {
+	filter := "select=*&order=title.asc"
+	if publishedOnly {
+		filter += "&published=is.true"
+	}
+	var courses []models.Course
+	err := fetchJSON("courses", filter, &courses)
+	return courses, err
+}
+
+// GetCourseTree devuelve el curso completo (sin ordenar: ver course.Sort)
+func GetCourseTree(id string) (models.Course, error) {
+	var courses []models.Course
+	if err := fetchJSON("courses", courseTree+"&limit=1&id=eq."+id, &courses); err != nil {
+		return models.Course{}, err
+	}
+	if len(courses) == 0 {
+		return models.Course{}, ErrNotFound
+	}
+	return courses[0], nil
+}
+
+func InsertCourse(c models.Course) error {
+	data := map[string]interface{}{"title": c.Title, "description": c.Description, "level": c.Level, "published": false}
+	return handleResponse(CallSupabase("POST", "courses", data, ""))
+}
+
+func SetCoursePublished(id string, published bool) error {
+	return patchToSupabase("courses", id, map[string]interface{}{"published": published})
+}
+
+// DeleteCourse borra el curso; unidades, lecciones y elementos caen en cascada
+func DeleteCourse(id string) error {
+	return handleResponse(CallSupabase("DELETE", "courses", nil, "id=eq."+id))
+}
+
+func InsertUnit(courseID int, title string, position int) error {
+	data := map[string]interface{}{"course_id": courseID, "title": title, "position": position}
+	return handleResponse(CallSupabase("POST", "units", data, ""))
+}
+
+func InsertLesson(unitID int, title string, position int) error {
+	data := map[string]interface{}{"unit_id": unitID, "title": title, "position": position}
+	return handleResponse(CallSupabase("POST", "lessons", data, ""))
+}
+
+func InsertLessonItem(lessonID int, contentType string, contentID, position int) error {
+	data := map[string]interface{}{
+		"lesson_id": lessonID, "content_type": contentType, "content_id": contentID, "position": position,
+	}
+	return handleResponse(CallSupabase("POST", "lesson_items", data, ""))
+}
+
+// DeleteCourseRow borra una unidad, lección o elemento (table ya validada)
+func DeleteCourseRow(table string, id int) error {
+	return handleResponse(CallSupabase("DELETE", table, nil, fmt.Sprintf("id=eq.%d", id)))
+}
+
+// ReorderRows guarda la posición de cada fila según su lugar en ids
+func ReorderRows(table string, ids []int) error {
+	for pos, id := range ids {
+		if err := patchToSupabase(table, fmt.Sprint(id), map[string]interface{}{"position": pos}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadLessonContent rellena cada elemento con su frase, quiz o recurso
+// haciendo una consulta id=in.(...) por tipo. Para los alumnos (public)
+// sólo carga lo publicado; el editor ve todo salvo lo archivado.
+func LoadLessonContent(c *models.Course, public bool) error {
+	status := ActiveFilter
+	if public {
+		status = PublicFilter
+	}
+	ids := map[string][]int{}
+	for _, u := range c.Units {
+		for _, l := range u.Lessons {
+			for _, it := range l.Items {
+				ids[it.ContentType] = append(ids[it.ContentType], it.ContentID)
+			}
+		}
+	}
+
+	sentences := map[int]*models.Sentence{}
+	quizzes := map[int]*models.Quiz{}
+	resources := map[int]*models.Resource{}
+	if len(ids["sentence"]) > 0 {
+		var rows []models.Sentence
+		if err := fetchJSON("sentences", "select=*&"+status+"&id=in.("+idList(ids["sentence"])+")", &rows); err != nil {
+			return err
+		}
+		for i := range rows {
+			sentences[rows[i].ID] = &rows[i]
+		}
+	}
+	if len(ids["quiz"]) > 0 {
+		var rows []models.Quiz
+		if err := fetchJSON("quizzes", "select=*&"+status+"&id=in.("+idList(ids["quiz"])+")", &rows); err != nil {
+			return err
+		}
+		for i := range rows {
+			quizzes[rows[i].ID] = &rows[i]
+		}
+	}
+	if len(ids["resource"]) > 0 {
+		var rows []models.Resource
+		if err := fetchJSON("resources", "select=*&"+status+"&id=in.("+idList(ids["resource"])+")", &rows); err != nil {
+			return err
+		}
+		for i := range rows {
+			resources[rows[i].ID] = &rows[i]
+		}
+	}
+
+	for u := range c.Units {
+		for l := range c.Units[u].Lessons {
+			items := c.Units[u].Lessons[l].Items
+			for i := range items {
+				switch id := items[i].ContentID; items[i].ContentType {
+				case "sentence":
+					items[i].Sentence = sentences[id]
+				case "quiz":
+					items[i].Quiz = quizzes[id]
+				case "resource":
+					items[i].Resource = resources[id]
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// --- PROGRESO DEL ALUMNO ---
+
+// MarkLessonComplete registra la lección como terminada; repetirla no es error
+// gracias al índice único (lesson_id, student_id)
+func MarkLessonComplete(lessonID int, student string) error {
+	data := map[string]interface{}{"lesson_id": lessonID, "student_id": student}
+	resp, err := CallSupabase("POST", "lesson_completions", data, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 && resp.StatusCode != http.StatusConflict {
+		return fmt.Errorf("error supabase: %d - %s", resp.StatusCode, resp.Status)
+	}
+	return nil
+}
+
+// CompletedLessons devuelve qué lecciones de la lista terminó el alumno
+func CompletedLessons(student string, lessonIDs []int) (map[int]bool, error) {
+	done := map[int]bool{}
+	if len(lessonIDs) == 0 {
+		return done, nil
+	}
+	var rows []struct {
+		LessonID int `json:"lesson_id"`
+	}
+	filter := fmt.Sprintf("select=lesson_id&student_id=eq.%s&lesson_id=in.(%s)", student, idList(lessonIDs))
+	if err := fetchJSON("lesson_completions", filter, &rows); err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		done[r.LessonID] = true
+	}
+	return done, nil
+}
+
+// CountCompletions cuenta cuántos alumnos completaron cada lección
+func CountCompletions(lessonIDs []int) (map[int]int, error) {
+	counts := map[int]int{}
+	if len(lessonIDs) == 0 {
+		return counts, nil
+	}
+	err := fetchAll("lesson_completions", "select=lesson_id&lesson_id=in.("+idList(lessonIDs)+")&order=id.asc", func(row json.RawMessage) error {
+		var r struct {
+			LessonID int `json:"lesson_id"`
+		}
+		if err := json.Unmarshal(row, &r); err != nil {
+			return err
+		}
+		counts[r.LessonID]++
+		return nil
+	})
+	return counts, err
+}
+
+func idList(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprint(id)
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"english-at-lima-cms/internal/models"
+)
+
+// ListContentTexts devuelve id y texto (la columna indicada) de todo el
+// contenido no archivado de la tabla, para buscar casi duplicados
+func ListContentTexts(table, column string) ([]models.ContentText, error) {
+	var texts []models.ContentText
+	filter := fmt.Sprintf("select=id,text:%s&%s&order=id.asc", column, ActiveFilter)
+	err := fetchAll(table, filter, func(row json.RawMessage) error {
+		var t models.ContentText
+		if err := json.Unmarshal(row, &t); err != nil {
+			return err
+		}
+		texts = append(texts, t)
+		return nil
+	})
+	return texts, err
+}
+
+// ListPublications trae el estado editorial de los ids pedidos
+func ListPublications(table string, ids []int) (map[int]models.Publication, error) {
+	var rows []struct {
+		ID int `json:"id"`
+		models.Publication
+	}
+	if err := fetchJSON(table, fmt.Sprintf("select=id,status,publish_at,unpublish_at&id=in.(%s)", idList(ids)), &rows); err != nil {
+		return nil, err
+	}
+	pubs := make(map[int]models.Publication, len(rows))
+	for _, r := range rows {
+		pubs[r.ID] = r.Publication
+	}
+	return pubs, nil
+}
+
+// MergeDuplicates conserva keep: las lecciones que usaban los demás pasan a
+// usar keep y los demás se archivan
+func MergeDuplicates(contentType, table string, keep int, others []int) error {
+	if len(others) == 0 {
+		return nil
+	}
+	items := fmt.Sprintf("content_type=eq.%s&content_id=in.(%s)", contentType, idList(others))
+	if _, err := patchWhere("lesson_items", items, map[string]interface{}{"content_id": keep}); err != nil {
+		return err
+	}
+	_, err := Transition(table, fmt.Sprintf("id=in.(%s)", idList(others)), models.StatusArchived)
+	return err
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"english-at-lima-cms/internal/models"
+	"fmt"
+)
+
+func ListEmbedKeys() ([]models.EmbedKey, error) {
+	var keys []models.EmbedKey
+	err := fetchJSON("embed_keys", "select=*&order=partner.asc", &keys)
+	return keys, err
+}
+
+// GetEmbedKey busca por la clave pública (no por id)
+func GetEmbedKey(key string) (models.EmbedKey, error) {
+	var keys []models.EmbedKey
+	if err := fetchJSON("embed_keys", "select=*&limit=1&key=eq."+key, &keys); err != nil {
+		return models.EmbedKey{}, err
+	}
+	if len(keys) == 0 {
+		return models.EmbedKey{}, ErrNotFound
+	}
+	return keys[0], nil
+}
+
+func InsertEmbedKey(k models.EmbedKey) error {
+	data := map[string]interface{}{
+		"key": k.Key, "partner": k.Partner, "allowed_origins": k.AllowedOrigins,
+		"mode": k.Mode, "theme": k.Theme,
+	}
+	if k.QuizID > 0 {
+		data["quiz_id"] = k.QuizID
+	}
+	return handleResponse(CallSupabase("POST", "embed_keys", data, ""))
+}
+
+func DeleteEmbedKey(id string) error {
+	return handleResponse(CallSupabase("DELETE", "embed_keys", nil, "id=eq."+id))
+}
+
+// AddEmbedViews suma n visitas de forma atómica mediante la función SQL
+// increment_embed_views(p_key text, p_count int)
+func AddEmbedViews(key string, n int) error {
+	payload := map[string]interface{}{"p_key": key, "p_count": n}
+	if err := handleResponse(CallSupabase("POST", "rpc/increment_embed_views", payload, "")); err != nil {
+		return fmt.Errorf("no se pudieron guardar las visitas de %s: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"encoding/json"
+	"english-at-lima-cms/internal/models"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+func ListExams(publishedOnly bool) ([]models.Exam, error) {
+	filter := "select=*&order=title.asc"
+	if publishedOnly {
+		filter += "&published=is.true"
+	}
+	var exams []models.Exam
+	err := fetchJSON("exams", filter, &exams)
+	return exams, err
+}
+
+func GetExam(id string) (models.Exam, error) {
+	var e models.Exam
+	err := fetchOne("exams", id, &e)
+	return e, err
+}
+
+func InsertExam(e models.Exam) error {
+	data := map[string]interface{}{
+		"title": e.Title, "description": e.Description, "rules": e.Rules,
+		"time_limit": e.TimeLimit, "max_attempts": e.MaxAttempts, "passing_score": e.PassingScore,
+		"published": false,
+	}
+	return handleResponse(CallSupabase("POST", "exams", data, ""))
+}
+
+func SetExamPublished(id string, published bool) error {
+	return patchToSupabase("exams", id, map[string]interface{}{"published": published})
+}
+
+// DeleteExam borra el examen; sus intentos caen en cascada
+func DeleteExam(id string) error {
+	return handleResponse(CallSupabase("DELETE", "exams", nil, "id=eq."+id))
+}
+
+// ListQuizPool devuelve los quizzes publicados que cumplen el filtro de una regla
+func ListQuizPool(filter string) ([]models.Quiz, error) {
+	if filter != "" {
+		filter = "&" + filter
+	}
+	var pool []models.Quiz
+	err := fetchAll("quizzes", "select=*&"+PublicFilter+"&order=id.asc"+filter, func(row json.RawMessage) error {
+		var q models.Quiz
+		if err := json.Unmarshal(row, &q); err != nil {
+			return err
+		}
+		pool = append(pool, q)
+		return nil
+	})
+	return pool, err
+}
+
+func GetQuizzesByIDs(ids []int) ([]models.Quiz, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var quizzes []models.Quiz
+	err := fetchJSON("quizzes", "select=*&id=in.("+idList(ids)+")", &quizzes)
+	return quizzes, err
+}
+
+// --- INTENTOS ---
+
+// ErrAttemptTaken es el choque con el índice único (exam_id, student_id,
+// attempt_no): otra petición del mismo alumno ya creó ese intento
+var ErrAttemptTaken = errors.New("el intento ya fue creado por otra petición")
+
+// InsertAttempt crea el intento y lo devuelve con su id y started_at
+func InsertAttempt(a models.ExamAttempt) (models.ExamAttempt, error) {
+	data := map[string]interface{}{
+		"exam_id": a.ExamID, "student_id": a.Student, "attempt_no": a.AttemptNo, "quiz_ids": a.QuizIDs,
+		"responses": a.Responses, "hints_used": a.HintsUsed, "deadline": a.Deadline,
+	}
+	resp, err := CallSupabase("POST", "exam_attempts", data, "")
+	if err != nil {
+		return a, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusConflict {
+		return a, ErrAttemptTaken
+	}
+	if resp.StatusCode >= 400 {
+		return a, fmt.Errorf("error supabase: %d - %s", resp.StatusCode, resp.Status)
+	}
+	var rows []models.ExamAttempt
+	if err := json.NewDecoder(resp.Body).Decode(&rows); err != nil {
+		return a, err
+	}
+	if len(rows) == 0 {
+		return a, ErrNotFound
+	}
+	return rows[0], nil
+}
+
+func GetAttempt(id string) (models.ExamAttempt, error) {
+	var a models.ExamAttempt
+	err := fetchOne("exam_attempts", id, &a)
+	return a, err
+}
+
+// ListAttempts trae los intentos de un examen, de un alumno si student no está vacío
+func ListAttempts(examID int, student string) ([]models.ExamAttempt, error) {
+	filter := fmt.Sprintf("select=*&exam_id=eq.%d&order=started_at.desc", examID)
+	if student != "" {
+		filter += "&student_id=eq." + student
+	}
+	var attempts []models.ExamAttempt
+	err := fetchAll("exam_attempts", filter, func(row json.RawMessage) error {
+		var a models.ExamAttempt
+		if err := json.Unmarshal(row, &a); err != nil {
+			return err
+		}
+		attempts = append(attempts, a)
+		return nil
+	})
+	return attempts, err
+}
+
+// SaveResponse guarda la respuesta de una pregunta con la función SQL
+// save_exam_response, que cambia sólo esa clave de responses (jsonb_set) y
+// sólo si el intento sigue abierto: dos respuestas simultáneas a preguntas
+// distintas no se pisan. Una respuesta vacía borra la clave.
+func SaveResponse(id, quizID int, response []string) error {
+	payload := map[string]interface{}{"p_attempt": id, "p_quiz": strconv.Itoa(quizID), "p_response": nil}
+	if len(response) > 0 {
+		payload["p_response"] = response
+	}
+	return handleResponse(CallSupabase("POST", "rpc/save_exam_response", payload, ""))
+}
+
+// UseHint suma una pista a la pregunta con la función SQL use_exam_hint, sin
+// pasar de max, y devuelve cuántas lleva pedidas. Como SaveResponse, toca
+// sólo esa clave y sólo si el intento sigue abierto.
+func UseHint(id, quizID, max int) (int, error) {
+	payload := map[string]interface{}{"p_attempt": id, "p_quiz": strconv.Itoa(quizID), "p_max": max}
+	resp, err := CallSupabase("POST", "rpc/use_exam_hint", payload, "")
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return 0, fmt.Errorf("error supabase: %d - %s", resp.StatusCode, resp.Status)
+	}
+	var used *int
+	if err := json.NewDecoder(resp.Body).Decode(&used); err != nil {
+		return 0, err
+	}
+	if used == nil {
+		// El intento ya estaba cerrado
+		return 0, ErrNotFound
+	}
+	return *used, nil
+}
+
+// FinishAttempt guarda la nota; el filtro evita cerrar dos veces el mismo
+// intento y el resultado dice cuántos cerró (0 si ya estaba entregado)
+func FinishAttempt(a models.ExamAttempt) (int, error) {
+	data := map[string]interface{}{
+		"submitted_at": a.SubmittedAt, "score": a.Score, "passed": a.Passed, "breakdown": a.Breakdown,
+	}
+	return patchWhere("exam_attempts", fmt.Sprintf("id=eq.%d&submitted_at=is.null", a.ID), data)
+}
+
+// ExpiredAttempts lista los intentos sin entregar cuya hora límite ya pasó
+func ExpiredAttempts(before string) ([]models.ExamAttempt, error) {
+	var attempts []models.ExamAttempt
+	err := fetchJSON("exam_attempts", "select=*&submitted_at=is.null&deadline=lt."+before+"&order=deadline.asc&limit=500", &attempts)
+	return attempts, err
+}
@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"encoding/json"
+)
+
+// ExportRows recorre las filas de la tabla que cumplen el filtro del listado,
+// por páginas. order debe terminar en una columna única (id) para que las
+// páginas no se solapen.
+func ExportRows(table, filter, order string, each func(json.RawMessage) error) error {
+	query := "select=*&order=" + order
+	if filter != "" {
+		query += "&" + filter
+	}
+	return fetchAll(table, query, each)
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"fmt"
+	"strconv"
+
+	"english-at-lima-cms/internal/models"
+)
+
+// importPayload arma la fila de cualquier contenido importable
+func importPayload(item interface{}) (map[string]interface{}, error) {
+	switch v := item.(type) {
+	case models.Sentence:
+		return sentencePayload(v), nil
+	case models.Quiz:
+		return quizPayload(v), nil
+	case models.Resource:
+		return resourcePayload(v), nil
+	}
+	return nil, fmt.Errorf("contenido no importable: %T", item)
+}
+
+// ImportChunk guarda un bloque de la importación: las filas nuevas en una
+// sola petición y las existentes una a una, escribiendo sólo las columnas
+// asignadas en el archivo (nunca el estado de publicación)
+func ImportChunk(table string, inserts []interface{}, updates map[int]interface{}, columns []string) error {
+	if len(inserts) > 0 {
+		rows := make([]map[string]interface{}, len(inserts))
+		for i, item := range inserts {
+			row, err := importPayload(item)
+			if err != nil {
+				return err
+			}
+			rows[i] = row
+		}
+		if err := handleResponse(CallSupabase("POST", table, rows, "")); err != nil {
+			return err
+		}
+	}
+
+	for id, item := range updates {
+		full, err := importPayload(item)
+		if err != nil {
+			return err
+		}
+		data := map[string]interface{}{}
+		for _, col := range columns {
+			if v, ok := full[col]; ok {
+				data[col] = v
+			}
+		}
+		if len(data) == 0 {
+			continue
+		}
+		if err := patchToSupabase(table, strconv.Itoa(id), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
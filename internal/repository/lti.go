@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"english-at-lima-cms/internal/models"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+func ListLTIPlatforms() ([]models.LTIPlatform, error) {
+	var platforms []models.LTIPlatform
+	err := fetchJSON("lti_platforms", "select=*&order=name.asc", &platforms)
+	return platforms, err
+}
+
+// GetLTIPlatform busca la plataforma por issuer y client_id. Sin client_id
+// (el inicio de sesión no siempre lo trae) sólo vale si el issuer tiene un
+// único registro.
+func GetLTIPlatform(issuer, clientID string) (models.LTIPlatform, error) {
+	filter := "select=*&limit=2&issuer=eq." + url.QueryEscape(issuer)
+	if clientID != "" {
+		filter += "&client_id=eq." + url.QueryEscape(clientID)
+	}
+	var platforms []models.LTIPlatform
+	if err := fetchJSON("lti_platforms", filter, &platforms); err != nil {
+		return models.LTIPlatform{}, err
+	}
+	switch len(platforms) {
+	case 0:
+		return models.LTIPlatform{}, ErrNotFound
+	case 1:
+		return platforms[0], nil
+	default:
+		return models.LTIPlatform{}, fmt.Errorf("hay varios registros para %s: la plataforma debe enviar client_id", issuer)
+	}
+}
+
+func InsertLTIPlatform(p models.LTIPlatform) error {
+	data := map[string]interface{}{
+		"name": p.Name, "issuer": p.Issuer, "client_id": p.ClientID, "deployment_ids": p.DeploymentIDs,
+		"auth_login_url": p.AuthLoginURL, "auth_token_url": p.AuthTokenURL, "jwks_url": p.JWKSURL,
+	}
+	return handleResponse(CallSupabase("POST", "lti_platforms", data, ""))
+}
+
+func DeleteLTIPlatform(id string) error {
+	return handleResponse(CallSupabase("DELETE", "lti_platforms", nil, "id=eq."+id))
+}
+
+// ClaimLTIGrade reserva el intento de una sesión LTI para enviarle la nota.
+// La clave primaria hace el resto: si otra petición (o el servidor antes de
+// reiniciarse) ya lo reservó, el insert choca y devuelve false.
+func ClaimLTIGrade(attempt string, expires time.Time) (bool, error) {
+	data := map[string]interface{}{"attempt": attempt, "expires_at": expires.UTC().Format(time.RFC3339)}
+	resp, err := CallSupabase("POST", "lti_grades", data, "")
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusConflict {
+		return false, nil
+	}
+	if resp.StatusCode >= 400 {
+		return false, fmt.Errorf("error supabase: %d - %s", resp.StatusCode, resp.Status)
+	}
+	return true, nil
+}
+
+// ReleaseLTIGrade libera el intento cuando la nota no llegó al LMS
+func ReleaseLTIGrade(attempt string) error {
+	return handleResponse(CallSupabase("DELETE", "lti_grades", nil, "attempt=eq."+url.QueryEscape(attempt)))
+}
+
+// ListLTIQuizzes son los quizzes publicados que el docente puede elegir por
+// deep linking, por nivel y los más nuevos primero
+func ListLTIQuizzes(limit int) ([]models.Quiz, error) {
+	var quizzes []models.Quiz
+	err := fetchJSON("quizzes", fmt.Sprintf("select=*&%s&order=level.asc,id.desc&limit=%d", PublicFilter, limit), &quizzes)
+	return quizzes, err
+}
@@ -0,0 +1,25 @@
+package repository
+
+import "english-at-lima-cms/internal/models"
+
+// FindMedia busca un archivo ya subido con el mismo hash y visibilidad
+func FindMedia(sha string, private bool) (*models.Media, error) {
+	var rows []models.Media
+	filter := "select=*&limit=1&sha256=eq." + sha
+	if private {
+		filter += "&private=is.true"
+	} else {
+		filter += "&private=is.false"
+	}
+	if err := fetchJSON("media", filter, &rows); err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	return &rows[0], nil
+}
+
+func InsertMedia(m models.Media) error {
+	return handleResponse(CallSupabase("POST", "media", m, ""))
+}
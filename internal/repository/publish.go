@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"english-at-lima-cms/internal/models"
+)
+
+// Filtros de estado: los alumnos sólo ven lo publicado y las listas del
+// admin ocultan lo archivado salvo que se pida
+const (
+	PublicFilter = "status=eq." + models.StatusPublished
+	ActiveFilter = "status=neq." + models.StatusArchived
+)
+
+// ContentTables son las tablas con estado editorial
+var ContentTables = []string{"sentences", "quizzes", "resources"}
+
+// Transition cambia a status las filas que cumplen el filtro y devuelve cuántas cambiaron
+func Transition(table, filter, status string) (int, error) {
+	return patchWhere(table, filter, map[string]interface{}{"status": status})
+}
+
+// patchWhere actualiza todas las filas que cumplen el filtro y devuelve cuántas eran
+func patchWhere(table, filter string, data map[string]interface{}) (int, error) {
+	resp, err := CallSupabase("PATCH", table, data, filter+"&select=id")
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return 0, fmt.Errorf("error supabase: %d - %s", resp.StatusCode, resp.Status)
+	}
+	var rows []json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&rows); err != nil {
+		return 0, err
+	}
+	return len(rows), nil
+}
+
+// withPublication añade estado y fechas al payload; las fechas vacías van
+// como null porque la columna es timestamptz
+func withPublication(data map[string]interface{}, p models.Publication) map[string]interface{} {
+	data["status"] = p.Status
+	data["publish_at"] = nullable(p.PublishAt)
+	data["unpublish_at"] = nullable(p.UnpublishAt)
+	return data
+}
+
+func nullable(ts string) interface{} {
+	if ts == "" {
+		return nil
+	}
+	return ts
+}
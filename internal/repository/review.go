@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"english-at-lima-cms/internal/models"
+)
+
+// --- ROLES DEL EQUIPO ---
+
+// GetStaffRole devuelve el rol del usuario en staff_roles; quien no figura es editor
+func GetStaffRole(email string) (string, error) {
+	var rows []struct {
+		Role string `json:"role"`
+	}
+	if err := fetchJSON("staff_roles", "select=role&limit=1&email=eq."+url.QueryEscape(email), &rows); err != nil {
+		return models.RoleEditor, err
+	}
+	if len(rows) == 0 || rows[0].Role == "" {
+		return models.RoleEditor, nil
+	}
+	return rows[0].Role, nil
+}
+
+// ListReviewers devuelve el correo de todos los revisores
+func ListReviewers() ([]string, error) {
+	var rows []struct {
+		Email string `json:"email"`
+	}
+	if err := fetchJSON("staff_roles", "select=email&role=eq."+models.RoleReviewer, &rows); err != nil {
+		return nil, err
+	}
+	emails := make([]string, len(rows))
+	for i, r := range rows {
+		emails[i] = r.Email
+	}
+	return emails, nil
+}
+
+// --- REVISIÓN EDITORIAL ---
+
+// SetReviewState aplica data sólo si la fila sigue en el estado from, para
+// que dos revisores no decidan a la vez sobre el mismo contenido
+func SetReviewState(table, id, from string, data map[string]interface{}) (bool, error) {
+	n, err := patchWhere(table, fmt.Sprintf("id=eq.%s&review_state=eq.%s", id, from), data)
+	return n > 0, err
+}
+
+// ListForReview carga en target las filas en revisión o con cambios pedidos
+func ListForReview(table string, target interface{}) error {
+	state := fmt.Sprintf("review_state=in.(%s,%s)", models.ReviewInReview, models.ReviewChanges)
+	return fetchJSON(table, "select=*&"+state+"&order=updated_at.asc", target)
+}
+
+func InsertReviewEvent(e models.ReviewEvent) error {
+	return handleResponse(CallSupabase("POST", "review_events", e, ""))
+}
+
+// ListReviewEvents devuelve el historial completo de un contenido, del más antiguo al más nuevo
+func ListReviewEvents(contentType, id string) ([]models.ReviewEvent, error) {
+	var events []models.ReviewEvent
+	filter := fmt.Sprintf("select=*&content_type=eq.%s&content_id=eq.%s&order=id.asc", contentType, id)
+	err := fetchAll("review_events", filter, func(row json.RawMessage) error {
+		var e models.ReviewEvent
+		if err := json.Unmarshal(row, &e); err != nil {
+			return err
+		}
+		events = append(events, e)
+		return nil
+	})
+	return events, err
+}
+
+// --- AVISOS DEL PANEL ---
+
+func InsertNotifications(ns []models.Notification) error {
+	if len(ns) == 0 {
+		return nil
+	}
+	return handleResponse(CallSupabase("POST", "notifications", ns, ""))
+}
+
+// ListNotifications devuelve los últimos 50 avisos del usuario
+func ListNotifications(recipient string) ([]models.Notification, error) {
+	var ns []models.Notification
+	err := fetchJSON("notifications", "select=*&recipient=eq."+url.QueryEscape(recipient)+"&order=id.desc&limit=50", &ns)
+	return ns, err
+}
+
+// CountUnread cuenta los avisos sin leer del usuario
+func CountUnread(recipient string) (int, error) {
+	var rows []json.RawMessage
+	err := fetchJSON("notifications", "select=id&read=is.false&limit=100&recipient=eq."+url.QueryEscape(recipient), &rows)
+	return len(rows), err
+}
+
+func MarkNotificationsRead(recipient string) error {
+	filter := "read=is.false&recipient=eq." + url.QueryEscape(recipient)
+	return handleResponse(CallSupabase("PATCH", "notifications", map[string]interface{}{"read": true}, filter))
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"bytes"
 	"encoding/json"
+	"english-at-lima-cms/internal/models"
 	"fmt"
 	"net/http"
 	"os"
@@ -10,8 +11,13 @@ import (
 
 // --- MOTOR PRINCIPAL ---
 
-// CallSupabase es la única función que toca la red
+// CallSupabase es la puerta de entrada a PostgREST; devuelve las filas escritas
 func CallSupabase(method, table string, body interface{}, filter string) (*http.Response, error) {
+	return callSupabase(method, table, body, filter, "return=representation")
+}
+
+// callSupabase es CallSupabase con la cabecera Prefer a elegir (upserts, return=minimal…)
+func callSupabase(method, table string, body interface{}, filter, prefer string) (*http.Response, error) {
 	url := fmt.Sprintf("%s/rest/v1/%s", os.Getenv("SUPABASE_URL"), table)
 	if filter != "" {
 		url += "?" + filter
@@ -26,7 +32,7 @@ func CallSupabase(method, table string, body interface{}, filter string) (*http.
 	req.Header.Set("apikey", os.Getenv("SUPABASE_KEY"))
 	req.Header.Set("Authorization", "Bearer "+os.Getenv("SUPABASE_KEY"))
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Prefer", "return=representation")
+	req.Header.Set("Prefer", prefer)
 
 	client := &http.Client{}
 	return client.Do(req)
@@ -47,19 +53,59 @@ func handleResponse(resp *http.Response, err error) error {
 
 // --- IMPLEMENTACIÓN DE INSERTS ---
 
-func InsertSentence(english, spanish string) error {
-	data := map[string]interface{}{"english": english, "spanish": spanish}
-	return handleResponse(CallSupabase("POST", "sentences", data, ""))
+func InsertSentence(s models.Sentence) error {
+	return handleResponse(CallSupabase("POST", "sentences", sentencePayload(s), ""))
+}
+
+// nonNil evita guardar null en las columnas jsonb de traducciones
+func nonNil(m map[string]string) map[string]string {
+	if m == nil {
+		return map[string]string{}
+	}
+	return m
+}
+
+// sentencePayload incluye los datos opcionales (nota, registro, ejemplos, IPA y audio)
+func sentencePayload(s models.Sentence) map[string]interface{} {
+	examples := s.Examples
+	if examples == nil {
+		examples = []string{}
+	}
+	return withPublication(map[string]interface{}{
+		"english": s.English, "spanish": s.Spanish,
+		"grammar_note": s.GrammarNote, "register": s.Register,
+		"examples": examples, "ipa": s.IPA, "audio_url": s.AudioURL,
+		"tags": s.Tags, "level": s.Level,
+		"translations": nonNil(s.Translations),
+	}, s.Publication)
+}
+
+func InsertResource(r models.Resource) error {
+	return handleResponse(CallSupabase("POST", "resources", resourcePayload(r), ""))
+}
+
+// resourcePayload limita las columnas que el formulario puede escribir
+func resourcePayload(r models.Resource) map[string]interface{} {
+	return withPublication(map[string]interface{}{
+		"title": r.Title, "url": r.URL, "type": r.Type,
+		"description": r.Description, "thumbnail_url": r.ThumbnailURL,
+		"duration": r.Duration, "site_name": r.SiteName,
+		"tags": r.Tags, "level": r.Level,
+		"title_translations": nonNil(r.TitleTranslations),
+	}, r.Publication)
 }
 
-func InsertResource(t, u, r string) error {
-	data := map[string]interface{}{"title": t, "url": u, "type": r}
-	return handleResponse(CallSupabase("POST", "resources", data, ""))
+func InsertQuiz(q models.Quiz) error {
+	return handleResponse(CallSupabase("POST", "quizzes", quizPayload(q), ""))
 }
 
-func InsertQuiz(q string, o []string, c string) error {
-	data := map[string]interface{}{"question": q, "options": o, "correct": c}
-	return handleResponse(CallSupabase("POST", "quizzes", data, ""))
+// quizPayload guarda tipo, opciones y respuestas de cualquier tipo de pregunta
+func quizPayload(q models.Quiz) map[string]interface{} {
+	return withPublication(map[string]interface{}{
+		"question": q.Question, "type": q.Type, "options": q.Options, "answers": q.Answers,
+		"tags": q.Tags, "level": q.Level,
+		"explanation": q.Explanation, "option_notes": q.OptionNotes, "hints": q.Hints,
+	}, q.Publication)
 }
 
 // --- IMPLEMENTACIÓN DE UPDATES (PATCH) ---
@@ -69,16 +115,16 @@ func patchToSupabase(table string, id string, data map[string]interface{}) error
 	return handleResponse(CallSupabase("PATCH", table, data, filter))
 }
 
-func UpdateSentence(id, t, tr string) error {
-	return patchToSupabase("sentences", id, map[string]interface{}{"english": t, "spanish": tr})
+func UpdateSentence(id string, s models.Sentence) error {
+	return patchToSupabase("sentences", id, sentencePayload(s))
 }
 
-func UpdateResource(id, t, u, r string) error {
-	return patchToSupabase("resources", id, map[string]interface{}{"title": t, "url": u, "type": r})
+func UpdateResource(id string, r models.Resource) error {
+	return patchToSupabase("resources", id, resourcePayload(r))
 }
 
-func UpdateQuiz(id, q string, o []string, c string) error {
-	return patchToSupabase("quizzes", id, map[string]interface{}{"question": q, "options": o, "correct": c})
+func UpdateQuiz(id string, q models.Quiz) error {
+	return patchToSupabase("quizzes", id, quizPayload(q))
 }
 
 // --- AUTENTICACIÓN ---
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"encoding/json"
+	"english-at-lima-cms/internal/models"
+	"fmt"
+)
+
+// ListTagCounts devuelve cada etiqueta con su uso en frases, quizzes y
+// recursos mediante la función SQL tag_counts()
+func ListTagCounts() ([]models.TagCount, error) {
+	resp, err := CallSupabase("POST", "rpc/tag_counts", map[string]interface{}{}, "order=tag.asc")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("error supabase: %d - %s", resp.StatusCode, resp.Status)
+	}
+	var counts []models.TagCount
+	err = json.NewDecoder(resp.Body).Decode(&counts)
+	return counts, err
+}
+
+// ReplaceTag cambia la etiqueta old por replacement en las tres tablas sin
+// duplicarla. Sirve para renombrar, fusionar y borrar reasignando; con
+// replacement vacío la etiqueta simplemente se quita.
+func ReplaceTag(old, replacement string) error {
+	payload := map[string]interface{}{"p_old": old, "p_new": nil}
+	if replacement != "" {
+		payload["p_new"] = replacement
+	}
+	if err := handleResponse(CallSupabase("POST", "rpc/replace_tag", payload, "")); err != nil {
+		return fmt.Errorf("no se pudo reemplazar la etiqueta %s: %w", old, err)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+// Package review implementa la revisión editorial: el autor envía el
+// contenido, un revisor lo aprueba (y se publica) o pide cambios con
+// comentarios por campo.
+package review
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"english-at-lima-cms/internal/models"
+)
+
+// Acciones del flujo de revisión
+const (
+	ActionSubmit         = "submit"
+	ActionApprove        = "approve"
+	ActionRequestChanges = "request_changes"
+)
+
+// Límites de los comentarios del revisor
+const (
+	MaxComments      = 20
+	MaxCommentLength = 1000
+)
+
+// General es el campo de los comentarios que no señalan un campo concreto
+const General = ""
+
+var (
+	ErrForbidden    = errors.New("sólo un revisor puede aprobar o pedir cambios")
+	ErrSelfApproval = errors.New("no puede aprobar un contenido que usted mismo envió")
+	ErrConflict     = errors.New("el contenido cambió de estado, recargue la página")
+)
+
+// Next devuelve el estado al que lleva la acción o un error si no está permitida
+func Next(state, action, role string) (string, error) {
+	switch action {
+	case ActionSubmit:
+		if state == models.ReviewInReview {
+			return "", fmt.Errorf("el contenido ya está en revisión")
+		}
+		return models.ReviewInReview, nil
+	case ActionApprove, ActionRequestChanges:
+		if role != models.RoleReviewer {
+			return "", ErrForbidden
+		}
+		if state != models.ReviewInReview {
+			return "", fmt.Errorf("sólo se puede decidir sobre contenido en revisión")
+		}
+		if action == ActionApprove {
+			return models.ReviewApproved, nil
+		}
+		return models.ReviewChanges, nil
+	}
+	return "", fmt.Errorf("acción desconocida: %s", action)
+}
+
+// CleanComments recorta los comentarios, quita los vacíos y comprueba que
+// cada uno apunte a un campo del contenido (o sea general)
+func CleanComments(comments []models.ReviewComment, fields []string) ([]models.ReviewComment, error) {
+	clean := []models.ReviewComment{}
+	for _, c := range comments {
+		c.Text = strings.TrimSpace(c.Text)
+		if c.Text == "" {
+			continue
+		}
+		if c.Field != General && !slices.Contains(fields, c.Field) {
+			return nil, fmt.Errorf("campo desconocido en el comentario: %s", c.Field)
+		}
+		if len(c.Text) > MaxCommentLength {
+			return nil, fmt.Errorf("cada comentario admite como máximo %d caracteres", MaxCommentLength)
+		}
+		clean = append(clean, c)
+	}
+	if len(clean) > MaxComments {
+		return nil, fmt.Errorf("se admiten como máximo %d comentarios", MaxComments)
+	}
+	return clean, nil
+}
+
+// Validate exige una nota o un comentario cuando se piden cambios
+func Validate(action, note string, comments []models.ReviewComment) error {
+	if len(note) > MaxCommentLength {
+		return fmt.Errorf("la nota admite como máximo %d caracteres", MaxCommentLength)
+	}
+	if action == ActionRequestChanges && note == "" && len(comments) == 0 {
+		return fmt.Errorf("explique qué hay que cambiar en al menos un comentario")
+	}
+	return nil
+}
+
+// Author es quien hizo el último envío a revisión según el historial
+func Author(history []models.ReviewEvent) string {
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Action == ActionSubmit {
+			return history[i].Actor
+		}
+	}
+	return ""
+}
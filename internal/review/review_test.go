@@ -0,0 +1,113 @@
+package review
+
+import (
+	"strings"
+	"testing"
+
+	"english-at-lima-cms/internal/models"
+)
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name    string
+		state   string
+		action  string
+		role    string
+		want    string
+		wantErr bool
+	}{
+		{"editor envía borrador nuevo", "", ActionSubmit, models.RoleEditor, models.ReviewInReview, false},
+		{"reenvío tras cambios", models.ReviewChanges, ActionSubmit, models.RoleEditor, models.ReviewInReview, false},
+		{"reenvío tras aprobar", models.ReviewApproved, ActionSubmit, models.RoleEditor, models.ReviewInReview, false},
+		{"doble envío", models.ReviewInReview, ActionSubmit, models.RoleEditor, "", true},
+		{"revisor aprueba", models.ReviewInReview, ActionApprove, models.RoleReviewer, models.ReviewApproved, false},
+		{"revisor pide cambios", models.ReviewInReview, ActionRequestChanges, models.RoleReviewer, models.ReviewChanges, false},
+		{"editor no aprueba", models.ReviewInReview, ActionApprove, models.RoleEditor, "", true},
+		{"editor no pide cambios", models.ReviewInReview, ActionRequestChanges, models.RoleEditor, "", true},
+		{"aprobar sin enviar", "", ActionApprove, models.RoleReviewer, "", true},
+		{"aprobar dos veces", models.ReviewApproved, ActionApprove, models.RoleReviewer, "", true},
+		{"acción desconocida", "", "publish", models.RoleReviewer, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Next(tt.state, tt.action, tt.role)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Next() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Next() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Next(models.ReviewInReview, ActionApprove, models.RoleEditor); err != ErrForbidden {
+		t.Errorf("un editor que aprueba debe recibir ErrForbidden, got %v", err)
+	}
+}
+
+func TestCleanComments(t *testing.T) {
+	fields := []string{"english", "spanish"}
+	tests := []struct {
+		name     string
+		comments []models.ReviewComment
+		want     int
+		wantErr  bool
+	}{
+		{"vacíos se descartan", []models.ReviewComment{{Field: "english", Text: "  "}}, 0, false},
+		{"campo conocido", []models.ReviewComment{{Field: "english", Text: " Falta el artículo "}}, 1, false},
+		{"comentario general", []models.ReviewComment{{Field: General, Text: "Revisar el tono"}}, 1, false},
+		{"campo desconocido", []models.ReviewComment{{Field: "password", Text: "x"}}, 0, true},
+		{"demasiado largo", []models.ReviewComment{{Field: "english", Text: strings.Repeat("a", MaxCommentLength+1)}}, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CleanComments(tt.comments, fields)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CleanComments() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(got) != tt.want {
+				t.Errorf("CleanComments() = %d comentarios, want %d", len(got), tt.want)
+			}
+		})
+	}
+
+	many := make([]models.ReviewComment, MaxComments+1)
+	for i := range many {
+		many[i] = models.ReviewComment{Text: "ok"}
+	}
+	if _, err := CleanComments(many, fields); err == nil {
+		t.Error("se esperaba error con más de MaxComments comentarios")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	comment := []models.ReviewComment{{Field: "english", Text: "Corregir"}}
+	if err := Validate(ActionRequestChanges, "", nil); err == nil {
+		t.Error("pedir cambios sin nota ni comentarios debe fallar")
+	}
+	if err := Validate(ActionRequestChanges, "Ver comentarios", nil); err != nil {
+		t.Errorf("una nota basta para pedir cambios: %v", err)
+	}
+	if err := Validate(ActionRequestChanges, "", comment); err != nil {
+		t.Errorf("un comentario basta para pedir cambios: %v", err)
+	}
+	if err := Validate(ActionApprove, "", nil); err != nil {
+		t.Errorf("aprobar no requiere comentarios: %v", err)
+	}
+}
+
+func TestAuthor(t *testing.T) {
+	history := []models.ReviewEvent{
+		{Action: ActionSubmit, Actor: "ana@lima.pe"},
+		{Action: ActionRequestChanges, Actor: "rev@lima.pe"},
+		{Action: ActionSubmit, Actor: "luis@lima.pe"},
+	}
+	if got := Author(history); got != "luis@lima.pe" {
+		t.Errorf("Author() = %q, want luis@lima.pe", got)
+	}
+	if got := Author(nil); got != "" {
+		t.Errorf("Author(nil) = %q, want vacío", got)
+	}
+}
@@ -0,0 +1,97 @@
+package review
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"english-at-lima-cms/internal/models"
+	"english-at-lima-cms/internal/publish"
+	"english-at-lima-cms/internal/repository"
+)
+
+// Target es el contenido sobre el que se actúa
+type Target struct {
+	Type  string // "sentence", "quiz" o "resource"
+	Table string
+	ID    int
+	Label string // texto corto para el historial y los avisos
+	models.Publication
+}
+
+// Path es la página de revisión del contenido en el panel
+func (t Target) Path() string {
+	return fmt.Sprintf("/admin/review/%s/%d", t.Type, t.ID)
+}
+
+// Apply ejecuta la acción: cambia el estado (y publica si se aprueba),
+// guarda la entrada del historial y avisa a quien corresponda
+func Apply(t Target, action, actor, role, note string, comments []models.ReviewComment, now time.Time) error {
+	next, err := Next(t.ReviewState, action, role)
+	if err != nil {
+		return err
+	}
+	if err := Validate(action, note, comments); err != nil {
+		return err
+	}
+	id := strconv.Itoa(t.ID)
+	history, err := repository.ListReviewEvents(t.Type, id)
+	if err != nil {
+		return err
+	}
+	author := Author(history)
+	if action == ActionApprove && author == actor {
+		return ErrSelfApproval
+	}
+
+	data := map[string]interface{}{"review_state": next}
+	if action == ActionApprove {
+		// Aprobar publica; si la fecha de publicación es futura queda programado
+		p := t.Publication
+		p.Status = models.StatusPublished
+		publish.Normalize(&p, now)
+		data["status"], data["publish_at"] = p.Status, p.PublishAt
+	}
+	ok, err := repository.SetReviewState(t.Table, id, t.ReviewState, data)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrConflict
+	}
+
+	event := models.ReviewEvent{ContentType: t.Type, ContentID: t.ID, Action: action, Actor: actor, Note: note, Comments: comments}
+	if err := repository.InsertReviewEvent(event); err != nil {
+		return err
+	}
+	return notify(t, action, actor, author)
+}
+
+// notify avisa a los revisores de cada envío y al autor de cada decisión
+func notify(t Target, action, actor, author string) error {
+	var recipients []string
+	var message string
+	switch action {
+	case ActionSubmit:
+		reviewers, err := repository.ListReviewers()
+		if err != nil {
+			return err
+		}
+		recipients = reviewers
+		message = fmt.Sprintf("📤 %s envió a revisión: %s", actor, t.Label)
+	case ActionApprove:
+		recipients = []string{author}
+		message = fmt.Sprintf("✅ %s aprobó y publicó: %s", actor, t.Label)
+	case ActionRequestChanges:
+		recipients = []string{author}
+		message = fmt.Sprintf("✏️ %s pidió cambios en: %s", actor, t.Label)
+	}
+
+	var ns []models.Notification
+	for _, r := range recipients {
+		if r != "" && r != actor {
+			ns = append(ns, models.Notification{Recipient: r, Message: message, Link: t.Path()})
+		}
+	}
+	return repository.InsertNotifications(ns)
+}
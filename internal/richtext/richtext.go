@@ -0,0 +1,201 @@
+// Package richtext convierte el Markdown de las notas (gramática,
+// explicaciones, descripciones) en HTML seguro: el Markdown se traduce a un
+// conjunto reducido de etiquetas y el resultado pasa siempre por un
+// sanitizador de lista blanca.
+package richtext
+
+import (
+	"html"
+	"html/template"
+	"regexp"
+	"strings"
+)
+
+var (
+	reBullet  = regexp.MustCompile(`^[-*+]\s+(.*)$`)
+	reOrdered = regexp.MustCompile(`^\d{1,3}[.)]\s+(.*)$`)
+	reQuote   = regexp.MustCompile(`^>\s?(.*)$`)
+)
+
+// Clean prepara el Markdown para guardarlo: quita los caracteres de control
+// (salvo saltos de línea y tabuladores) y los espacios de los extremos
+func Clean(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.Map(func(r rune) rune {
+		if (r < 0x20 && r != '\n' && r != '\t') || r == 0x7f {
+			return -1
+		}
+		return r
+	}, s)
+	return strings.TrimSpace(s)
+}
+
+// Render convierte Markdown en HTML seguro para las plantillas
+func Render(md string) template.HTML {
+	// El HTML se genera escapando todo el texto y se vuelve a sanitizar por si acaso
+	return template.HTML(SanitizeHTML(toHTML(Clean(md))))
+}
+
+// Plain quita las marcas de Markdown, para las meta descripciones
+func Plain(md string) string {
+	lines := strings.Split(Clean(md), "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		for _, re := range []*regexp.Regexp{reBullet, reOrdered, reQuote} {
+			if m := re.FindStringSubmatch(line); m != nil {
+				line = m[1]
+				break
+			}
+		}
+		lines[i] = line
+	}
+	text := html.UnescapeString(stripTags(inline(strings.Join(lines, " "))))
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// toHTML traduce los bloques: párrafos (las líneas seguidas se unen con
+// <br>), listas con "-", "*" o "1." y citas con ">"
+func toHTML(md string) string {
+	var b strings.Builder
+	block := "" // "p", "ul", "ol" o "blockquote"
+	var lines []string
+
+	flush := func() {
+		switch block {
+		case "p", "blockquote":
+			b.WriteString("<" + block + ">")
+			if block == "blockquote" {
+				b.WriteString("<p>")
+			}
+			for i, l := range lines {
+				if i > 0 {
+					b.WriteString("<br>")
+				}
+				b.WriteString(inline(l))
+			}
+			if block == "blockquote" {
+				b.WriteString("</p>")
+			}
+			b.WriteString("</" + block + ">")
+		case "ul", "ol":
+			b.WriteString("<" + block + ">")
+			for _, l := range lines {
+				b.WriteString("<li>" + inline(l) + "</li>")
+			}
+			b.WriteString("</" + block + ">")
+		}
+		block, lines = "", nil
+	}
+	add := func(kind, line string) {
+		if block != kind {
+			flush()
+			block = kind
+		}
+		lines = append(lines, line)
+	}
+
+	for _, line := range strings.Split(md, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			flush()
+			continue
+		}
+		if m := reBullet.FindStringSubmatch(line); m != nil {
+			add("ul", m[1])
+		} else if m := reOrdered.FindStringSubmatch(line); m != nil {
+			add("ol", m[1])
+		} else if m := reQuote.FindStringSubmatch(line); m != nil {
+			add("blockquote", m[1])
+		} else {
+			add("p", line)
+		}
+	}
+	flush()
+	return b.String()
+}
+
+// inline traduce `código`, **negrita**, *cursiva* y [texto](url) y escapa
+// todo lo demás. El guion bajo no marca cursiva porque los quizzes de
+// completar usan ___ para el hueco.
+func inline(s string) string {
+	var b strings.Builder
+	for len(s) > 0 {
+		switch {
+		case s[0] == '\\' && len(s) > 1 && strings.ContainsRune("\\`*[]()", rune(s[1])):
+			b.WriteString(html.EscapeString(s[1:2]))
+			s = s[2:]
+			continue
+		case s[0] == '`':
+			if end := strings.IndexByte(s[1:], '`'); end > 0 {
+				b.WriteString("<code>" + html.EscapeString(s[1:1+end]) + "</code>")
+				s = s[end+2:]
+				continue
+			}
+		case strings.HasPrefix(s, "**"):
+			if end := strings.Index(s[2:], "**"); end > 0 {
+				b.WriteString("<strong>" + inline(s[2:2+end]) + "</strong>")
+				s = s[end+4:]
+				continue
+			}
+		case s[0] == '*' && len(s) > 1 && s[1] != ' ':
+			if end := strings.IndexByte(s[1:], '*'); end > 0 && s[end] != ' ' {
+				b.WriteString("<em>" + inline(s[1:1+end]) + "</em>")
+				s = s[end+2:]
+				continue
+			}
+		case s[0] == '[':
+			if text, url, rest, ok := link(s); ok {
+				if SafeURL(url) {
+					b.WriteString(`<a href="` + html.EscapeString(url) + `">` + inline(text) + "</a>")
+				} else {
+					b.WriteString(inline(text))
+				}
+				s = rest
+				continue
+			}
+		}
+		// Texto normal hasta el siguiente carácter especial
+		next := strings.IndexAny(s[1:], "\\`*[")
+		if next < 0 {
+			next = len(s) - 1
+		}
+		b.WriteString(html.EscapeString(s[:next+1]))
+		s = s[next+1:]
+	}
+	return b.String()
+}
+
+// link reconoce [texto](url) al principio de s. Los paréntesis de la URL
+// tienen que estar equilibrados, como en .../wiki/Verb_(grammar)
+func link(s string) (text, url, rest string, ok bool) {
+	close := strings.Index(s, "](")
+	if close < 1 {
+		return "", "", "", false
+	}
+	end := closingParen(s[close+2:])
+	if end < 1 {
+		return "", "", "", false
+	}
+	text, url = s[1:close], strings.TrimSpace(s[close+2:close+2+end])
+	if strings.ContainsAny(text, "[]") || strings.ContainsAny(url, " \t") {
+		return "", "", "", false
+	}
+	return text, url, s[close+3+end:], true
+}
+
+// closingParen es la posición del ")" que cierra el destino del enlace, o -1
+func closingParen(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
@@ -0,0 +1,179 @@
+package richtext
+
+import (
+	"strings"
+	"testing"
+
+	xhtml "golang.org/x/net/html"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		md   string
+		want string
+	}{
+		{"párrafo", "Hola", "<p>Hola</p>"},
+		{"líneas seguidas", "uno\ndos", "<p>uno<br>dos</p>"},
+		{"párrafos", "uno\n\ndos", "<p>uno</p><p>dos</p>"},
+		{"negrita y cursiva", "**does** *not*", "<p><strong>does</strong> <em>not</em></p>"},
+		{"código", "use `do not`", "<p>use <code>do not</code></p>"},
+		{"lista", "- am\n- is\n- are", "<ul><li>am</li><li>is</li><li>are</li></ul>"},
+		{"lista numerada", "1. I\n2) you", "<ol><li>I</li><li>you</li></ol>"},
+		{"cita", "> To be", "<blockquote><p>To be</p></blockquote>"},
+		{"enlace", "[BBC](https://bbc.co.uk)", `<p><a href="https://bbc.co.uk" rel="nofollow noopener noreferrer" target="_blank">BBC</a></p>`},
+		{"enlace relativo", "[curso](/cursos/1)", `<p><a href="/cursos/1" rel="nofollow noopener noreferrer" target="_blank">curso</a></p>`},
+		{"enlace javascript", "[clic](javascript:alert(1))", "<p>clic</p>"},
+		{"enlace con paréntesis", "[verb](https://en.wikipedia.org/wiki/Verb_(grammar)) y más", `<p><a href="https://en.wikipedia.org/wiki/Verb_(grammar)" rel="nofollow noopener noreferrer" target="_blank">verb</a> y más</p>`},
+		{"paréntesis sin cerrar", "[a](https://x.com/(b", "<p>[a](https://x.com/(b</p>"},
+		{"hueco de completar", "I ___ to school", "<p>I ___ to school</p>"},
+		{"asterisco suelto", "2 * 3 = 6", "<p>2 * 3 = 6</p>"},
+		{"escape", `\*no\*`, "<p>*no*</p>"},
+		{"html escapado", "<b>hola</b> & <script>x</script>", "<p>&lt;b&gt;hola&lt;/b&gt; &amp; &lt;script&gt;x&lt;/script&gt;</p>"},
+		{"entidades", "&lt;script&gt;", "<p>&amp;lt;script&amp;gt;</p>"},
+		{"vacío", "  \n ", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(Render(tt.md)); got != tt.want {
+				t.Errorf("Render(%q) = %q, want %q", tt.md, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeHTML(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`<p onclick="x()">hola</p>`, "<p>hola</p>"},
+		{`<script>alert(1)</script>ok`, "ok"},
+		{`<img src=x onerror=alert(1)>`, ""},
+		{`<a href="javascript:alert(1)">x</a>`, `<a rel="nofollow noopener noreferrer" target="_blank">x</a>`},
+		{`<a href="JaVaScRiPt&#58;alert(1)">x</a>`, `<a rel="nofollow noopener noreferrer" target="_blank">x</a>`},
+		{`<a href="java&#x09;script:alert(1)">x</a>`, `<a rel="nofollow noopener noreferrer" target="_blank">x</a>`},
+		{`<a href="https://ok.pe" style="x">x</a>`, `<a href="https://ok.pe" rel="nofollow noopener noreferrer" target="_blank">x</a>`},
+		{`<strong><em>sin cerrar`, "<strong><em>sin cerrar</em></strong>"},
+		{`</p>suelto`, "suelto"},
+		{`<div><b>hola</b></div>`, "hola"},
+		{`<svg><script>alert(1)</script></svg>después`, "después"},
+		{`&lt;script&gt;`, "&lt;script&gt;"},
+	}
+	for _, tt := range tests {
+		if got := SanitizeHTML(tt.in); got != tt.want {
+			t.Errorf("SanitizeHTML(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSafeURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://example.com", true},
+		{"HTTP://example.com", true},
+		{"mailto:profe@lima.pe", true},
+		{"/recursos/3", true},
+		{"#ejemplos", true},
+		{"../a:b", true},
+		{"", false},
+		{"javascript:alert(1)", false},
+		{" javascript:alert(1)", false},
+		{"java\tscript:alert(1)", false},
+		{"data:text/html,<script>", false},
+		{"vbscript:msgbox", false},
+	}
+	for _, tt := range tests {
+		if got := SafeURL(tt.url); got != tt.want {
+			t.Errorf("SafeURL(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestPlain(t *testing.T) {
+	got := Plain("**Does** not\n- use `to`\n- [ver](https://x.pe) &")
+	if want := "Does not use to ver &"; got != want {
+		t.Errorf("Plain() = %q, want %q", got, want)
+	}
+}
+
+func TestClean(t *testing.T) {
+	if got := Clean(" uno\r\n- dos\x00\x1b \t"); got != "uno\n- dos" {
+		t.Errorf("Clean() = %q", got)
+	}
+}
+
+var xssSeeds = []string{
+	"<script>alert(1)</script>",
+	"<img src=x onerror=alert(1)>",
+	"<a href='javascript:alert(1)'>x</a>",
+	"[x](javascript:alert(1))",
+	"[x](JAVASCRIPT:alert(1))",
+	"[x](java&#x09;script:alert(1))",
+	"[x](data:text/html;base64,PHNjcmlwdD4=)",
+	"[<img src=x onerror=alert(1)>](https://ok.pe)",
+	"**<svg onload=alert(1)>**",
+	"`<script>`",
+	"&lt;script&gt;alert(1)&lt;/script&gt;",
+	"<<script>script>alert(1)<</script>/script>",
+	"<a href=\"https://ok.pe\" onmouseover=\"alert(1)\">x</a>",
+	"<p style=\"background:url(javascript:alert(1))\">x</p>",
+	"<iframe src=\"javascript:alert(1)\"></iframe>",
+	"- [a](https://ok.pe \"t\" onclick=x)\n> <b onclick=x>",
+}
+
+// assertSafe comprueba con el parser de HTML lo que vería el navegador
+func assertSafe(t *testing.T, out string) {
+	t.Helper()
+	z := xhtml.NewTokenizer(strings.NewReader(out))
+	for {
+		tt := z.Next()
+		if tt == xhtml.ErrorToken {
+			break
+		}
+		tok := z.Token()
+		if tt != xhtml.StartTagToken && tt != xhtml.SelfClosingTagToken {
+			continue
+		}
+		if !allowed[tok.DataAtom] {
+			t.Fatalf("etiqueta no permitida <%s> en %q", tok.Data, out)
+		}
+		for _, a := range tok.Attr {
+			switch {
+			case strings.HasPrefix(strings.ToLower(a.Key), "on"):
+				t.Fatalf("manejador de eventos %s en %q", a.Key, out)
+			case a.Key == "href" && !SafeURL(a.Val):
+				t.Fatalf("URL peligrosa %q en %q", a.Val, out)
+			case a.Key != "href" && a.Key != "rel" && a.Key != "target":
+				t.Fatalf("atributo no permitido %s en %q", a.Key, out)
+			}
+		}
+	}
+	if strings.Contains(strings.ToLower(out), "<script") {
+		t.Fatalf("<script> en %q", out)
+	}
+	if again := SanitizeHTML(out); again != out {
+		t.Fatalf("SanitizeHTML no es idempotente: %q → %q", out, again)
+	}
+}
+
+func FuzzRender(f *testing.F) {
+	for _, s := range xssSeeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, md string) {
+		assertSafe(t, string(Render(md)))
+	})
+}
+
+func FuzzSanitizeHTML(f *testing.F) {
+	for _, s := range xssSeeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, in string) {
+		assertSafe(t, SanitizeHTML(in))
+	})
+}
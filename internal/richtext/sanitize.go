@@ -0,0 +1,120 @@
+package richtext
+
+import (
+	"html"
+	"regexp"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// allowed es la lista blanca de etiquetas; ninguna admite atributos salvo href en <a>
+var allowed = map[atom.Atom]bool{
+	atom.P: true, atom.Br: true, atom.Strong: true, atom.Em: true, atom.Code: true,
+	atom.Ul: true, atom.Ol: true, atom.Li: true, atom.Blockquote: true, atom.A: true,
+}
+
+// dropped son las etiquetas cuyo contenido tampoco se muestra
+var dropped = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Iframe: true, atom.Object: true,
+	atom.Embed: true, atom.Template: true, atom.Noscript: true, atom.Textarea: true,
+	atom.Title: true, atom.Svg: true, atom.Math: true, atom.Select: true,
+}
+
+// SafeURL admite enlaces http(s), mailto y rutas relativas. Cualquier
+// carácter de control o espacio la invalida: los navegadores los ignoran y
+// "java\tscript:" acabaría ejecutándose.
+func SafeURL(u string) bool {
+	if u == "" || strings.IndexFunc(u, func(r rune) bool { return r <= 0x20 || r == 0x7f }) >= 0 {
+		return false
+	}
+	colon := strings.IndexByte(u, ':')
+	if colon < 0 || strings.ContainsAny(u[:colon], "/?#") {
+		return true // relativa: no tiene esquema
+	}
+	switch strings.ToLower(u[:colon]) {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+// SanitizeHTML deja sólo las etiquetas de la lista blanca, sin atributos
+// (salvo un href seguro en los enlaces), escapa todo el texto y cierra las
+// etiquetas que queden abiertas
+func SanitizeHTML(s string) string {
+	z := xhtml.NewTokenizer(strings.NewReader(s))
+	var b strings.Builder
+	var open []atom.Atom
+	skip := 0 // profundidad dentro de una etiqueta descartada con su contenido
+
+	for {
+		tt := z.Next()
+		if tt == xhtml.ErrorToken {
+			break // io.EOF o HTML imposible de seguir leyendo
+		}
+		tok := z.Token()
+		switch tt {
+		case xhtml.StartTagToken, xhtml.SelfClosingTagToken:
+			if dropped[tok.DataAtom] {
+				if tt == xhtml.StartTagToken {
+					skip++
+				}
+				continue
+			}
+			if skip > 0 || !allowed[tok.DataAtom] {
+				continue
+			}
+			if tok.DataAtom == atom.Br {
+				b.WriteString("<br>")
+				continue
+			}
+			b.WriteString("<" + tok.DataAtom.String())
+			if tok.DataAtom == atom.A {
+				for _, a := range tok.Attr {
+					if a.Namespace == "" && a.Key == "href" && SafeURL(a.Val) {
+						b.WriteString(` href="` + html.EscapeString(a.Val) + `"`)
+						break
+					}
+				}
+				b.WriteString(` rel="nofollow noopener noreferrer" target="_blank"`)
+			}
+			b.WriteString(">")
+			open = append(open, tok.DataAtom)
+		case xhtml.EndTagToken:
+			if dropped[tok.DataAtom] {
+				if skip > 0 {
+					skip--
+				}
+				continue
+			}
+			if skip > 0 || !allowed[tok.DataAtom] {
+				continue
+			}
+			// Se cierra sólo si está abierta, cerrando también las de dentro
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] == tok.DataAtom {
+					for j := len(open) - 1; j >= i; j-- {
+						b.WriteString("</" + open[j].String() + ">")
+					}
+					open = open[:i]
+					break
+				}
+			}
+		case xhtml.TextToken:
+			if skip == 0 {
+				b.WriteString(html.EscapeString(tok.Data))
+			}
+		}
+	}
+	for i := len(open) - 1; i >= 0; i-- {
+		b.WriteString("</" + open[i].String() + ">")
+	}
+	return b.String()
+}
+
+var reTag = regexp.MustCompile(`<[^>]*>`)
+
+// stripTags quita las etiquetas del HTML generado por inline
+func stripTags(s string) string { return reTag.ReplaceAllString(s, "") }
@@ -0,0 +1,130 @@
+// Package sentence valida los datos opcionales de una frase: nota
+// gramatical, registro, ejemplos extra, transcripción IPA y audio.
+package sentence
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"english-at-lima-cms/internal/i18n"
+	"english-at-lima-cms/internal/models"
+)
+
+// Límites de los campos opcionales
+const (
+	MaxGrammarNote   = 500
+	MaxExamples      = 5
+	MinExampleLength = 5
+	MaxExampleLength = 300
+	MaxIPALength     = 200
+	MaxTranslation   = 500
+)
+
+// ipaSymbols son los signos de la IPA que no son letras ni diacríticos:
+// acento primario y secundario, largas, límite de sílaba y de grupo, enlace
+const ipaSymbols = "ˈˌːˑ.|‖‿-"
+
+// ValidRegister indica si el registro existe ("" = neutro)
+func ValidRegister(r string) bool {
+	return r == "" || r == models.RegisterFormal || r == models.RegisterInformal
+}
+
+// Normalize recorta los campos y deja la IPA entre barras si no trae delimitadores
+func Normalize(s *models.Sentence) {
+	s.GrammarNote = strings.TrimSpace(s.GrammarNote)
+	s.Register = strings.ToLower(strings.TrimSpace(s.Register))
+	s.IPA = NormalizeIPA(s.IPA)
+	s.AudioURL = strings.TrimSpace(s.AudioURL)
+	s.Translations = i18n.Clean(s.Translations)
+
+	examples := []string{}
+	for _, e := range s.Examples {
+		if e = strings.Join(strings.Fields(e), " "); e != "" {
+			examples = append(examples, e)
+		}
+	}
+	s.Examples = examples
+}
+
+// NormalizeIPA colapsa espacios y envuelve la transcripción en /…/ cuando
+// el profesor la escribe sin barras ni corchetes
+func NormalizeIPA(raw string) string {
+	ipa := strings.Join(strings.Fields(raw), " ")
+	if ipa == "" {
+		return ""
+	}
+	if !strings.HasPrefix(ipa, "/") && !strings.HasPrefix(ipa, "[") {
+		ipa = "/" + ipa + "/"
+	}
+	return ipa
+}
+
+// Validate comprueba los campos opcionales y las traducciones; inglés y
+// español se validan aparte
+func Validate(s models.Sentence) error {
+	if len(s.GrammarNote) > MaxGrammarNote {
+		return fmt.Errorf("la nota gramatical excede el límite de %d caracteres", MaxGrammarNote)
+	}
+	if err := i18n.CheckLength(s.Translations, MaxTranslation); err != nil {
+		return err
+	}
+	if !ValidRegister(s.Register) {
+		return fmt.Errorf("el registro debe ser formal o informal")
+	}
+	if err := validateExamples(s); err != nil {
+		return err
+	}
+	if err := ValidateIPA(s.IPA); err != nil {
+		return err
+	}
+	if s.AudioURL != "" {
+		u, err := url.Parse(s.AudioURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("el audio debe ser una URL http(s)")
+		}
+	}
+	return nil
+}
+
+func validateExamples(s models.Sentence) error {
+	if len(s.Examples) > MaxExamples {
+		return fmt.Errorf("se admiten como máximo %d ejemplos", MaxExamples)
+	}
+	seen := map[string]bool{strings.ToLower(s.English): true}
+	for _, e := range s.Examples {
+		if len(e) < MinExampleLength || len(e) > MaxExampleLength {
+			return fmt.Errorf("cada ejemplo debe tener entre %d y %d caracteres", MinExampleLength, MaxExampleLength)
+		}
+		key := strings.ToLower(e)
+		if seen[key] {
+			return fmt.Errorf("el ejemplo '%s' está repetido", e)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// ValidateIPA acepta /fonémica/ o [fonética] con letras (incluidos los
+// símbolos IPA), diacríticos y los signos de ipaSymbols; nada de dígitos
+// ni marcado
+func ValidateIPA(ipa string) error {
+	if ipa == "" {
+		return nil
+	}
+	if len(ipa) > MaxIPALength {
+		return fmt.Errorf("la transcripción IPA excede el límite de %d caracteres", MaxIPALength)
+	}
+	open, end := ipa[0], ipa[len(ipa)-1]
+	if len(ipa) < 3 || !(open == '/' && end == '/' || open == '[' && end == ']') {
+		return fmt.Errorf("la transcripción IPA debe ir entre /barras/ o [corchetes]")
+	}
+	for _, r := range ipa[1 : len(ipa)-1] {
+		if unicode.IsLetter(r) || unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Lm, r) || r == ' ' || strings.ContainsRune(ipaSymbols, r) {
+			continue
+		}
+		return fmt.Errorf("la transcripción IPA contiene un símbolo no válido: %q", r)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package sentence
+
+import (
+	"english-at-lima-cms/internal/models"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	base := models.Sentence{English: "How are you?", Spanish: "¿Cómo estás?"}
+	with := func(f func(*models.Sentence)) models.Sentence {
+		s := base
+		f(&s)
+		return s
+	}
+	tests := []struct {
+		name    string
+		s       models.Sentence
+		wantErr bool
+	}{
+		{"Sin datos opcionales", base, false},
+		{"Completa", with(func(s *models.Sentence) {
+			s.GrammarNote = "Saludo habitual"
+			s.Register = "Informal"
+			s.Examples = []string{"How are you doing?", "How are you today?"}
+			s.IPA = "haʊ ɑːr juː"
+			s.AudioURL = "https://cdn.example.com/how.mp3"
+		}), false},
+		{"Nota demasiado larga", with(func(s *models.Sentence) { s.GrammarNote = strings.Repeat("a", 501) }), true},
+		{"Registro inventado", with(func(s *models.Sentence) { s.Register = "slang" }), true},
+		{"Seis ejemplos", with(func(s *models.Sentence) {
+			s.Examples = []string{"one one", "two two", "three three", "four four", "five five", "six six"}
+		}), true},
+		{"Ejemplo corto", with(func(s *models.Sentence) { s.Examples = []string{"Hi"} }), true},
+		{"Ejemplo repetido", with(func(s *models.Sentence) { s.Examples = []string{"How are you doing?", "how are you doing?"} }), true},
+		{"Ejemplo igual a la frase", with(func(s *models.Sentence) { s.Examples = []string{"how are you?"} }), true},
+		{"IPA fonética", with(func(s *models.Sentence) { s.IPA = "[ˈwɔːtə]" }), false},
+		{"IPA con dígitos", with(func(s *models.Sentence) { s.IPA = "/h4ʊ/" }), true},
+		{"IPA con marcado", with(func(s *models.Sentence) { s.IPA = "/<b>haʊ</b>/" }), true},
+		{"IPA mal cerrada", with(func(s *models.Sentence) { s.IPA = "[haʊ/" }), true},
+		{"Audio sin esquema", with(func(s *models.Sentence) { s.AudioURL = "cdn.example.com/how.mp3" }), true},
+		{"Traducción demasiado larga", with(func(s *models.Sentence) { s.Translations = map[string]string{"pt": strings.Repeat("a", 501)} }), true},
+		{"Audio javascript", with(func(s *models.Sentence) { s.AudioURL = "javascript:alert(1)" }), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tt.s
+			Normalize(&s)
+			if err := Validate(s); (err != nil) != tt.wantErr {
+				t.Errorf("error esperado %v, obtenido %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	s := models.Sentence{
+		GrammarNote: "  Nota ",
+		Register:    " FORMAL ",
+		Examples:    []string{"", "  Good   morning, sir ", "   "},
+		IPA:         "  ɡʊd   ˈmɔːnɪŋ ",
+	}
+	Normalize(&s)
+	if s.GrammarNote != "Nota" || s.Register != "formal" || s.IPA != "/ɡʊd ˈmɔːnɪŋ/" {
+		t.Errorf("campos mal normalizados: %+v", s)
+	}
+	if len(s.Examples) != 1 || s.Examples[0] != "Good morning, sir" {
+		t.Errorf("ejemplos mal normalizados: %q", s.Examples)
+	}
+
+	if got := NormalizeIPA("[ˈwɔːtə]"); got != "[ˈwɔːtə]" {
+		t.Errorf("no debe tocar los corchetes: %q", got)
+	}
+}
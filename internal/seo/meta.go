@@ -0,0 +1,144 @@
+package seo
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"english-at-lima-cms/internal/models"
+	"english-at-lima-cms/internal/richtext"
+)
+
+const defaultSiteURL = "https://english-at-lima-cms-go-gin-htmx-supabase.onrender.com"
+
+// Meta reúne todo lo que necesita el <head> de una página pública
+type Meta struct {
+	Title       string
+	Description string
+	Canonical   string
+	OGType      string
+	Image       string
+	JSONLD      template.JS
+}
+
+// SiteURL devuelve la URL pública del sitio (SITE_URL) sin barra final
+func SiteURL() string {
+	if u := strings.TrimSpace(os.Getenv("SITE_URL")); u != "" {
+		return strings.TrimRight(u, "/")
+	}
+	return defaultSiteURL
+}
+
+// Rutas canónicas de cada tipo de contenido
+func SentencePath(id int) string { return fmt.Sprintf("/frases/%d", id) }
+func QuizPath(id int) string     { return fmt.Sprintf("/quizzes/%d", id) }
+func ResourcePath(id int) string { return fmt.Sprintf("/recursos/%d", id) }
+func CoursePath(id int) string   { return fmt.Sprintf("/cursos/%d", id) }
+
+func SentenceMeta(s models.Sentence) Meta {
+	canonical := SiteURL() + SentencePath(s.ID)
+	return Meta{
+		Title:       truncate(s.English, 60) + " | English At Lima",
+		Description: truncate(fmt.Sprintf("«%s» en español: %s", s.English, s.Spanish), 160),
+		Canonical:   canonical,
+		OGType:      "article",
+		Image:       SiteURL() + "/static/logo.webp",
+		JSONLD: toJSONLD(map[string]interface{}{
+			"@context":             "https://schema.org",
+			"@type":                "LearningResource",
+			"name":                 s.English,
+			"description":          s.Spanish,
+			"url":                  canonical,
+			"inLanguage":           "en",
+			"learningResourceType": "Phrase",
+			"educationalLevel":     "Beginner",
+			"isAccessibleForFree":  true,
+		}),
+	}
+}
+
+func QuizMeta(q models.Quiz) Meta {
+	canonical := SiteURL() + QuizPath(q.ID)
+	question := map[string]interface{}{
+		"@type":           "Question",
+		"name":            q.Question,
+		"eduQuestionType": "Multiple choice",
+		"acceptedAnswer": map[string]interface{}{
+			"@type": "Answer",
+			"text":  q.CorrectLabel(),
+		},
+	}
+	return Meta{
+		Title:       truncate(q.Question, 60) + " | Quiz de Inglés",
+		Description: truncate("Pon a prueba tu inglés: "+q.Question, 160),
+		Canonical:   canonical,
+		OGType:      "article",
+		Image:       SiteURL() + "/static/logo.webp",
+		JSONLD: toJSONLD(map[string]interface{}{
+			"@context":            "https://schema.org",
+			"@type":               "Quiz",
+			"name":                q.Question,
+			"url":                 canonical,
+			"inLanguage":          "en",
+			"isAccessibleForFree": true,
+			"hasPart":             []interface{}{question},
+		}),
+	}
+}
+
+func ResourceMeta(r models.Resource) Meta {
+	canonical := SiteURL() + ResourcePath(r.ID)
+	description := fmt.Sprintf("Material de inglés (%s): %s", r.Type, r.Title)
+	if plain := richtext.Plain(r.Description); plain != "" {
+		description = plain
+	}
+	image := SiteURL() + "/static/logo.webp"
+	if r.ThumbnailURL != "" {
+		image = r.ThumbnailURL
+	}
+	ld := map[string]interface{}{
+		"@context":             "https://schema.org",
+		"@type":                "LearningResource",
+		"name":                 r.Title,
+		"description":          description,
+		"url":                  canonical,
+		"sameAs":               r.URL,
+		"image":                image,
+		"learningResourceType": r.Type,
+		"inLanguage":           "en",
+		"isAccessibleForFree":  true,
+	}
+	if r.Duration > 0 {
+		ld["timeRequired"] = fmt.Sprintf("PT%dS", r.Duration)
+	}
+	return Meta{
+		Title:       truncate(r.Title, 60) + " | Recursos de Inglés",
+		Description: truncate(description, 160),
+		Canonical:   canonical,
+		OGType:      "article",
+		Image:       image,
+		JSONLD:      toJSONLD(ld),
+	}
+}
+
+// toJSONLD serializa el bloque para <script type="application/ld+json">.
+// encoding/json ya escapa <, > y & así que no se puede cerrar la etiqueta.
+func toJSONLD(v interface{}) template.JS {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return template.JS(raw)
+}
+
+func truncate(s string, max int) string {
+	s = strings.TrimSpace(s)
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	runes := []rune(s)
+	return strings.TrimSpace(string(runes[:max-1])) + "…"
+}
@@ -0,0 +1,123 @@
+package seo
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// MaxURLsPerSitemap es el límite del protocolo sitemaps.org por archivo
+const MaxURLsPerSitemap = 50000
+
+const sitemapNS = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// Entry es una URL pública con su fecha de última modificación
+type Entry struct {
+	Loc     string
+	LastMod time.Time
+}
+
+type urlSet struct {
+	XMLName xml.Name   `xml:"urlset"`
+	NS      string     `xml:"xmlns,attr"`
+	URLs    []urlEntry `xml:"url"`
+}
+
+type urlEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name   `xml:"sitemapindex"`
+	NS       string     `xml:"xmlns,attr"`
+	Sitemaps []urlEntry `xml:"sitemap"`
+}
+
+// PageCount devuelve cuántos archivos sitemap hacen falta para total URLs
+func PageCount(total int) int {
+	if total <= 0 {
+		return 1
+	}
+	return (total + MaxURLsPerSitemap - 1) / MaxURLsPerSitemap
+}
+
+// Page devuelve el tramo de entradas del sitemap número page (empieza en 1)
+func Page(entries []Entry, page int) ([]Entry, error) {
+	if page < 1 || page > PageCount(len(entries)) {
+		return nil, fmt.Errorf("sitemap %d no existe", page)
+	}
+	start := (page - 1) * MaxURLsPerSitemap
+	end := start + MaxURLsPerSitemap
+	if end > len(entries) {
+		end = len(entries)
+	}
+	return entries[start:end], nil
+}
+
+// WriteURLSet escribe un <urlset> con las entradas indicadas
+func WriteURLSet(w io.Writer, entries []Entry) error {
+	set := urlSet{NS: sitemapNS}
+	for _, e := range entries {
+		set.URLs = append(set.URLs, urlEntry{Loc: e.Loc, LastMod: formatLastMod(e.LastMod)})
+	}
+	return writeXML(w, set)
+}
+
+// WriteIndex escribe un <sitemapindex> que apunta a baseURL/sitemap/N.xml.
+// El lastmod de cada archivo es el más reciente de sus entradas.
+func WriteIndex(w io.Writer, baseURL string, entries []Entry) error {
+	index := sitemapIndex{NS: sitemapNS}
+	for page := 1; page <= PageCount(len(entries)); page++ {
+		chunk, _ := Page(entries, page)
+		var newest time.Time
+		for _, e := range chunk {
+			if e.LastMod.After(newest) {
+				newest = e.LastMod
+			}
+		}
+		index.Sitemaps = append(index.Sitemaps, urlEntry{
+			Loc:     fmt.Sprintf("%s/sitemap/%d.xml", strings.TrimRight(baseURL, "/"), page),
+			LastMod: formatLastMod(newest),
+		})
+	}
+	return writeXML(w, index)
+}
+
+// WriteRobots genera el robots.txt: todo indexable salvo el panel, el login
+// y la salida a los recursos (cada visita cuenta como recurso abierto)
+func WriteRobots(w io.Writer, baseURL string) error {
+	_, err := fmt.Fprintf(w, "User-agent: *\nAllow: /\nDisallow: /admin\nDisallow: /login\nDisallow: /logout\nDisallow: /recursos/*/abrir\n\nSitemap: %s/sitemap.xml\n",
+		strings.TrimRight(baseURL, "/"))
+	return err
+}
+
+// ParseTimestamp interpreta el updated_at que devuelve Supabase (timestamptz)
+func ParseTimestamp(value string) time.Time {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func formatLastMod(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format("2006-01-02")
+}
+
+func writeXML(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	return enc.Flush()
+}
@@ -0,0 +1,63 @@
+package seo
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"english-at-lima-cms/internal/models"
+)
+
+func TestSitemapSplit(t *testing.T) {
+	entries := make([]Entry, MaxURLsPerSitemap+10)
+	for i := range entries {
+		entries[i] = Entry{Loc: fmt.Sprintf("https://lima.test/frases/%d", i+1)}
+	}
+	entries[MaxURLsPerSitemap+5].LastMod = time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	if got := PageCount(len(entries)); got != 2 {
+		t.Fatalf("esperaba 2 sitemaps, obtuve %d", got)
+	}
+
+	second, err := Page(entries, 2)
+	if err != nil || len(second) != 10 {
+		t.Fatalf("el segundo sitemap debería tener 10 URLs: %d (%v)", len(second), err)
+	}
+	if _, err := Page(entries, 3); err == nil {
+		t.Error("❌ Se aceptó un número de sitemap inexistente")
+	}
+
+	var buf bytes.Buffer
+	if err := WriteIndex(&buf, "https://lima.test/", entries); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{"<sitemapindex", "https://lima.test/sitemap/1.xml", "https://lima.test/sitemap/2.xml", "<lastmod>2025-03-01</lastmod>"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("el índice no contiene %q", want)
+		}
+	}
+}
+
+func TestRobotsBlocksAdmin(t *testing.T) {
+	var buf bytes.Buffer
+	_ = WriteRobots(&buf, "https://lima.test")
+	if !strings.Contains(buf.String(), "Disallow: /admin") {
+		t.Error("❌ robots.txt no protege el panel de administración")
+	}
+	if !strings.Contains(buf.String(), "Disallow: /recursos/*/abrir") {
+		t.Error("❌ robots.txt deja que los crawlers cuenten como recursos abiertos")
+	}
+	if !strings.Contains(buf.String(), "Sitemap: https://lima.test/sitemap.xml") {
+		t.Error("❌ robots.txt no anuncia el sitemap")
+	}
+}
+
+func TestJSONLDCannotCloseScript(t *testing.T) {
+	q := models.Quiz{ID: 7, Question: "</script><script>alert(1)</script>", Type: models.QuizSingle, Options: []string{"x", "y"}, Answers: []string{"x"}}
+	if strings.Contains(string(QuizMeta(q).JSONLD), "</script>") {
+		t.Error("❌ El JSON-LD permite cerrar la etiqueta <script>")
+	}
+}
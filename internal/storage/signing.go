@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+// signingKey usa MEDIA_SIGNING_KEY y, si no existe, el secreto de sesión
+func signingKey() []byte {
+	if k := os.Getenv("MEDIA_SIGNING_KEY"); k != "" {
+		return []byte(k)
+	}
+	return []byte(os.Getenv("SESSION_SECRET"))
+}
+
+func signature(key string, expires int64) string {
+	mac := hmac.New(sha256.New, signingKey())
+	fmt.Fprintf(mac, "%s\n%d", key, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignedQuery devuelve "exp=...&sig=..." para que la URL caduque pasado ttl
+func SignedQuery(key string, ttl time.Duration) string {
+	expires := time.Now().Add(ttl).Unix()
+	v := url.Values{}
+	v.Set("exp", strconv.FormatInt(expires, 10))
+	v.Set("sig", signature(key, expires))
+	return v.Encode()
+}
+
+// VerifySignature comprueba firma y caducidad de una URL de archivo privado
+func VerifySignature(key, exp, sig string) bool {
+	expires, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(signature(key, expires)))
+}
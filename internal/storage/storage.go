@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrNotFound se devuelve cuando la clave no existe en el almacenamiento
+var ErrNotFound = errors.New("archivo no encontrado")
+
+// PrivatePrefix marca los archivos que sólo se sirven con URL firmada
+const PrivatePrefix = "private/"
+
+// Object es un archivo abierto listo para http.ServeContent (soporta Range)
+type Object struct {
+	io.ReadSeekCloser
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+// Driver abstrae dónde viven los archivos subidos por los profesores
+type Driver interface {
+	Put(key string, r io.Reader, size int64, contentType string) error
+	Open(key string) (*Object, error)
+	Delete(key string) error
+}
+
+// New elige el driver según STORAGE_DRIVER ("local" por defecto o "supabase")
+func New() (Driver, error) {
+	switch strings.ToLower(os.Getenv("STORAGE_DRIVER")) {
+	case "", "local":
+		dir := os.Getenv("STORAGE_DIR")
+		if dir == "" {
+			dir = "./uploads"
+		}
+		return NewLocal(dir)
+	case "supabase":
+		bucket := os.Getenv("STORAGE_BUCKET")
+		if bucket == "" {
+			bucket = "media"
+		}
+		return NewSupabase(os.Getenv("SUPABASE_URL"), os.Getenv("SUPABASE_KEY"), bucket), nil
+	default:
+		return nil, fmt.Errorf("STORAGE_DRIVER desconocido: %s", os.Getenv("STORAGE_DRIVER"))
+	}
+}
+
+// validKey impide claves con ".." o rutas absolutas que escapen del bucket
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
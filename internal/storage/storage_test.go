@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+var fakePDF = append([]byte("%PDF-1.4\n"), bytes.Repeat([]byte("x"), 2000)...)
+
+func TestSpoolDetectsAndHashes(t *testing.T) {
+	up, err := Spool(bytes.NewReader(fakePDF))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer up.Close()
+
+	if up.ContentType != "application/pdf" || up.Rule.Kind != "pdf" {
+		t.Errorf("tipo detectado incorrecto: %s", up.ContentType)
+	}
+	if up.Size != int64(len(fakePDF)) || len(up.SHA256) != 64 {
+		t.Errorf("tamaño o hash incorrectos: %d %s", up.Size, up.SHA256)
+	}
+	if !strings.HasSuffix(up.Key(false), up.SHA256+".pdf") || !strings.HasPrefix(up.Key(true), PrivatePrefix) {
+		t.Errorf("clave inesperada: %s", up.Key(false))
+	}
+}
+
+func TestSpoolRejects(t *testing.T) {
+	t.Run("Ejecutable disfrazado", func(t *testing.T) {
+		if _, err := Spool(strings.NewReader("MZ\x90\x00 esto no es un pdf")); err == nil {
+			t.Error("❌ Se aceptó un tipo de archivo no permitido")
+		}
+	})
+	t.Run("Imagen gigante", func(t *testing.T) {
+		png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 6<<20)...)
+		if _, err := Spool(bytes.NewReader(png)); !errors.Is(err, ErrTooLarge) {
+			t.Errorf("❌ Se esperaba ErrTooLarge, obtuve %v", err)
+		}
+	})
+}
+
+func TestLocalDriver(t *testing.T) {
+	d, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Put("files/ab/test.pdf", bytes.NewReader(fakePDF), int64(len(fakePDF)), "application/pdf"); err != nil {
+		t.Fatal(err)
+	}
+	obj, err := d.Open("files/ab/test.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer obj.Close()
+	if obj.Size != int64(len(fakePDF)) {
+		t.Errorf("tamaño incorrecto: %d", obj.Size)
+	}
+	if _, err := d.Open("../../etc/passwd"); err == nil {
+		t.Error("❌ Path traversal permitido en el driver local")
+	}
+	if _, err := d.Open("files/no-existe.pdf"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("se esperaba ErrNotFound, obtuve %v", err)
+	}
+}
+
+func TestSupabaseDriverRange(t *testing.T) {
+	content := []byte("0123456789abcdefghij")
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/storage/v1/object/media/files/a.mp3" {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, "a.mp3", time.Now(), bytes.NewReader(content))
+	}))
+	defer stub.Close()
+
+	d := NewSupabase(stub.URL, "key", "media")
+	obj, err := d.Open("files/a.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer obj.Close()
+	if obj.Size != int64(len(content)) {
+		t.Fatalf("tamaño incorrecto: %d", obj.Size)
+	}
+
+	if _, err := obj.Seek(10, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	rest, _ := io.ReadAll(obj)
+	if string(rest) != "abcdefghij" {
+		t.Errorf("lectura por Range incorrecta: %q", rest)
+	}
+	if _, err := d.Open("files/otro.mp3"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("se esperaba ErrNotFound, obtuve %v", err)
+	}
+}
+
+func TestSignedURLs(t *testing.T) {
+	t.Setenv("MEDIA_SIGNING_KEY", "secreto-de-prueba")
+	key := "private/files/ab/x.pdf"
+
+	q := SignedQuery(key, time.Minute)
+	values := parseQuery(t, q)
+	if !VerifySignature(key, values.Get("exp"), values.Get("sig")) {
+		t.Error("❌ Una firma válida fue rechazada")
+	}
+	if VerifySignature("private/files/ab/otro.pdf", values.Get("exp"), values.Get("sig")) {
+		t.Error("❌ La firma sirve para otro archivo")
+	}
+
+	expired := parseQuery(t, SignedQuery(key, -time.Minute))
+	if VerifySignature(key, expired.Get("exp"), expired.Get("sig")) {
+		t.Error("❌ Se aceptó un enlace caducado")
+	}
+}
+
+func parseQuery(t *testing.T, q string) url.Values {
+	t.Helper()
+	v, err := url.ParseQuery(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Supabase habla con la API de Supabase Storage (/storage/v1/object). Las
+// lecturas se hacen con cabeceras Range, así que también sirve con cualquier
+// backend compatible con S3 que esté detrás del mismo endpoint.
+type Supabase struct {
+	baseURL string
+	apiKey  string
+	bucket  string
+	client  *http.Client
+}
+
+func NewSupabase(baseURL, apiKey, bucket string) *Supabase {
+	return &Supabase{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		bucket:  bucket,
+		client:  &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+func (s *Supabase) objectURL(key string) (string, error) {
+	if !validKey(key) {
+		return "", fmt.Errorf("clave inválida: %q", key)
+	}
+	return fmt.Sprintf("%s/storage/v1/object/%s/%s", s.baseURL, s.bucket, key), nil
+}
+
+func (s *Supabase) do(method, key string, body io.Reader, setup func(*http.Request)) (*http.Response, error) {
+	url, err := s.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("apikey", s.apiKey)
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	if setup != nil {
+		setup(req)
+	}
+	return s.client.Do(req)
+}
+
+func (s *Supabase) Put(key string, r io.Reader, size int64, contentType string) error {
+	resp, err := s.do("POST", key, r, func(req *http.Request) {
+		req.ContentLength = size
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("x-upsert", "true")
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("error storage: %d - %s", resp.StatusCode, resp.Status)
+	}
+	return nil
+}
+
+// Open pide el primer byte para conocer tamaño y tipo sin descargar el archivo
+func (s *Supabase) Open(key string) (*Object, error) {
+	resp, err := s.do("GET", key, nil, func(req *http.Request) {
+		req.Header.Set("Range", "bytes=0-0")
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusBadRequest:
+		return nil, ErrNotFound
+	case resp.StatusCode >= 400:
+		return nil, fmt.Errorf("error storage: %d - %s", resp.StatusCode, resp.Status)
+	}
+
+	size, err := totalSize(resp)
+	if err != nil {
+		return nil, err
+	}
+	modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return &Object{
+		ReadSeekCloser: &rangeReader{store: s, key: key, size: size},
+		Size:           size,
+		ContentType:    resp.Header.Get("Content-Type"),
+		ModTime:        modTime,
+	}, nil
+}
+
+func (s *Supabase) Delete(key string) error {
+	resp, err := s.do("DELETE", key, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("error storage: %d - %s", resp.StatusCode, resp.Status)
+	}
+	return nil
+}
+
+// totalSize lee el total de "Content-Range: bytes 0-0/12345" (o Content-Length
+// si el servidor ignoró el Range y devolvió el archivo entero)
+func totalSize(resp *http.Response) (int64, error) {
+	if cr := resp.Header.Get("Content-Range"); cr != "" {
+		if i := strings.LastIndex(cr, "/"); i >= 0 {
+			return strconv.ParseInt(cr[i+1:], 10, 64)
+		}
+	}
+	if resp.ContentLength >= 0 {
+		return resp.ContentLength, nil
+	}
+	return 0, errors.New("el storage no informó el tamaño del archivo")
+}
+
+// rangeReader implementa io.ReadSeeker pidiendo sólo el tramo que el
+// navegador necesita. Mientras las lecturas sean secuenciales reutiliza la
+// misma respuesta HTTP; un Seek la cierra y la siguiente lectura abre otra.
+type rangeReader struct {
+	store  *Supabase
+	key    string
+	size   int64
+	offset int64
+	body   io.ReadCloser
+}
+
+func (r *rangeReader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+	if r.body == nil {
+		resp, err := r.store.do("GET", r.key, nil, func(req *http.Request) {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", r.offset))
+		})
+		if err != nil {
+			return 0, err
+		}
+		if resp.StatusCode != http.StatusPartialContent && !(resp.StatusCode == http.StatusOK && r.offset == 0) {
+			resp.Body.Close()
+			return 0, fmt.Errorf("error storage: %d - %s", resp.StatusCode, resp.Status)
+		}
+		r.body = resp.Body
+	}
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+	return n, err
+}
+
+func (r *rangeReader) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = r.offset + offset
+	case io.SeekEnd:
+		abs = r.size + offset
+	default:
+		return 0, errors.New("whence inválido")
+	}
+	if abs < 0 {
+		return 0, errors.New("posición negativa")
+	}
+	if abs != r.offset {
+		r.closeBody()
+		r.offset = abs
+	}
+	return abs, nil
+}
+
+func (r *rangeReader) Close() error {
+	r.closeBody()
+	return nil
+}
+
+func (r *rangeReader) closeBody() {
+	if r.body != nil {
+		r.body.Close()
+		r.body = nil
+	}
+}
@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Rule define qué tipos aceptamos y cuánto pueden pesar
+type Rule struct {
+	Ext      string
+	Kind     string // "pdf", "audio", "video" o "image" (igual que Resource.Type)
+	MaxBytes int64
+}
+
+// Rules se indexa por el Content-Type detectado a partir de los bytes reales,
+// nunca por la extensión o el Content-Type que manda el navegador
+var Rules = map[string]Rule{
+	"application/pdf": {".pdf", "pdf", 20 << 20},
+	"audio/mpeg":      {".mp3", "audio", 30 << 20},
+	"audio/wave":      {".wav", "audio", 30 << 20},
+	"audio/ogg":       {".ogg", "audio", 30 << 20},
+	"audio/mp4":       {".m4a", "audio", 30 << 20},
+	"video/mp4":       {".mp4", "video", 100 << 20},
+	"video/webm":      {".webm", "video", 100 << 20},
+	"image/png":       {".png", "image", 5 << 20},
+	"image/jpeg":      {".jpg", "image", 5 << 20},
+	"image/webp":      {".webp", "image", 5 << 20},
+}
+
+// MaxUploadBytes es el mayor límite por tipo más margen para los campos del
+// formulario; AllowUploads lo usa en las rutas de subida del panel
+func MaxUploadBytes() int64 {
+	var max int64
+	for _, r := range Rules {
+		if r.MaxBytes > max {
+			max = r.MaxBytes
+		}
+	}
+	return max + 1<<20
+}
+
+// ErrTooLarge indica que el archivo supera el límite de su tipo
+var ErrTooLarge = errors.New("el archivo supera el tamaño permitido para su tipo")
+
+// Upload es un archivo ya volcado a disco, hasheado y clasificado
+type Upload struct {
+	File        *os.File
+	SHA256      string
+	Size        int64
+	ContentType string
+	Rule        Rule
+}
+
+// Key es el nombre definitivo en el storage: el hash garantiza que el mismo
+// archivo subido dos veces ocupe un solo lugar
+func (u *Upload) Key(private bool) string {
+	key := "files/" + u.SHA256[:2] + "/" + u.SHA256 + u.Rule.Ext
+	if private {
+		return PrivatePrefix + key
+	}
+	return key
+}
+
+// Close elimina el temporal
+func (u *Upload) Close() error {
+	u.File.Close()
+	return os.Remove(u.File.Name())
+}
+
+// Spool copia el archivo a un temporal calculando el SHA-256 y detectando el
+// tipo real. Corta la copia en cuanto se pasa del límite de ese tipo.
+func Spool(r io.Reader) (*Upload, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("no se pudo leer el archivo: %w", err)
+	}
+	head = head[:n]
+
+	contentType := Sniff(head)
+	rule, ok := Rules[contentType]
+	if !ok {
+		return nil, fmt.Errorf("tipo de archivo no permitido: %s", contentType)
+	}
+
+	tmp, err := os.CreateTemp("", "eal-upload-*")
+	if err != nil {
+		return nil, err
+	}
+	up := &Upload{File: tmp, ContentType: contentType, Rule: rule}
+
+	hash := sha256.New()
+	limited := io.LimitReader(io.MultiReader(bytes.NewReader(head), r), rule.MaxBytes+1)
+	size, err := io.Copy(io.MultiWriter(tmp, hash), limited)
+	if err != nil {
+		up.Close()
+		return nil, err
+	}
+	if size > rule.MaxBytes {
+		up.Close()
+		return nil, ErrTooLarge
+	}
+
+	up.Size = size
+	up.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		up.Close()
+		return nil, err
+	}
+	return up, nil
+}
+
+// Sniff amplía http.DetectContentType con formatos de audio que no reconoce
+func Sniff(head []byte) string {
+	if len(head) >= 12 && string(head[4:8]) == "ftyp" {
+		brand := string(head[8:12])
+		if strings.HasPrefix(brand, "M4A") {
+			return "audio/mp4"
+		}
+		return "video/mp4"
+	}
+	// MP3 sin etiqueta ID3: empieza directamente con una cabecera de frame
+	if len(head) >= 2 && head[0] == 0xFF && head[1]&0xE0 == 0xE0 {
+		return "audio/mpeg"
+	}
+	ct := http.DetectContentType(head)
+	if i := strings.Index(ct, ";"); i >= 0 {
+		ct = ct[:i]
+	}
+	if ct == "application/ogg" {
+		return "audio/ogg"
+	}
+	return ct
+}
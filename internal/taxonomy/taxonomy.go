@@ -0,0 +1,113 @@
+// Package taxonomy reúne las etiquetas libres y los niveles MCER (A1–C2)
+// que comparten frases, quizzes y recursos.
+package taxonomy
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+// Levels son los niveles del Marco Común Europeo, de menor a mayor
+var Levels = []string{"A1", "A2", "B1", "B2", "C1", "C2"}
+
+const (
+	MaxTags      = 10
+	MaxTagLength = 30
+)
+
+// LevelOption es una opción del <select> de niveles
+type LevelOption struct {
+	Code     string
+	Selected bool
+}
+
+// LevelOptions lista A1–C2 marcando el nivel actual
+func LevelOptions(current string) []LevelOption {
+	opts := make([]LevelOption, len(Levels))
+	for i, l := range Levels {
+		opts[i] = LevelOption{Code: l, Selected: l == current}
+	}
+	return opts
+}
+
+// ValidLevel indica si el nivel es uno de los seis del MCER
+func ValidLevel(level string) bool {
+	for _, l := range Levels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeLevel acepta "a2" o " A2 " y devuelve "A2"
+func NormalizeLevel(level string) string {
+	return strings.ToUpper(strings.TrimSpace(level))
+}
+
+// NormalizeTag pasa la etiqueta a minúsculas, deja sólo letras, números,
+// espacios y guiones y colapsa los espacios repetidos. Así "Restaurant  "
+// y "restaurant" son la misma etiqueta y el valor es seguro en un filtro.
+func NormalizeTag(tag string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(strings.TrimSpace(tag)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-':
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		case unicode.IsSpace(r) || r == '_':
+			space = true
+		}
+	}
+	out := []rune(b.String())
+	if len(out) > MaxTagLength {
+		out = out[:MaxTagLength]
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// ParseTags separa el campo del formulario por comas, normaliza cada
+// etiqueta y descarta vacías y repetidas conservando el orden
+func ParseTags(raw string) []string {
+	tags := []string{}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(raw, ",") {
+		tag := NormalizeTag(part)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// Validate exige un nivel válido y un máximo de MaxTags etiquetas
+func Validate(level string, tags []string) error {
+	if !ValidLevel(level) {
+		return fmt.Errorf("debe elegir un nivel MCER (A1–C2)")
+	}
+	if len(tags) > MaxTags {
+		return fmt.Errorf("máximo %d etiquetas por elemento", MaxTags)
+	}
+	return nil
+}
+
+// Filter arma el filtro PostgREST por etiqueta y nivel ("" si no hay
+// ninguno). La etiqueta se normaliza antes, así nunca trae comillas ni llaves.
+func Filter(tag, level string) string {
+	var parts []string
+	if t := NormalizeTag(tag); t != "" {
+		parts = append(parts, "tags=cs."+url.PathEscape(`{"`+t+`"}`))
+	}
+	if l := NormalizeLevel(level); ValidLevel(l) {
+		parts = append(parts, "level=eq."+l)
+	}
+	return strings.Join(parts, "&")
+}
@@ -0,0 +1,84 @@
+package taxonomy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Restaurant", "restaurant"},
+		{"  phrasal   Verbs ", "phrasal verbs"},
+		{"small_talk", "small talk"},
+		{"Año-Nuevo", "año-nuevo"},
+		{`x"},{"y`, "xy"},
+		{"<script>", "script"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeTag(tt.in); got != tt.want {
+			t.Errorf("NormalizeTag(%q) = %q, esperaba %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	got := ParseTags("Restaurant, food ,restaurant,, viajes")
+	want := []string{"restaurant", "food", "viajes"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseTags = %v, esperaba %v", got, want)
+	}
+	if tags := ParseTags(""); tags == nil || len(tags) != 0 {
+		t.Errorf("sin etiquetas debe devolver un slice vacío (no nil) para guardar '{}': %#v", tags)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		level   string
+		tags    []string
+		wantErr bool
+	}{
+		{"Nivel válido", "A2", []string{"restaurant"}, false},
+		{"Sin nivel", "", nil, true},
+		{"Nivel inventado", "D1", nil, true},
+		{"Demasiadas etiquetas", "B1", make([]string, MaxTags+1), true},
+	}
+	for _, tt := range tests {
+		if err := Validate(tt.level, tt.tags); (err != nil) != tt.wantErr {
+			t.Errorf("%s: error esperado %v, obtenido %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		tag, level, want string
+	}{
+		{"", "", ""},
+		{"Restaurant", "a2", "tags=cs.%7B%22restaurant%22%7D&level=eq.A2"},
+		{"", "B1", "level=eq.B1"},
+		{"", "Z9", ""},
+		{"small talk", "", "tags=cs.%7B%22small%20talk%22%7D"},
+	}
+	for _, tt := range tests {
+		if got := Filter(tt.tag, tt.level); got != tt.want {
+			t.Errorf("Filter(%q, %q) = %q, esperaba %q", tt.tag, tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestLevelOptions(t *testing.T) {
+	opts := LevelOptions("B2")
+	if len(opts) != len(Levels) || opts[0].Code != "A1" || opts[0].Selected || !opts[3].Selected {
+		t.Fatalf("opciones inesperadas: %+v", opts)
+	}
+	for _, o := range LevelOptions("") {
+		if o.Selected {
+			t.Errorf("sin nivel no debería marcarse %s", o.Code)
+		}
+	}
+}
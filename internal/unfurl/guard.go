@@ -0,0 +1,86 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrBlockedAddress se devuelve cuando la URL apunta a la red interna
+var ErrBlockedAddress = errors.New("dirección no permitida")
+
+// blockedPrefixes son los rangos a los que nunca debe conectarse el servidor:
+// red local, loopback, metadatos de la nube (169.254.169.254), CGNAT, etc.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.88.99.0/24"), // relés 6to4
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("224.0.0.0/4"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("::/128"),
+	netip.MustParsePrefix("::1/128"),
+	// NAT64, Teredo y 6to4 llevan una IPv4 dentro: 64:ff9b::a9fe:a9fe es 169.254.169.254
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("2001::/32"),
+	netip.MustParsePrefix("2002::/16"),
+	netip.MustParsePrefix("fc00::/7"),
+	netip.MustParsePrefix("fe80::/10"),
+	netip.MustParsePrefix("ff00::/8"),
+}
+
+// IsPublicIP indica si la IP es enrutable en Internet
+func IsPublicIP(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, p := range blockedPrefixes {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	return addr.IsValid()
+}
+
+// guardedDialer valida la IP en el momento de conectar (después de resolver
+// el DNS), así un dominio que resuelve a 127.0.0.1 tampoco pasa
+func guardedDialer(allow func(netip.Addr) bool) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	d := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip, err := netip.ParseAddr(host)
+			if err != nil {
+				return err
+			}
+			if !allow(ip) {
+				return fmt.Errorf("%w: %s", ErrBlockedAddress, ip)
+			}
+			return nil
+		},
+	}
+	return d.DialContext
+}
+
+// NewTransport crea un transporte HTTP que sólo se conecta a las IPs que
+// acepta allow; en producción se usa con IsPublicIP
+func NewTransport(allow func(netip.Addr) bool) *http.Transport {
+	return &http.Transport{
+		DialContext:         guardedDialer(allow),
+		TLSHandshakeTimeout: 5 * time.Second,
+		MaxIdleConns:        10,
+	}
+}
@@ -0,0 +1,305 @@
+package unfurl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// Metadata es lo que se extrae de la página para prellenar el recurso
+type Metadata struct {
+	Title        string
+	Description  string
+	ThumbnailURL string
+	Duration     int // segundos, 0 si no aplica
+	SiteName     string
+	Type         string // "video", "audio", "pdf" o "web"
+}
+
+// Fetcher descarga páginas de terceros con límites de tiempo, tamaño y red
+type Fetcher struct {
+	client   *http.Client
+	MaxBytes int64
+}
+
+// New crea un Fetcher que sólo se conecta a IPs públicas
+func New() *Fetcher {
+	return newFetcher(IsPublicIP)
+}
+
+func newFetcher(allow func(netip.Addr) bool) *Fetcher {
+	transport := NewTransport(allow)
+	transport.ResponseHeaderTimeout = 5 * time.Second
+	return &Fetcher{
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   8 * time.Second,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= 3 {
+					return errors.New("demasiadas redirecciones")
+				}
+				if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+					return errors.New("redirección a un esquema no permitido")
+				}
+				return nil
+			},
+		},
+		MaxBytes: 1 << 20,
+	}
+}
+
+// Fetch obtiene la metadata OpenGraph y, si la página lo anuncia, oEmbed
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (Metadata, error) {
+	var meta Metadata
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return meta, fmt.Errorf("URL debe ser válida y segura (http/https)")
+	}
+
+	resp, err := f.get(ctx, u.String(), "text/html,application/xhtml+xml")
+	if err != nil {
+		return meta, err
+	}
+	defer resp.Body.Close()
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if kind := kindFromContentType(mediaType); kind != "" {
+		// Enlace directo a un PDF o audio: no hay HTML que analizar
+		meta.Type = kind
+		meta.Title = titleFromPath(resp.Request.URL.Path)
+		meta.SiteName = resp.Request.URL.Hostname()
+		return meta, nil
+	}
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return meta, fmt.Errorf("tipo de contenido no soportado: %s", mediaType)
+	}
+
+	page := parseHTML(io.LimitReader(resp.Body, f.MaxBytes), resp.Request.URL)
+	meta = page.Metadata
+	if page.OEmbedURL != "" {
+		if oe, err := f.fetchOEmbed(ctx, page.OEmbedURL); err == nil {
+			meta = merge(meta, oe)
+		}
+	}
+	if meta.ThumbnailURL != "" {
+		meta.ThumbnailURL = resolve(resp.Request.URL, meta.ThumbnailURL)
+	}
+	if meta.SiteName == "" {
+		meta.SiteName = resp.Request.URL.Hostname()
+	}
+	if meta.Type == "" {
+		meta.Type = "web"
+	}
+	return meta, nil
+}
+
+func (f *Fetcher) get(ctx context.Context, target, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "EnglishAtLima-Unfurl/1.0")
+	req.Header.Set("Accept", accept)
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("la página respondió %d", resp.StatusCode)
+	}
+	return resp, nil
+}
+
+type oembed struct {
+	Title        string          `json:"title"`
+	Type         string          `json:"type"`
+	ProviderName string          `json:"provider_name"`
+	ThumbnailURL string          `json:"thumbnail_url"`
+	Description  string          `json:"description"`
+	Duration     json.RawMessage `json:"duration"`
+}
+
+func (f *Fetcher) fetchOEmbed(ctx context.Context, endpoint string) (Metadata, error) {
+	resp, err := f.get(ctx, endpoint, "application/json")
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer resp.Body.Close()
+
+	var oe oembed
+	if err := json.NewDecoder(io.LimitReader(resp.Body, f.MaxBytes)).Decode(&oe); err != nil {
+		return Metadata{}, err
+	}
+	meta := Metadata{
+		Title:        oe.Title,
+		Description:  oe.Description,
+		ThumbnailURL: oe.ThumbnailURL,
+		SiteName:     oe.ProviderName,
+		Duration:     parseSeconds(strings.Trim(string(oe.Duration), `"`)),
+	}
+	if oe.Type == "video" {
+		meta.Type = "video"
+	}
+	return meta, nil
+}
+
+// merge completa los huecos de OpenGraph con lo que aporte oEmbed
+func merge(og, oe Metadata) Metadata {
+	if og.Title == "" {
+		og.Title = oe.Title
+	}
+	if og.Description == "" {
+		og.Description = oe.Description
+	}
+	if og.ThumbnailURL == "" {
+		og.ThumbnailURL = oe.ThumbnailURL
+	}
+	if og.SiteName == "" {
+		og.SiteName = oe.SiteName
+	}
+	if og.Duration == 0 {
+		og.Duration = oe.Duration
+	}
+	if og.Type == "" {
+		og.Type = oe.Type
+	}
+	return og
+}
+
+type parsedPage struct {
+	Metadata
+	OEmbedURL string
+}
+
+// parseHTML recorre sólo el <head>: OpenGraph, <title>, description y el
+// <link rel="alternate" type="application/json+oembed">
+func parseHTML(r io.Reader, base *url.URL) parsedPage {
+	var page parsedPage
+	var fallbackTitle, fallbackDesc string
+	z := html.NewTokenizer(r)
+
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return finish(page, fallbackTitle, fallbackDesc)
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			switch tok.Data {
+			case "meta":
+				applyMeta(&page.Metadata, attrs(tok), &fallbackDesc)
+			case "link":
+				a := attrs(tok)
+				if strings.EqualFold(a["type"], "application/json+oembed") {
+					page.OEmbedURL = resolve(base, a["href"])
+				}
+			case "title":
+				if z.Next() == html.TextToken {
+					fallbackTitle = strings.TrimSpace(z.Token().Data)
+				}
+			case "body":
+				return finish(page, fallbackTitle, fallbackDesc)
+			}
+		}
+	}
+}
+
+func finish(page parsedPage, title, desc string) parsedPage {
+	if page.Title == "" {
+		page.Title = title
+	}
+	if page.Description == "" {
+		page.Description = desc
+	}
+	return page
+}
+
+func applyMeta(m *Metadata, a map[string]string, fallbackDesc *string) {
+	key := a["property"]
+	if key == "" {
+		key = a["name"]
+	}
+	content := strings.TrimSpace(a["content"])
+	switch strings.ToLower(key) {
+	case "og:title":
+		m.Title = content
+	case "og:description":
+		m.Description = content
+	case "og:image", "og:image:url", "og:image:secure_url":
+		if m.ThumbnailURL == "" {
+			m.ThumbnailURL = content
+		}
+	case "og:site_name":
+		m.SiteName = content
+	case "og:video:duration", "video:duration", "music:duration":
+		m.Duration = parseSeconds(content)
+	case "og:type":
+		switch {
+		case strings.HasPrefix(content, "video"):
+			m.Type = "video"
+		case strings.HasPrefix(content, "music"):
+			m.Type = "audio"
+		}
+	case "description":
+		*fallbackDesc = content
+	}
+}
+
+func attrs(tok html.Token) map[string]string {
+	a := make(map[string]string, len(tok.Attr))
+	for _, at := range tok.Attr {
+		a[strings.ToLower(at.Key)] = at.Val
+	}
+	return a
+}
+
+func resolve(base *url.URL, href string) string {
+	ref, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return ""
+	}
+	return base.ResolveReference(ref).String()
+}
+
+func parseSeconds(s string) int {
+	if n, err := strconv.Atoi(strings.TrimSpace(s)); err == nil && n > 0 {
+		return n
+	}
+	if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil && f > 0 {
+		return int(f)
+	}
+	return 0
+}
+
+func kindFromContentType(mediaType string) string {
+	switch {
+	case mediaType == "application/pdf":
+		return "pdf"
+	case strings.HasPrefix(mediaType, "audio/"):
+		return "audio"
+	case strings.HasPrefix(mediaType, "video/"):
+		return "video"
+	}
+	return ""
+}
+
+func titleFromPath(p string) string {
+	name := p[strings.LastIndex(p, "/")+1:]
+	if i := strings.LastIndex(name, "."); i > 0 {
+		name = name[:i]
+	}
+	name, _ = url.PathUnescape(name)
+	return strings.TrimSpace(strings.NewReplacer("-", " ", "_", " ").Replace(name))
+}
@@ -0,0 +1,105 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func allowAll(netip.Addr) bool { return true }
+
+func TestFetchOpenGraphAndOEmbed(t *testing.T) {
+	var srvURL string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/video", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(`<html><head>
+			<title>Fallback</title>
+			<meta property="og:title" content="Phrasal verbs in 5 minutes">
+			<meta property="og:image" content="/thumb.jpg">
+			<meta property="og:type" content="video.other">
+			<link rel="alternate" type="application/json+oembed" href="` + srvURL + `/oembed?url=x">
+		</head><body><title>no debe leerse</title></body></html>`))
+	})
+	mux.HandleFunc("/oembed", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"type":"video","title":"ignorado","provider_name":"Vimeo","duration":305,"description":"Aprende phrasal verbs"}`))
+	})
+	mux.HandleFunc("/guia.pdf", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		_, _ = w.Write([]byte("%PDF-1.4"))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	srvURL = srv.URL
+
+	f := newFetcher(allowAll)
+
+	meta, err := f.Fetch(context.Background(), srv.URL+"/video")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Title != "Phrasal verbs in 5 minutes" || meta.Type != "video" {
+		t.Errorf("OpenGraph mal leído: %+v", meta)
+	}
+	if meta.SiteName != "Vimeo" || meta.Duration != 305 || meta.Description != "Aprende phrasal verbs" {
+		t.Errorf("oEmbed no completó los huecos: %+v", meta)
+	}
+	if meta.ThumbnailURL != srv.URL+"/thumb.jpg" {
+		t.Errorf("miniatura relativa sin resolver: %s", meta.ThumbnailURL)
+	}
+
+	pdf, err := f.Fetch(context.Background(), srv.URL+"/guia.pdf")
+	if err != nil || pdf.Type != "pdf" || pdf.Title != "guia" {
+		t.Errorf("PDF directo mal clasificado: %+v (%v)", pdf, err)
+	}
+}
+
+func TestSizeLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<html><head>" + strings.Repeat("<meta name=x content=y>", 100000)))
+		_, _ = w.Write([]byte(`<meta property="og:title" content="demasiado lejos"></head>`))
+	}))
+	defer srv.Close()
+
+	f := newFetcher(allowAll)
+	f.MaxBytes = 64 << 10
+	meta, err := f.Fetch(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Title == "demasiado lejos" {
+		t.Error("❌ Se leyó más allá del límite de tamaño")
+	}
+}
+
+func TestSSRFGuard(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("❌ El servidor interno recibió la petición")
+	}))
+	defer srv.Close()
+
+	_, err := New().Fetch(context.Background(), srv.URL)
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("se esperaba ErrBlockedAddress, obtuve %v", err)
+	}
+
+	// Metadatos de la nube escondidos en una dirección NAT64
+	if _, err := New().Fetch(context.Background(), "http://[64:ff9b::a9fe:a9fe]/"); !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("NAT64: se esperaba ErrBlockedAddress, obtuve %v", err)
+	}
+
+	for _, ip := range []string{"127.0.0.1", "10.1.2.3", "169.254.169.254", "192.168.1.10", "::1", "fd00::1", "::ffff:127.0.0.1",
+		"64:ff9b::a9fe:a9fe", "64:ff9b:1::a00:1", "2002:7f00:1::", "2001:0:4136:e378:8000:63bf:3fff:fdd2", "192.88.99.1"} {
+		if IsPublicIP(netip.MustParseAddr(ip)) {
+			t.Errorf("❌ %s se consideró pública", ip)
+		}
+	}
+	if !IsPublicIP(netip.MustParseAddr("8.8.8.8")) {
+		t.Error("8.8.8.8 debería ser pública")
+	}
+}
@@ -0,0 +1,110 @@
+package xapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeLRS es un LRS mínimo en memoria: exige la versión de xAPI y basic auth,
+// guarda los statements por id y se le puede pedir que falle
+type fakeLRS struct {
+	srv *httptest.Server
+
+	mu         sync.Mutex
+	statements map[string]Statement
+	posts      int
+	down       bool // responde 503 a todo
+	failNext   int  // responde 503 a los próximos pedidos
+	reject     func(Statement) bool
+}
+
+const (
+	lrsUser     = "eal"
+	lrsPassword = "s3cret"
+)
+
+func newFakeLRS(t *testing.T) *fakeLRS {
+	t.Helper()
+	l := &fakeLRS{statements: map[string]Statement{}}
+	l.srv = httptest.NewServer(http.HandlerFunc(l.serve))
+	t.Cleanup(l.srv.Close)
+	return l
+}
+
+func (l *fakeLRS) serve(w http.ResponseWriter, r *http.Request) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.posts++
+	if l.down || l.failNext > 0 {
+		l.failNext--
+		http.Error(w, "mantenimiento", http.StatusServiceUnavailable)
+		return
+	}
+	if user, pass, ok := r.BasicAuth(); !ok || user != lrsUser || pass != lrsPassword {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPost || r.URL.Path != "/xapi/statements" || r.Header.Get("X-Experience-API-Version") != Version {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	var batch []Statement
+	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// El lote es atómico: si algo falla no se guarda ninguno
+	for _, st := range batch {
+		if _, dup := l.statements[st.ID]; dup {
+			http.Error(w, "conflict "+st.ID, http.StatusConflict)
+			return
+		}
+		if l.reject != nil && l.reject(st) {
+			http.Error(w, "invalid statement "+st.ID, http.StatusBadRequest)
+			return
+		}
+	}
+	ids := []string{}
+	for _, st := range batch {
+		l.statements[st.ID] = st
+		ids = append(ids, st.ID)
+	}
+	json.NewEncoder(w).Encode(ids)
+}
+
+func (l *fakeLRS) stored() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.statements)
+}
+
+func (l *fakeLRS) sent() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.posts
+}
+
+func (l *fakeLRS) set(f func(l *fakeLRS)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f(l)
+}
+
+// newSender conecta un envío al LRS falso con un buffer en una carpeta
+// temporal y sin envíos por tiempo: los tests llaman a Flush
+func newSender(t *testing.T, l *fakeLRS, dir string) *Sender {
+	t.Helper()
+	s, err := NewSender(Config{
+		Endpoint: l.srv.URL + "/xapi/", Username: lrsUser, Password: lrsPassword,
+		SpoolDir: dir, BatchSize: 10, FlushInterval: time.Hour, Retries: 2, Backoff: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+	return s
+}
@@ -0,0 +1,344 @@
+package xapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config es la conexión con el LRS y el comportamiento del envío
+type Config struct {
+	Endpoint string // URL base del LRS, p. ej. https://lrs.example.com/xapi/
+	Username string
+	Password string
+
+	SpoolDir      string        // carpeta del buffer en disco
+	MaxSpool      int64         // tamaño máximo del buffer (bytes)
+	BatchSize     int           // statements por pedido
+	FlushInterval time.Duration // espera máxima antes de enviar un lote incompleto
+	Retries       int           // reintentos de un lote antes de pasarlo al disco
+	Backoff       time.Duration // espera antes del primer reintento; se duplica en cada uno
+	Client        *http.Client
+}
+
+const queueSize = 1000
+
+// Sender envía los statements en segundo plano: Emit nunca bloquea la
+// petición del alumno
+type Sender struct {
+	cfg   Config
+	url   string
+	queue chan Statement
+	flush chan chan struct{}
+	stop  chan struct{}
+	done  chan struct{}
+	spool *spool
+
+	// offline se activa cuando un lote agota los reintentos: mientras dure,
+	// los lotes van directo al disco y sólo se prueba el LRS con el buffer
+	offline bool
+}
+
+func NewSender(cfg Config) (*Sender, error) {
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("XAPI_ENDPOINT no es una URL válida: %q", cfg.Endpoint)
+	}
+	if cfg.SpoolDir == "" {
+		cfg.SpoolDir = "./xapi-spool"
+	}
+	if cfg.MaxSpool <= 0 {
+		cfg.MaxSpool = 100 << 20
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 50
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 10 * time.Second
+	}
+	if cfg.Retries < 0 {
+		cfg.Retries = 0
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = time.Second
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 15 * time.Second}
+	}
+	sp, err := openSpool(cfg.SpoolDir, cfg.MaxSpool)
+	if err != nil {
+		return nil, fmt.Errorf("buffer de xAPI: %w", err)
+	}
+	s := &Sender{
+		cfg:   cfg,
+		url:   strings.TrimRight(cfg.Endpoint, "/") + "/statements",
+		queue: make(chan Statement, queueSize),
+		flush: make(chan chan struct{}),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+		spool: sp,
+	}
+	go s.run()
+	return s, nil
+}
+
+// Emit encola el statement. Si la cola está llena (el LRS va lento) el
+// statement va directo al disco.
+func (s *Sender) Emit(st Statement) {
+	select {
+	case s.queue <- st:
+	default:
+		if err := s.spool.write([]Statement{st}); err != nil {
+			fmt.Println("❌ Statement xAPI perdido:", err)
+		}
+	}
+}
+
+// Flush envía lo encolado y prueba el buffer en disco; vuelve al terminar
+func (s *Sender) Flush() {
+	ack := make(chan struct{})
+	select {
+	case s.flush <- ack:
+		<-ack
+	case <-s.done:
+	}
+}
+
+// Close envía lo que queda en la cola (o lo guarda en disco) y detiene el envío
+func (s *Sender) Close() {
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+	<-s.done
+}
+
+func (s *Sender) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	s.replay()
+	var batch []Statement
+	for {
+		select {
+		case st := <-s.queue:
+			if batch = append(batch, st); len(batch) >= s.cfg.BatchSize {
+				s.deliver(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			batch = s.sendAll(batch)
+			s.replay()
+		case ack := <-s.flush:
+			batch = s.sendAll(batch)
+			s.replay()
+			close(ack)
+		case <-s.stop:
+			s.sendAll(batch)
+			return
+		}
+	}
+}
+
+// sendAll vacía la cola en lotes completos y envía también el último, incompleto
+func (s *Sender) sendAll(batch []Statement) []Statement {
+	for {
+		select {
+		case st := <-s.queue:
+			if batch = append(batch, st); len(batch) >= s.cfg.BatchSize {
+				s.deliver(batch)
+				batch = nil
+			}
+		default:
+			if len(batch) > 0 {
+				s.deliver(batch)
+			}
+			return nil
+		}
+	}
+}
+
+// deliver envía un lote con reintentos y, si el LRS no contesta, lo guarda
+// en disco. Si el LRS rechaza el lote se parte en dos para no perder los
+// statements válidos por uno malo.
+func (s *Sender) deliver(batch []Statement) {
+	if s.offline {
+		s.save(batch)
+		return
+	}
+	err := s.send(batch)
+	for i := 0; err != nil && retryable(err) && i < s.cfg.Retries; i++ {
+		time.Sleep(s.cfg.Backoff << i)
+		err = s.send(batch)
+	}
+	switch {
+	case err == nil:
+	case retryable(err):
+		fmt.Println("⚠️ LRS no disponible, los statements quedan en disco:", err)
+		s.offline = true
+		s.save(batch)
+	case len(batch) > 1:
+		s.deliver(batch[:len(batch)/2])
+		s.deliver(batch[len(batch)/2:])
+	default:
+		fmt.Printf("❌ El LRS rechazó el statement %s: %v\n", batch[0].ID, err)
+	}
+}
+
+func (s *Sender) save(batch []Statement) {
+	if err := s.spool.write(batch); err != nil {
+		fmt.Printf("❌ %d statements xAPI perdidos: %v\n", len(batch), err)
+	}
+}
+
+// replay reenvía los lotes del disco, del más antiguo al más nuevo, hasta
+// que se acaban o el LRS vuelve a fallar
+func (s *Sender) replay() {
+	for {
+		name, batch, err := s.spool.oldest()
+		if err != nil {
+			fmt.Println("❌ No se pudo leer el buffer de xAPI:", err)
+			return
+		}
+		if name == "" {
+			s.offline = false
+			return
+		}
+		err = s.send(batch)
+		switch {
+		case err == nil:
+			s.spool.remove(name)
+		case retryable(err):
+			s.offline = true
+			return
+		default:
+			fmt.Printf("❌ El LRS rechazó el lote %s, queda como .rejected: %v\n", name, err)
+			s.spool.reject(name)
+		}
+	}
+}
+
+// lrsError es una respuesta del LRS que no es de éxito
+type lrsError struct {
+	status int
+	body   string
+}
+
+func (e *lrsError) Error() string {
+	return "LRS " + strconv.Itoa(e.status) + ": " + e.body
+}
+
+// retryable distingue un LRS caído o saturado (se reintenta) de un lote
+// inválido (reenviarlo daría el mismo error)
+func retryable(err error) bool {
+	var le *lrsError
+	if errors.As(err, &le) {
+		return le.status == http.StatusRequestTimeout || le.status == http.StatusTooManyRequests || le.status >= 500
+	}
+	return true
+}
+
+// send envía el lote. Un 409 sólo dice que algún id ya estaba en el LRS y
+// el LRS descarta el lote entero, así que se reenvía statement a statement
+// y se dan por entregados los que el LRS ya tenía.
+func (s *Sender) send(batch []Statement) error {
+	err := s.post(batch)
+	if !conflict(err) {
+		return err
+	}
+	if len(batch) == 1 {
+		return nil
+	}
+	for _, st := range batch {
+		if err := s.send([]Statement{st}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func conflict(err error) bool {
+	var le *lrsError
+	return errors.As(err, &le) && le.status == http.StatusConflict
+}
+
+func (s *Sender) post(batch []Statement) error {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return &lrsError{status: http.StatusBadRequest, body: err.Error()}
+	}
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Experience-API-Version", Version)
+	if s.cfg.Username != "" || s.cfg.Password != "" {
+		req.SetBasicAuth(s.cfg.Username, s.cfg.Password)
+	}
+	resp, err := s.cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	switch {
+	case resp.StatusCode < 300:
+		return nil
+	default:
+		return &lrsError{status: resp.StatusCode, body: strings.TrimSpace(string(msg))}
+	}
+}
+
+// --- ENVÍO GLOBAL ---
+
+var std *Sender
+
+// Start activa el envío si XAPI_ENDPOINT está configurado. Sin LRS, Emit no
+// hace nada.
+func Start() error {
+	endpoint := strings.TrimSpace(os.Getenv("XAPI_ENDPOINT"))
+	if endpoint == "" {
+		return nil
+	}
+	cfg := Config{
+		Endpoint: endpoint,
+		Username: os.Getenv("XAPI_USERNAME"),
+		Password: os.Getenv("XAPI_PASSWORD"),
+		SpoolDir: os.Getenv("XAPI_SPOOL_DIR"),
+		Retries:  3,
+	}
+	if d, err := time.ParseDuration(os.Getenv("XAPI_FLUSH_INTERVAL")); err == nil && d > 0 {
+		cfg.FlushInterval = d
+	}
+	if n, err := strconv.Atoi(os.Getenv("XAPI_BATCH_SIZE")); err == nil && n > 0 {
+		cfg.BatchSize = n
+	}
+	s, err := NewSender(cfg)
+	if err != nil {
+		return err
+	}
+	std = s
+	fmt.Println("📡 Envío de statements xAPI activo")
+	return nil
+}
+
+// Emit encola los statements en el envío global
+func Emit(statements ...Statement) {
+	if std == nil {
+		return
+	}
+	for _, st := range statements {
+		std.Emit(st)
+	}
+}
@@ -0,0 +1,134 @@
+package xapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errSpoolFull evita llenar el disco si el LRS pasa días caído
+var errSpoolFull = errors.New("el buffer en disco de xAPI está lleno")
+
+// spool guarda en disco los lotes que no se pudieron enviar, un archivo
+// JSON por lote. Los nombres empiezan por la hora, así que el orden
+// alfabético es el de llegada. Los lotes que el LRS rechaza quedan como
+// .rejected para revisarlos a mano.
+type spool struct {
+	dir string
+	max int64
+
+	mu   sync.Mutex
+	size int64
+	seq  int
+}
+
+func openSpool(dir string, max int64) (*spool, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	sp := &spool{dir: dir, max: max}
+	names, err := sp.files()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			sp.size += info.Size()
+		}
+	}
+	return sp, nil
+}
+
+func (sp *spool) write(batch []Statement) error {
+	data, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	if sp.size+int64(len(data)) > sp.max {
+		return errSpoolFull
+	}
+	sp.seq++
+	name := fmt.Sprintf("%019d-%06d.json", time.Now().UnixNano(), sp.seq)
+	// Se escribe aparte y se renombra: un corte a medias no deja un lote roto
+	tmp := filepath.Join(sp.dir, name+".tmp")
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(sp.dir, name)); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	sp.size += int64(len(data))
+	return nil
+}
+
+// files son los lotes pendientes, del más antiguo al más nuevo
+func (sp *spool) files() ([]string, error) {
+	entries, err := os.ReadDir(sp.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+			names = append(names, e.Name())
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// oldest devuelve el lote más antiguo; name vacío si no queda ninguno. Un
+// archivo ilegible se aparta como rechazado y se pasa al siguiente.
+func (sp *spool) oldest() (string, []Statement, error) {
+	names, err := sp.files()
+	if err != nil {
+		return "", nil, err
+	}
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(sp.dir, name))
+		if err != nil {
+			return "", nil, err
+		}
+		var batch []Statement
+		if err := json.Unmarshal(data, &batch); err != nil {
+			fmt.Println("⚠️ Lote de xAPI ilegible en disco:", name, err)
+			sp.reject(name)
+			continue
+		}
+		return name, batch, nil
+	}
+	return "", nil, nil
+}
+
+func (sp *spool) remove(name string) {
+	sp.forget(name, func(path string) error { return os.Remove(path) })
+}
+
+func (sp *spool) reject(name string) {
+	sp.forget(name, func(path string) error { return os.Rename(path, path+".rejected") })
+}
+
+// forget saca el lote de la cola (borrándolo o apartándolo) y descuenta su tamaño
+func (sp *spool) forget(name string, move func(path string) error) {
+	path := filepath.Join(sp.dir, name)
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	if err := move(path); err != nil {
+		fmt.Println("❌ No se pudo sacar el lote de xAPI del disco:", err)
+		return
+	}
+	sp.mu.Lock()
+	sp.size -= info.Size()
+	sp.mu.Unlock()
+}
@@ -0,0 +1,253 @@
+// Package xapi emite statements xAPI (Experience API 1.0.3) con lo que hacen
+// los alumnos (responder quizzes, rendir exámenes, completar lecciones,
+// repasar frases y abrir recursos) y los envía a un Learning Record Store en
+// segundo plano, por lotes y con reintentos, guardándolos en disco mientras
+// el LRS no responde.
+package xapi
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"fmt"
+	"strings"
+	"time"
+
+	"english-at-lima-cms/internal/models"
+	"english-at-lima-cms/internal/seo"
+)
+
+// Version es la versión de xAPI que se declara en cada pedido al LRS
+const Version = "1.0.3"
+
+const (
+	platform     = "English at Lima"
+	activityType = "http://adlnet.gov/expapi/activities/"
+)
+
+type Statement struct {
+	ID        string   `json:"id"`
+	Actor     Agent    `json:"actor"`
+	Verb      Verb     `json:"verb"`
+	Object    Activity `json:"object"`
+	Result    *Result  `json:"result,omitempty"`
+	Context   *Context `json:"context,omitempty"`
+	Timestamp string   `json:"timestamp"`
+}
+
+// Agent identifica al alumno con una cuenta del sitio (o de la plataforma
+// LTI), nunca con su correo: los alumnos del sitio son anónimos
+type Agent struct {
+	ObjectType string  `json:"objectType"`
+	Account    Account `json:"account"`
+}
+
+type Account struct {
+	HomePage string `json:"homePage"`
+	Name     string `json:"name"`
+}
+
+type Verb struct {
+	ID      string            `json:"id"`
+	Display map[string]string `json:"display"`
+}
+
+type Activity struct {
+	ObjectType string      `json:"objectType"`
+	ID         string      `json:"id"`
+	Definition *Definition `json:"definition,omitempty"`
+}
+
+type Definition struct {
+	Name            map[string]string `json:"name,omitempty"`
+	Type            string            `json:"type,omitempty"`
+	InteractionType string            `json:"interactionType,omitempty"`
+}
+
+type Result struct {
+	Success    *bool  `json:"success,omitempty"`
+	Completion *bool  `json:"completion,omitempty"`
+	Response   string `json:"response,omitempty"`
+	Score      *Score `json:"score,omitempty"`
+	Duration   string `json:"duration,omitempty"`
+}
+
+type Score struct {
+	Scaled float64 `json:"scaled"`
+	Raw    float64 `json:"raw"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+}
+
+type Context struct {
+	Registration      string             `json:"registration,omitempty"`
+	Platform          string             `json:"platform"`
+	Language          string             `json:"language"`
+	ContextActivities *ContextActivities `json:"contextActivities,omitempty"`
+}
+
+type ContextActivities struct {
+	Parent []Activity `json:"parent"`
+}
+
+// Verbos del vocabulario de ADL
+var (
+	Attempted   = verb("attempted", "intentó")
+	Answered    = verb("answered", "respondió")
+	Completed   = verb("completed", "completó")
+	Passed      = verb("passed", "aprobó")
+	Failed      = verb("failed", "desaprobó")
+	Experienced = verb("experienced", "vio")
+)
+
+func verb(name, es string) Verb {
+	return Verb{ID: "http://adlnet.gov/expapi/verbs/" + name, Display: map[string]string{"en-US": name, "es": es}}
+}
+
+// Student es el alumno anónimo del sitio (la cookie de progreso)
+func Student(id string) Agent {
+	return Agent{ObjectType: "Agent", Account: Account{HomePage: seo.SiteURL(), Name: id}}
+}
+
+// LTIUser es el alumno lanzado desde un LMS: su cuenta es la de la plataforma
+func LTIUser(issuer, subject string) Agent {
+	return Agent{ObjectType: "Agent", Account: Account{HomePage: issuer, Name: subject}}
+}
+
+func activity(path, kind string, name map[string]string) Activity {
+	return Activity{ObjectType: "Activity", ID: seo.SiteURL() + path, Definition: &Definition{Name: name, Type: kind}}
+}
+
+// interactionTypes traduce los tipos de quiz a los de cmi.interaction
+var interactionTypes = map[string]string{
+	models.QuizSingle:    "choice",
+	models.QuizMultiple:  "choice",
+	models.QuizTrueFalse: "choice",
+	models.QuizFill:      "fill-in",
+	models.QuizOrdering:  "sequencing",
+}
+
+func quizActivity(q models.Quiz) Activity {
+	a := activity(seo.QuizPath(q.ID), activityType+"cmi.interaction", map[string]string{"en": q.Question})
+	a.Definition.InteractionType = interactionTypes[q.Type]
+	if a.Definition.InteractionType == "" {
+		a.Definition.InteractionType = "choice"
+	}
+	return a
+}
+
+func examActivity(e models.Exam) Activity {
+	return activity(fmt.Sprintf("/examenes/%d", e.ID), activityType+"assessment", map[string]string{"es": e.Title})
+}
+
+// New arma un statement con id propio (el LRS lo usa para descartar los
+// repetidos cuando un lote se reenvía) y la hora actual
+func New(actor Agent, v Verb, object Activity) Statement {
+	return Statement{
+		ID: newUUID(), Actor: actor, Verb: v, Object: object,
+		Context:   &Context{Platform: platform, Language: "es"},
+		Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
+	}
+}
+
+// QuizAnswered es la respuesta corregida de un quiz suelto (página pública,
+// lección o LMS)
+func QuizAnswered(actor Agent, q models.Quiz, response []string, correct bool) Statement {
+	st := New(actor, Answered, quizActivity(q))
+	st.Result = &Result{Success: &correct, Response: strings.Join(response, "[,]")}
+	return st
+}
+
+// ExamAttempted marca el inicio de un intento de examen
+func ExamAttempted(actor Agent, e models.Exam, a models.ExamAttempt) Statement {
+	st := New(actor, Attempted, examActivity(e))
+	st.Context.Registration = registration(a)
+	return st
+}
+
+// ExamAnswered es una respuesta guardada durante el examen: la corrección
+// llega con ExamGraded, al cerrar el intento
+func ExamAnswered(actor Agent, e models.Exam, a models.ExamAttempt, quizID int, response []string) Statement {
+	st := New(actor, Answered, Activity{ObjectType: "Activity", ID: seo.SiteURL() + seo.QuizPath(quizID)})
+	st.Result = &Result{Response: strings.Join(response, "[,]")}
+	st.Context.Registration = registration(a)
+	st.Context.ContextActivities = &ContextActivities{Parent: []Activity{examActivity(e)}}
+	return st
+}
+
+// ExamGraded son el completed con la nota y el passed o failed de un intento
+// cerrado, fechados a la hora de entrega
+func ExamGraded(actor Agent, e models.Exam, a models.ExamAttempt) []Statement {
+	done := true
+	result := &Result{
+		Completion: &done, Success: &a.Passed,
+		Score: &Score{Scaled: float64(a.Score) / 100, Raw: float64(a.Score), Min: 0, Max: 100},
+	}
+	started, err1 := time.Parse(time.RFC3339, a.StartedAt)
+	submitted, err2 := time.Parse(time.RFC3339, a.SubmittedAt)
+	if err1 == nil && err2 == nil && !submitted.Before(started) {
+		result.Duration = fmt.Sprintf("PT%dS", int(submitted.Sub(started).Seconds()))
+	}
+
+	outcome := Failed
+	if a.Passed {
+		outcome = Passed
+	}
+	var out []Statement
+	for _, v := range []Verb{Completed, outcome} {
+		st := New(actor, v, examActivity(e))
+		st.Result = result
+		st.Context.Registration = registration(a)
+		if err2 == nil {
+			st.Timestamp = submitted.UTC().Format(time.RFC3339Nano)
+		}
+		out = append(out, st)
+	}
+	return out
+}
+
+// LessonCompleted es la lección que el alumno marcó como terminada
+func LessonCompleted(actor Agent, co models.Course, l models.Lesson) Statement {
+	st := New(actor, Completed, activity(fmt.Sprintf("%s/lecciones/%d", seo.CoursePath(co.ID), l.ID), activityType+"lesson", map[string]string{"es": l.Title}))
+	st.Context.ContextActivities = &ContextActivities{Parent: []Activity{
+		activity(seo.CoursePath(co.ID), activityType+"course", map[string]string{"es": co.Title}),
+	}}
+	return st
+}
+
+// SentenceReviewed es el repaso de una frase, la tarjeta del sitio
+func SentenceReviewed(actor Agent, s models.Sentence) Statement {
+	return New(actor, Experienced, activity(seo.SentencePath(s.ID), seo.SiteURL()+"/xapi/activities/flashcard", map[string]string{"en": s.English}))
+}
+
+// ResourceOpened es un recurso abierto desde el sitio
+func ResourceOpened(actor Agent, r models.Resource) Statement {
+	kind := activityType + "link"
+	switch r.Type {
+	case "audio", "video":
+		kind = activityType + "media"
+	case "pdf":
+		kind = activityType + "file"
+	}
+	return New(actor, Experienced, activity(seo.ResourcePath(r.ID), kind, map[string]string{"es": r.Title}))
+}
+
+// registration agrupa los statements de un intento: es un UUID derivado del
+// intento (versión 5), así que es el mismo en todos los procesos
+func registration(a models.ExamAttempt) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("%s/examenes/%d/intentos/%d", seo.SiteURL(), a.ExamID, a.ID)))
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	return formatUUID(sum[:16])
+}
+
+func newUUID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return formatUUID(b)
+}
+
+func formatUUID(b []byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,235 @@
+package xapi
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"english-at-lima-cms/internal/models"
+)
+
+func emitN(s *Sender, n int) {
+	for i := 0; i < n; i++ {
+		s.Emit(New(Student("a1b2"), Experienced, Activity{ObjectType: "Activity", ID: "https://example.com/frases/1"}))
+	}
+}
+
+func spooled(t *testing.T, dir string, suffix string) int {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*"+suffix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(files)
+}
+
+func TestBatching(t *testing.T) {
+	l := newFakeLRS(t)
+	s := newSender(t, l, t.TempDir())
+	emitN(s, 25)
+	s.Flush()
+	if l.stored() != 25 || l.sent() != 3 {
+		t.Fatalf("guardados %d en %d pedidos, esperaba 25 en 3", l.stored(), l.sent())
+	}
+}
+
+func TestRetry(t *testing.T) {
+	l := newFakeLRS(t)
+	dir := t.TempDir()
+	s := newSender(t, l, dir)
+	l.set(func(l *fakeLRS) { l.failNext = 2 })
+	emitN(s, 5)
+	s.Flush()
+	if l.stored() != 5 || l.sent() != 3 || spooled(t, dir, ".json") != 0 {
+		t.Fatalf("guardados %d en %d pedidos, %d lotes en disco", l.stored(), l.sent(), spooled(t, dir, ".json"))
+	}
+}
+
+func TestSpoolWhileDown(t *testing.T) {
+	l := newFakeLRS(t)
+	dir := t.TempDir()
+	l.set(func(l *fakeLRS) { l.down = true })
+
+	s := newSender(t, l, dir)
+	emitN(s, 7)
+	s.Flush()
+	if l.stored() != 0 || spooled(t, dir, ".json") != 1 {
+		t.Fatalf("con el LRS caído el lote debería ir al disco: %d guardados, %d lotes", l.stored(), spooled(t, dir, ".json"))
+	}
+	// Ya se sabe que está caído: el siguiente lote no gasta reintentos
+	posts := l.sent()
+	emitN(s, 3)
+	s.Flush()
+	if got := l.sent() - posts; got != 1 || spooled(t, dir, ".json") != 2 {
+		t.Fatalf("esperaba sólo el sondeo del buffer, hubo %d pedidos y %d lotes", got, spooled(t, dir, ".json"))
+	}
+	s.Close()
+
+	// El proceso se reinicia con el LRS ya disponible: el buffer se reenvía
+	l.set(func(l *fakeLRS) { l.down = false })
+	s = newSender(t, l, dir)
+	emitN(s, 1)
+	s.Flush()
+	if l.stored() != 11 || spooled(t, dir, ".json") != 0 {
+		t.Fatalf("guardados %d, quedan %d lotes en disco", l.stored(), spooled(t, dir, ".json"))
+	}
+}
+
+func TestRejected(t *testing.T) {
+	l := newFakeLRS(t)
+	dir := t.TempDir()
+	s := newSender(t, l, dir)
+	bad := New(Student("a1b2"), Failed, Activity{ObjectType: "Activity", ID: "https://example.com/examenes/1"})
+	l.set(func(l *fakeLRS) { l.reject = func(st Statement) bool { return st.ID == bad.ID } })
+
+	emitN(s, 4)
+	s.Emit(bad)
+	emitN(s, 3)
+	s.Flush()
+	if l.stored() != 7 || spooled(t, dir, ".json") != 0 {
+		t.Fatalf("el statement inválido no debería arrastrar al resto: %d guardados", l.stored())
+	}
+
+	// Un lote del disco que el LRS rechaza queda apartado
+	if err := s.spool.write([]Statement{bad}); err != nil {
+		t.Fatal(err)
+	}
+	s.Flush()
+	if spooled(t, dir, ".json") != 0 || spooled(t, dir, ".rejected") != 1 {
+		t.Fatalf("el lote rechazado debería quedar como .rejected")
+	}
+}
+
+func TestResentBatch(t *testing.T) {
+	// El LRS guardó el lote pero la respuesta se perdió: al reenviarlo
+	// contesta 409 y el lote se da por entregado
+	l := newFakeLRS(t)
+	dir := t.TempDir()
+	s := newSender(t, l, dir)
+	st := New(Student("a1b2"), Completed, Activity{ObjectType: "Activity", ID: "https://example.com/cursos/1/lecciones/2"})
+	s.Emit(st)
+	s.Flush()
+	if err := s.spool.write([]Statement{st}); err != nil {
+		t.Fatal(err)
+	}
+	s.Flush()
+	if l.stored() != 1 || spooled(t, dir, ".json") != 0 || spooled(t, dir, ".rejected") != 0 {
+		t.Fatalf("el reenvío debería darse por entregado")
+	}
+}
+
+func TestBatchWithStoredStatement(t *testing.T) {
+	// Un statement del lote ya estaba en el LRS: el 409 no debe llevarse por
+	// delante a los demás
+	l := newFakeLRS(t)
+	dir := t.TempDir()
+	s := newSender(t, l, dir)
+	st := New(Student("a1b2"), Completed, Activity{ObjectType: "Activity", ID: "https://example.com/cursos/1/lecciones/2"})
+	s.Emit(st)
+	s.Flush()
+
+	s.Emit(st)
+	emitN(s, 3)
+	s.Flush()
+	if l.stored() != 4 || spooled(t, dir, ".json") != 0 || spooled(t, dir, ".rejected") != 0 {
+		t.Fatalf("guardados %d, esperaba 4: el conflicto de un id no debería perder el resto", l.stored())
+	}
+
+	// Lo mismo con un lote del disco
+	if err := s.spool.write(append([]Statement{st}, New(Student("c3d4"), Experienced, Activity{ObjectType: "Activity", ID: "https://example.com/frases/2"}))); err != nil {
+		t.Fatal(err)
+	}
+	s.Flush()
+	if l.stored() != 5 || spooled(t, dir, ".json") != 0 || spooled(t, dir, ".rejected") != 0 {
+		t.Fatalf("guardados %d, esperaba 5 tras reenviar el buffer", l.stored())
+	}
+}
+
+func TestSpoolFull(t *testing.T) {
+	sp, err := openSpool(t.TempDir(), 600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := New(Student("a1b2"), Experienced, Activity{ObjectType: "Activity", ID: "https://example.com/recursos/1"})
+	if err := sp.write([]Statement{st}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sp.write([]Statement{st, st}); err != errSpoolFull {
+		t.Fatalf("esperaba buffer lleno, obtuvo %v", err)
+	}
+	name, _, _ := sp.oldest()
+	sp.remove(name)
+	if err := sp.write([]Statement{st}); err != nil {
+		t.Fatalf("al vaciarse debería volver a aceptar: %v", err)
+	}
+}
+
+func TestNewSenderRejectsEndpoint(t *testing.T) {
+	for _, endpoint := range []string{"", "lrs.example.com/xapi", "ftp://lrs.example.com/"} {
+		if _, err := NewSender(Config{Endpoint: endpoint, SpoolDir: t.TempDir()}); err == nil {
+			t.Errorf("%q debería rechazarse", endpoint)
+		}
+	}
+}
+
+var reUUID = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[45][0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestStatements(t *testing.T) {
+	t.Setenv("SITE_URL", "https://english.example.com/")
+	student := Student("a1b2")
+
+	q := models.Quiz{ID: 12, Question: "She ___ to work", Type: models.QuizFill}
+	st := QuizAnswered(student, q, []string{"goes"}, true)
+	if st.Actor.Account.HomePage != "https://english.example.com" || st.Verb.ID != "http://adlnet.gov/expapi/verbs/answered" ||
+		st.Object.ID != "https://english.example.com/quizzes/12" || st.Object.Definition.InteractionType != "fill-in" ||
+		!*st.Result.Success || st.Result.Response != "goes" || !reUUID.MatchString(st.ID) {
+		t.Fatalf("statement de quiz inesperado: %+v", st)
+	}
+
+	e := models.Exam{ID: 3, Title: "Final A2"}
+	a := models.ExamAttempt{ID: 40, ExamID: 3, Score: 75, Passed: true, StartedAt: "2026-10-19T12:00:00Z", SubmittedAt: "2026-10-19T12:20:30Z"}
+	graded := ExamGraded(student, e, a)
+	if len(graded) != 2 || graded[0].Verb.ID != Completed.ID || graded[1].Verb.ID != Passed.ID {
+		t.Fatalf("verbos del examen: %+v", graded)
+	}
+	r := graded[1].Result
+	if r.Score.Scaled != 0.75 || r.Score.Raw != 75 || r.Duration != "PT1230S" || !*r.Completion ||
+		graded[1].Timestamp != "2026-10-19T12:20:30Z" || graded[1].Object.ID != "https://english.example.com/examenes/3" {
+		t.Fatalf("resultado del examen inesperado: %+v %s", r, graded[1].Timestamp)
+	}
+	a.Passed = false
+	if ExamGraded(student, e, a)[1].Verb.ID != Failed.ID {
+		t.Error("un intento desaprobado debería emitir failed")
+	}
+
+	// Todos los statements del intento comparten la registration, y es un UUID
+	reg := ExamAttempted(student, e, a).Context.Registration
+	answered := ExamAnswered(student, e, a, 12, []string{"goes"})
+	if !reUUID.MatchString(reg) || reg != answered.Context.Registration || reg != graded[0].Context.Registration {
+		t.Fatalf("registration inestable: %q %q", reg, answered.Context.Registration)
+	}
+	if other := ExamAttempted(student, e, models.ExamAttempt{ID: 41, ExamID: 3}); other.Context.Registration == reg {
+		t.Error("otro intento debería tener otra registration")
+	}
+	if answered.Context.ContextActivities.Parent[0].ID != "https://english.example.com/examenes/3" || answered.Result.Success != nil {
+		t.Errorf("respuesta de examen inesperada: %+v", answered)
+	}
+
+	for typ, want := range map[string]string{"video": "media", "pdf": "file", "link": "link"} {
+		if got := ResourceOpened(student, models.Resource{ID: 5, Type: typ}).Object.Definition.Type; !strings.HasSuffix(got, "/"+want) {
+			t.Errorf("recurso %s: tipo %s", typ, got)
+		}
+	}
+
+	lti := QuizAnswered(LTIUser("https://moodle.example.com", "student-42"), q, []string{"go"}, false)
+	raw, _ := json.Marshal(lti)
+	var decoded map[string]interface{}
+	json.Unmarshal(raw, &decoded)
+	actor := decoded["actor"].(map[string]interface{})["account"].(map[string]interface{})
+	result := decoded["result"].(map[string]interface{})
+	if actor["homePage"] != "https://moodle.example.com" || actor["name"] != "student-42" || result["success"] != false {
+		t.Errorf("JSON inesperado: %s", raw)
+	}
+}
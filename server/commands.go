@@ -0,0 +1,98 @@
+package main
+
+import (
+	"archive/zip"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"english-at-lima-cms/internal/backup"
+	"english-at-lima-cms/internal/repository"
+)
+
+// runCommand ejecuta los subcomandos de mantenimiento:
+//
+//	server backup [-o archivo.zip]
+//	server restore [-mode empty|merge] [-check] archivo.zip
+func runCommand(args []string) int {
+	switch args[0] {
+	case "backup":
+		return runBackup(args[1:])
+	case "restore":
+		return runRestore(args[1:])
+	}
+	fmt.Fprintf(os.Stderr, "comando desconocido: %s (use backup o restore)\n", args[0])
+	return 2
+}
+
+func runBackup(args []string) int {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	out := fs.String("o", "backup-"+time.Now().Format("20060102-150405")+".zip", "archivo de salida")
+	if fs.Parse(args) != nil {
+		return 2
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		return 1
+	}
+	m, err := backup.Write(f, repository.Backup{}, time.Now())
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(*out)
+		fmt.Fprintln(os.Stderr, "❌ Copia fallida:", err)
+		return 1
+	}
+	for _, t := range m.Tables {
+		fmt.Printf("  %-20s %7d filas\n", t.Name, t.Rows)
+	}
+	fmt.Println("✅ Copia guardada en", *out)
+	return 0
+}
+
+func runRestore(args []string) int {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	mode := fs.String("mode", backup.ModeEmpty, "empty (base vacía) o merge (sobre datos existentes)")
+	check := fs.Bool("check", false, "sólo verificar la copia, sin escribir nada")
+	if fs.Parse(args) != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "uso: restore [-mode empty|merge] [-check] archivo.zip")
+		return 2
+	}
+
+	zr, err := zip.OpenReader(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌ No se pudo abrir la copia:", err)
+		return 1
+	}
+	defer zr.Close()
+
+	if *check {
+		m, err := backup.Verify(&zr.Reader)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "❌", err)
+			return 1
+		}
+		fmt.Printf("✅ Copia válida del %s (esquema %d, %d tablas)\n", m.CreatedAt, m.Schema, len(m.Tables))
+		return 0
+	}
+
+	written, err := backup.Restore(&zr.Reader, repository.Backup{}, *mode)
+	for _, t := range backup.Tables {
+		if n, ok := written[t.Name]; ok {
+			fmt.Printf("  %-20s %7d filas\n", t.Name, n)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Restauración fallida:", err)
+		return 1
+	}
+	fmt.Println("✅ Restauración completa")
+	return 0
+}
@@ -1,38 +1,84 @@
 package main
 
 import (
+	"english-at-lima-cms/internal/embed"
+	"english-at-lima-cms/internal/exam"
 	"english-at-lima-cms/internal/handlers"
+	"english-at-lima-cms/internal/linkcheck"
 
 	"english-at-lima-cms/internal/middleware"
+	"english-at-lima-cms/internal/publish"
+	"english-at-lima-cms/internal/storage"
+	"english-at-lima-cms/internal/xapi"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-contrib/sessions/cookie"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"strings"
 )
 
 func main() {
+	_ = godotenv.Load()
+
+	// Subcomandos de mantenimiento (backup, restore): no arrancan el servidor
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
+	}
 
 	// Sincronizar IPs baneadas antes de aceptar peticiones
 	middleware.LoadBlacklist()
 	middleware.StartBlacklistCleaner() // Inicia el cronómetro de limpieza
+	embed.StartViewFlusher()           // Vuelca las visitas de los widgets
+	linkcheck.StartScheduler()         // Revisa los enlaces de los recursos
+	exam.StartCloser()                 // Califica los exámenes vencidos
+	publish.StartPublisher()           // Publica y retira el contenido programado
+	// Envía los statements de aprendizaje al LRS, si hay uno configurado
+	if err := xapi.Start(); err != nil {
+		fmt.Println("⚠️ xAPI desactivado:", err)
+	}
+
+	media, err := storage.New()
+	if err != nil {
+		fmt.Println("⚠️ Subida de archivos desactivada:", err)
+	} else {
+		handlers.InitMedia(media)
+	}
+	if err := handlers.InitLTI(); err != nil {
+		fmt.Println("⚠️ LTI desactivado:", err)
+	}
 
-	_ = godotenv.Load()
 	r := setupRouter()
 
+	r.SetFuncMap(handlers.TemplateFuncs())
+	r.LoadHTMLGlob("templates/*.html")
+	r.Static("/static", "./static")
+
+	_ = r.Run(":8080")
+}
+
+func setupRouter() *gin.Engine {
+	r := gin.Default()
+
+	// Los middlewares globales deben registrarse ANTES que las rutas:
+	// gin solo los aplica a las rutas declaradas después de r.Use
+
 	// El IPBlocker debe ser el PRIMER middleware de todos
 	r.Use(middleware.IPBlocker())
-	r.Use(middleware.GlobalSecurityInspector()) // El que revisa XSS
 
 	// LIMITAR TODAS LAS PETICIONES A 2MB
 	// Si alguien intenta enviar más que esto (como un texto infinito),
 	// el servidor le cierra la puerta en la cara automáticamente.
-	r.Use(MaxAllowedSize(2 << 20))
+	// Sólo los formularios del panel con archivo admiten más (AllowUploads).
+	// Va antes del inspector, que lee el cuerpo entero.
+	r.Use(MaxAllowedSize(middleware.MaxInspectedBody))
+	r.Use(middleware.GlobalSecurityInspector()) // El que revisa XSS
 
-	// 1. Configurar el almacenamiento de la sesión (usa una clave secreta)
+	// Configurar el almacenamiento de la sesión (usa una clave secreta)
 	store := cookie.NewStore([]byte(os.Getenv("SESSION_SECRET")))
 	store.Options(sessions.Options{
 		Path:     "/",
@@ -43,18 +89,6 @@ func main() {
 	})
 	r.Use(sessions.Sessions("mysession", store))
 
-	// 2. Aplicar el middleware de sesiones a TODO el servidor
-	r.Use(sessions.Sessions("mysession", store))
-
-	r.LoadHTMLGlob("templates/*.html")
-	r.Static("/static", "./static")
-
-	_ = r.Run(":8080")
-}
-
-func setupRouter() *gin.Engine {
-	r := gin.Default()
-
 	// Rutas públicas
 	r.GET("/login", handlers.ShowLogin)
 	r.POST("/login", middleware.RateLimiter(), handlers.Login)
@@ -64,53 +98,181 @@ func setupRouter() *gin.Engine {
     c.JSON(200, gin.H{"message": "Servidor funcionando"})
 })
 
+	// Páginas públicas indexables (SEO)
+	r.GET("/public", handlers.ShowPublicHome)
+	r.GET("/frases/:id", handlers.ShowSentencePage)
+	r.POST("/frases/:id/repaso", handlers.ReviewSentence)
+	r.GET("/quizzes/:id", handlers.ShowQuizPage)
+	r.POST("/quizzes/:id/responder", handlers.AnswerQuiz)
+	r.GET("/quizzes/:id/pistas/:n", handlers.QuizHint)
+	r.GET("/recursos/:id", handlers.ShowResourcePage)
+	r.GET("/recursos/:id/abrir", handlers.OpenResource)
+	r.GET("/cursos", handlers.ShowCourses)
+	r.GET("/cursos/:id", handlers.ShowCourse)
+	r.GET("/cursos/:id/lecciones/:lesson", handlers.ShowLesson)
+	r.POST("/cursos/:id/lecciones/:lesson/completar", handlers.CompleteLesson)
+	r.GET("/examenes", handlers.ShowExams)
+	r.GET("/examenes/:id", handlers.ShowExam)
+	r.POST("/examenes/:id/intentos", handlers.StartAttempt)
+	r.GET("/examenes/:id/intentos/:attempt", handlers.ShowAttempt)
+	r.POST("/examenes/:id/intentos/:attempt/respuestas", handlers.AnswerQuestion)
+	r.POST("/examenes/:id/intentos/:attempt/entregar", handlers.SubmitAttempt)
+	r.POST("/examenes/:id/intentos/:attempt/pista", handlers.RequestHint)
+	r.GET("/sitemap.xml", handlers.Sitemap)
+	r.GET("/sitemap/:page", handlers.SitemapPage)
+	r.GET("/robots.txt", handlers.Robots)
+
+	// API pública de solo lectura (JSON)
+	r.GET("/api/frases", handlers.APISentences)
+	r.GET("/api/frases/:id", handlers.APISentence)
+
+	// Widgets incrustables para colegios socios
+	r.GET("/embed/widget.js", handlers.EmbedScript)
+	r.GET("/embed/frame/:key", handlers.EmbedFrame)
+
+	// Herramienta LTI 1.3 para los LMS de los clientes
+	r.GET("/lti/jwks", handlers.LTIJWKS)
+	r.GET("/lti/login", handlers.LTILogin)
+	r.POST("/lti/login", handlers.LTILogin)
+	r.POST("/lti/launch", handlers.LTILaunch)
+	r.POST("/lti/responder", handlers.LTIAnswer)
+	r.POST("/lti/deep-link", handlers.LTIDeepLink)
+
+	// Archivos subidos (los privados requieren URL firmada)
+	r.GET("/media/*key", handlers.ServeMedia)
+
 	// Grupo Admin PROTEGIDO
 	admin := r.Group("/admin")
 	admin.Use(middleware.AuthRequired())
 	{
-		admin.GET("/", func(c *gin.Context) {
-			c.HTML(http.StatusOK, "admin.html", nil)
-		})
+		admin.GET("/", handlers.GetAdminDashboard)
 
 		admin.GET("/logout", handlers.Logout)
 
 		admin.GET("/logs", handlers.GetAuditLogs)
 
+		// Vista previa de borradores tal como la verá el alumno
+		admin.GET("/preview/:type/:id", handlers.PreviewContent)
+		admin.POST("/markdown/preview", handlers.PreviewMarkdown)
+
+		// Revisión editorial y avisos del equipo
+		admin.GET("/review", handlers.ReviewQueue)
+		admin.GET("/review/:type/:id", handlers.ShowReview)
+		admin.POST("/review/:type/:id/submit", handlers.SubmitForReview)
+		admin.POST("/review/:type/:id/approve", handlers.ApproveContent)
+		admin.POST("/review/:type/:id/changes", handlers.RequestChanges)
+		admin.GET("/notifications", handlers.GetNotifications)
+		admin.GET("/notifications/count", handlers.NotificationCount)
+
 		// --- MÓDULO FRASES ---
 		admin.GET("/sentences", handlers.GetSentences)
 		admin.GET("/sentences/new", handlers.NewSentenceForm)
-		admin.POST("/sentences/save", handlers.SaveSentence)
-		admin.POST("/sentences/update/:id", handlers.UpdateSentence)
+		admin.POST("/sentences/save", AllowUploads(), handlers.SaveSentence)
+		admin.GET("/sentences/edit/:id", handlers.EditSentenceForm)
+		admin.POST("/sentences/update/:id", AllowUploads(), handlers.UpdateSentence)
 		admin.DELETE("/sentences/:id", handlers.DeleteSentence)
 
 		// --- MÓDULO RECURSOS ---
 		admin.GET("/resources", handlers.GetResources)
 		admin.GET("/resources/new", handlers.NewResourceForm)
-		admin.POST("/resources/save", handlers.SaveResource)
-		admin.POST("/resources/update/:id", handlers.UpdateResource)
+		admin.POST("/resources/save", AllowUploads(), handlers.SaveResource)
+		admin.GET("/resources/edit/:id", handlers.EditResourceForm)
+		admin.POST("/resources/update/:id", AllowUploads(), handlers.UpdateResource)
 		admin.DELETE("/resources/:id", handlers.DeleteResource)
+		admin.GET("/resources/unfurl", handlers.UnfurlResource)
+		admin.GET("/resources/broken", handlers.GetBrokenLinks)
+		admin.POST("/resources/linkcheck", handlers.RunLinkCheck)
+		admin.POST("/resources/:id/fix", handlers.FixResourceURL)
+		admin.POST("/resources/:id/archive", handlers.ArchiveResource)
 
 		// --- MÓDULO QUIZZES ---
 		admin.GET("/quizzes", handlers.GetQuizzes)
 		admin.GET("/quizzes/new", handlers.NewQuizForm)
+		admin.GET("/quizzes/fields", handlers.QuizFields)
+		admin.GET("/quizzes/edit/:id", handlers.EditQuizForm)
 		admin.POST("/quizzes/save", handlers.SaveQuiz)
 		admin.POST("/quizzes/update/:id", handlers.UpdateQuiz)
+		admin.POST("/quizzes/:id/feedback", handlers.UpdateQuizFeedback)
 		admin.DELETE("/quizzes/:id", handlers.DeleteQuiz)
 
+		// --- CURSOS ---
+		admin.GET("/courses", handlers.GetCourses)
+		admin.POST("/courses/save", handlers.SaveCourse)
+		admin.GET("/courses/picker", handlers.CoursePicker)
+		admin.GET("/courses/:id", handlers.GetCourseEditor)
+		admin.POST("/courses/:id/publish", handlers.PublishCourse)
+		admin.DELETE("/courses/:id", handlers.DeleteCourse)
+		admin.POST("/courses/:id/units", handlers.AddUnit)
+		admin.POST("/courses/:id/units/:node/lessons", handlers.AddLesson)
+		admin.POST("/courses/:id/lessons/:node/items", handlers.AddLessonItem)
+		admin.POST("/courses/:id/reorder/:kind", handlers.ReorderCourse)
+		admin.DELETE("/courses/:id/:kind/:node", handlers.DeleteCourseNode)
+
+		// --- EXÁMENES ---
+		admin.GET("/exams", handlers.GetExams)
+		admin.POST("/exams/save", handlers.SaveExam)
+		admin.GET("/exams/:id", handlers.GetExamResults)
+		admin.POST("/exams/:id/publish", handlers.PublishExam)
+		admin.DELETE("/exams/:id", handlers.DeleteExam)
+
+		// --- ETIQUETAS ---
+		admin.GET("/tags", handlers.GetTags)
+		admin.POST("/tags/rename", handlers.RenameTag)
+		admin.POST("/tags/merge", handlers.MergeTag)
+		admin.POST("/tags/delete", handlers.DeleteTag)
+
+		// --- WIDGETS PARA SOCIOS ---
+		admin.GET("/embeds", handlers.GetEmbedKeys)
+		admin.POST("/embeds/save", handlers.SaveEmbedKey)
+		admin.DELETE("/embeds/:id", handlers.DeleteEmbedKey)
+
+		// --- PLATAFORMAS LTI ---
+		admin.GET("/lti", handlers.GetLTIPlatforms)
+		admin.POST("/lti/save", handlers.SaveLTIPlatform)
+		admin.DELETE("/lti/:id", handlers.DeleteLTIPlatform)
+
+		// --- CASI DUPLICADOS ---
+		admin.GET("/duplicates", handlers.GetDuplicates)
+		admin.POST("/duplicates/:type/merge", handlers.MergeDuplicates)
+
+		// --- GENERADOR DE HUECOS ---
+		admin.GET("/cloze", handlers.GetClozeDrafts)
+		admin.POST("/cloze/sentence", handlers.GenerateCloze)
+		admin.POST("/cloze/batch", handlers.GenerateClozeBatch)
+
 		// Búsqueda y Stats
 		admin.GET("/search", handlers.GlobalSearch)
 		admin.GET("/stats", handlers.GetStats)
 
-		// EXPORTAR A CSV
-		admin.GET("/sentences/export", handlers.ExportSentencesCSV)
-		admin.GET("/quizzes/export", handlers.ExportQuizzesCSV)
+		// IMPORTAR CSV / XLSX (y bancos de preguntas de Moodle)
+		admin.GET("/import", handlers.GetImport)
+		admin.POST("/import/upload", handlers.UploadImport)
+		admin.POST("/import/preview", handlers.PreviewImport)
+		admin.POST("/import/commit", handlers.CommitImport)
+		admin.POST("/moodle/import", handlers.ImportMoodle)
+
+		// COPIA DE SEGURIDAD (la restauración va por el comando restore)
+		admin.GET("/backup", handlers.DownloadBackup)
+
+		// EXPORTAR (CSV, XLSX o JSON con los filtros del listado)
+		admin.GET("/export/:type", handlers.ExportContent)
+		admin.GET("/sentences/export", handlers.LegacyExport("sentence"))
+		admin.GET("/quizzes/export", handlers.LegacyExport("quiz"))
+		admin.GET("/anki", handlers.ExportAnki)
+		admin.GET("/moodle", handlers.ExportMoodle)
+		admin.GET("/lms", handlers.ExportLMS)
 	}
 
 	return r
 }
 
+// rawBodyKey guarda el cuerpo sin límite para que AllowUploads pueda
+// ampliarlo en las rutas de subida
+const rawBodyKey = "rawBody"
+
 func MaxAllowedSize(limit int64) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		c.Set(rawBodyKey, c.Request.Body)
 		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
 		c.Next()
 
@@ -125,3 +287,16 @@ func MaxAllowedSize(limit int64) gin.HandlerFunc {
 		}
 	}
 }
+
+// AllowUploads sube el límite de MaxAllowedSize al de storage.Rules en las
+// rutas del panel que reciben archivos. Va después de AuthRequired: un
+// visitante sin sesión nunca puede enviar más de 2MB.
+func AllowUploads() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		raw, ok := c.Get(rawBodyKey)
+		if body, isBody := raw.(io.ReadCloser); ok && isBody && strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+			c.Request.Body = http.MaxBytesReader(c.Writer, body, storage.MaxUploadBytes())
+		}
+		c.Next()
+	}
+}
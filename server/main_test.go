@@ -0,0 +1,45 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// endlessBody es un cuerpo de tamaño arbitrario que cuenta lo que se leyó
+type endlessBody struct {
+	left, read int
+}
+
+func (b *endlessBody) Read(p []byte) (int, error) {
+	if b.left == 0 {
+		return 0, io.EOF
+	}
+	n := min(len(p), b.left)
+	for i := range p[:n] {
+		p[i] = 'a'
+	}
+	b.left -= n
+	b.read += n
+	return n, nil
+}
+
+func TestOversizedBodyRejected(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := setupRouter()
+
+	body := &endlessBody{left: 64 << 20}
+	req := httptest.NewRequest(http.MethodPost, "/login", body)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("un cuerpo de 64MB debería rechazarse con 413, obtuvo %d", w.Code)
+	}
+	if body.read > 3<<20 {
+		t.Errorf("se leyeron %d bytes antes de cortar", body.read)
+	}
+}